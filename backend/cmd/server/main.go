@@ -62,6 +62,8 @@ func main() {
 			log.Fatalf("Graceful shutdown failed: %v", err)
 		}
 
+		application.Close()
+
 		log.Println("Server stopped gracefully")
 	}
 }
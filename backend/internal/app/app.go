@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"log"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -10,16 +11,28 @@ import (
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/handlers"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/mail"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/oidc"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
 	mongorepo "github.com/Yeras1kAITU/aitu_fanpage/internal/repository/mongo"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/search"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
 )
 
 type App struct {
-	cfg      *config.Config
-	router   *chi.Mux
-	db       *mongo.Database
-	handlers *handlers.HandlerContainer
+	cfg         *config.Config
+	router      *chi.Mux
+	db          *mongo.Database
+	handlers    *handlers.HandlerContainer
+	viewCounter *service.ViewCounter
+	searchIndex *search.InvertedIndex
+	suggest     *service.SuggestService
+	related     *service.RelatedService
+	savedSearch *service.SavedSearchService
+	dataExport  *service.DataExportService
+	deletion    *service.AccountDeletionService
 }
 
 func New(cfg *config.Config) (*App, error) {
@@ -38,36 +51,162 @@ func New(cfg *config.Config) (*App, error) {
 	var postRepo repository.PostRepository = mongorepo.NewPostRepository(db)
 	var userRepo repository.UserRepository = mongorepo.NewUserRepository(db)
 	var commentRepo repository.CommentRepository = mongorepo.NewCommentRepository(db)
+	var followRepo repository.FollowRepository = mongorepo.NewFollowRepository(db)
+	var bookmarkRepo repository.BookmarkRepository = mongorepo.NewBookmarkRepository(db)
+	var likeRepo repository.LikeRepository = mongorepo.NewLikeRepository(db)
+	var bookmarkCollectionRepo repository.BookmarkCollectionRepository = mongorepo.NewBookmarkCollectionRepository(db)
+	var eventRepo repository.EventRepository = mongorepo.NewEventRepository(db)
+	var eventAttendeeRepo repository.EventAttendeeRepository = mongorepo.NewEventAttendeeRepository(db)
+	var searchQueryRepo repository.SearchQueryRepository = mongorepo.NewSearchQueryRepository(db)
+	var refreshTokenRepo repository.RefreshTokenRepository = mongorepo.NewRefreshTokenRepository(db)
+	var sessionRepo repository.SessionRepository = mongorepo.NewSessionRepository(db)
+	var passwordResetRepo repository.PasswordResetRepository = mongorepo.NewPasswordResetRepository(db)
+	var inviteRepo repository.InviteRepository = mongorepo.NewInviteRepository(db)
+	var twoFactorChallengeRepo repository.TwoFactorChallengeRepository = mongorepo.NewTwoFactorChallengeRepository(db)
+	var notificationRepo repository.NotificationRepository = mongorepo.NewNotificationRepository(db)
+	var savedSearchRepo repository.SavedSearchRepository = mongorepo.NewSavedSearchRepository(db)
+	var loginThrottleRepo repository.LoginThrottleRepository = mongorepo.NewLoginThrottleRepository(db)
+	var accessTokenRepo repository.AccessTokenRepository = mongorepo.NewAccessTokenRepository(db)
+	var oidcLoginRepo repository.OIDCLoginRepository = mongorepo.NewOIDCLoginRepository(db)
+	var auditLogRepo repository.AuditLogRepository = mongorepo.NewAuditLogRepository(db)
+	var rolePermissionRepo repository.RolePermissionRepository = mongorepo.NewRolePermissionRepository(db)
+	var dataExportRepo repository.DataExportRepository = mongorepo.NewDataExportRepository(db)
+
+	policyEngine := policy.NewEngine(auditLogRepo, cfg.Policy.AuditLogRetention)
+	if cfg.Policy.File != "" {
+		if err := policyEngine.LoadFile(cfg.Policy.File); err != nil {
+			return nil, err
+		}
+	}
+	policyService := service.NewPolicyService(policyEngine, rolePermissionRepo, auditLogRepo, userRepo)
+	if err := policyService.Load(); err != nil {
+		log.Printf("Stored role permissions not loaded: %v", err)
+	}
+
+	viewCounter := service.NewViewCounter(postRepo, cfg.Views)
+
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := mailer.(mail.NopMailer); ok && cfg.Mail.RequireVerification {
+		log.Printf("MAIL_DRIVER is none: verification emails are dropped and new users cannot post until verified")
+	}
 
-	authService := service.NewAuthService(userRepo, cfg)
-	postService := service.NewPostService(postRepo, userRepo, commentRepo)
-	commentService := service.NewCommentService(commentRepo, userRepo, postRepo)
 	fileService := service.NewFileService(cfg.Upload)
-	userService := service.NewUserService(userRepo)
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, sessionRepo, passwordResetRepo, inviteRepo, twoFactorChallengeRepo, loginThrottleRepo, accessTokenRepo, policyEngine, fileService, mailer, cfg)
+	if cfg.OIDC.Enabled() {
+		authService.SetOIDC(oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+		}), oidcLoginRepo)
+	}
+	postService := service.NewPostService(postRepo, userRepo, commentRepo, followRepo, bookmarkRepo, likeRepo, viewCounter, policyEngine)
+	commentService := service.NewCommentService(commentRepo, userRepo, postRepo, policyEngine)
+	userService := service.NewUserService(userRepo, policyEngine)
+	userService.AddListener(authService)
+	followService := service.NewFollowService(followRepo, userRepo)
+	profileService := service.NewProfileService(userRepo, postRepo, commentRepo, likeRepo, followRepo, eventRepo, eventAttendeeRepo)
+	bookmarkService := service.NewBookmarkService(bookmarkRepo, bookmarkCollectionRepo, postRepo)
+	analyzer := search.TextAnalyzer{}
+	relatedService := service.NewRelatedService(postRepo, likeRepo, analyzer)
+	postService.AddListener(relatedService)
+	searchService := service.NewSearchService(postRepo, commentRepo, userRepo, eventRepo, analyzer)
+	var searchIndex *search.InvertedIndex
+	if cfg.Search.Backend == "memory" {
+		searchIndex = search.NewInvertedIndex(analyzer)
+		searchService.SetIndex(searchIndex)
+		loaded := false
+		if cfg.Search.SnapshotPath != "" {
+			if err := searchIndex.LoadSnapshot(cfg.Search.SnapshotPath, cfg.Search.SnapshotMaxAge); err != nil {
+				log.Printf("Search snapshot not loaded: %v", err)
+			} else {
+				loaded = true
+			}
+		}
+		if !loaded {
+			if err := searchService.BuildIndex(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	postService.AddListener(searchService)
+	commentService.AddListener(searchService)
+	authService.AddListener(searchService)
+	userService.AddListener(searchService)
+
+	suggestService := service.NewSuggestService(postRepo, userRepo, searchQueryRepo, cfg.Search)
+	if err := suggestService.Build(); err != nil {
+		log.Printf("Search suggestions not fully built: %v", err)
+	}
+	postService.AddListener(suggestService)
+	authService.AddListener(suggestService)
+	userService.AddListener(suggestService)
+	searchService.SetRecorder(suggestService)
+
+	notificationService := service.NewNotificationService(notificationRepo, userRepo)
+	notificationService.AddSender(service.NewEmailNotificationSender(mailer, cfg))
+	userService.AddListener(notificationService)
+	savedSearchService := service.NewSavedSearchService(savedSearchRepo, postRepo, searchService, notificationService)
+	postService.AddListener(savedSearchService)
+	userService.AddListener(savedSearchService)
+	dataExportService := service.NewDataExportService(dataExportRepo, userRepo, postRepo, commentRepo, likeRepo, fileService, notificationService, cfg)
+	userService.AddListener(dataExportService)
+	accountDeletionService := service.NewAccountDeletionService(userRepo, postRepo, commentRepo, likeRepo, followRepo, bookmarkRepo, bookmarkCollectionRepo, eventAttendeeRepo, sessionRepo, refreshTokenRepo, accessTokenRepo, userService, postService, commentService, fileService, notificationService, cfg)
 
 	authHandler := handlers.NewAuthHandler(authService)
-	postHandler := handlers.NewPostHandler(postService, fileService)
+	postHandler := handlers.NewPostHandler(postService, fileService, bookmarkService, relatedService, searchService)
+	searchHandler := handlers.NewSearchHandler(searchService, suggestService, bookmarkService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService, savedSearchService)
 	commentHandler := handlers.NewCommentHandler(commentService)
 	userHandler := handlers.NewUserHandler(userService)
-	adminHandler := handlers.NewAdminHandler(postService, userService, commentService)
-	mediaHandler := handlers.NewMediaHandler(fileService, postService)
+	adminHandler := handlers.NewAdminHandler(postService, userService, commentService, authService, policyService, accountDeletionService)
+	mediaHandler := handlers.NewMediaHandler(fileService, postService, userService)
 	analyticsHandler := handlers.NewAnalyticsHandler(postService)
+	followHandler := handlers.NewFollowHandler(followService)
+	bookmarkHandler := handlers.NewBookmarkHandler(bookmarkService)
+	shareHandler := handlers.NewShareHandler(postService, cfg.Server.PublicURL)
+	profileHandler := handlers.NewProfileHandler(profileService, bookmarkService)
+	dataExportHandler := handlers.NewDataExportHandler(dataExportService)
+	accountDeletionHandler := handlers.NewAccountDeletionHandler(accountDeletionService)
 
 	app := &App{
-		cfg: cfg,
-		db:  db,
+		cfg:         cfg,
+		db:          db,
+		viewCounter: viewCounter,
+		searchIndex: searchIndex,
+		suggest:     suggestService,
+		related:     relatedService,
+		savedSearch: savedSearchService,
+		dataExport:  dataExportService,
+		deletion:    accountDeletionService,
 		handlers: &handlers.HandlerContainer{
-			Auth:      authHandler,
-			Post:      postHandler,
-			Comment:   commentHandler,
-			User:      userHandler,
-			Admin:     adminHandler,
-			Media:     mediaHandler,
-			Analytics: analyticsHandler,
+			Auth:         authHandler,
+			Post:         postHandler,
+			Comment:      commentHandler,
+			User:         userHandler,
+			Admin:        adminHandler,
+			Media:        mediaHandler,
+			Analytics:    analyticsHandler,
+			Follow:       followHandler,
+			Bookmark:     bookmarkHandler,
+			Share:        shareHandler,
+			Search:       searchHandler,
+			Notification: notificationHandler,
+			Profile:      profileHandler,
+			DataExport:   dataExportHandler,
+			Deletion:     accountDeletionHandler,
 		},
 	}
 
-	app.setupRouter(authService.GetTokenAuth())
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	app.setupRouter(authService.GetTokenAuth(), trustedProxies)
 
 	return app, nil
 }
@@ -81,3 +220,20 @@ func (a *App) healthCheck(w http.ResponseWriter, r *http.Request) {
 func (a *App) Router() *chi.Mux {
 	return a.router
 }
+
+// Close flushes buffered counters and query logs, stops background workers
+// and snapshots the search index. Call it after the HTTP server has stopped.
+func (a *App) Close() {
+	a.viewCounter.Stop()
+	a.suggest.Stop()
+	a.related.Stop()
+	a.savedSearch.Stop()
+	a.dataExport.Stop()
+	a.deletion.Stop()
+
+	if a.searchIndex != nil && a.cfg.Search.SnapshotPath != "" {
+		if err := a.searchIndex.SaveSnapshot(a.cfg.Search.SnapshotPath); err != nil {
+			log.Printf("Failed to save search snapshot: %v", err)
+		}
+	}
+}
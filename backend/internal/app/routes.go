@@ -1,6 +1,7 @@
 package app
 
 import (
+	"net"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -9,10 +10,11 @@ import (
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func (a *App) setupRouter(authMid *middleware.AuthMiddleware) {
+func (a *App) setupRouter(authMid *middleware.AuthMiddleware, trustedProxies []*net.IPNet) {
 	r := chi.NewRouter()
 
 	allowedOrigins := []string{"*"}
@@ -30,6 +32,7 @@ func (a *App) setupRouter(authMid *middleware.AuthMiddleware) {
 	}))
 
 	// Middleware
+	r.Use(middleware.TrustedProxies(trustedProxies))
 	r.Use(chimiddleware.Logger)
 	r.Use(chimiddleware.Recoverer)
 	r.Use(chimiddleware.CleanPath)
@@ -46,35 +49,59 @@ func (a *App) setupRouter(authMid *middleware.AuthMiddleware) {
 	r.Route("/api", func(r chi.Router) {
 
 		r.Post("/auth/register", a.handlers.Auth.Register)
+		r.Get("/auth/register/policy", a.handlers.Auth.GetRegistrationPolicy)
 		r.Post("/auth/login", a.handlers.Auth.Login)
+		r.Post("/auth/login/2fa", a.handlers.Auth.CompleteTwoFactorLogin)
+		r.Get("/auth/oidc", a.handlers.Auth.GetOIDCConfig)
+		r.Get("/auth/oidc/login", a.handlers.Auth.BeginOIDCLogin)
+		r.Get("/auth/oidc/callback", a.handlers.Auth.OIDCCallback)
+		r.Post("/auth/refresh", a.handlers.Auth.Refresh)
+		r.Post("/auth/logout", a.handlers.Auth.Logout)
+		r.Post("/auth/verify-email", a.handlers.Auth.VerifyEmail)
+		r.Post("/auth/password/forgot", a.handlers.Auth.ForgotPassword)
+		r.Post("/auth/password/reset", a.handlers.Auth.ResetPassword)
+
+		// Public post listings; the optional token only personalizes
+		// fields such as bookmarked_by_me. Personal access tokens reach
+		// a route only through RequireScope.
+		r.Group(func(r chi.Router) {
+			r.Use(authMid.Authenticator)
+			r.Use(authMid.RequireScope(models.ScopePostsRead))
+			r.Get("/posts/pinned", a.handlers.Post.GetPinnedPosts)
+			r.Get("/posts/featured", a.handlers.Post.GetFeaturedPosts)
+			r.Get("/posts/popular", a.handlers.Post.GetPopularPosts)
+			r.Get("/posts/search", a.handlers.Post.SearchPosts)
+			r.Get("/search", a.handlers.Search.Search)
+			r.Get("/search/suggest", a.handlers.Search.Suggest)
+			r.Get("/posts/feed", a.handlers.Post.GetFeed)
+			r.Get("/posts", a.handlers.Post.GetPosts)
+		})
 
-		r.Get("/posts/pinned", a.handlers.Post.GetPinnedPosts)
-		r.Get("/posts/featured", a.handlers.Post.GetFeaturedPosts)
-		r.Get("/posts/popular", a.handlers.Post.GetPopularPosts)
 		r.Get("/posts/categories/stats", a.handlers.Post.GetCategoriesStats)
-		r.Get("/posts/search", a.handlers.Post.SearchPosts)
-		r.Get("/posts/feed", a.handlers.Post.GetFeed)
-
-		r.Get("/posts", a.handlers.Post.GetPosts)
+		r.Get("/users/{id}/avatar", a.handlers.Auth.GetAvatar)
+		r.Get("/exports/{id}/download", a.handlers.DataExport.DownloadExport)
 
 		r.Route("/posts/{id}", func(r chi.Router) {
-			r.Get("/", a.handlers.Post.GetPost)
+			r.With(authMid.Authenticator, authMid.RequireScope(models.ScopePostsRead)).Get("/", a.handlers.Post.GetPost)
+			r.With(authMid.Authenticator, authMid.RequireScope(models.ScopePostsRead)).Get("/related", a.handlers.Post.GetRelatedPosts)
 			r.Group(func(r chi.Router) {
 				r.Use(authMid.Authenticator)
-				r.Put("/", a.handlers.Post.UpdatePost)
-				r.Delete("/", a.handlers.Post.DeletePost)
+				r.With(authMid.RequireScope(models.ScopePostsWrite)).Put("/", a.handlers.Post.UpdatePost)
+				r.With(authMid.RequireScope(models.ScopePostsWrite)).Delete("/", a.handlers.Post.DeletePost)
 				r.Post("/like", a.handlers.Post.LikePost)
 				r.Delete("/like", a.handlers.Post.UnlikePost)
+				r.Post("/bookmark", a.handlers.Bookmark.AddBookmark)
+				r.Delete("/bookmark", a.handlers.Bookmark.RemoveBookmark)
 				r.Get("/likes", a.handlers.Post.GetPostLikes)
-				r.Post("/pin", a.handlers.Post.PinPost)
-				r.Delete("/pin", a.handlers.Post.UnpinPost)
-				r.Post("/feature", a.handlers.Post.FeaturePost)
-				r.Delete("/feature", a.handlers.Post.UnfeaturePost)
+				r.With(authMid.RequirePermission(policy.PostsPin)).Post("/pin", a.handlers.Post.PinPost)
+				r.With(authMid.RequirePermission(policy.PostsPin)).Delete("/pin", a.handlers.Post.UnpinPost)
+				r.With(authMid.RequirePermission(policy.PostsFeature)).Post("/feature", a.handlers.Post.FeaturePost)
+				r.With(authMid.RequirePermission(policy.PostsFeature)).Delete("/feature", a.handlers.Post.UnfeaturePost)
 
 				r.Route("/comments", func(r chi.Router) {
-					r.Post("/", a.handlers.Comment.CreateComment)
-					r.Get("/", a.handlers.Comment.GetComments)
-					r.Get("/count", a.handlers.Comment.GetCommentCount)
+					r.With(authMid.RequireScope(models.ScopeCommentsWrite)).Post("/", a.handlers.Comment.CreateComment)
+					r.With(authMid.RequireScope(models.ScopePostsRead)).Get("/", a.handlers.Comment.GetComments)
+					r.With(authMid.RequireScope(models.ScopePostsRead)).Get("/count", a.handlers.Comment.GetCommentCount)
 				})
 			})
 		})
@@ -82,33 +109,98 @@ func (a *App) setupRouter(authMid *middleware.AuthMiddleware) {
 		r.Group(func(r chi.Router) {
 			r.Use(authMid.Authenticator)
 
-			r.Post("/posts", a.handlers.Post.CreatePost)
+			r.With(authMid.RequireScope(models.ScopePostsWrite)).Post("/posts", a.handlers.Post.CreatePost)
 
 			r.Route("/users", func(r chi.Router) {
-				r.Get("/me", a.handlers.Auth.GetProfile)
+				r.With(authMid.RequireScope(models.ScopeProfileRead)).Get("/me", a.handlers.Auth.GetProfile)
 				r.Put("/me", a.handlers.Auth.UpdateProfile)
 				r.Put("/me/password", a.handlers.Auth.ChangePassword)
+				r.Post("/me/verify-email/resend", a.handlers.Auth.ResendVerification)
+				r.Get("/me/sessions", a.handlers.Auth.GetSessions)
+				r.Delete("/me/sessions", a.handlers.Auth.RevokeAllSessions)
+				r.Delete("/me/sessions/{sessionId}", a.handlers.Auth.RevokeSession)
+				r.Get("/me/2fa", a.handlers.Auth.GetTwoFactorStatus)
+				r.Post("/me/2fa/setup", a.handlers.Auth.BeginTwoFactorSetup)
+				r.Post("/me/2fa/enable", a.handlers.Auth.EnableTwoFactor)
+				r.Post("/me/2fa/disable", a.handlers.Auth.DisableTwoFactor)
+				r.Post("/me/2fa/recovery-codes", a.handlers.Auth.RegenerateRecoveryCodes)
+				r.Post("/me/avatar", a.handlers.Auth.UploadAvatar)
+				r.Delete("/me/avatar", a.handlers.Auth.DeleteAvatar)
+				r.Get("/me/tokens", a.handlers.Auth.GetAccessTokens)
+				r.Post("/me/tokens", a.handlers.Auth.CreateAccessToken)
+				r.Delete("/me/tokens/{tokenId}", a.handlers.Auth.RevokeAccessToken)
+				r.Get("/me/bookmarks", a.handlers.Bookmark.GetBookmarks)
+				r.Put("/me/bookmarks/{postId}", a.handlers.Bookmark.MoveBookmark)
+				r.Get("/me/bookmarks/collections", a.handlers.Bookmark.GetCollections)
+				r.Post("/me/bookmarks/collections", a.handlers.Bookmark.CreateCollection)
+				r.Put("/me/bookmarks/collections/{collectionId}", a.handlers.Bookmark.UpdateCollection)
+				r.Delete("/me/bookmarks/collections/{collectionId}", a.handlers.Bookmark.DeleteCollection)
+				r.Get("/me/subscriptions", a.handlers.Follow.GetSubscriptions)
+				r.Post("/me/subscriptions/categories/{category}", a.handlers.Follow.SubscribeCategory)
+				r.Delete("/me/subscriptions/categories/{category}", a.handlers.Follow.UnsubscribeCategory)
+				r.Post("/me/subscriptions/tags/{tag}", a.handlers.Follow.SubscribeTag)
+				r.Delete("/me/subscriptions/tags/{tag}", a.handlers.Follow.UnsubscribeTag)
+				r.Get("/me/notifications", a.handlers.Notification.GetNotifications)
+				r.Post("/me/notifications/read-all", a.handlers.Notification.MarkAllRead)
+				r.Post("/me/notifications/{notificationId}/read", a.handlers.Notification.MarkRead)
+				r.Get("/me/notifications/channels", a.handlers.Notification.GetChannels)
+				r.Put("/me/notifications/channels/{channel}", a.handlers.Notification.SetChannel)
+				r.Get("/me/saved-searches", a.handlers.Notification.GetSavedSearches)
+				r.Post("/me/saved-searches", a.handlers.Notification.CreateSavedSearch)
+				r.Put("/me/saved-searches/{searchId}", a.handlers.Notification.UpdateSavedSearch)
+				r.Delete("/me/saved-searches/{searchId}", a.handlers.Notification.DeleteSavedSearch)
+				r.Get("/me/privacy", a.handlers.Profile.GetPrivacy)
+				r.Put("/me/privacy", a.handlers.Profile.UpdatePrivacy)
+				r.Post("/me/export", a.handlers.DataExport.RequestExport)
+				r.Get("/me/export", a.handlers.DataExport.GetExport)
+				r.Get("/me/deletion", a.handlers.Deletion.GetDeletion)
+				r.Post("/me/deletion", a.handlers.Deletion.ScheduleDeletion)
+				r.Put("/me/deletion", a.handlers.Deletion.UpdateDeletion)
+				r.Delete("/me/deletion", a.handlers.Deletion.CancelDeletion)
 				r.Get("/{id}", a.handlers.User.GetUserProfile)
+				// Public profile pages; the optional token lets followers
+				// see sections shared with followers only
+				r.Get("/{id}/profile", a.handlers.Profile.GetProfile)
+				r.Get("/{id}/posts", a.handlers.Profile.GetPosts)
+				r.Get("/{id}/comments", a.handlers.Profile.GetComments)
+				r.Get("/{id}/events", a.handlers.Profile.GetEvents)
+				r.Get("/{id}/activity", a.handlers.Profile.GetActivity)
 				r.Get("/{id}/stats", a.handlers.User.GetUserStats)
+				r.Post("/{id}/follow", a.handlers.Follow.FollowUser)
+				r.Delete("/{id}/follow", a.handlers.Follow.UnfollowUser)
+				r.Get("/{id}/followers", a.handlers.Follow.GetFollowers)
+				r.Get("/{id}/following", a.handlers.Follow.GetFollowing)
 			})
 
 			r.Route("/comments/{id}", func(r chi.Router) {
-				r.Put("/", a.handlers.Comment.UpdateComment)
-				r.Delete("/", a.handlers.Comment.DeleteComment)
+				r.With(authMid.RequireScope(models.ScopeCommentsWrite)).Put("/", a.handlers.Comment.UpdateComment)
+				r.With(authMid.RequireScope(models.ScopeCommentsWrite)).Delete("/", a.handlers.Comment.DeleteComment)
 			})
 
-			r.Post("/media/upload", a.handlers.Media.UploadMedia)
-			r.Get("/media/info/{url}", a.handlers.Media.GetMediaInfo)
+			r.With(authMid.RequireScope(models.ScopePostsWrite)).Post("/media/upload", a.handlers.Media.UploadMedia)
+			r.With(authMid.RequireScope(models.ScopePostsRead)).Get("/media/info/{url}", a.handlers.Media.GetMediaInfo)
 
 			r.Route("/admin", func(r chi.Router) {
-				r.Use(authMid.RequireRole(models.RoleAdmin))
-				r.Get("/stats", a.handlers.Admin.GetSystemStats)
-				r.Get("/users", a.handlers.Admin.GetAllUsers)
-				r.Get("/users/search", a.handlers.Admin.SearchUsers)
+				r.With(authMid.RequirePermission(policy.AnalyticsView)).Get("/stats", a.handlers.Admin.GetSystemStats)
+				r.With(authMid.RequirePermission(policy.UsersView)).Get("/users", a.handlers.Admin.GetAllUsers)
+				r.With(authMid.RequirePermission(policy.UsersView)).Get("/users/search", a.handlers.Admin.SearchUsers)
+				r.With(authMid.RequirePermission(policy.AnalyticsView)).Get("/search/zero-results", a.handlers.Search.GetZeroResultQueries)
+				r.Group(func(r chi.Router) {
+					r.Use(authMid.RequirePermission(policy.InvitesManage))
+					r.Get("/invites", a.handlers.Admin.GetInvites)
+					r.Post("/invites", a.handlers.Admin.CreateInvite)
+					r.Delete("/invites/{inviteId}", a.handlers.Admin.DeleteInvite)
+				})
+				r.With(authMid.RequirePermission(policy.UsersUnlock)).Get("/login-locks", a.handlers.Admin.GetLoginLocks)
+				r.With(authMid.RequirePermission(policy.UsersUnlock)).Delete("/login-locks/{lockId}", a.handlers.Admin.ClearLoginLock)
+				r.With(authMid.RequirePermission(policy.RolesManage)).Get("/permissions", a.handlers.Admin.GetPermissions)
+				r.With(authMid.RequirePermission(policy.RolesManage)).Put("/permissions/{role}", a.handlers.Admin.UpdateRolePermissions)
+				r.With(authMid.RequirePermission(policy.AuditLogView)).Get("/audit-log", a.handlers.Admin.GetAuditLog)
 				r.Route("/users/{id}", func(r chi.Router) {
-					r.Put("/role", a.handlers.Admin.UpdateUserRole)
-					r.Put("/status/{action}", a.handlers.Admin.ToggleUserStatus)
-					r.Delete("/", a.handlers.Admin.DeleteUser)
+					r.With(authMid.RequirePermission(policy.UsersManage)).Put("/role", a.handlers.Admin.UpdateUserRole)
+					r.With(authMid.RequirePermission(policy.UsersManage)).Put("/status/{action}", a.handlers.Admin.ToggleUserStatus)
+					r.With(authMid.RequirePermission(policy.UsersReset2FA)).Delete("/2fa", a.handlers.Admin.ResetTwoFactor)
+					r.With(authMid.RequirePermission(policy.UsersDelete)).Delete("/", a.handlers.Admin.DeleteUser)
 				})
 			})
 		})
@@ -119,6 +211,7 @@ func (a *App) setupRouter(authMid *middleware.AuthMiddleware) {
 	})
 
 	r.Get("/uploads/*", a.handlers.Media.ServeMedia)
+	r.Get("/p/{id}", a.handlers.Share.SharePost)
 
 	fs := http.FileServer(http.Dir("./frontend"))
 
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -10,11 +12,23 @@ type Config struct {
 	Database DatabaseConfig
 	JWT      JWTConfig
 	Upload   UploadConfig
+	Views    ViewConfig
+	Search   SearchConfig
+	Mail     MailConfig
+	Register RegistrationConfig
+	Auth     AuthConfig
+	OIDC     OIDCConfig
+	Policy   PolicyConfig
+	Export   ExportConfig
 }
 
 type ServerConfig struct {
-	Port string
-	Env  string
+	Port      string
+	Env       string
+	PublicURL string
+	// TrustedProxies lists the reverse proxies, as addresses or CIDR
+	// ranges, whose forwarding headers give the client address.
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -22,8 +36,12 @@ type DatabaseConfig struct {
 	Name string
 }
 
+// JWTConfig sets the signing key and lifetimes. Access tokens are short
+// lived; refresh tokens are exchanged for new pairs until they expire.
 type JWTConfig struct {
-	SecretKey string
+	SecretKey       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
 }
 
 type UploadConfig struct {
@@ -38,6 +56,114 @@ type UploadConfig struct {
 	EnableThumbnails bool
 }
 
+type ViewConfig struct {
+	DedupWindow   time.Duration
+	FlushInterval time.Duration
+	BufferSize    int
+	HashSalt      string
+	BotUserAgents []string
+	// DedupMaxEntries bounds the viewers remembered for deduplication;
+	// past it views are counted without being remembered.
+	DedupMaxEntries int
+}
+
+// SearchConfig selects the search backend: "memory" for the in-process
+// index or "mongo" for the database text index.
+type SearchConfig struct {
+	Backend        string
+	SnapshotPath   string
+	SnapshotMaxAge time.Duration
+
+	QueryLogFlushInterval time.Duration
+	PopularQueryRefresh   time.Duration
+	PopularQueryWindow    time.Duration
+	// QueryLogSalt keys the hash that tells searchers apart in the log.
+	QueryLogSalt string
+}
+
+// MailConfig selects the mailer: "smtp", "file" to write messages into
+// OutboxDir, or "none". RequireVerification gates posting and commenting
+// on a confirmed email address.
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	OutboxDir    string
+
+	RequireVerification bool
+	VerificationTTL     time.Duration
+	PasswordResetTTL    time.Duration
+}
+
+// RegistrationConfig limits who may sign up. Domains match the address
+// domain or any subdomain of it. RoleDomains maps a domain to the role new
+// accounts from it get, e.g. "staff.astanait.edu.kz=moderator".
+type RegistrationConfig struct {
+	AllowedDomains []string
+	BlockedDomains []string
+	RoleDomains    map[string]string
+	InviteOnly     bool
+	InviteTTL      time.Duration
+}
+
+// AuthConfig tunes two-factor login and brute-force protection. Issuer is
+// the account label shown in authenticator apps. After BackoffAfter failed
+// logins for an email (IPBackoffAfter for an IP, which students may share)
+// each further attempt waits twice as long, from BackoffBase up to
+// BackoffMax; LockoutThreshold failures lock the account for
+// LockoutDuration. Failures older than FailureWindow are forgotten.
+type AuthConfig struct {
+	TwoFactorIssuer       string
+	TwoFactorChallengeTTL time.Duration
+
+	LoginBackoffAfter     int
+	LoginIPBackoffAfter   int
+	LoginBackoffBase      time.Duration
+	LoginBackoffMax       time.Duration
+	LoginLockoutThreshold int
+	LoginLockoutDuration  time.Duration
+	LoginFailureWindow    time.Duration
+
+	// DeletionGracePeriod is how long a user can cancel deleting their
+	// account.
+	DeletionGracePeriod time.Duration
+}
+
+// OIDCConfig enables single sign-on with an OpenID provider when Issuer is
+// set. RedirectURL defaults to the callback under PUBLIC_URL; with
+// AutoRegister off, only users who already have an account can sign in.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	ProviderName string
+	AutoRegister bool
+	LoginTTL     time.Duration
+}
+
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != ""
+}
+
+// PolicyConfig points at an optional JSON file that overrides the default
+// role permissions. Audit entries are kept for AuditLogRetention.
+type PolicyConfig struct {
+	File              string
+	AuditLogRetention time.Duration
+}
+
+// ExportConfig sets where personal data archives are built and how long
+// their download links work before the archives are deleted.
+type ExportConfig struct {
+	Dir     string
+	LinkTTL time.Duration
+}
+
 type ImageSize struct {
 	Name   string
 	Width  int
@@ -50,15 +176,19 @@ func Load() *Config {
 
 	return &Config{
 		Server: ServerConfig{
-			Port: port,
-			Env:  env,
+			Port:           port,
+			Env:            env,
+			PublicURL:      getEnv("PUBLIC_URL", ""),
+			TrustedProxies: parseList(getEnv("TRUSTED_PROXIES", "")),
 		},
 		Database: DatabaseConfig{
 			URI:  getEnv("MONGODB_URI", "mongodb://localhost:27017"),
 			Name: getEnv("DATABASE_NAME", "aitu_fanpage"),
 		},
 		JWT: JWTConfig{
-			SecretKey: getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+			SecretKey:       getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+			AccessTokenTTL:  parseDuration(getEnv("JWT_ACCESS_TTL", "15m")),
+			RefreshTokenTTL: parseDuration(getEnv("JWT_REFRESH_TTL", "720h")),
 		},
 		Upload: UploadConfig{
 			UploadDir:        getEnv("UPLOAD_DIR", "./uploads"),
@@ -76,7 +206,101 @@ func Load() *Config {
 			},
 			EnableThumbnails: parseBool(getEnv("ENABLE_THUMBNAILS", "true")),
 		},
+		Views: ViewConfig{
+			DedupWindow:     parseDuration(getEnv("VIEW_DEDUP_WINDOW", "30m")),
+			FlushInterval:   parseDuration(getEnv("VIEW_FLUSH_INTERVAL", "10s")),
+			BufferSize:      parseInt(getEnv("VIEW_BUFFER_SIZE", "500")),
+			HashSalt:        getEnv("VIEW_HASH_SALT", getEnv("JWT_SECRET", "your-secret-key-change-in-production")),
+			BotUserAgents:   append(defaultBotUserAgents, parseList(getEnv("VIEW_BOT_USER_AGENTS", ""))...),
+			DedupMaxEntries: parseInt(getEnv("VIEW_DEDUP_MAX_ENTRIES", "100000")),
+		},
+		Search: SearchConfig{
+			Backend:        strings.ToLower(getEnv("SEARCH_BACKEND", "memory")),
+			SnapshotPath:   getEnv("SEARCH_SNAPSHOT_PATH", ""),
+			SnapshotMaxAge: parseDuration(getEnv("SEARCH_SNAPSHOT_MAX_AGE", "1h")),
+
+			QueryLogFlushInterval: parseDuration(getEnv("SEARCH_QUERY_LOG_FLUSH_INTERVAL", "10s")),
+			PopularQueryRefresh:   parseDuration(getEnv("SEARCH_POPULAR_REFRESH", "10m")),
+			PopularQueryWindow:    parseDuration(getEnv("SEARCH_POPULAR_WINDOW", "168h")),
+			QueryLogSalt:          getEnv("SEARCH_QUERY_LOG_SALT", getEnv("JWT_SECRET", "your-secret-key-change-in-production")),
+		},
+		Mail: MailConfig{
+			Driver:       strings.ToLower(getEnv("MAIL_DRIVER", "none")),
+			From:         getEnv("MAIL_FROM", "AITU Fanpage <no-reply@localhost>"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			OutboxDir:    getEnv("MAIL_OUTBOX_DIR", "./outbox"),
+
+			RequireVerification: parseBool(getEnv("REQUIRE_EMAIL_VERIFICATION", "true")),
+			VerificationTTL:     parseDuration(getEnv("EMAIL_VERIFICATION_TTL", "48h")),
+			PasswordResetTTL:    parseDuration(getEnv("PASSWORD_RESET_TTL", "1h")),
+		},
+		Register: RegistrationConfig{
+			AllowedDomains: parseList(getEnv("REGISTRATION_ALLOWED_DOMAINS", "")),
+			BlockedDomains: append(defaultDisposableDomains, parseList(getEnv("REGISTRATION_BLOCKED_DOMAINS", ""))...),
+			RoleDomains:    parseMap(getEnv("REGISTRATION_ROLE_DOMAINS", "")),
+			InviteOnly:     parseBool(getEnv("REGISTRATION_INVITE_ONLY", "false")),
+			InviteTTL:      parseDuration(getEnv("REGISTRATION_INVITE_TTL", "336h")),
+		},
+		Auth: AuthConfig{
+			TwoFactorIssuer:       getEnv("TWO_FACTOR_ISSUER", "AITU Fanpage"),
+			TwoFactorChallengeTTL: parseDuration(getEnv("TWO_FACTOR_CHALLENGE_TTL", "5m")),
+
+			LoginBackoffAfter:     parseInt(getEnv("LOGIN_BACKOFF_AFTER", "3")),
+			LoginIPBackoffAfter:   parseInt(getEnv("LOGIN_IP_BACKOFF_AFTER", "20")),
+			LoginBackoffBase:      parseDuration(getEnv("LOGIN_BACKOFF_BASE", "1s")),
+			LoginBackoffMax:       parseDuration(getEnv("LOGIN_BACKOFF_MAX", "15m")),
+			LoginLockoutThreshold: parseInt(getEnv("LOGIN_LOCKOUT_THRESHOLD", "10")),
+			LoginLockoutDuration:  parseDuration(getEnv("LOGIN_LOCKOUT_DURATION", "30m")),
+			LoginFailureWindow:    parseDuration(getEnv("LOGIN_FAILURE_WINDOW", "1h")),
+
+			DeletionGracePeriod: parseDuration(getEnv("ACCOUNT_DELETION_GRACE_PERIOD", "336h")),
+		},
+		OIDC: OIDCConfig{
+			Issuer:       getEnv("OIDC_ISSUER", ""),
+			ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("OIDC_REDIRECT_URL", defaultOIDCRedirectURL(getEnv("PUBLIC_URL", ""), port)),
+			Scopes:       strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+			ProviderName: getEnv("OIDC_PROVIDER_NAME", "AITU"),
+			AutoRegister: parseBool(getEnv("OIDC_AUTO_REGISTER", "true")),
+			LoginTTL:     parseDuration(getEnv("OIDC_LOGIN_TTL", "10m")),
+		},
+		Policy: PolicyConfig{
+			File:              getEnv("POLICY_FILE", ""),
+			AuditLogRetention: parseDuration(getEnv("AUDIT_LOG_RETENTION", "2160h")),
+		},
+		Export: ExportConfig{
+			Dir:     getEnv("EXPORT_DIR", "./exports"),
+			LinkTTL: parseDuration(getEnv("EXPORT_LINK_TTL", "72h")),
+		},
+	}
+}
+
+var defaultBotUserAgents = []string{
+	"bot", "crawler", "spider", "slurp", "preview", "facebookexternalhit",
+	"whatsapp", "telegram", "headless", "lighthouse", "curl", "wget",
+	"python-requests", "go-http-client", "httpclient", "okhttp",
+}
+
+// defaultDisposableDomains are throwaway mail services that are always
+// refused; REGISTRATION_BLOCKED_DOMAINS adds to them.
+var defaultDisposableDomains = []string{
+	"mailinator.com", "guerrillamail.com", "guerrillamail.net", "sharklasers.com",
+	"10minutemail.com", "temp-mail.org", "tempmail.com", "tempmail.net",
+	"yopmail.com", "trashmail.com", "getnada.com", "dispostable.com",
+	"maildrop.cc", "throwawaymail.com", "fakeinbox.com", "mintemail.com",
+	"mohmal.com", "emailondeck.com", "mailnesia.com", "tempail.com",
+}
+
+// defaultOIDCRedirectURL is the callback route under the public origin.
+func defaultOIDCRedirectURL(publicURL, port string) string {
+	if publicURL == "" {
+		publicURL = "http://localhost:" + port
 	}
+	return strings.TrimRight(publicURL, "/") + "/api/auth/oidc/callback"
 }
 
 func getEnv(key, defaultValue string) string {
@@ -110,3 +334,35 @@ func parseBool(s string) bool {
 	}
 	return b
 }
+
+func parseDuration(s string) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+func parseList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseMap reads "key=value,key=value" pairs, lowercasing both sides.
+func parseMap(s string) map[string]string {
+	m := make(map[string]string)
+	for _, item := range parseList(s) {
+		key, value, ok := strings.Cut(item, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if ok && key != "" && value != "" {
+			m[key] = value
+		}
+	}
+	return m
+}
@@ -0,0 +1,19 @@
+package dto
+
+// ScheduleDeletionRequest confirms deleting the account with the
+// password. Content is "delete" or "anonymize".
+type ScheduleDeletionRequest struct {
+	Password string `json:"password" validate:"required"`
+	Content  string `json:"content" validate:"required"`
+}
+
+type UpdateDeletionRequest struct {
+	Content string `json:"content" validate:"required"`
+}
+
+type AccountDeletionResponse struct {
+	Scheduled    bool   `json:"scheduled"`
+	Content      string `json:"content,omitempty"`
+	RequestedAt  string `json:"requested_at,omitempty"`
+	ScheduledFor string `json:"scheduled_for,omitempty"`
+}
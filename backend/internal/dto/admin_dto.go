@@ -27,3 +27,64 @@ type SystemStats struct {
 	TotalLikes    int            `json:"total_likes"`
 	UsersByRole   map[string]int `json:"users_by_role"`
 }
+
+type CreateInviteRequest struct {
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role,omitempty" validate:"omitempty,oneof=student alumni moderator"`
+	Note  string `json:"note,omitempty"`
+}
+
+// InviteResponse carries Code and Link only when the invite is created.
+type InviteResponse struct {
+	ID        string `json:"id"`
+	Code      string `json:"code,omitempty"`
+	Link      string `json:"link,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Note      string `json:"note,omitempty"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	UsedAt    string `json:"used_at,omitempty"`
+	UsedBy    string `json:"used_by,omitempty"`
+}
+
+// LoginLockResponse is an account or IP with recent failed logins. Locked
+// accounts cannot sign in until LockedUntil; RetryAfter is in seconds.
+type LoginLockResponse struct {
+	ID            string `json:"id"`
+	Kind          string `json:"kind"`
+	UserID        string `json:"user_id,omitempty"`
+	Email         string `json:"email,omitempty"`
+	IP            string `json:"ip,omitempty"`
+	Failures      int    `json:"failures"`
+	LastFailureAt string `json:"last_failure_at"`
+	Locked        bool   `json:"locked"`
+	LockedUntil   string `json:"locked_until,omitempty"`
+	RetryAfter    int    `json:"retry_after"`
+}
+
+// PermissionInfo describes a permission; Ownable ones may be granted with
+// the ":own" suffix.
+type PermissionInfo struct {
+	Name    string `json:"name"`
+	Ownable bool   `json:"ownable"`
+}
+
+type UpdateRolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+type AuditEntryResponse struct {
+	ID           string `json:"id"`
+	Action       string `json:"action"`
+	UserID       string `json:"user_id"`
+	Role         string `json:"role"`
+	Permission   string `json:"permission,omitempty"`
+	ResourceType string `json:"resource_type,omitempty"`
+	ResourceID   string `json:"resource_id,omitempty"`
+	Route        string `json:"route,omitempty"`
+	IP           string `json:"ip,omitempty"`
+	Detail       string `json:"detail,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
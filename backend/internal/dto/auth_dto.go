@@ -4,6 +4,26 @@ type RegisterRequest struct {
 	Email       string `json:"email" validate:"required,email"`
 	Password    string `json:"password" validate:"required,min=8"`
 	DisplayName string `json:"display_name" validate:"required,min=2"`
+	InviteCode  string `json:"invite_code,omitempty"`
+}
+
+// ErrorResponse is a rejection clients can branch on by Code.
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type RegistrationPolicyResponse struct {
+	AllowedDomains []string `json:"allowed_domains"`
+	InviteOnly     bool     `json:"invite_only"`
+}
+
+// OIDCConfigResponse describes single sign-on; LoginURL is where the
+// browser goes to start it.
+type OIDCConfigResponse struct {
+	Enabled      bool   `json:"enabled"`
+	ProviderName string `json:"provider_name,omitempty"`
+	LoginURL     string `json:"login_url,omitempty"`
 }
 
 type LoginRequest struct {
@@ -11,19 +31,101 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// AuthResponse carries the access token in Token and the refresh token
+// used to renew it; ExpiresIn is the access token lifetime in seconds.
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  UserProfile `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"`
+	ExpiresIn    int         `json:"expires_in"`
+	User         UserProfile `json:"user"`
+}
+
+// TwoFactorChallengeResponse is the login answer for users with two-factor
+// authentication; post the token with a code to /auth/login/2fa.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+// TwoFactorLoginRequest takes a code from the authenticator app or a
+// recovery code.
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorSetupResponse carries the secret for manual entry and the same
+// otpauth URI as a PNG data URI to scan.
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// EnableTwoFactorResponse replaces the client's tokens, since enabling
+// signs out every earlier session, and lists the recovery codes once.
+type EnableTwoFactorResponse struct {
+	AuthResponse
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type SessionResponse struct {
+	ID         string `json:"id"`
+	Device     string `json:"device"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	Current    bool   `json:"current"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
 }
 
 type UserProfile struct {
-	ID           string `json:"id"`
-	Email        string `json:"email"`
-	DisplayName  string `json:"display_name"`
-	Role         string `json:"role"`
-	ProfileImage string `json:"profile_image,omitempty"`
-	Bio          string `json:"bio,omitempty"`
-	CreatedAt    string `json:"created_at"`
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	TwoFactor     bool   `json:"two_factor_enabled"`
+	DisplayName   string `json:"display_name"`
+	Role          string `json:"role"`
+	ProfileImage  string `json:"profile_image,omitempty"`
+	Bio           string `json:"bio,omitempty"`
+	CreatedAt     string `json:"created_at"`
 }
 
 type UpdateProfileRequest struct {
@@ -36,3 +138,23 @@ type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" validate:"required"`
 	NewPassword     string `json:"new_password" validate:"required,min=8"`
 }
+
+// CreateAccessTokenRequest omits ExpiresInDays, or sets it to 0, for a
+// token that does not expire.
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty" validate:"omitempty,min=1,max=365"`
+}
+
+// AccessTokenResponse carries Token only when the token is created.
+type AccessTokenResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Token      string   `json:"token,omitempty"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+}
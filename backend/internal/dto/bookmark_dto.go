@@ -0,0 +1,33 @@
+package dto
+
+type BookmarkRequest struct {
+	CollectionID string `json:"collection_id,omitempty"`
+}
+
+type BookmarkResponse struct {
+	PostID       string        `json:"post_id"`
+	CollectionID string        `json:"collection_id,omitempty"`
+	BookmarkedAt string        `json:"bookmarked_at"`
+	Post         *PostResponse `json:"post,omitempty"`
+}
+
+type BookmarkListResponse struct {
+	Bookmarks []BookmarkResponse `json:"bookmarks"`
+	Total     int64              `json:"total"`
+	Limit     int                `json:"limit"`
+	Offset    int                `json:"offset"`
+}
+
+type BookmarkCollectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type BookmarkCollectionResponse struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	BookmarkCount int64  `json:"bookmark_count"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
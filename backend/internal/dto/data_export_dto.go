@@ -0,0 +1,14 @@
+package dto
+
+// DataExportResponse describes an export. DownloadURL is set while the
+// archive can be downloaded.
+type DataExportResponse struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	Size        int64  `json:"size,omitempty"`
+	Error       string `json:"error,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	DownloadURL string `json:"download_url,omitempty"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+type FollowUserResponse struct {
+	ID             string `json:"id"`
+	DisplayName    string `json:"display_name"`
+	Role           string `json:"role"`
+	ProfileImage   string `json:"profile_image,omitempty"`
+	FollowerCount  int    `json:"follower_count"`
+	FollowingCount int    `json:"following_count"`
+}
+
+type FollowListResponse struct {
+	Users  []FollowUserResponse `json:"users"`
+	Total  int                  `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+type SubscriptionsResponse struct {
+	Categories []string `json:"categories"`
+	Tags       []string `json:"tags"`
+}
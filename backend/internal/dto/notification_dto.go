@@ -0,0 +1,53 @@
+package dto
+
+type NotificationResponse struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Link      string `json:"link,omitempty"`
+	Read      bool   `json:"read"`
+	CreatedAt string `json:"created_at"`
+}
+
+type NotificationListResponse struct {
+	Notifications []NotificationResponse `json:"notifications"`
+	UnreadCount   int64                  `json:"unread_count"`
+	Limit         int                    `json:"limit"`
+	Offset        int                    `json:"offset"`
+}
+
+type NotificationChannelRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+type NotificationChannelsResponse struct {
+	Channels map[string]bool `json:"channels"`
+}
+
+// SavedSearchRequest creates a saved search from the post search filters.
+// On update only name, frequency and muted are applied.
+type SavedSearchRequest struct {
+	Name      string `json:"name"`
+	Query     string `json:"q"`
+	Category  string `json:"category,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Frequency string `json:"frequency,omitempty"`
+	Muted     *bool  `json:"muted,omitempty"`
+}
+
+type SavedSearchResponse struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Query        string `json:"q,omitempty"`
+	Category     string `json:"category,omitempty"`
+	Tag          string `json:"tag,omitempty"`
+	Author       string `json:"author,omitempty"`
+	Frequency    string `json:"frequency"`
+	Muted        bool   `json:"muted"`
+	PendingCount int    `json:"pending_count"`
+	LastAlertAt  string `json:"last_alert_at,omitempty"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
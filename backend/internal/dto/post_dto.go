@@ -36,6 +36,7 @@ type PostResponse struct {
 	CreatedAt       string              `json:"created_at"`
 	UpdatedAt       string              `json:"updated_at"`
 	PopularityScore float64             `json:"popularity_score,omitempty"`
+	BookmarkedByMe  bool                `json:"bookmarked_by_me"`
 }
 
 type PostFilterRequest struct {
@@ -58,7 +59,23 @@ type LikeResponse struct {
 	LikedAt  string `json:"liked_at"`
 }
 
+type FeedResponse struct {
+	Posts      []PostResponse `json:"posts"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
 type SearchResponse struct {
-	Posts []PostResponse `json:"posts"`
-	Total int            `json:"total"`
+	Posts  []SearchPostResponse `json:"posts"`
+	Total  int                  `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+// SearchPostResponse is a post with HTML-escaped highlights in which
+// matched words are wrapped in <mark>.
+type SearchPostResponse struct {
+	PostResponse
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+	Score          float64 `json:"score,omitempty"`
 }
@@ -0,0 +1,88 @@
+package dto
+
+type BadgeResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ProfileResponse is a public profile. Sections the viewer may not see
+// are false in Sections and left out.
+type ProfileResponse struct {
+	PublicUserProfile
+	Badges   []BadgeResponse          `json:"badges"`
+	Sections map[string]bool          `json:"sections"`
+	Posts    *ProfilePostsResponse    `json:"posts,omitempty"`
+	Comments *ProfileCommentsResponse `json:"comments,omitempty"`
+	Events   *ProfileEventsResponse   `json:"events,omitempty"`
+}
+
+type ProfilePostsResponse struct {
+	Posts   []PostResponse `json:"posts"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+	HasMore bool           `json:"has_more"`
+}
+
+type ProfileCommentResponse struct {
+	CommentResponse
+	PostTitle string `json:"post_title"`
+}
+
+type ProfileCommentsResponse struct {
+	Comments []ProfileCommentResponse `json:"comments"`
+	Limit    int                      `json:"limit"`
+	Offset   int                      `json:"offset"`
+	HasMore  bool                     `json:"has_more"`
+}
+
+type ProfileEventResponse struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	Location      string `json:"location"`
+	Category      string `json:"category"`
+	Status        string `json:"status"`
+	StartDate     string `json:"start_date"`
+	EndDate       string `json:"end_date"`
+	AttendeeCount int    `json:"attendee_count"`
+}
+
+type ProfileEventsResponse struct {
+	Events  []ProfileEventResponse `json:"events"`
+	Limit   int                    `json:"limit"`
+	Offset  int                    `json:"offset"`
+	HasMore bool                   `json:"has_more"`
+}
+
+// ActivityItemResponse is a post, comment or like. The post fields are
+// those of the post written, commented on or liked.
+type ActivityItemResponse struct {
+	Type           string `json:"type"`
+	CreatedAt      string `json:"created_at"`
+	PostID         string `json:"post_id"`
+	PostTitle      string `json:"post_title"`
+	PostAuthorID   string `json:"post_author_id"`
+	PostAuthorName string `json:"post_author_name"`
+	CommentID      string `json:"comment_id,omitempty"`
+	Content        string `json:"content,omitempty"`
+}
+
+type ActivityResponse struct {
+	Items      []ActivityItemResponse `json:"items"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+type PrivacySettingsRequest struct {
+	Posts    string `json:"posts,omitempty"`
+	Comments string `json:"comments,omitempty"`
+	Likes    string `json:"likes,omitempty"`
+	Events   string `json:"events,omitempty"`
+}
+
+type PrivacySettingsResponse struct {
+	Posts    string `json:"posts"`
+	Comments string `json:"comments"`
+	Likes    string `json:"likes"`
+	Events   string `json:"events"`
+}
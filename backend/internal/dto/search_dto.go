@@ -0,0 +1,83 @@
+package dto
+
+// UnifiedSearchResponse groups search results by type. Scores share one
+// scale, so results of different types can be compared or merged.
+type UnifiedSearchResponse struct {
+	Query    string              `json:"query"`
+	Type     string              `json:"type"`
+	Limit    int                 `json:"limit"`
+	Offset   int                 `json:"offset"`
+	Posts    *SearchPostGroup    `json:"posts,omitempty"`
+	Comments *SearchCommentGroup `json:"comments,omitempty"`
+	Users    *SearchUserGroup    `json:"users,omitempty"`
+	Events   *SearchEventGroup   `json:"events,omitempty"`
+}
+
+type SearchPostGroup struct {
+	Total   int                  `json:"total"`
+	Results []SearchPostResponse `json:"results"`
+}
+
+type SearchCommentGroup struct {
+	Total   int                     `json:"total"`
+	Results []SearchCommentResponse `json:"results"`
+}
+
+type SearchUserGroup struct {
+	Total   int                  `json:"total"`
+	Results []SearchUserResponse `json:"results"`
+}
+
+type SearchEventGroup struct {
+	Total   int                   `json:"total"`
+	Results []SearchEventResponse `json:"results"`
+}
+
+type SearchCommentResponse struct {
+	ID         string  `json:"id"`
+	PostID     string  `json:"post_id"`
+	PostTitle  string  `json:"post_title"`
+	AuthorID   string  `json:"author_id"`
+	AuthorName string  `json:"author_name"`
+	Snippet    string  `json:"snippet"`
+	CreatedAt  string  `json:"created_at"`
+	Score      float64 `json:"score"`
+}
+
+// SearchUserResponse holds public profile fields only.
+type SearchUserResponse struct {
+	ID            string  `json:"id"`
+	DisplayName   string  `json:"display_name"`
+	NameHighlight string  `json:"name_highlight"`
+	Role          string  `json:"role"`
+	ProfileImage  string  `json:"profile_image,omitempty"`
+	BioSnippet    string  `json:"bio_snippet,omitempty"`
+	FollowerCount int     `json:"follower_count"`
+	Score         float64 `json:"score"`
+}
+
+type SearchEventResponse struct {
+	ID             string  `json:"id"`
+	Title          string  `json:"title"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+	Location       string  `json:"location"`
+	Category       string  `json:"category"`
+	Status         string  `json:"status"`
+	StartDate      string  `json:"start_date"`
+	EndDate        string  `json:"end_date"`
+	AttendeeCount  int     `json:"attendee_count"`
+	Score          float64 `json:"score"`
+}
+
+type SuggestResponse struct {
+	Suggestions []SuggestionResponse `json:"suggestions"`
+}
+
+// SuggestionResponse is one completion. Kind is post, tag, user or query;
+// ID is set for posts and users so the client can link straight to them.
+type SuggestionResponse struct {
+	Text string `json:"text"`
+	Kind string `json:"kind"`
+	ID   string `json:"id,omitempty"`
+}
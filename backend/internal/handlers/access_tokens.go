@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+func (h *AuthHandler) GetAccessTokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.authService.ListAccessTokens(userID)
+	if err != nil {
+		http.Error(w, "Failed to get access tokens: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dto.AccessTokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		response = append(response, mapAccessTokenResponse(token))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tokens": response,
+		"scopes": models.AccessTokenScopes,
+	})
+}
+
+// CreateAccessToken issues a personal access token. Its value is only ever
+// returned here.
+func (h *AuthHandler) CreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CreateAccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	token, value, err := h.authService.CreateAccessToken(userID, service.AccessTokenInput{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresIn: time.Duration(req.ExpiresInDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrAccessTokenNameRequired) || errors.Is(err, service.ErrInvalidAccessTokenScope) ||
+			errors.Is(err, service.ErrNoAccessTokenScopes) || errors.Is(err, service.ErrInvalidAccessTokenTTL) {
+			status = http.StatusBadRequest
+		} else if errors.Is(err, service.ErrTooManyAccessTokens) {
+			status = http.StatusConflict
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	response := mapAccessTokenResponse(token)
+	response.Token = value
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *AuthHandler) RevokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokenID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "tokenId"))
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RevokeAccessToken(userID, tokenID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrAccessTokenNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func mapAccessTokenResponse(token *models.AccessToken) dto.AccessTokenResponse {
+	response := dto.AccessTokenResponse{
+		ID:        token.ID.Hex(),
+		Name:      token.Name,
+		Prefix:    token.Prefix,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if token.ExpiresAt != nil {
+		response.ExpiresAt = token.ExpiresAt.Format("2006-01-02T15:04:05Z")
+	}
+	if token.LastUsedAt != nil {
+		response.LastUsedAt = token.LastUsedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return response
+}
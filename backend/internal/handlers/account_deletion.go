@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+type AccountDeletionHandler struct {
+	service *service.AccountDeletionService
+}
+
+func NewAccountDeletionHandler(service *service.AccountDeletionService) *AccountDeletionHandler {
+	return &AccountDeletionHandler{service: service}
+}
+
+func (h *AccountDeletionHandler) GetDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deletion, err := h.service.Status(userID)
+	if err != nil {
+		writeAccountDeletionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapAccountDeletion(deletion))
+}
+
+func (h *AccountDeletionHandler) ScheduleDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.ScheduleDeletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.service.Schedule(userID, req.Password, models.DeletionMode(req.Content))
+	if err != nil {
+		writeAccountDeletionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(mapAccountDeletion(deletion))
+}
+
+func (h *AccountDeletionHandler) UpdateDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.UpdateDeletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.service.SetMode(userID, models.DeletionMode(req.Content))
+	if err != nil {
+		writeAccountDeletionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapAccountDeletion(deletion))
+}
+
+func (h *AccountDeletionHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.Cancel(userID); err != nil {
+		writeAccountDeletionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapAccountDeletion(nil))
+}
+
+func mapAccountDeletion(deletion *models.AccountDeletion) dto.AccountDeletionResponse {
+	if deletion == nil {
+		return dto.AccountDeletionResponse{}
+	}
+	return dto.AccountDeletionResponse{
+		Scheduled:    true,
+		Content:      string(deletion.Mode),
+		RequestedAt:  deletion.RequestedAt.Format("2006-01-02T15:04:05Z"),
+		ScheduledFor: deletion.ScheduledFor.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func writeAccountDeletionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidDeletionMode), errors.Is(err, service.ErrInvalidCredentials):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrDeletionScheduled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrDeletionNotScheduled), errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "Failed to update account deletion: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
 )
 
@@ -20,13 +23,19 @@ type AdminHandler struct {
 	postService    *service.PostService
 	userService    *service.UserService
 	commentService *service.CommentService
+	authService    *service.AuthService
+	policyService  *service.PolicyService
+	deletion       *service.AccountDeletionService
 }
 
-func NewAdminHandler(postService *service.PostService, userService *service.UserService, commentService *service.CommentService) *AdminHandler {
+func NewAdminHandler(postService *service.PostService, userService *service.UserService, commentService *service.CommentService, authService *service.AuthService, policyService *service.PolicyService, deletion *service.AccountDeletionService) *AdminHandler {
 	return &AdminHandler{
 		postService:    postService,
 		userService:    userService,
 		commentService: commentService,
+		authService:    authService,
+		policyService:  policyService,
+		deletion:       deletion,
 	}
 }
 
@@ -179,10 +188,26 @@ func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.userService.DeleteUser(adminID, userID); err != nil {
+	// The user's posts and comments stay, credited to a deleted user,
+	// unless content=delete
+	mode := models.DeletionModeAnonymize
+	if content := r.URL.Query().Get("content"); content != "" {
+		mode = models.DeletionMode(content)
+	}
+
+	if err := h.deletion.DeleteUser(adminID, userID, middleware.GetTwoFactorFromContext(r.Context()), mode); err != nil {
+		if errors.Is(err, policy.ErrTwoFactorRequired) {
+			middleware.WriteTwoFactorRequired(w, r)
+			return
+		}
 		status := http.StatusInternalServerError
-		if errors.Is(err, service.ErrPermissionDenied) {
+		switch {
+		case errors.Is(err, service.ErrPermissionDenied):
 			status = http.StatusForbidden
+		case errors.Is(err, service.ErrInvalidDeletionMode), errors.Is(err, service.ErrCannotDeleteSelf):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrUserNotFound):
+			status = http.StatusNotFound
 		}
 		http.Error(w, err.Error(), status)
 		return
@@ -203,8 +228,7 @@ func (h *AdminHandler) GetSystemStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	admin, err := h.userService.GetUserByID(adminID)
-	if err != nil || !admin.CanViewAnalytics() {
+	if err := h.userService.Authorize(adminID, policy.AnalyticsView, nil); err != nil {
 		http.Error(w, "Permission denied", http.StatusForbidden)
 		return
 	}
@@ -258,8 +282,7 @@ func (h *AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	admin, err := h.userService.GetUserByID(adminID)
-	if err != nil || !admin.CanManageUsers() {
+	if err := h.userService.Authorize(adminID, policy.UsersView, nil); err != nil {
 		http.Error(w, "Permission denied", http.StatusForbidden)
 		return
 	}
@@ -304,3 +327,325 @@ func (h *AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
+
+// CreateInvite issues an invite. The code is only ever returned here.
+func (h *AdminHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	invite, code, err := h.authService.CreateInvite(adminID, service.InviteInput{
+		Email: req.Email,
+		Role:  req.Role,
+		Note:  req.Note,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPermissionDenied) {
+			status = http.StatusForbidden
+		} else if errors.Is(err, service.ErrInvalidInviteRole) || errors.Is(err, service.ErrInvalidEmail) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	response := mapInviteResponse(invite)
+	response.Code = code
+	response.Link = h.authService.InviteLink(code)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *AdminHandler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o > 0 {
+		offset = o
+	}
+
+	invites, err := h.authService.ListInvites(limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to get invites: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dto.InviteResponse, 0, len(invites))
+	for _, invite := range invites {
+		response = append(response, mapInviteResponse(invite))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"invites": response,
+		"limit":   limit,
+		"offset":  offset,
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// ResetTwoFactor removes a user's 2FA enrolment when they lost both their
+// device and recovery codes.
+func (h *AdminHandler) ResetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ResetTwoFactor(adminID, userID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPermissionDenied) {
+			status = http.StatusForbidden
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, service.ErrTwoFactorNotEnabled) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) DeleteInvite(w http.ResponseWriter, r *http.Request) {
+	inviteID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "inviteId"))
+	if err != nil {
+		http.Error(w, "Invalid invite ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.DeleteInvite(inviteID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInviteNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetLoginLocks lists locked accounts and the accounts and IPs that are
+// being slowed down after failed logins.
+func (h *AdminHandler) GetLoginLocks(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o > 0 {
+		offset = o
+	}
+
+	locks, err := h.authService.ListLoginLocks(limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to get login locks: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dto.LoginLockResponse, 0, len(locks))
+	for _, lock := range locks {
+		item := dto.LoginLockResponse{
+			ID:            lock.ID.Hex(),
+			Kind:          lock.Kind,
+			Email:         lock.Email,
+			IP:            lock.IP,
+			Failures:      lock.Failures,
+			LastFailureAt: lock.LastFailureAt.Format("2006-01-02T15:04:05Z"),
+			Locked:        lock.Locked,
+			RetryAfter:    int(math.Ceil(lock.RetryAfter.Seconds())),
+		}
+		if lock.UserID != nil {
+			item.UserID = lock.UserID.Hex()
+		}
+		if lock.Locked {
+			item.LockedUntil = lock.LockedUntil.Format("2006-01-02T15:04:05Z")
+		}
+		response = append(response, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"locks":  response,
+		"limit":  limit,
+		"offset": offset,
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *AdminHandler) ClearLoginLock(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	lockID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "lockId"))
+	if err != nil {
+		http.Error(w, "Invalid lock ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ClearLoginLock(adminID, lockID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPermissionDenied) {
+			status = http.StatusForbidden
+		} else if errors.Is(err, service.ErrLoginLockNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPermissions lists every permission and what each role is granted.
+func (h *AdminHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions := make([]dto.PermissionInfo, 0, len(policy.Permissions))
+	for perm, ownable := range policy.Permissions {
+		permissions = append(permissions, dto.PermissionInfo{Name: string(perm), Ownable: ownable})
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].Name < permissions[j].Name
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"permissions": permissions,
+		"roles":       h.policyService.Roles(),
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *AdminHandler) UpdateRolePermissions(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.UpdateRolePermissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role := models.UserRole(chi.URLParam(r, "role"))
+	grants, err := h.policyService.UpdateRole(adminID, role, req.Permissions)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrPermissionDenied) {
+			status = http.StatusForbidden
+		} else if errors.Is(err, service.ErrInvalidRole) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, policy.ErrInvalidGrant) || errors.Is(err, service.ErrAdminLockout) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"role":        role,
+		"permissions": grants,
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// GetAuditLog lists audit entries, newest first, optionally only those
+// with the given action.
+func (h *AdminHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o > 0 {
+		offset = o
+	}
+	action := r.URL.Query().Get("action")
+
+	entries, err := h.policyService.ListAuditLog(action, limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to get audit log: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dto.AuditEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		item := dto.AuditEntryResponse{
+			ID:           entry.ID.Hex(),
+			Action:       entry.Action,
+			UserID:       entry.UserID.Hex(),
+			Role:         string(entry.Role),
+			Permission:   entry.Permission,
+			ResourceType: entry.ResourceType,
+			Route:        entry.Route,
+			IP:           entry.IP,
+			Detail:       entry.Detail,
+			CreatedAt:    entry.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		}
+		if entry.ResourceID != nil {
+			item.ResourceID = entry.ResourceID.Hex()
+		}
+		response = append(response, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": response,
+		"limit":   limit,
+		"offset":  offset,
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func mapInviteResponse(invite *models.Invite) dto.InviteResponse {
+	response := dto.InviteResponse{
+		ID:        invite.ID.Hex(),
+		Email:     invite.Email,
+		Role:      string(invite.Role),
+		Note:      invite.Note,
+		CreatedBy: invite.CreatedBy.Hex(),
+		CreatedAt: invite.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		ExpiresAt: invite.ExpiresAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if invite.UsedAt != nil {
+		response.UsedAt = invite.UsedAt.Format("2006-01-02T15:04:05Z")
+	}
+	if invite.UsedBy != nil {
+		response.UsedBy = invite.UsedBy.Hex()
+	}
+	return response
+}
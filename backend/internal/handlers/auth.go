@@ -2,10 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
 )
 
@@ -27,38 +34,31 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.authService.Register(req)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if err == service.ErrEmailAlreadyExists {
-			status = http.StatusConflict
-		} else if err == service.ErrInvalidEmail || err == service.ErrWeakPassword {
-			status = http.StatusBadRequest
-		}
-		http.Error(w, err.Error(), status)
+		writeRegisterError(w, err)
 		return
 	}
 
-	token, err := h.authService.GetTokenAuth().GenerateToken(user)
+	tokens, err := h.authService.IssueTokens(user, sessionClient(r))
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	response := dto.AuthResponse{
-		Token: token,
-		User: dto.UserProfile{
-			ID:           user.ID.Hex(),
-			Email:        user.Email,
-			DisplayName:  user.DisplayName,
-			Role:         string(user.Role),
-			ProfileImage: user.ProfileImage,
-			Bio:          user.Bio,
-			CreatedAt:    user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		},
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(mapAuthResponse(tokens, user))
+}
+
+// GetRegistrationPolicy tells the sign-up form which domains are accepted
+// and whether an invite is needed.
+func (h *AuthHandler) GetRegistrationPolicy(w http.ResponseWriter, r *http.Request) {
+	policy := h.authService.RegistrationPolicy()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.RegistrationPolicyResponse{
+		AllowedDomains: append([]string{}, policy.AllowedDomains()...),
+		InviteOnly:     policy.InviteOnly(),
+	})
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -69,31 +69,258 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, user, err := h.authService.Login(req)
+	result, err := h.authService.Login(req, sessionClient(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			status = http.StatusTooManyRequests
+			if errors.Is(err, service.ErrAccountLocked) {
+				status = http.StatusLocked
+			}
+		} else if err == service.ErrInvalidCredentials {
+			status = http.StatusUnauthorized
+		} else if err == service.ErrAccountDeactivated {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Challenge != "" {
+		json.NewEncoder(w).Encode(dto.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    result.Challenge,
+			ExpiresIn:         int(result.ChallengeExpiresIn.Seconds()),
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(mapAuthResponse(result.Tokens, result.User))
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req dto.RefreshRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, user, err := h.authService.Refresh(req.RefreshToken, sessionClient(r))
 	if err != nil {
 		status := http.StatusInternalServerError
-		if err == service.ErrInvalidCredentials {
+		if err == service.ErrInvalidRefreshToken || err == service.ErrRefreshTokenReused {
 			status = http.StatusUnauthorized
+		} else if err == service.ErrAccountDeactivated {
+			status = http.StatusForbidden
 		}
 		http.Error(w, err.Error(), status)
 		return
 	}
 
-	response := dto.AuthResponse{
-		Token: token,
-		User: dto.UserProfile{
-			ID:           user.ID.Hex(),
-			Email:        user.Email,
-			DisplayName:  user.DisplayName,
-			Role:         string(user.Role),
-			ProfileImage: user.ProfileImage,
-			Bio:          user.Bio,
-			CreatedAt:    user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		},
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapAuthResponse(tokens, user))
+}
+
+// Logout ends the session of the refresh token, which also invalidates the
+// access tokens issued for it.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req dto.RefreshRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.Logout(req.RefreshToken); err != nil {
+		http.Error(w, "Failed to log out: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Logged out successfully",
+	})
+}
+
+// GetSessions lists the devices the user is signed in on. The one making
+// the request is marked current.
+func (h *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	currentID, _ := middleware.GetSessionIDFromContext(r.Context())
+
+	sessions, err := h.authService.ListSessions(userID)
+	if err != nil {
+		http.Error(w, "Failed to get sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, dto.SessionResponse{
+			ID:         session.ID.Hex(),
+			Device:     session.Device,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			LastSeenAt: session.LastSeenAt.Format("2006-01-02T15:04:05Z"),
+			Current:    session.ID == currentID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessions": response,
+	})
+}
+
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "sessionId"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RevokeSession(userID, sessionID); err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrSessionNotFound {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeAllSessions logs the user out everywhere, this device included.
+func (h *AuthHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.authService.RevokeAllSessions(userID); err != nil {
+		http.Error(w, "Failed to revoke sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail confirms the address from the token in a verification link.
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyEmailRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.authService.VerifyEmail(req.Token); err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrInvalidVerificationToken {
+			status = http.StatusBadRequest
+		} else if err == service.ErrVerificationTokenExpired {
+			status = http.StatusGone
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Email verified successfully",
+	})
+}
+
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.authService.ResendVerification(userID); err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrEmailAlreadyVerified {
+			status = http.StatusConflict
+		} else if err == service.ErrVerificationCooldown {
+			status = http.StatusTooManyRequests
+		} else if err == service.ErrUserNotFound {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Verification email sent",
+	})
+}
+
+// ForgotPassword always answers 202 so it cannot be used to probe which
+// emails are registered.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ForgotPasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ForgotPassword(req.Email); err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrInvalidEmail {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If an account exists for this email, a reset link is on its way",
+	})
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ResetPasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrInvalidResetToken || err == service.ErrWeakPassword {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password has been reset; sign in with the new password",
+	})
 }
 
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
@@ -109,15 +336,7 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := dto.UserProfile{
-		ID:           user.ID.Hex(),
-		Email:        user.Email,
-		DisplayName:  user.DisplayName,
-		Role:         string(user.Role),
-		ProfileImage: user.ProfileImage,
-		Bio:          user.Bio,
-		CreatedAt:    user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	}
+	response := mapUserProfile(user)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -138,19 +357,15 @@ func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.authService.UpdateProfile(userID, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidProfileImage) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
 
-	response := dto.UserProfile{
-		ID:           user.ID.Hex(),
-		Email:        user.Email,
-		DisplayName:  user.DisplayName,
-		Role:         string(user.Role),
-		ProfileImage: user.ProfileImage,
-		Bio:          user.Bio,
-		CreatedAt:    user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	}
+	response := mapUserProfile(user)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -185,3 +400,63 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		"message": "Password updated successfully",
 	})
 }
+
+func mapAuthResponse(tokens *service.TokenPair, user *models.User) dto.AuthResponse {
+	return dto.AuthResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
+		User:         mapUserProfile(user),
+	}
+}
+
+func mapUserProfile(user *models.User) dto.UserProfile {
+	return dto.UserProfile{
+		ID:            user.ID.Hex(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		TwoFactor:     user.HasTwoFactor(),
+		DisplayName:   user.DisplayName,
+		Role:          string(user.Role),
+		ProfileImage:  user.AvatarURL(),
+		Bio:           user.Bio,
+		CreatedAt:     user.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func sessionClient(r *http.Request) service.SessionClient {
+	return service.SessionClient{
+		UserAgent: r.UserAgent(),
+		IP:        middleware.ClientIP(r),
+	}
+}
+
+// writeRegisterError answers with a JSON body carrying a stable code for
+// every expected rejection.
+func writeRegisterError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	response := dto.ErrorResponse{Code: "internal_error", Message: "Failed to register"}
+
+	var regErr *service.RegistrationError
+	switch {
+	case errors.As(err, &regErr):
+		status = http.StatusForbidden
+		if regErr == service.ErrInvalidInvite || regErr == service.ErrInviteEmailMismatch {
+			status = http.StatusBadRequest
+		}
+		response = dto.ErrorResponse{Code: regErr.Code, Message: regErr.Message}
+	case err == service.ErrEmailAlreadyExists:
+		status = http.StatusConflict
+		response = dto.ErrorResponse{Code: "email_taken", Message: err.Error()}
+	case err == service.ErrInvalidEmail:
+		status = http.StatusBadRequest
+		response = dto.ErrorResponse{Code: "invalid_email", Message: err.Error()}
+	case err == service.ErrWeakPassword:
+		status = http.StatusBadRequest
+		response = dto.ErrorResponse{Code: "weak_password", Message: err.Error()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+// UploadAvatar takes the image in the "avatar" field. crop_x, crop_y,
+// crop_width and crop_height select the part to keep, in pixels of the
+// uploaded image; without them the centered square is used.
+func (h *AuthHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Failed to parse form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, fileHeader, err := r.FormFile("avatar")
+	if err != nil {
+		http.Error(w, "No avatar uploaded", http.StatusBadRequest)
+		return
+	}
+
+	crop, err := parseCrop(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.authService.SetAvatar(userID, fileHeader, crop)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidAvatar) || errors.Is(err, service.ErrInvalidCrop) {
+			status = http.StatusBadRequest
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapUserProfile(user))
+}
+
+func (h *AuthHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.authService.RemoveAvatar(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapUserProfile(user))
+}
+
+// GetAvatar redirects to the user's image at the requested size, or draws
+// their identicon.
+func (h *AuthHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	size := 128
+	if s, err := strconv.Atoi(r.URL.Query().Get("size")); err == nil && s > 0 {
+		size = s
+	}
+
+	location, png, err := h.authService.Avatar(userID, size)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Short caching, as the user may upload an avatar at any time
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if location != "" {
+		http.Redirect(w, r, location, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
+
+func parseCrop(r *http.Request) (image.Rectangle, error) {
+	fields := []string{"crop_x", "crop_y", "crop_width", "crop_height"}
+	values := make([]int, len(fields))
+	given := 0
+	for i, field := range fields {
+		raw := r.FormValue(field)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			return image.Rectangle{}, errors.New("Invalid " + field)
+		}
+		values[i] = v
+		given++
+	}
+
+	switch {
+	case given == 0:
+		return image.Rectangle{}, nil
+	case given < len(fields):
+		return image.Rectangle{}, errors.New("Crop needs crop_x, crop_y, crop_width and crop_height")
+	case values[2] == 0 || values[3] == 0:
+		return image.Rectangle{}, errors.New("Crop must not be empty")
+	}
+	return image.Rect(values[0], values[1], values[0]+values[2], values[1]+values[3]), nil
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+type BookmarkHandler struct {
+	service *service.BookmarkService
+}
+
+func NewBookmarkHandler(service *service.BookmarkService) *BookmarkHandler {
+	return &BookmarkHandler{service: service}
+}
+
+func (h *BookmarkHandler) AddBookmark(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.BookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	collectionID, err := parseOptionalID(req.CollectionID)
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	bookmark, err := h.service.AddBookmark(userID, postID, collectionID)
+	if err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mapBookmarkToResponse(bookmark, nil))
+}
+
+func (h *BookmarkHandler) RemoveBookmark(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RemoveBookmark(userID, postID); err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Bookmark removed successfully",
+	})
+}
+
+func (h *BookmarkHandler) MoveBookmark(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "postId"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.BookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	collectionID, err := parseOptionalID(req.CollectionID)
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.MoveBookmark(userID, postID, collectionID); err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Bookmark moved successfully",
+	})
+}
+
+func (h *BookmarkHandler) GetBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	collectionID, err := parseOptionalID(r.URL.Query().Get("collection_id"))
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	entries, total, err := h.service.GetBookmarks(userID, collectionID, limit, offset)
+	if err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	response := dto.BookmarkListResponse{
+		Bookmarks: []dto.BookmarkResponse{},
+		Total:     total,
+		Limit:     limit,
+		Offset:    offset,
+	}
+	for _, entry := range entries {
+		response.Bookmarks = append(response.Bookmarks, mapBookmarkToResponse(entry.Bookmark, entry.Post))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *BookmarkHandler) GetCollections(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	collections, err := h.service.GetCollections(userID)
+	if err != nil {
+		http.Error(w, "Failed to get collections: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responses := []dto.BookmarkCollectionResponse{}
+	for _, item := range collections {
+		responses = append(responses, mapCollectionToResponse(item.Collection, item.Count))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *BookmarkHandler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.BookmarkCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.service.CreateCollection(userID, req.Name, req.Description)
+	if err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mapCollectionToResponse(collection, 0))
+}
+
+func (h *BookmarkHandler) UpdateCollection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	collectionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "collectionId"))
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.BookmarkCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.UpdateCollection(userID, collectionID, req.Name, req.Description)
+	if err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapCollectionToResponse(updated.Collection, updated.Count))
+}
+
+func (h *BookmarkHandler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	collectionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "collectionId"))
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteCollection(userID, collectionID); err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Collection deleted successfully",
+	})
+}
+
+func writeBookmarkError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrPostNotFound),
+		errors.Is(err, service.ErrBookmarkNotFound),
+		errors.Is(err, service.ErrCollectionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrAlreadyBookmarked),
+		errors.Is(err, service.ErrCollectionNameTaken):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrInvalidCollectionName):
+		status = http.StatusBadRequest
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func parseOptionalID(value string) (*primitive.ObjectID, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func mapBookmarkToResponse(bookmark *models.Bookmark, post *models.Post) dto.BookmarkResponse {
+	response := dto.BookmarkResponse{
+		PostID:       bookmark.PostID.Hex(),
+		BookmarkedAt: bookmark.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if bookmark.CollectionID != nil {
+		response.CollectionID = bookmark.CollectionID.Hex()
+	}
+	if post != nil {
+		postResponse := mapPostToResponse(post)
+		postResponse.BookmarkedByMe = true
+		response.Post = &postResponse
+	}
+	return response
+}
+
+func mapCollectionToResponse(collection *models.BookmarkCollection, count int64) dto.BookmarkCollectionResponse {
+	return dto.BookmarkCollectionResponse{
+		ID:            collection.ID.Hex(),
+		Name:          collection.Name,
+		Description:   collection.Description,
+		BookmarkCount: count,
+		CreatedAt:     collection.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:     collection.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -10,6 +11,7 @@ import (
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
 )
 
@@ -48,6 +50,10 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 
 	comment, err := h.service.CreateComment(postID, userID, req.Content)
 	if err != nil {
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, "Failed to create comment: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -139,8 +145,12 @@ func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comment, err := h.service.UpdateComment(commentID, userID, req.Content)
+	comment, err := h.service.UpdateComment(commentID, userID, middleware.GetTwoFactorFromContext(r.Context()), req.Content)
 	if err != nil {
+		if errors.Is(err, policy.ErrTwoFactorRequired) {
+			middleware.WriteTwoFactorRequired(w, r)
+			return
+		}
 		if err.Error() == "not authorized to edit this comment" {
 			http.Error(w, err.Error(), http.StatusForbidden)
 			return
@@ -177,8 +187,12 @@ func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.DeleteComment(commentID, userID)
+	err = h.service.DeleteComment(commentID, userID, middleware.GetTwoFactorFromContext(r.Context()))
 	if err != nil {
+		if errors.Is(err, policy.ErrTwoFactorRequired) {
+			middleware.WriteTwoFactorRequired(w, r)
+			return
+		}
 		if err.Error() == "not authorized to delete this comment" {
 			http.Error(w, err.Error(), http.StatusForbidden)
 			return
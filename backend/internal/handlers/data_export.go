@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+type DataExportHandler struct {
+	service *service.DataExportService
+}
+
+func NewDataExportHandler(service *service.DataExportService) *DataExportHandler {
+	return &DataExportHandler{service: service}
+}
+
+func (h *DataExportHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.service.Request(userID)
+	if err != nil {
+		if errors.Is(err, service.ErrExportInProgress) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to start export: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(h.mapExport(export))
+}
+
+func (h *DataExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.service.Latest(userID)
+	if err != nil {
+		if errors.Is(err, service.ErrExportNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get export: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.mapExport(export))
+}
+
+// DownloadExport serves an archive to anyone holding its signed link, so
+// the link in the notification email works without signing in.
+func (h *DataExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	exportID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid export ID", http.StatusBadRequest)
+		return
+	}
+
+	file, export, err := h.service.Open(exportID, r.URL.Query().Get("signature"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="aitu-fanpage-export-`+export.CreatedAt.Format("2006-01-02")+`.zip"`)
+	w.Header().Set("Content-Length", strconv.FormatInt(export.Size, 10))
+	w.Header().Set("Cache-Control", "private, no-store")
+	io.Copy(w, file)
+}
+
+func (h *DataExportHandler) mapExport(export *models.DataExport) dto.DataExportResponse {
+	response := dto.DataExportResponse{
+		ID:          export.ID.Hex(),
+		Status:      string(export.Status),
+		Size:        export.Size,
+		Error:       export.Error,
+		CreatedAt:   export.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		DownloadURL: h.service.DownloadURL(export),
+	}
+	if export.CompletedAt != nil {
+		response.CompletedAt = export.CompletedAt.Format("2006-01-02T15:04:05Z")
+	}
+	if export.ExpiresAt != nil {
+		response.ExpiresAt = export.ExpiresAt.Format("2006-01-02T15:04:05Z")
+	}
+	return response
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+type FollowHandler struct {
+	service *service.FollowService
+}
+
+func NewFollowHandler(service *service.FollowService) *FollowHandler {
+	return &FollowHandler{service: service}
+}
+
+func (h *FollowHandler) FollowUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	targetID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Follow(userID, targetID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrCannotFollowSelf) {
+			status = http.StatusBadRequest
+		} else if errors.Is(err, service.ErrAlreadyFollowing) {
+			status = http.StatusConflict
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "User followed successfully",
+		"following": true,
+	})
+}
+
+func (h *FollowHandler) UnfollowUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	targetID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Unfollow(userID, targetID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrNotFollowing) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "User unfollowed successfully",
+		"following": false,
+	})
+}
+
+func (h *FollowHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	h.listFollows(w, r, h.service.GetFollowers)
+}
+
+func (h *FollowHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	h.listFollows(w, r, h.service.GetFollowing)
+}
+
+func (h *FollowHandler) listFollows(w http.ResponseWriter, r *http.Request, list func(primitive.ObjectID, int, int) ([]*models.User, int, error)) {
+	userID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	users, total, err := list(userID, limit, offset)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	response := dto.FollowListResponse{
+		Users:  []dto.FollowUserResponse{},
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, user := range users {
+		if !user.IsActive {
+			continue
+		}
+		response.Users = append(response.Users, dto.FollowUserResponse{
+			ID:             user.ID.Hex(),
+			DisplayName:    user.DisplayName,
+			Role:           string(user.Role),
+			ProfileImage:   user.AvatarURL(),
+			FollowerCount:  user.FollowerCount,
+			FollowingCount: user.FollowingCount,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *FollowHandler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	writeSubscriptions(w, user)
+}
+
+func (h *FollowHandler) SubscribeCategory(w http.ResponseWriter, r *http.Request) {
+	h.updateSubscription(w, r, h.service.SubscribeCategory, "category")
+}
+
+func (h *FollowHandler) UnsubscribeCategory(w http.ResponseWriter, r *http.Request) {
+	h.updateSubscription(w, r, h.service.UnsubscribeCategory, "category")
+}
+
+func (h *FollowHandler) SubscribeTag(w http.ResponseWriter, r *http.Request) {
+	h.updateSubscription(w, r, h.service.SubscribeTag, "tag")
+}
+
+func (h *FollowHandler) UnsubscribeTag(w http.ResponseWriter, r *http.Request) {
+	h.updateSubscription(w, r, h.service.UnsubscribeTag, "tag")
+}
+
+func (h *FollowHandler) updateSubscription(w http.ResponseWriter, r *http.Request, update func(primitive.ObjectID, string) (*models.User, error), param string) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := update(userID, chi.URLParam(r, param))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidCategory) || errors.Is(err, service.ErrInvalidTag) {
+			status = http.StatusBadRequest
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	writeSubscriptions(w, user)
+}
+
+func writeSubscriptions(w http.ResponseWriter, user *models.User) {
+	response := dto.SubscriptionsResponse{
+		Categories: []string{},
+		Tags:       []string{},
+	}
+	for _, category := range user.SubscribedCategories {
+		response.Categories = append(response.Categories, string(category))
+	}
+	response.Tags = append(response.Tags, user.SubscribedTags...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
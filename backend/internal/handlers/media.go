@@ -12,6 +12,7 @@ import (
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
 )
 
@@ -21,10 +22,11 @@ type MediaHandler struct {
 	userService *service.UserService
 }
 
-func NewMediaHandler(fileService *service.FileService, postService *service.PostService) *MediaHandler {
+func NewMediaHandler(fileService *service.FileService, postService *service.PostService, userService *service.UserService) *MediaHandler {
 	return &MediaHandler{
 		fileService: fileService,
 		postService: postService,
+		userService: userService,
 	}
 }
 
@@ -83,9 +85,7 @@ func (h *MediaHandler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user is admin
-	user, err := h.postService.GetUserByID(userID)
-	if err != nil || !user.IsAdmin() {
+	if err := h.userService.Authorize(userID, policy.MediaDelete, nil); err != nil {
 		http.Error(w, "Permission denied", http.StatusForbidden)
 		return
 	}
@@ -96,7 +96,7 @@ func (h *MediaHandler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mediaURL, err = url.QueryUnescape(mediaURL)
+	mediaURL, err := url.QueryUnescape(mediaURL)
 	if err != nil {
 		http.Error(w, "Invalid media URL", http.StatusBadRequest)
 		return
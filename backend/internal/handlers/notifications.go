@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+type NotificationHandler struct {
+	service            *service.NotificationService
+	savedSearchService *service.SavedSearchService
+}
+
+func NewNotificationHandler(service *service.NotificationService, savedSearchService *service.SavedSearchService) *NotificationHandler {
+	return &NotificationHandler{
+		service:            service,
+		savedSearchService: savedSearchService,
+	}
+}
+
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit, offset := 20, 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o > 0 {
+		offset = o
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, unread, err := h.service.GetNotifications(userID, unreadOnly, limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to get notifications: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.NotificationListResponse{
+		Notifications: make([]dto.NotificationResponse, 0, len(notifications)),
+		UnreadCount:   unread,
+		Limit:         limit,
+		Offset:        offset,
+	}
+	for _, notification := range notifications {
+		response.Notifications = append(response.Notifications, mapNotificationToResponse(notification))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notificationID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "notificationId"))
+	if err != nil {
+		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.MarkRead(userID, notificationID); err != nil {
+		if errors.Is(err, service.ErrNotificationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to mark notification read: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Notification marked as read",
+	})
+}
+
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	count, err := h.service.MarkAllRead(userID)
+	if err != nil {
+		http.Error(w, "Failed to mark notifications read: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Notifications marked as read",
+		"updated": count,
+	})
+}
+
+func (h *NotificationHandler) GetChannels(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	channels, err := h.service.Channels(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.NotificationChannelsResponse{Channels: channels})
+}
+
+func (h *NotificationHandler) SetChannel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.NotificationChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	channels, err := h.service.SetChannel(userID, chi.URLParam(r, "channel"), req.Enabled)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownNotificationChannel):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to update channel: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.NotificationChannelsResponse{Channels: channels})
+}
+
+func (h *NotificationHandler) GetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	searches, err := h.savedSearchService.List(userID)
+	if err != nil {
+		http.Error(w, "Failed to get saved searches: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]dto.SavedSearchResponse, 0, len(searches))
+	for _, saved := range searches {
+		responses = append(responses, mapSavedSearchToResponse(saved))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *NotificationHandler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.SavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	authorID, err := parseOptionalID(req.Author)
+	if err != nil {
+		http.Error(w, "Invalid author ID", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.savedSearchService.Create(userID, service.SavedSearchInput{
+		Name:      req.Name,
+		Query:     req.Query,
+		Category:  req.Category,
+		Tag:       req.Tag,
+		AuthorID:  authorID,
+		Frequency: req.Frequency,
+		Muted:     req.Muted,
+	})
+	if err != nil {
+		writeSavedSearchError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mapSavedSearchToResponse(saved))
+}
+
+func (h *NotificationHandler) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	searchID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "searchId"))
+	if err != nil {
+		http.Error(w, "Invalid saved search ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.SavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.savedSearchService.Update(userID, searchID, service.SavedSearchInput{
+		Name:      req.Name,
+		Frequency: req.Frequency,
+		Muted:     req.Muted,
+	})
+	if err != nil {
+		writeSavedSearchError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapSavedSearchToResponse(saved))
+}
+
+func (h *NotificationHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	searchID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "searchId"))
+	if err != nil {
+		http.Error(w, "Invalid saved search ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.savedSearchService.Delete(userID, searchID); err != nil {
+		writeSavedSearchError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Saved search deleted successfully",
+	})
+}
+
+func writeSavedSearchError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrSavedSearchNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrTooManySavedSearches):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrEmptySearch),
+		errors.Is(err, service.ErrInvalidCategory),
+		errors.Is(err, service.ErrInvalidSavedSearchName),
+		errors.Is(err, service.ErrInvalidAlertFrequency):
+		status = http.StatusBadRequest
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func mapNotificationToResponse(notification *models.Notification) dto.NotificationResponse {
+	return dto.NotificationResponse{
+		ID:        notification.ID.Hex(),
+		Type:      string(notification.Type),
+		Title:     notification.Title,
+		Body:      notification.Body,
+		Link:      notification.Link,
+		Read:      notification.Read,
+		CreatedAt: notification.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func mapSavedSearchToResponse(saved *models.SavedSearch) dto.SavedSearchResponse {
+	response := dto.SavedSearchResponse{
+		ID:           saved.ID.Hex(),
+		Name:         saved.Name,
+		Query:        saved.Query,
+		Category:     saved.Category,
+		Tag:          saved.Tag,
+		Frequency:    string(saved.Frequency),
+		Muted:        saved.Muted,
+		PendingCount: len(saved.PendingPostIDs),
+		CreatedAt:    saved.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:    saved.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if saved.AuthorID != nil {
+		response.Author = saved.AuthorID.Hex()
+	}
+	if !saved.LastAlertAt.IsZero() {
+		response.LastAlertAt = saved.LastAlertAt.Format("2006-01-02T15:04:05Z")
+	}
+	return response
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+// oidcStateCookie binds the callback to the browser that started the
+// sign-in, so a state taken from another login cannot be replayed here.
+const oidcStateCookie = "oidc_state"
+
+// GetOIDCConfig tells the login page whether to show the single sign-on
+// button.
+func (h *AuthHandler) GetOIDCConfig(w http.ResponseWriter, r *http.Request) {
+	response := dto.OIDCConfigResponse{Enabled: h.authService.OIDCEnabled()}
+	if response.Enabled {
+		response.ProviderName = h.authService.OIDCProviderName()
+		response.LoginURL = "/api/auth/oidc/login"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// BeginOIDCLogin redirects the browser to the identity provider.
+func (h *AuthHandler) BeginOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.authService.BeginOIDCLogin()
+	if err != nil {
+		if err == service.ErrOIDCDisabled {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		redirectOIDCError(w, r, "failed")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback finishes the sign-in and hands the tokens to the frontend in
+// the URL fragment, which browsers do not send to servers.
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/api/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if query.Get("error") != "" {
+		redirectOIDCError(w, r, "cancelled")
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		redirectOIDCError(w, r, "invalid_state")
+		return
+	}
+
+	result, err := h.authService.CompleteOIDCLogin(query.Get("code"), state, sessionClient(r))
+	if err != nil {
+		redirectOIDCError(w, r, oidcErrorCode(err))
+		return
+	}
+
+	fragment := url.Values{}
+	target := "/oidc-callback.html#"
+	if result.Challenge != "" {
+		target = "/login.html#"
+		fragment.Set("challenge_token", result.Challenge)
+		fragment.Set("expires_in", strconv.Itoa(int(result.ChallengeExpiresIn.Seconds())))
+	} else {
+		fragment.Set("token", result.Tokens.AccessToken)
+		fragment.Set("refresh_token", result.Tokens.RefreshToken)
+		fragment.Set("expires_in", strconv.Itoa(int(result.Tokens.ExpiresIn.Seconds())))
+	}
+
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.Redirect(w, r, target+fragment.Encode(), http.StatusFound)
+}
+
+func oidcErrorCode(err error) string {
+	var registrationErr *service.RegistrationError
+	switch {
+	case errors.As(err, &registrationErr):
+		return registrationErr.Code
+	case err == service.ErrInvalidOIDCState:
+		return "invalid_state"
+	case err == service.ErrOIDCEmailUnverified:
+		return "email_unverified"
+	case err == service.ErrOIDCNoAccount:
+		return "no_account"
+	case err == service.ErrOIDCAccountLinked:
+		return "account_linked"
+	case err == service.ErrAccountDeactivated:
+		return "account_deactivated"
+	default:
+		return "failed"
+	}
+}
+
+func redirectOIDCError(w http.ResponseWriter, r *http.Request, code string) {
+	http.Redirect(w, r, "/login.html?sso_error="+url.QueryEscape(code), http.StatusFound)
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,28 +15,43 @@ import (
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
 )
 
 type PostHandler struct {
-	service     *service.PostService
-	fileService *service.FileService
+	service         *service.PostService
+	fileService     *service.FileService
+	bookmarkService *service.BookmarkService
+	relatedService  *service.RelatedService
+	searchService   *service.SearchService
 }
 
 type HandlerContainer struct {
-	Auth      *AuthHandler
-	Post      *PostHandler
-	Comment   *CommentHandler
-	User      *UserHandler
-	Admin     *AdminHandler
-	Media     *MediaHandler
-	Analytics *AnalyticsHandler
+	Auth         *AuthHandler
+	Post         *PostHandler
+	Comment      *CommentHandler
+	User         *UserHandler
+	Admin        *AdminHandler
+	Media        *MediaHandler
+	Analytics    *AnalyticsHandler
+	Follow       *FollowHandler
+	Bookmark     *BookmarkHandler
+	Share        *ShareHandler
+	Search       *SearchHandler
+	Notification *NotificationHandler
+	Profile      *ProfileHandler
+	DataExport   *DataExportHandler
+	Deletion     *AccountDeletionHandler
 }
 
-func NewPostHandler(service *service.PostService, fileService *service.FileService) *PostHandler {
+func NewPostHandler(service *service.PostService, fileService *service.FileService, bookmarkService *service.BookmarkService, relatedService *service.RelatedService, searchService *service.SearchService) *PostHandler {
 	return &PostHandler{
-		service:     service,
-		fileService: fileService,
+		service:         service,
+		fileService:     fileService,
+		bookmarkService: bookmarkService,
+		relatedService:  relatedService,
+		searchService:   searchService,
 	}
 }
 
@@ -104,11 +121,15 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	// Create post
 	post, err := h.service.CreatePost(req, userID, uploadedFiles)
 	if err != nil {
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, "Failed to create post: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	response := h.mapPostToResponse(post)
+	response := mapPostToResponse(post)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -166,10 +187,7 @@ func (h *PostHandler) GetPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var responses []dto.PostResponse
-	for _, post := range posts {
-		responses = append(responses, h.mapPostToResponse(post))
-	}
+	responses := h.mapPostsToResponses(r, posts)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(responses); err != nil {
@@ -191,13 +209,63 @@ func (h *PostHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := h.mapPostToResponse(post)
+	h.service.RecordView(post, viewerFromRequest(r))
+
+	response := h.mapPostsToResponses(r, []*models.Post{post})[0]
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
 
+func (h *PostHandler) GetRelatedPosts(w http.ResponseWriter, r *http.Request) {
+	postID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 5
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 20 {
+			limit = l
+		}
+	}
+
+	posts, err := h.relatedService.GetRelatedPosts(postID, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrPostNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get related posts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responses := h.mapPostsToResponses(r, posts)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(responses); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func viewerFromRequest(r *http.Request) service.Viewer {
+	viewer := service.Viewer{
+		IP:        middleware.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+
+	if userID, ok := middleware.GetUserIDFromContext(r.Context()); ok {
+		viewer.UserID = &userID
+	}
+
+	purpose := strings.ToLower(r.Header.Get("Sec-Purpose") + r.Header.Get("Purpose") + r.Header.Get("X-Purpose"))
+	viewer.Prefetch = strings.Contains(purpose, "prefetch") || strings.Contains(purpose, "preview")
+
+	return viewer
+}
+
 func (h *PostHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
@@ -207,6 +275,7 @@ func (h *PostHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
 
 	limitStr := r.URL.Query().Get("limit")
 	category := r.URL.Query().Get("category")
+	cursor := r.URL.Query().Get("cursor")
 
 	limit := 20
 	if limitStr != "" {
@@ -214,28 +283,28 @@ func (h *PostHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
 			limit = l
 		}
 	}
-
-	offsetStr := r.URL.Query().Get("offset")
-	offset := 0
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+	if limit > 100 {
+		limit = 100
 	}
 
-	posts, err := h.service.GetFeed(userID, category, limit, offset)
+	page, err := h.service.GetFeed(userID, category, cursor, limit)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to get feed: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	var responses []dto.PostResponse
-	for _, post := range posts {
-		responses = append(responses, h.mapPostToResponse(post))
+	response := dto.FeedResponse{
+		Posts:      []dto.PostResponse{},
+		NextCursor: page.NextCursor,
 	}
+	response.Posts = append(response.Posts, h.mapPostsToResponses(r, page.Posts)...)
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(responses); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
@@ -358,8 +427,12 @@ func (h *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := h.service.UpdatePost(postID, userID, req)
+	post, err := h.service.UpdatePost(postID, userID, middleware.GetTwoFactorFromContext(r.Context()), req)
 	if err != nil {
+		if errors.Is(err, policy.ErrTwoFactorRequired) {
+			middleware.WriteTwoFactorRequired(w, r)
+			return
+		}
 		if strings.Contains(err.Error(), "not authorized") {
 			http.Error(w, err.Error(), http.StatusForbidden)
 			return
@@ -368,7 +441,7 @@ func (h *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := h.mapPostToResponse(post)
+	response := h.mapPostsToResponses(r, []*models.Post{post})[0]
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
@@ -389,8 +462,12 @@ func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.DeletePost(postID, userID)
+	err = h.service.DeletePost(postID, userID, middleware.GetTwoFactorFromContext(r.Context()))
 	if err != nil {
+		if errors.Is(err, policy.ErrTwoFactorRequired) {
+			middleware.WriteTwoFactorRequired(w, r)
+			return
+		}
 		if strings.Contains(err.Error(), "not authorized") {
 			http.Error(w, err.Error(), http.StatusForbidden)
 			return
@@ -429,37 +506,102 @@ func (h *PostHandler) GetPostLikes(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PostHandler) SearchPosts(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		http.Error(w, "Search query is required", http.StatusBadRequest)
-		return
+	q := r.URL.Query()
+
+	params := service.PostSearchParams{
+		Query:    q.Get("q"),
+		Category: q.Get("category"),
+		Tag:      q.Get("tag"),
+		Limit:    20,
+		Searcher: searcherFromRequest(r),
 	}
 
-	limitStr := r.URL.Query().Get("limit")
-	limit := 20
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 {
+		params.Limit = l
+	}
+	if o, err := strconv.Atoi(q.Get("offset")); err == nil && o > 0 {
+		params.Offset = o
+	}
+
+	if author := q.Get("author"); author != "" {
+		authorID, err := primitive.ObjectIDFromHex(author)
+		if err != nil {
+			http.Error(w, "Invalid author ID", http.StatusBadRequest)
+			return
 		}
+		params.AuthorID = &authorID
+	}
+
+	var err error
+	if params.From, err = parseSearchDate(q.Get("from"), false); err != nil {
+		http.Error(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	if params.To, err = parseSearchDate(q.Get("to"), true); err != nil {
+		http.Error(w, "Invalid to date", http.StatusBadRequest)
+		return
 	}
 
-	posts, err := h.service.SearchPosts(query, limit)
+	result, err := h.searchService.SearchPosts(params)
 	if err != nil {
-		http.Error(w, "Failed to search posts: "+err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, service.ErrEmptySearch):
+			http.Error(w, "Search query is required", http.StatusBadRequest)
+		case errors.Is(err, service.ErrInvalidCategory), errors.Is(err, service.ErrInvalidDateRange):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to search posts: "+err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
-	var responses []dto.PostResponse
-	for _, post := range posts {
-		responses = append(responses, h.mapPostToResponse(post))
+	posts := make([]*models.Post, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		posts = append(posts, hit.Post)
+	}
+	postResponses := h.mapPostsToResponses(r, posts)
+
+	response := dto.SearchResponse{
+		Posts:  make([]dto.SearchPostResponse, 0, len(result.Hits)),
+		Total:  result.Total,
+		Limit:  result.Limit,
+		Offset: result.Offset,
+	}
+	for i, hit := range result.Hits {
+		response.Posts = append(response.Posts, dto.SearchPostResponse{
+			PostResponse:   postResponses[i],
+			TitleHighlight: hit.TitleHighlight,
+			Snippet:        hit.Snippet,
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(responses); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
 
+// parseSearchDate accepts RFC 3339 timestamps or plain dates. A plain date
+// used as an upper bound covers the whole day.
+func parseSearchDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
 func (h *PostHandler) GetPinnedPosts(w http.ResponseWriter, r *http.Request) {
 	limitStr := r.URL.Query().Get("limit")
 	limit := 5
@@ -475,10 +617,7 @@ func (h *PostHandler) GetPinnedPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var responses []dto.PostResponse
-	for _, post := range posts {
-		responses = append(responses, h.mapPostToResponse(post))
-	}
+	responses := h.mapPostsToResponses(r, posts)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(responses); err != nil {
@@ -501,10 +640,7 @@ func (h *PostHandler) GetFeaturedPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var responses []dto.PostResponse
-	for _, post := range posts {
-		responses = append(responses, h.mapPostToResponse(post))
-	}
+	responses := h.mapPostsToResponses(r, posts)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(responses); err != nil {
@@ -536,10 +672,7 @@ func (h *PostHandler) GetPopularPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var responses []dto.PostResponse
-	for _, post := range posts {
-		responses = append(responses, h.mapPostToResponse(post))
-	}
+	responses := h.mapPostsToResponses(r, posts)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(responses); err != nil {
@@ -570,10 +703,7 @@ func (h *PostHandler) GetPostsByTags(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var responses []dto.PostResponse
-	for _, post := range posts {
-		responses = append(responses, h.mapPostToResponse(post))
-	}
+	responses := h.mapPostsToResponses(r, posts)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(responses); err != nil {
@@ -806,7 +936,29 @@ func (h *PostHandler) UnfeaturePost(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *PostHandler) mapPostToResponse(post *models.Post) dto.PostResponse {
+// mapPostsToResponses maps posts and marks the ones the current user has
+// bookmarked, using a single lookup for the whole page.
+func (h *PostHandler) mapPostsToResponses(r *http.Request, posts []*models.Post) []dto.PostResponse {
+	return mapPostsForUser(r, h.bookmarkService, posts)
+}
+
+func mapPostsForUser(r *http.Request, bookmarkService *service.BookmarkService, posts []*models.Post) []dto.PostResponse {
+	var bookmarked map[primitive.ObjectID]bool
+	if userID, ok := middleware.GetUserIDFromContext(r.Context()); ok && len(posts) > 0 {
+		bookmarked = bookmarkService.BookmarkedPostIDs(userID, posts)
+	}
+
+	var responses []dto.PostResponse
+	for _, post := range posts {
+		response := mapPostToResponse(post)
+		response.BookmarkedByMe = bookmarked[post.ID]
+		responses = append(responses, response)
+	}
+
+	return responses
+}
+
+func mapPostToResponse(post *models.Post) dto.PostResponse {
 	response := dto.PostResponse{
 		ID:              post.ID.Hex(),
 		AuthorID:        post.AuthorID.Hex(),
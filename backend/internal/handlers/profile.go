@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+type ProfileHandler struct {
+	service         *service.ProfileService
+	bookmarkService *service.BookmarkService
+}
+
+func NewProfileHandler(service *service.ProfileService, bookmarkService *service.BookmarkService) *ProfileHandler {
+	return &ProfileHandler{
+		service:         service,
+		bookmarkService: bookmarkService,
+	}
+}
+
+func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	limit, _ := profilePage(r)
+
+	profile, err := h.service.GetProfile(profileViewer(r), userID, limit)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	response := dto.ProfileResponse{
+		PublicUserProfile: mapPublicUserProfile(profile.User),
+		Badges:            make([]dto.BadgeResponse, 0, len(profile.Badges)),
+		Sections:          make(map[string]bool, len(profile.Visible)),
+	}
+	for _, badge := range profile.Badges {
+		response.Badges = append(response.Badges, dto.BadgeResponse{
+			ID:          badge.ID,
+			Name:        badge.Name,
+			Description: badge.Description,
+		})
+	}
+	for section, visible := range profile.Visible {
+		response.Sections[string(section)] = visible
+	}
+	if profile.Posts != nil {
+		response.Posts = h.mapPosts(r, profile.Posts, limit, 0)
+	}
+	if profile.Comments != nil {
+		response.Comments = mapProfileComments(profile.Comments, limit, 0)
+	}
+	if profile.Events != nil {
+		response.Events = mapProfileEvents(profile.Events, limit, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *ProfileHandler) GetPosts(w http.ResponseWriter, r *http.Request) {
+	userID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	limit, offset := profilePage(r)
+
+	page, err := h.service.GetPosts(profileViewer(r), userID, limit, offset)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.mapPosts(r, page, limit, offset))
+}
+
+func (h *ProfileHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	userID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	limit, offset := profilePage(r)
+
+	page, err := h.service.GetComments(profileViewer(r), userID, limit, offset)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapProfileComments(page, limit, offset))
+}
+
+func (h *ProfileHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	userID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	limit, offset := profilePage(r)
+
+	page, err := h.service.GetEvents(profileViewer(r), userID, limit, offset)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapProfileEvents(page, limit, offset))
+}
+
+func (h *ProfileHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	userID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	page, err := h.service.GetActivity(profileViewer(r), userID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	response := dto.ActivityResponse{
+		Items:      make([]dto.ActivityItemResponse, 0, len(page.Items)),
+		NextCursor: page.NextCursor,
+	}
+	for _, item := range page.Items {
+		entry := dto.ActivityItemResponse{
+			Type:           item.Kind,
+			CreatedAt:      item.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			PostID:         item.Post.ID.Hex(),
+			PostTitle:      item.Post.Title,
+			PostAuthorID:   item.Post.AuthorID.Hex(),
+			PostAuthorName: item.Post.AuthorName,
+		}
+		switch item.Kind {
+		case service.ActivityPost:
+			entry.Content = item.Post.Description
+		case service.ActivityComment:
+			entry.CommentID = item.Comment.ID.Hex()
+			entry.Content = item.Comment.Content
+		}
+		response.Items = append(response.Items, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *ProfileHandler) GetPrivacy(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.service.Privacy(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapPrivacySettings(settings))
+}
+
+func (h *ProfileHandler) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.PrivacySettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.UpdatePrivacy(userID, models.PrivacySettings{
+		Posts:    models.Visibility(req.Posts),
+		Comments: models.Visibility(req.Comments),
+		Likes:    models.Visibility(req.Likes),
+		Events:   models.Visibility(req.Events),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidVisibility):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to update privacy settings: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapPrivacySettings(settings))
+}
+
+func (h *ProfileHandler) mapPosts(r *http.Request, page *service.ProfilePostPage, limit, offset int) *dto.ProfilePostsResponse {
+	response := &dto.ProfilePostsResponse{
+		Posts:   []dto.PostResponse{},
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: page.HasMore,
+	}
+	response.Posts = append(response.Posts, mapPostsForUser(r, h.bookmarkService, page.Posts)...)
+	return response
+}
+
+func mapProfileComments(page *service.ProfileCommentPage, limit, offset int) *dto.ProfileCommentsResponse {
+	response := &dto.ProfileCommentsResponse{
+		Comments: make([]dto.ProfileCommentResponse, 0, len(page.Comments)),
+		Limit:    limit,
+		Offset:   offset,
+		HasMore:  page.HasMore,
+	}
+	for _, item := range page.Comments {
+		response.Comments = append(response.Comments, dto.ProfileCommentResponse{
+			CommentResponse: dto.CommentResponse{
+				ID:         item.Comment.ID.Hex(),
+				PostID:     item.Comment.PostID.Hex(),
+				AuthorID:   item.Comment.AuthorID.Hex(),
+				AuthorName: item.Comment.AuthorName,
+				Content:    item.Comment.Content,
+				CreatedAt:  item.Comment.CreatedAt.Format("2006-01-02T15:04:05Z"),
+				UpdatedAt:  item.Comment.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+			},
+			PostTitle: item.PostTitle,
+		})
+	}
+	return response
+}
+
+func mapProfileEvents(page *service.ProfileEventPage, limit, offset int) *dto.ProfileEventsResponse {
+	response := &dto.ProfileEventsResponse{
+		Events:  make([]dto.ProfileEventResponse, 0, len(page.Events)),
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: page.HasMore,
+	}
+	for _, event := range page.Events {
+		response.Events = append(response.Events, dto.ProfileEventResponse{
+			ID:            event.ID.Hex(),
+			Title:         event.Title,
+			Description:   event.Description,
+			Location:      event.Location,
+			Category:      string(event.Category),
+			Status:        string(event.Status),
+			StartDate:     event.StartDate.Format("2006-01-02T15:04:05Z"),
+			EndDate:       event.EndDate.Format("2006-01-02T15:04:05Z"),
+			AttendeeCount: event.AttendeeCount,
+		})
+	}
+	return response
+}
+
+func mapPrivacySettings(settings models.PrivacySettings) dto.PrivacySettingsResponse {
+	return dto.PrivacySettingsResponse{
+		Posts:    string(settings.Posts),
+		Comments: string(settings.Comments),
+		Likes:    string(settings.Likes),
+		Events:   string(settings.Events),
+	}
+}
+
+// profileViewer is the signed-in user, or nil for anonymous visitors.
+func profileViewer(r *http.Request) *primitive.ObjectID {
+	if userID, ok := middleware.GetUserIDFromContext(r.Context()); ok {
+		return &userID
+	}
+	return nil
+}
+
+func profilePage(r *http.Request) (limit, offset int) {
+	limit = 10
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	return limit, offset
+}
+
+func writeProfileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrProfileSectionHidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrInvalidCursor):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to get profile: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+type SearchHandler struct {
+	searchService   *service.SearchService
+	suggestService  *service.SuggestService
+	bookmarkService *service.BookmarkService
+}
+
+func NewSearchHandler(searchService *service.SearchService, suggestService *service.SuggestService, bookmarkService *service.BookmarkService) *SearchHandler {
+	return &SearchHandler{
+		searchService:   searchService,
+		suggestService:  suggestService,
+		bookmarkService: bookmarkService,
+	}
+}
+
+// Search handles GET /api/search?q=&type=. Only the requested groups are
+// present in the response.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	params := service.UnifiedSearchParams{
+		Query:    q.Get("q"),
+		Type:     q.Get("type"),
+		Searcher: searcherFromRequest(r),
+	}
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 {
+		params.Limit = l
+	}
+	if o, err := strconv.Atoi(q.Get("offset")); err == nil && o > 0 {
+		params.Offset = o
+	}
+
+	result, err := h.searchService.Search(params)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmptySearch):
+			http.Error(w, "Search query is required", http.StatusBadRequest)
+		case errors.Is(err, service.ErrInvalidSearchType):
+			http.Error(w, "Invalid search type", http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to search: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := dto.UnifiedSearchResponse{
+		Query:  result.Query,
+		Type:   result.Type,
+		Limit:  result.Limit,
+		Offset: result.Offset,
+	}
+
+	all := result.Type == service.SearchTypeAll
+	if all || result.Type == service.SearchTypePosts {
+		response.Posts = h.mapPostGroup(r, result)
+	}
+	if all || result.Type == service.SearchTypeComments {
+		response.Comments = mapCommentGroup(result)
+	}
+	if all || result.Type == service.SearchTypeUsers {
+		response.Users = mapUserGroup(result)
+	}
+	if all || result.Type == service.SearchTypeEvents {
+		response.Events = mapEventGroup(result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// searcherFromRequest tells searchers apart for the query log: by account
+// when signed in, otherwise by address.
+func searcherFromRequest(r *http.Request) string {
+	if userID, ok := middleware.GetUserIDFromContext(r.Context()); ok {
+		return "user:" + userID.Hex()
+	}
+	return "ip:" + middleware.ClientIP(r)
+}
+
+// Suggest handles GET /api/search/suggest?q=, completing the prefix from
+// post titles, tags, display names and popular queries.
+func (h *SearchHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	suggestions := h.suggestService.Suggest(r.URL.Query().Get("q"), searcherFromRequest(r), limit)
+
+	response := dto.SuggestResponse{
+		Suggestions: make([]dto.SuggestionResponse, 0, len(suggestions)),
+	}
+	for _, s := range suggestions {
+		suggestion := dto.SuggestionResponse{
+			Text: s.Text,
+			Kind: s.Kind,
+		}
+		if s.Kind == service.SuggestKindPost || s.Kind == service.SuggestKindUser {
+			suggestion.ID = s.ID
+		}
+		response.Suggestions = append(response.Suggestions, suggestion)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=30")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// GetZeroResultQueries lists the most frequent logged queries that found
+// nothing within the last `days` days (default 7).
+func (h *SearchHandler) GetZeroResultQueries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	days := 7
+	if d, err := strconv.Atoi(q.Get("days")); err == nil && d > 0 && d <= 90 {
+		days = d
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	source := q.Get("source")
+	if source != "" && source != models.SearchSourceSearch && source != models.SearchSourceSuggest {
+		http.Error(w, "Invalid source", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.suggestService.ZeroResultQueries(source, time.Now().AddDate(0, 0, -days), limit)
+	if err != nil {
+		http.Error(w, "Failed to get queries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if stats == nil {
+		stats = []*models.SearchQueryStat{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":    days,
+		"queries": stats,
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *SearchHandler) mapPostGroup(r *http.Request, result *service.UnifiedSearchResult) *dto.SearchPostGroup {
+	posts := make([]*models.Post, 0, len(result.Posts))
+	for _, hit := range result.Posts {
+		posts = append(posts, hit.Post)
+	}
+	postResponses := mapPostsForUser(r, h.bookmarkService, posts)
+
+	group := &dto.SearchPostGroup{
+		Total:   result.PostTotal,
+		Results: make([]dto.SearchPostResponse, 0, len(result.Posts)),
+	}
+	for i, hit := range result.Posts {
+		group.Results = append(group.Results, dto.SearchPostResponse{
+			PostResponse:   postResponses[i],
+			TitleHighlight: hit.TitleHighlight,
+			Snippet:        hit.Snippet,
+			Score:          hit.Score,
+		})
+	}
+	return group
+}
+
+func mapCommentGroup(result *service.UnifiedSearchResult) *dto.SearchCommentGroup {
+	group := &dto.SearchCommentGroup{
+		Total:   result.CommentTotal,
+		Results: make([]dto.SearchCommentResponse, 0, len(result.Comments)),
+	}
+	for _, hit := range result.Comments {
+		group.Results = append(group.Results, dto.SearchCommentResponse{
+			ID:         hit.Comment.ID.Hex(),
+			PostID:     hit.Comment.PostID.Hex(),
+			PostTitle:  hit.PostTitle,
+			AuthorID:   hit.Comment.AuthorID.Hex(),
+			AuthorName: hit.Comment.AuthorName,
+			Snippet:    hit.Snippet,
+			CreatedAt:  hit.Comment.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			Score:      hit.Score,
+		})
+	}
+	return group
+}
+
+func mapUserGroup(result *service.UnifiedSearchResult) *dto.SearchUserGroup {
+	group := &dto.SearchUserGroup{
+		Total:   result.UserTotal,
+		Results: make([]dto.SearchUserResponse, 0, len(result.Users)),
+	}
+	for _, hit := range result.Users {
+		group.Results = append(group.Results, dto.SearchUserResponse{
+			ID:            hit.User.ID.Hex(),
+			DisplayName:   hit.User.DisplayName,
+			NameHighlight: hit.NameHighlight,
+			Role:          string(hit.User.Role),
+			ProfileImage:  hit.User.AvatarURL(),
+			BioSnippet:    hit.BioSnippet,
+			FollowerCount: hit.User.FollowerCount,
+			Score:         hit.Score,
+		})
+	}
+	return group
+}
+
+func mapEventGroup(result *service.UnifiedSearchResult) *dto.SearchEventGroup {
+	group := &dto.SearchEventGroup{
+		Total:   result.EventTotal,
+		Results: make([]dto.SearchEventResponse, 0, len(result.Events)),
+	}
+	for _, hit := range result.Events {
+		group.Results = append(group.Results, dto.SearchEventResponse{
+			ID:             hit.Event.ID.Hex(),
+			Title:          hit.Event.Title,
+			TitleHighlight: hit.TitleHighlight,
+			Snippet:        hit.Snippet,
+			Location:       hit.Event.Location,
+			Category:       string(hit.Event.Category),
+			Status:         string(hit.Event.Status),
+			StartDate:      hit.Event.StartDate.Format("2006-01-02T15:04:05Z"),
+			EndDate:        hit.Event.EndDate.Format("2006-01-02T15:04:05Z"),
+			AttendeeCount:  hit.Event.AttendeeCount,
+			Score:          hit.Score,
+		})
+	}
+	return group
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+//go:embed templates/share_post.html templates/share_error.html
+var shareTemplatesFS embed.FS
+
+var shareTemplates = template.Must(template.ParseFS(shareTemplatesFS, "templates/*.html"))
+
+const shareDescriptionLength = 200
+
+type sharePageData struct {
+	Title        string
+	Description  string
+	Category     string
+	PublishedAt  string
+	ImageURL     string
+	CanonicalURL string
+	AppURL       string
+}
+
+type shareErrorData struct {
+	Title   string
+	Message string
+}
+
+type ShareHandler struct {
+	postService *service.PostService
+	publicURL   string
+}
+
+func NewShareHandler(postService *service.PostService, publicURL string) *ShareHandler {
+	if publicURL == "" {
+		log.Printf("PUBLIC_URL is not set; share pages will use relative links, which some link previews ignore")
+	}
+	return &ShareHandler{
+		postService: postService,
+		publicURL:   strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+// SharePost renders a minimal page carrying Open Graph and Twitter Card
+// metadata for link previews, then redirects browsers to the SPA.
+func (h *ShareHandler) SharePost(w http.ResponseWriter, r *http.Request) {
+	postID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		h.renderError(w, http.StatusNotFound, "Post not found", "This post does not exist.")
+		return
+	}
+
+	post, err := h.postService.GetPostByID(postID)
+	if err != nil {
+		h.renderError(w, http.StatusNotFound, "Post not found", "This post does not exist.")
+		return
+	}
+
+	if post.IsArchived {
+		h.renderError(w, http.StatusGone, "Post removed", "This post is no longer available.")
+		return
+	}
+
+	// Never derived from the request: Host and X-Forwarded-Proto are up to
+	// the client, and the page is cached publicly
+	baseURL := h.publicURL
+	data := sharePageData{
+		Title:        post.Title,
+		Description:  shareDescription(post),
+		Category:     string(post.Category),
+		PublishedAt:  post.CreatedAt.UTC().Format(time.RFC3339),
+		CanonicalURL: baseURL + "/p/" + post.ID.Hex(),
+		AppURL:       baseURL + "/post-detail.html?id=" + post.ID.Hex(),
+	}
+	if thumbnail := post.GetThumbnailURL(); thumbnail != "" {
+		data.ImageURL = absoluteURL(baseURL, thumbnail)
+	}
+
+	h.render(w, http.StatusOK, "share_post.html", data)
+}
+
+func (h *ShareHandler) renderError(w http.ResponseWriter, status int, title, message string) {
+	h.render(w, status, "share_error.html", shareErrorData{Title: title, Message: message})
+}
+
+func (h *ShareHandler) render(w http.ResponseWriter, status int, name string, data interface{}) {
+	var buf bytes.Buffer
+	if err := shareTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if status == http.StatusOK {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	}
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func shareDescription(post *models.Post) string {
+	text := post.Description
+	if strings.TrimSpace(text) == "" {
+		text = post.Content
+	}
+	text = strings.Join(strings.Fields(text), " ")
+
+	if utf8.RuneCountInString(text) <= shareDescriptionLength {
+		return text
+	}
+
+	runes := []rune(text)[:shareDescriptionLength]
+	if i := strings.LastIndex(string(runes), " "); i > shareDescriptionLength/2 {
+		return string(runes)[:i] + "…"
+	}
+	return string(runes) + "…"
+}
+
+func absoluteURL(baseURL, path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return baseURL + path
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+type sharePosts struct {
+	repository.PostRepository
+	post *models.Post
+}
+
+func (r *sharePosts) FindByID(id primitive.ObjectID) (*models.Post, error) {
+	if r.post == nil || r.post.ID != id {
+		return nil, mongo.ErrNoDocuments
+	}
+	return r.post, nil
+}
+
+func serveSharePage(publicURL string, post *models.Post, host string) *httptest.ResponseRecorder {
+	postService := service.NewPostService(&sharePosts{post: post}, nil, nil, nil, nil, nil, nil, nil)
+	router := chi.NewRouter()
+	router.Get("/p/{id}", NewShareHandler(postService, publicURL).SharePost)
+
+	req := httptest.NewRequest(http.MethodGet, "/p/"+post.ID.Hex(), nil)
+	req.Host = host
+	req.Header.Set("X-Forwarded-Proto", "javascript")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSharePageIgnoresRequestHost(t *testing.T) {
+	post := &models.Post{ID: primitive.NewObjectID(), Title: "Hackathon results", Content: "We won", CreatedAt: time.Now()}
+
+	for _, publicURL := range []string{"https://fanpage.test", ""} {
+		rec := serveSharePage(publicURL, post, "evil.example")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d", rec.Code)
+		}
+		body := rec.Body.String()
+		if strings.Contains(body, "evil.example") || strings.Contains(body, "javascript:") {
+			t.Errorf("PUBLIC_URL %q: page uses the request host:\n%s", publicURL, body)
+		}
+		canonical := `<link rel="canonical" href="` + publicURL + "/p/" + post.ID.Hex() + `">`
+		if !strings.Contains(body, canonical) {
+			t.Errorf("PUBLIC_URL %q: no %s in\n%s", publicURL, canonical, body)
+		}
+	}
+}
+
+func TestSharePagePublishedTimeIsUTC(t *testing.T) {
+	almaty := time.FixedZone("ALMT", 5*60*60)
+	post := &models.Post{ID: primitive.NewObjectID(), Title: "Hackathon results", CreatedAt: time.Date(2025, 3, 1, 9, 30, 0, 0, almaty)}
+
+	body := serveSharePage("https://fanpage.test", post, "fanpage.test").Body.String()
+	if want := `content="2025-03-01T04:30:00Z"`; !strings.Contains(body, want) {
+		t.Errorf("no %s in\n%s", want, body)
+	}
+}
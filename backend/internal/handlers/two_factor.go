@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
+)
+
+// CompleteTwoFactorLogin is the second login step: the challenge from
+// Login plus a code from the app or a recovery code.
+func (h *AuthHandler) CompleteTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var req dto.TwoFactorLoginRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, user, err := h.authService.CompleteTwoFactorLogin(req.ChallengeToken, req.Code, sessionClient(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrInvalidTwoFactorCode || err == service.ErrInvalidTwoFactorChallenge {
+			status = http.StatusUnauthorized
+		} else if err == service.ErrAccountDeactivated {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapAuthResponse(tokens, user))
+}
+
+func (h *AuthHandler) GetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.authService.TwoFactorStatus(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.TwoFactorStatusResponse{
+		Enabled:           status.Enabled,
+		Required:          status.Required,
+		RecoveryCodesLeft: status.RecoveryCodesLeft,
+	})
+}
+
+// BeginTwoFactorSetup returns a new secret to scan. It takes effect only
+// once confirmed through EnableTwoFactor.
+func (h *AuthHandler) BeginTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setup, err := h.authService.BeginTwoFactorSetup(userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrTwoFactorAlreadyEnabled {
+			status = http.StatusConflict
+		} else if err == service.ErrUserNotFound {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.TwoFactorSetupResponse{
+		Secret:     setup.Secret,
+		OTPAuthURI: setup.URI,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(setup.QRCode),
+	})
+}
+
+func (h *AuthHandler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, codes, err := h.authService.EnableTwoFactor(userID, req.Code, sessionClient(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrInvalidTwoFactorCode || err == service.ErrTwoFactorSetupNotStarted {
+			status = http.StatusBadRequest
+		} else if err == service.ErrTwoFactorAlreadyEnabled {
+			status = http.StatusConflict
+		} else if err == service.ErrUserNotFound {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	user, err := h.authService.GetCurrentUser(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.EnableTwoFactorResponse{
+		AuthResponse:  mapAuthResponse(tokens, user),
+		RecoveryCodes: codes,
+	})
+}
+
+func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.DisableTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(userID, req.Password, req.Code); err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrInvalidTwoFactorCode || err == service.ErrInvalidCredentials || err == service.ErrTwoFactorNotEnabled {
+			status = http.StatusBadRequest
+		} else if err == service.ErrTwoFactorRequiredForRole {
+			status = http.StatusForbidden
+		} else if err == service.ErrUserNotFound {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes invalidates the old recovery codes and returns a
+// new set.
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrInvalidTwoFactorCode || err == service.ErrTwoFactorNotEnabled {
+			status = http.StatusBadRequest
+		} else if err == service.ErrUserNotFound {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
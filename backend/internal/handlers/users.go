@@ -9,6 +9,8 @@ import (
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/service"
 )
 
@@ -34,20 +36,24 @@ func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := dto.PublicUserProfile{
-		ID:           user.ID.Hex(),
-		DisplayName:  user.DisplayName,
-		Role:         string(user.Role),
-		ProfileImage: user.ProfileImage,
-		Bio:          user.Bio,
-		PostCount:    user.PostCount,
-		LikeCount:    user.LikeCount,
-		CommentCount: user.CommentCount,
-		CreatedAt:    user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(mapPublicUserProfile(user))
+}
+
+func mapPublicUserProfile(user *models.User) dto.PublicUserProfile {
+	return dto.PublicUserProfile{
+		ID:             user.ID.Hex(),
+		DisplayName:    user.DisplayName,
+		Role:           string(user.Role),
+		ProfileImage:   user.AvatarURL(),
+		Bio:            user.Bio,
+		PostCount:      user.PostCount,
+		LikeCount:      user.LikeCount,
+		CommentCount:   user.CommentCount,
+		FollowerCount:  user.FollowerCount,
+		FollowingCount: user.FollowingCount,
+		CreatedAt:      user.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
 }
 
 func (h *UserHandler) GetUserStats(w http.ResponseWriter, r *http.Request) {
@@ -64,14 +70,8 @@ func (h *UserHandler) GetUserStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user is requesting their own stats or is admin
-	currentUser, err := h.service.GetUserByID(currentUserID)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	if currentUserID != userID && !currentUser.CanViewAnalytics() {
+	// Everyone may see their own stats; users.view_stats covers others
+	if err := h.service.Authorize(currentUserID, policy.UsersViewStats, policy.Owned("user", userID, userID)); err != nil {
 		http.Error(w, "Permission denied", http.StatusForbidden)
 		return
 	}
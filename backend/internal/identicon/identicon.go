@@ -0,0 +1,85 @@
+// Package identicon draws the default avatar: a mirrored 5x5 pattern in a
+// color derived from a seed, so each user gets a stable, distinct image.
+package identicon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// cells is the pattern size; the left three columns are mirrored.
+const cells = 5
+
+// PNG renders the identicon for seed as a size x size image. A margin of
+// half a cell surrounds the pattern.
+func PNG(seed string, size int) ([]byte, error) {
+	sum := sha256.Sum256([]byte(seed))
+
+	background := color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+	foreground := hslColor(float64(uint16(sum[0])<<8|uint16(sum[1]))/65536, 0.55, 0.5)
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{background, foreground})
+
+	var pattern [cells][cells]bool
+	bit := 0
+	for x := 0; x < (cells+1)/2; x++ {
+		for y := 0; y < cells; y++ {
+			on := sum[2+bit/8]&(1<<(bit%8)) != 0
+			pattern[y][x] = on
+			pattern[y][cells-1-x] = on
+			bit++
+		}
+	}
+
+	// Coordinates are in half cells, which leaves the margin
+	halves := 2 * (cells + 1)
+	for py := 0; py < size; py++ {
+		y := py*halves/size - 1
+		for px := 0; px < size; px++ {
+			x := px*halves/size - 1
+			if x < 0 || y < 0 || x >= 2*cells || y >= 2*cells {
+				continue
+			}
+			if pattern[y/2][x/2] {
+				img.Pix[py*img.Stride+px] = 1
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func hslColor(h, s, l float64) color.RGBA {
+	hue := func(t float64) uint8 {
+		q := l + s - l*s
+		if l < 0.5 {
+			q = l * (1 + s)
+		}
+		p := 2*l - q
+		if t < 0 {
+			t++
+		}
+		if t > 1 {
+			t--
+		}
+		var v float64
+		switch {
+		case t < 1.0/6:
+			v = p + (q-p)*6*t
+		case t < 0.5:
+			v = q
+		case t < 2.0/3:
+			v = p + (q-p)*(2.0/3-t)*6
+		default:
+			v = p
+		}
+		return uint8(v*255 + 0.5)
+	}
+	return color.RGBA{R: hue(h + 1.0/3), G: hue(h), B: hue(h - 1.0/3), A: 0xff}
+}
@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes each message as an .eml file into an outbox directory
+// instead of sending it. Meant for development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if dir == "" {
+		dir = "./outbox"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	body, err := compose(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	suffix, err := randomHex(4)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), suffix)
+	return os.WriteFile(filepath.Join(m.dir, name), body, 0644)
+}
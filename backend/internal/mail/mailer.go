@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+)
+
+// Message is one email with a plain text and an HTML body. Either body may
+// be empty.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns the mailer selected by cfg.Driver: "smtp", "file" or "none".
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("mail: SMTP_HOST is required for the smtp driver")
+		}
+		return NewSMTPMailer(cfg), nil
+	case "file":
+		return NewFileMailer(cfg.OutboxDir, cfg.From)
+	case "", "none":
+		return NopMailer{}, nil
+	default:
+		return nil, fmt.Errorf("mail: unknown driver %q", cfg.Driver)
+	}
+}
+
+// NopMailer drops every message.
+type NopMailer struct{}
+
+func (NopMailer) Send(msg Message) error {
+	return nil
+}
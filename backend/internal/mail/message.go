@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// compose renders msg as an RFC 5322 message. With both bodies present it
+// is multipart/alternative, text first so clients prefer the HTML part.
+func compose(from string, msg Message) ([]byte, error) {
+	boundary, err := randomHex(12)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if at := strings.LastIndex(addr.Address, "@"); at >= 0 {
+			domain = addr.Address[at+1:]
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", id, domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	switch {
+	case msg.Text != "" && msg.HTML != "":
+		fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain", msg.Text},
+			{"text/html", msg.HTML},
+		} {
+			fmt.Fprintf(&buf, "--%s\r\n", boundary)
+			if err := writePart(&buf, part.contentType, part.body); err != nil {
+				return nil, err
+			}
+		}
+		fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	case msg.HTML != "":
+		if err := writePart(&buf, "text/html", msg.HTML); err != nil {
+			return nil, err
+		}
+	default:
+		if err := writePart(&buf, "text/plain", msg.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writePart(buf *bytes.Buffer, contentType, body string) error {
+	fmt.Fprintf(buf, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	buf.WriteString("\r\n")
+	return nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,108 @@
+package mail
+
+import (
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+)
+
+const (
+	smtpDialTimeout = 10 * time.Second
+	smtpSendTimeout = 30 * time.Second
+)
+
+// SMTPMailer delivers through an SMTP relay. Port 465 uses implicit TLS;
+// other ports upgrade with STARTTLS when the server offers it.
+type SMTPMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(cfg config.MailConfig) *SMTPMailer {
+	port := cfg.SMTPPort
+	if port == "" {
+		port = "587"
+	}
+	return &SMTPMailer{
+		host:     cfg.SMTPHost,
+		port:     port,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+		from:     cfg.From,
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	body, err := compose(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.host, m.port)
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+
+	var conn net.Conn
+	if m.port == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: m.host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpSendTimeout))
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
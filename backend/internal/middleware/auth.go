@@ -2,28 +2,46 @@ package middleware
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-chi/jwtauth/v5"
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
 )
 
+// sessionTouchInterval throttles last-seen writes to one per session per
+// interval.
+const sessionTouchInterval = time.Minute
+
 type AuthMiddleware struct {
-	tokenAuth *jwtauth.JWTAuth
-	userRepo  repository.UserRepository
-	cfg       *config.Config
+	tokenAuth       *jwtauth.JWTAuth
+	userRepo        repository.UserRepository
+	sessionRepo     repository.SessionRepository
+	accessTokenRepo repository.AccessTokenRepository
+	policy          *policy.Engine
+	cfg             *config.Config
 }
 
-func NewAuthMiddleware(cfg *config.Config, userRepo repository.UserRepository) *AuthMiddleware {
+func NewAuthMiddleware(cfg *config.Config, userRepo repository.UserRepository, sessionRepo repository.SessionRepository, accessTokenRepo repository.AccessTokenRepository, engine *policy.Engine) *AuthMiddleware {
 	return &AuthMiddleware{
-		tokenAuth: jwtauth.New("HS256", []byte(cfg.JWT.SecretKey), nil),
-		userRepo:  userRepo,
-		cfg:       cfg,
+		tokenAuth:       jwtauth.New("HS256", []byte(cfg.JWT.SecretKey), nil),
+		userRepo:        userRepo,
+		sessionRepo:     sessionRepo,
+		accessTokenRepo: accessTokenRepo,
+		policy:          engine,
+		cfg:             cfg,
 	}
 }
 
@@ -37,10 +55,20 @@ func (am *AuthMiddleware) Authenticator(next http.Handler) http.Handler {
 			return
 		}
 
+		if strings.HasPrefix(tokenString, models.AccessTokenPrefix) {
+			am.authenticateAccessToken(w, r, tokenString, next)
+			return
+		}
+
 		token, err := jwtauth.VerifyToken(am.tokenAuth, tokenString)
+		if err == nil && token.Expiration().IsZero() {
+			// Tokens issued before expiry was introduced never expire
+			err = jwtauth.ErrExpired
+		}
 		if err != nil {
 			// If token is invalid, still continue but without user context
-			// This allows public
+			// This allows public. The header tells clients to refresh.
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -63,66 +91,112 @@ func (am *AuthMiddleware) Authenticator(next http.Handler) http.Handler {
 			return
 		}
 
+		// A revoked session invalidates its access tokens right away
+		// instead of when they expire
+		session, ok := am.activeSession(claims, userID)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		user, err := am.userRepo.FindByID(userID)
 		if err != nil || !user.IsActive {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), "user", user)
-		ctx = context.WithValue(ctx, "userID", userID)
-		ctx = context.WithValue(ctx, "userRole", string(user.Role))
+		if now := time.Now(); now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+			if err := am.sessionRepo.Touch(session.ID, ClientIP(r), now); err != nil {
+				log.Printf("Failed to update session %s: %v", session.ID.Hex(), err)
+			}
+		}
+
+		ctx := withUser(r.Context(), user)
+		ctx = context.WithValue(ctx, "sessionID", session.ID)
+		ctx = context.WithValue(ctx, "twoFactor", session.TwoFactor)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func (am *AuthMiddleware) RequireRole(role models.UserRole) func(next http.Handler) http.Handler {
+// authenticateAccessToken recognizes a personal access token. Its user is
+// only put in the context by RequireScope, so routes that do not ask for a
+// scope treat the request as anonymous.
+func (am *AuthMiddleware) authenticateAccessToken(w http.ResponseWriter, r *http.Request, tokenString string, next http.Handler) {
+	if am.accessTokenRepo == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	now := time.Now()
+	sum := sha256.Sum256([]byte(tokenString))
+	token, err := am.accessTokenRepo.FindByHash(hex.EncodeToString(sum[:]))
+	if err != nil || token.IsExpired(now) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	user, err := am.userRepo.FindByID(token.UserID)
+	if err != nil || !user.IsActive {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= sessionTouchInterval {
+		if err := am.accessTokenRepo.Touch(token.ID, ClientIP(r), now); err != nil {
+			log.Printf("Failed to update access token %s: %v", token.ID.Hex(), err)
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), "accessToken", token)
+	ctx = context.WithValue(ctx, "accessTokenUser", user)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope lets personal access tokens with scope through as their
+// user and turns away those without it. Requests signed in with a session
+// are not limited by scopes.
+func (am *AuthMiddleware) RequireScope(scope string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userRole := r.Context().Value("userRole")
-			if userRole == nil {
-				http.Error(w, "Authentication required", http.StatusUnauthorized)
+			token, ok := r.Context().Value("accessToken").(*models.AccessToken)
+			if !ok {
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			if string(role) != userRole.(string) && userRole.(string) != string(models.RoleAdmin) {
-				http.Error(w, "Insufficient permissions", http.StatusForbidden)
+			if !token.HasScope(scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+				http.Error(w, "Access token lacks the "+scope+" scope", http.StatusForbidden)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			user := r.Context().Value("accessTokenUser").(*models.User)
+			next.ServeHTTP(w, r.WithContext(withUser(r.Context(), user)))
 		})
 	}
 }
 
-func (am *AuthMiddleware) RequireAnyRole(roles ...models.UserRole) func(next http.Handler) http.Handler {
+// RequirePermission lets a request through when the policy grants its
+// user perm. Denials are audited by the policy engine.
+func (am *AuthMiddleware) RequirePermission(perm policy.Permission) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userRole := r.Context().Value("userRole")
-			if userRole == nil {
+			user, ok := GetUserFromContext(r.Context())
+			if !ok {
 				http.Error(w, "Authentication required", http.StatusUnauthorized)
 				return
 			}
 
-			hasRole := false
-			currentRole := userRole.(string)
-
-			// Admin can do anything
-			if currentRole == string(models.RoleAdmin) {
-				next.ServeHTTP(w, r)
+			if err := am.policy.AuthorizeRoute(user, perm, r.Method+" "+r.URL.Path, ClientIP(r)); err != nil {
+				http.Error(w, "Insufficient permissions", http.StatusForbidden)
 				return
 			}
 
-			for _, role := range roles {
-				if currentRole == string(role) {
-					hasRole = true
-					break
-				}
-			}
-
-			if !hasRole {
-				http.Error(w, "Insufficient permissions", http.StatusForbidden)
+			if !policy.TwoFactorSatisfied(user, GetTwoFactorFromContext(r.Context())) {
+				WriteTwoFactorRequired(w, r)
 				return
 			}
 
@@ -131,18 +205,62 @@ func (am *AuthMiddleware) RequireAnyRole(roles ...models.UserRole) func(next htt
 	}
 }
 
-func (am *AuthMiddleware) GenerateToken(user *models.User) (string, error) {
+// WriteTwoFactorRequired answers with a code the client can send the user
+// to the enrolment page on. Access tokens never pass a TOTP check, so they
+// are told plainly instead.
+func WriteTwoFactorRequired(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.Context().Value("accessToken").(*models.AccessToken); ok {
+		http.Error(w, "Access tokens cannot be used for staff actions", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{
+		Code:    "two_factor_required",
+		Message: "Your role requires two-factor authentication; enable it and sign in again",
+	})
+}
+
+func (am *AuthMiddleware) activeSession(claims map[string]interface{}, userID primitive.ObjectID) (*models.Session, bool) {
+	sid, _ := claims["sid"].(string)
+	sessionID, err := primitive.ObjectIDFromHex(sid)
+	if err != nil {
+		return nil, false
+	}
+
+	session, err := am.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive(time.Now()) {
+		return nil, false
+	}
+	return session, true
+}
+
+// GenerateToken issues an access token for the session that expires after
+// the configured access TTL. Each token carries a unique jti.
+func (am *AuthMiddleware) GenerateToken(user *models.User, sessionID primitive.ObjectID) (string, error) {
 	claims := map[string]interface{}{
 		"user_id": user.ID.Hex(),
 		"email":   user.Email,
 		"role":    string(user.Role),
 		"name":    user.DisplayName,
+		"jti":     uuid.NewString(),
+		"sid":     sessionID.Hex(),
 	}
+	jwtauth.SetIssuedNow(claims)
+	jwtauth.SetExpiryIn(claims, am.AccessTokenTTL())
 
 	_, tokenString, err := am.tokenAuth.Encode(claims)
 	return tokenString, err
 }
 
+func (am *AuthMiddleware) AccessTokenTTL() time.Duration {
+	if am.cfg.JWT.AccessTokenTTL <= 0 {
+		return 15 * time.Minute
+	}
+	return am.cfg.JWT.AccessTokenTTL
+}
+
 func extractToken(r *http.Request) string {
 	bearerToken := r.Header.Get("Authorization")
 	if bearerToken == "" {
@@ -156,6 +274,12 @@ func extractToken(r *http.Request) string {
 	return bearerToken
 }
 
+func withUser(ctx context.Context, user *models.User) context.Context {
+	ctx = context.WithValue(ctx, "user", user)
+	ctx = context.WithValue(ctx, "userID", user.ID)
+	return context.WithValue(ctx, "userRole", string(user.Role))
+}
+
 func GetUserFromContext(ctx context.Context) (*models.User, bool) {
 	user, ok := ctx.Value("user").(*models.User)
 	return user, ok
@@ -165,3 +289,15 @@ func GetUserIDFromContext(ctx context.Context) (primitive.ObjectID, bool) {
 	userID, ok := ctx.Value("userID").(primitive.ObjectID)
 	return userID, ok
 }
+
+// GetTwoFactorFromContext reports whether the request's session passed a
+// TOTP check.
+func GetTwoFactorFromContext(ctx context.Context) bool {
+	twoFactor, _ := ctx.Value("twoFactor").(bool)
+	return twoFactor
+}
+
+func GetSessionIDFromContext(ctx context.Context) (primitive.ObjectID, bool) {
+	sessionID, ok := ctx.Value("sessionID").(primitive.ObjectID)
+	return sessionID, ok
+}
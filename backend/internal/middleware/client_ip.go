@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseTrustedProxies reads a list of proxy addresses or CIDR ranges.
+func ParseTrustedProxies(list []string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, entry := range list {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// TrustedProxies works out the client address of each request for
+// ClientIP. Forwarding headers are only believed when the peer is one of
+// the proxies: X-Forwarded-For is read from the right, skipping proxy
+// hops, since each proxy appends to whatever the client sent.
+func TrustedProxies(proxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r, proxies)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "clientIP", ip)))
+		})
+	}
+}
+
+// ClientIP is the address TrustedProxies resolved, or the peer address
+// when it did not run.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value("clientIP").(string); ok {
+		return ip
+	}
+	return peerIP(r)
+}
+
+func resolveClientIP(r *http.Request, proxies []*net.IPNet) string {
+	peer := peerIP(r)
+	if !isTrustedProxy(peer, proxies) {
+		return peer
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if !isTrustedProxy(hop, proxies) {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return peer
+}
+
+func peerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func isTrustedProxy(addr string, proxies []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
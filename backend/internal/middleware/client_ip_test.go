@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "", "203.0.113.7"},
+		{"untrusted peer cannot forward", "203.0.113.7:5000", []string{"1.2.3.4"}, "1.2.3.4", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:5000", []string{"198.51.100.9"}, "", "198.51.100.9"},
+		{"spoofed left-most hop is ignored", "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.9"}, "", "198.51.100.9"},
+		{"proxy chain", "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.9, 192.168.1.1"}, "", "198.51.100.9"},
+		{"repeated headers", "10.0.0.2:5000", []string{"1.2.3.4", "198.51.100.9"}, "", "198.51.100.9"},
+		{"real ip from trusted proxy", "10.0.0.2:5000", nil, "198.51.100.9", "198.51.100.9"},
+		{"garbage falls back to peer", "10.0.0.2:5000", []string{"not-an-ip"}, "", "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			var got string
+			TrustedProxies(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesRejectsInvalid(t *testing.T) {
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected an error for an invalid range")
+	}
+}
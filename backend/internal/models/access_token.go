@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scopes a personal access token can be granted. A token only reaches
+// routes that ask for one of its scopes.
+const (
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopeCommentsWrite = "comments:write"
+	ScopeProfileRead   = "profile:read"
+)
+
+// AccessTokenPrefix starts every personal access token, which tells them
+// apart from JWTs.
+const AccessTokenPrefix = "afp_"
+
+var AccessTokenScopes = []string{ScopePostsRead, ScopePostsWrite, ScopeCommentsWrite, ScopeProfileRead}
+
+// AccessToken is a personal access token for bots and integrations. It is
+// stored by the SHA-256 of its value; Prefix is kept so users can tell
+// their tokens apart.
+type AccessToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	UserID     primitive.ObjectID `bson:"user_id"`
+	Name       string             `bson:"name"`
+	TokenHash  string             `bson:"token_hash"`
+	Prefix     string             `bson:"prefix"`
+	Scopes     []string           `bson:"scopes"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty"`
+	LastUsedIP string             `bson:"last_used_ip,omitempty"`
+}
+
+func NewAccessToken(userID primitive.ObjectID, name, tokenHash, prefix string, scopes []string, expiresAt *time.Time) *AccessToken {
+	return &AccessToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Name:      name,
+		TokenHash: tokenHash,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+}
+
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *AccessToken) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(now)
+}
+
+func IsAccessTokenScope(scope string) bool {
+	for _, s := range AccessTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
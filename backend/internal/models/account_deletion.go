@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// DeletionMode decides what happens to a deleted user's posts and
+// comments.
+type DeletionMode string
+
+const (
+	DeletionModeDelete    DeletionMode = "delete"
+	DeletionModeAnonymize DeletionMode = "anonymize"
+)
+
+// DeletedUserName is shown as the author of anonymized content.
+const DeletedUserName = "Deleted user"
+
+func IsValidDeletionMode(mode DeletionMode) bool {
+	return mode == DeletionModeDelete || mode == DeletionModeAnonymize
+}
+
+// AccountDeletion is a scheduled self-deletion. The user can cancel it or
+// change Mode until ScheduledFor passes.
+type AccountDeletion struct {
+	Mode         DeletionMode `bson:"mode"`
+	RequestedAt  time.Time    `bson:"requested_at"`
+	ScheduledFor time.Time    `bson:"scheduled_for"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit actions.
+const (
+	AuditPermissionDenied   = "permission_denied"
+	AuditPermissionsChanged = "role_permissions_changed"
+)
+
+// AuditEntry records a security-relevant event: who tried what, on which
+// resource and through which route. Route is empty for checks made inside
+// services.
+type AuditEntry struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty"`
+	Action       string              `bson:"action"`
+	UserID       primitive.ObjectID  `bson:"user_id"`
+	Role         UserRole            `bson:"role"`
+	Permission   string              `bson:"permission,omitempty"`
+	ResourceType string              `bson:"resource_type,omitempty"`
+	ResourceID   *primitive.ObjectID `bson:"resource_id,omitempty"`
+	Route        string              `bson:"route,omitempty"`
+	IP           string              `bson:"ip,omitempty"`
+	Detail       string              `bson:"detail,omitempty"`
+	CreatedAt    time.Time           `bson:"created_at"`
+	ExpiresAt    time.Time           `bson:"expires_at"`
+}
+
+func NewAuditEntry(action string, user *User, retention time.Duration) *AuditEntry {
+	now := time.Now()
+	return &AuditEntry{
+		ID:        primitive.NewObjectID(),
+		Action:    action,
+		UserID:    user.ID,
+		Role:      user.Role,
+		CreatedAt: now,
+		ExpiresAt: now.Add(retention),
+	}
+}
+
+// RolePermissions overrides the permissions of a role. Stored overrides
+// win over the built-in defaults and the policy file.
+type RolePermissions struct {
+	Role        UserRole           `bson:"_id"`
+	Permissions []string           `bson:"permissions"`
+	UpdatedAt   time.Time          `bson:"updated_at"`
+	UpdatedBy   primitive.ObjectID `bson:"updated_by"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Badge is an achievement shown on the public profile. Badges are derived
+// from the account and its counters rather than stored.
+type Badge struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+var badgeRules = []struct {
+	badge  Badge
+	earned func(u *User, now time.Time) bool
+}{
+	{
+		Badge{ID: "staff", Name: "Staff", Description: "Admin or moderator of the fanpage"},
+		func(u *User, now time.Time) bool { return u.Role == RoleAdmin || u.Role == RoleModerator },
+	},
+	{
+		Badge{ID: "alumni", Name: "Alumni", Description: "Graduated from the university"},
+		func(u *User, now time.Time) bool { return u.Role == RoleAlumni },
+	},
+	{
+		Badge{ID: "verified", Name: "Verified", Description: "Confirmed their university email"},
+		func(u *User, now time.Time) bool { return u.EmailVerified },
+	},
+	{
+		Badge{ID: "veteran", Name: "Veteran", Description: "Member for over a year"},
+		func(u *User, now time.Time) bool { return now.Sub(u.CreatedAt) >= 365*24*time.Hour },
+	},
+	{
+		Badge{ID: "first_post", Name: "First Post", Description: "Published a post"},
+		func(u *User, now time.Time) bool { return u.PostCount >= 1 },
+	},
+	{
+		Badge{ID: "prolific", Name: "Prolific", Description: "Published 25 posts"},
+		func(u *User, now time.Time) bool { return u.PostCount >= 25 },
+	},
+	{
+		Badge{ID: "conversationalist", Name: "Conversationalist", Description: "Wrote 50 comments"},
+		func(u *User, now time.Time) bool { return u.CommentCount >= 50 },
+	},
+	{
+		Badge{ID: "popular", Name: "Popular", Description: "Followed by 50 people"},
+		func(u *User, now time.Time) bool { return u.FollowerCount >= 50 },
+	},
+}
+
+// Badges lists the badges the user has earned as of now.
+func (u *User) Badges(now time.Time) []Badge {
+	badges := []Badge{}
+	for _, rule := range badgeRules {
+		if rule.earned(u, now) {
+			badges = append(badges, rule.badge)
+		}
+	}
+	return badges
+}
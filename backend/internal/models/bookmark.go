@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Bookmark struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	PostID       primitive.ObjectID  `bson:"post_id" json:"post_id"`
+	CollectionID *primitive.ObjectID `bson:"collection_id" json:"collection_id,omitempty"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+}
+
+type BookmarkCollection struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+func NewBookmark(userID, postID primitive.ObjectID, collectionID *primitive.ObjectID) *Bookmark {
+	return &Bookmark{
+		ID:           primitive.NewObjectID(),
+		UserID:       userID,
+		PostID:       postID,
+		CollectionID: collectionID,
+		CreatedAt:    time.Now(),
+	}
+}
+
+func NewBookmarkCollection(userID primitive.ObjectID, name, description string) *BookmarkCollection {
+	now := time.Now()
+	return &BookmarkCollection{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Name:        name,
+		Description: description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DataExportStatus string
+
+const (
+	DataExportPending DataExportStatus = "pending"
+	DataExportRunning DataExportStatus = "running"
+	DataExportReady   DataExportStatus = "ready"
+	DataExportFailed  DataExportStatus = "failed"
+	DataExportExpired DataExportStatus = "expired"
+)
+
+// DataExport is a user's request for an archive of their data. Active is
+// set while the export is pending or running; a unique index on it allows
+// one such export per user.
+type DataExport struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Status      DataExportStatus   `bson:"status" json:"status"`
+	Active      bool               `bson:"active" json:"-"`
+	Size        int64              `bson:"size,omitempty" json:"size,omitempty"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	StartedAt   *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+func NewDataExport(userID primitive.ObjectID) *DataExport {
+	return &DataExport{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Status:    DataExportPending,
+		Active:    true,
+		CreatedAt: time.Now(),
+	}
+}
+
+// IsDownloadable reports whether the archive is ready and its link has not
+// expired.
+func (e *DataExport) IsDownloadable(now time.Time) bool {
+	return e.Status == DataExportReady && e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+}
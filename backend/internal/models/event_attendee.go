@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventAttendee records that a user is attending an event.
+type EventAttendee struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EventID   primitive.ObjectID `bson:"event_id" json:"event_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+func NewEventAttendee(eventID, userID primitive.ObjectID) *EventAttendee {
+	return &EventAttendee{
+		ID:        primitive.NewObjectID(),
+		EventID:   eventID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+}
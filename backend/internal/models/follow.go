@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Follow struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FollowerID  primitive.ObjectID `bson:"follower_id" json:"follower_id"`
+	FollowingID primitive.ObjectID `bson:"following_id" json:"following_id"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+func NewFollow(followerID, followingID primitive.ObjectID) *Follow {
+	return &Follow{
+		ID:          primitive.NewObjectID(),
+		FollowerID:  followerID,
+		FollowingID: followingID,
+		CreatedAt:   time.Now(),
+	}
+}
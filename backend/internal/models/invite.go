@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invite admits one registration while sign-up is invite-only, or from a
+// domain outside the allow-list. The code is stored by its SHA-256; Email
+// and Role, when set, pin the address and the role of the new account.
+type Invite struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	CodeHash  string              `bson:"code_hash" json:"-"`
+	Email     string              `bson:"email,omitempty" json:"email,omitempty"`
+	Role      UserRole            `bson:"role,omitempty" json:"role,omitempty"`
+	Note      string              `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedBy primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time           `bson:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time          `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	UsedBy    *primitive.ObjectID `bson:"used_by,omitempty" json:"used_by,omitempty"`
+}
+
+func NewInvite(codeHash string, createdBy primitive.ObjectID, ttl time.Duration) *Invite {
+	now := time.Now()
+	return &Invite{
+		ID:        primitive.NewObjectID(),
+		CodeHash:  codeHash,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+func (i *Invite) IsUsable(now time.Time) bool {
+	return i.UsedAt == nil && i.ExpiresAt.After(now)
+}
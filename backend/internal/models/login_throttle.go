@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	LoginThrottleAccount = "account"
+	LoginThrottleIP      = "ip"
+)
+
+// LoginThrottle counts recent failed sign-ins for one key: an email address
+// (whether or not an account exists for it) or a client IP. UserID and
+// Email are filled in only for existing accounts.
+type LoginThrottle struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty"`
+	Key           string              `bson:"key"`
+	Kind          string              `bson:"kind"`
+	UserID        *primitive.ObjectID `bson:"user_id,omitempty"`
+	Email         string              `bson:"email,omitempty"`
+	IP            string              `bson:"ip,omitempty"`
+	Failures      int                 `bson:"failures"`
+	LastFailureAt time.Time           `bson:"last_failure_at"`
+	LockedUntil   *time.Time          `bson:"locked_until,omitempty"`
+	ExpiresAt     time.Time           `bson:"expires_at"`
+}
+
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && t.LockedUntil.After(now)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	NotificationChannelInApp = "in_app"
+	NotificationChannelEmail = "email"
+)
+
+type NotificationType string
+
+const (
+	NotificationSavedSearch     NotificationType = "saved_search"
+	NotificationDataExport      NotificationType = "data_export"
+	NotificationAccountDeletion NotificationType = "account_deletion"
+)
+
+type Notification struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Type      NotificationType   `bson:"type" json:"type"`
+	Title     string             `bson:"title" json:"title"`
+	Body      string             `bson:"body" json:"body"`
+	Link      string             `bson:"link,omitempty" json:"link,omitempty"`
+	Read      bool               `bson:"read" json:"read"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+func NewNotification(userID primitive.ObjectID, notificationType NotificationType, title, body, link string) *Notification {
+	return &Notification{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Type:      notificationType,
+		Title:     title,
+		Body:      body,
+		Link:      link,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExternalIdentity links a user to an account at an OpenID provider. The
+// issuer and subject pair is the stable key; Email is what the provider
+// reported when the link was made.
+type ExternalIdentity struct {
+	Issuer   string    `bson:"issuer"`
+	Subject  string    `bson:"subject"`
+	Email    string    `bson:"email,omitempty"`
+	LinkedAt time.Time `bson:"linked_at"`
+}
+
+// OIDCLogin is an authorization request in flight, stored by the SHA-256
+// of its state parameter until the provider redirects back.
+type OIDCLogin struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	StateHash    string             `bson:"state_hash"`
+	Nonce        string             `bson:"nonce"`
+	CodeVerifier string             `bson:"code_verifier"`
+	CreatedAt    time.Time          `bson:"created_at"`
+	ExpiresAt    time.Time          `bson:"expires_at"`
+	UsedAt       *time.Time         `bson:"used_at,omitempty"`
+}
+
+func NewOIDCLogin(stateHash, nonce, codeVerifier string, ttl time.Duration) *OIDCLogin {
+	now := time.Now()
+	return &OIDCLogin{
+		ID:           primitive.NewObjectID(),
+		StateHash:    stateHash,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+	}
+}
+
+// Identity returns the user's link to the provider issuer, or nil.
+func (u *User) Identity(issuer string) *ExternalIdentity {
+	for i := range u.Identities {
+		if u.Identities[i].Issuer == issuer {
+			return &u.Identities[i]
+		}
+	}
+	return nil
+}
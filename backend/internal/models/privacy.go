@@ -0,0 +1,66 @@
+package models
+
+type Visibility string
+
+const (
+	VisibilityEveryone  Visibility = "everyone"
+	VisibilityFollowers Visibility = "followers"
+	VisibilityOnlyMe    Visibility = "only_me"
+)
+
+func IsValidVisibility(v Visibility) bool {
+	switch v {
+	case VisibilityEveryone, VisibilityFollowers, VisibilityOnlyMe:
+		return true
+	}
+	return false
+}
+
+// ProfileSection is a part of the public profile the user can hide.
+type ProfileSection string
+
+const (
+	SectionPosts    ProfileSection = "posts"
+	SectionComments ProfileSection = "comments"
+	SectionLikes    ProfileSection = "likes"
+	SectionEvents   ProfileSection = "events"
+)
+
+var ProfileSections = []ProfileSection{SectionPosts, SectionComments, SectionLikes, SectionEvents}
+
+// PrivacySettings says who sees each profile section. Empty fields, as on
+// accounts created before the settings existed, mean everyone.
+type PrivacySettings struct {
+	Posts    Visibility `bson:"posts,omitempty" json:"posts"`
+	Comments Visibility `bson:"comments,omitempty" json:"comments"`
+	Likes    Visibility `bson:"likes,omitempty" json:"likes"`
+	Events   Visibility `bson:"events,omitempty" json:"events"`
+}
+
+func (p PrivacySettings) Of(section ProfileSection) Visibility {
+	var v Visibility
+	switch section {
+	case SectionPosts:
+		v = p.Posts
+	case SectionComments:
+		v = p.Comments
+	case SectionLikes:
+		v = p.Likes
+	case SectionEvents:
+		v = p.Events
+	}
+	if v == "" {
+		return VisibilityEveryone
+	}
+	return v
+}
+
+// Normalized fills in the defaults, so clients always see every field.
+func (p PrivacySettings) Normalized() PrivacySettings {
+	return PrivacySettings{
+		Posts:    p.Of(SectionPosts),
+		Comments: p.Of(SectionComments),
+		Likes:    p.Of(SectionLikes),
+		Events:   p.Of(SectionEvents),
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is stored by the SHA-256 of its value. Every rotation
+// issues a new token in the same family; RotatedAt marks a token that has
+// already been exchanged, so presenting it again signals theft.
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	FamilyID  primitive.ObjectID `bson:"family_id" json:"family_id"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	RotatedAt *time.Time         `bson:"rotated_at,omitempty" json:"rotated_at,omitempty"`
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+func NewRefreshToken(userID, familyID primitive.ObjectID, tokenHash string, ttl time.Duration) *RefreshToken {
+	now := time.Now()
+	return &RefreshToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AlertFrequency string
+
+const (
+	AlertInstant AlertFrequency = "instant"
+	AlertDaily   AlertFrequency = "daily"
+	AlertWeekly  AlertFrequency = "weekly"
+)
+
+// Interval is the minimum time between digests; zero for instant alerts.
+func (f AlertFrequency) Interval() time.Duration {
+	switch f {
+	case AlertDaily:
+		return 24 * time.Hour
+	case AlertWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+func (f AlertFrequency) IsValid() bool {
+	return f == AlertInstant || f == AlertDaily || f == AlertWeekly
+}
+
+// SavedSearch is a post search a user wants to hear about. Matches for
+// daily and weekly searches wait in PendingPostIDs until the next digest.
+type SavedSearch struct {
+	ID             primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	Name           string               `bson:"name" json:"name"`
+	Query          string               `bson:"query,omitempty" json:"query,omitempty"`
+	Category       string               `bson:"category,omitempty" json:"category,omitempty"`
+	Tag            string               `bson:"tag,omitempty" json:"tag,omitempty"`
+	AuthorID       *primitive.ObjectID  `bson:"author_id,omitempty" json:"author_id,omitempty"`
+	Frequency      AlertFrequency       `bson:"frequency" json:"frequency"`
+	Muted          bool                 `bson:"muted" json:"muted"`
+	PendingPostIDs []primitive.ObjectID `bson:"pending_post_ids,omitempty" json:"-"`
+	LastAlertAt    time.Time            `bson:"last_alert_at,omitempty" json:"last_alert_at,omitempty"`
+	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+func NewSavedSearch(userID primitive.ObjectID, name string, frequency AlertFrequency) *SavedSearch {
+	now := time.Now()
+	return &SavedSearch{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Name:      name,
+		Frequency: frequency,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	SearchSourceSearch  = "search"
+	SearchSourceSuggest = "suggest"
+)
+
+// SearchQueryLog records a query without anything identifying who sent it.
+// Searcher is a salted hash that only tells searchers apart, so popular
+// queries can count people rather than requests. CreatedAt is truncated to
+// the hour.
+type SearchQueryLog struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Query       string             `bson:"query" json:"query"`
+	Source      string             `bson:"source" json:"source"`
+	ResultCount int                `bson:"result_count" json:"result_count"`
+	Searcher    string             `bson:"searcher,omitempty" json:"-"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// SearchQueryStat aggregates the logs of one query.
+type SearchQueryStat struct {
+	Query     string    `bson:"_id" json:"query"`
+	Count     int       `bson:"count" json:"count"`
+	Searchers int       `bson:"searchers" json:"searchers"`
+	LastSeen  time.Time `bson:"last_seen" json:"last_seen"`
+}
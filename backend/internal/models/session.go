@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one signed-in device. Its ID doubles as the family ID of the
+// refresh tokens issued for that login and as the sid claim of its access
+// tokens, so revoking the session cuts off both.
+type Session struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Device     string             `bson:"device" json:"device"`
+	UserAgent  string             `bson:"user_agent" json:"user_agent"`
+	IP         string             `bson:"ip" json:"ip"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	LastSeenAt time.Time          `bson:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	// TwoFactor records that the login passed a TOTP check.
+	TwoFactor bool `bson:"two_factor" json:"two_factor"`
+}
+
+func NewSession(userID primitive.ObjectID, device, userAgent, ip string, ttl time.Duration) *Session {
+	now := time.Now()
+	return &Session{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		Device:     device,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(now)
+}
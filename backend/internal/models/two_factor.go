@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TwoFactor is a user's TOTP enrolment. Secrets are stored encrypted and
+// recovery codes hashed; PendingSecret is set between starting enrolment
+// and confirming the first code.
+type TwoFactor struct {
+	Enabled       bool       `bson:"enabled"`
+	Secret        string     `bson:"secret,omitempty"`
+	PendingSecret string     `bson:"pending_secret,omitempty"`
+	RecoveryCodes []string   `bson:"recovery_codes,omitempty"`
+	LastCounter   int64      `bson:"last_counter"`
+	EnabledAt     *time.Time `bson:"enabled_at,omitempty"`
+}
+
+// TwoFactorChallenge is the second login step: it is issued once the
+// password checks out and exchanged for tokens with a valid code.
+type TwoFactorChallenge struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	TokenHash string             `bson:"token_hash"`
+	Attempts  int                `bson:"attempts"`
+	CreatedAt time.Time          `bson:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty"`
+}
+
+func NewTwoFactorChallenge(userID primitive.ObjectID, tokenHash string, ttl time.Duration) *TwoFactorChallenge {
+	now := time.Now()
+	return &TwoFactorChallenge{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+// RequiresTwoFactor reports whether the role may only act after a TOTP
+// login.
+func (r UserRole) RequiresTwoFactor() bool {
+	return r == RoleAdmin || r == RoleModerator
+}
+
+func (u *User) HasTwoFactor() bool {
+	return u.TwoFactor != nil && u.TwoFactor.Enabled
+}
@@ -17,20 +17,36 @@ const (
 )
 
 type User struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Email        string             `bson:"email" json:"email"`
-	PasswordHash string             `bson:"password_hash" json:"-"`
-	DisplayName  string             `bson:"display_name" json:"display_name"`
-	Role         UserRole           `bson:"role" json:"role"`
-	ProfileImage string             `bson:"profile_image,omitempty" json:"profile_image,omitempty"`
-	Bio          string             `bson:"bio,omitempty" json:"bio,omitempty"`
-	IsActive     bool               `bson:"is_active" json:"is_active"`
-	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
-	LastLoginAt  time.Time          `bson:"last_login_at,omitempty" json:"last_login_at,omitempty"`
-	PostCount    int                `bson:"post_count" json:"post_count"`
-	LikeCount    int                `bson:"like_count" json:"like_count"`
-	CommentCount int                `bson:"comment_count" json:"comment_count"`
+	ID                   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email                string             `bson:"email" json:"email"`
+	PasswordHash         string             `bson:"password_hash" json:"-"`
+	DisplayName          string             `bson:"display_name" json:"display_name"`
+	Role                 UserRole           `bson:"role" json:"role"`
+	ProfileImage         string             `bson:"profile_image,omitempty" json:"profile_image,omitempty"`
+	Avatar               string             `bson:"avatar,omitempty" json:"-"`
+	Bio                  string             `bson:"bio,omitempty" json:"bio,omitempty"`
+	IsActive             bool               `bson:"is_active" json:"is_active"`
+	EmailVerified        bool               `bson:"email_verified" json:"email_verified"`
+	EmailVerifiedAt      *time.Time         `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	VerificationSentAt   *time.Time         `bson:"verification_sent_at,omitempty" json:"-"`
+	PendingRole          UserRole           `bson:"pending_role,omitempty" json:"-"` // Granted once the email is verified
+	TwoFactor            *TwoFactor         `bson:"two_factor" json:"-"`
+	Identities           []ExternalIdentity `bson:"identities,omitempty" json:"-"`
+	CreatedAt            time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt            time.Time          `bson:"updated_at" json:"updated_at"`
+	LastLoginAt          time.Time          `bson:"last_login_at,omitempty" json:"last_login_at,omitempty"`
+	PostCount            int                `bson:"post_count" json:"post_count"`
+	LikeCount            int                `bson:"like_count" json:"like_count"`
+	CommentCount         int                `bson:"comment_count" json:"comment_count"`
+	FollowerCount        int                `bson:"follower_count" json:"follower_count"`
+	FollowingCount       int                `bson:"following_count" json:"following_count"`
+	SubscribedCategories []PostCategory     `bson:"subscribed_categories" json:"subscribed_categories,omitempty"`
+	SubscribedTags       []string           `bson:"subscribed_tags" json:"subscribed_tags,omitempty"`
+	// DisabledNotificationChannels lists the channels the user opted out
+	// of; every other channel is enabled.
+	DisabledNotificationChannels []string         `bson:"disabled_notification_channels" json:"disabled_notification_channels,omitempty"`
+	Privacy                      PrivacySettings  `bson:"privacy" json:"-"`
+	Deletion                     *AccountDeletion `bson:"deletion" json:"-"`
 }
 
 func NewUser(email, password, displayName string, role UserRole) (*User, error) {
@@ -75,6 +91,15 @@ func (u *User) UpdateLastLogin() {
 	u.LastLoginAt = time.Now()
 }
 
+// AvatarURL is the uploaded or linked profile image, or the generated
+// identicon for users without one.
+func (u *User) AvatarURL() string {
+	if u.ProfileImage != "" {
+		return u.ProfileImage
+	}
+	return "/api/users/" + u.ID.Hex() + "/avatar"
+}
+
 // Role check methods
 func (u *User) IsAdmin() bool {
 	return u.Role == RoleAdmin
@@ -92,40 +117,6 @@ func (u *User) IsModerator() bool {
 	return u.Role == RoleModerator
 }
 
-func (u *User) CanManageUsers() bool {
-	return u.IsAdmin() || u.IsModerator()
-}
-
-func (u *User) CanManagePosts() bool {
-	return u.IsAdmin() || u.IsModerator()
-}
-
-func (u *User) CanManageComments() bool {
-	return u.IsAdmin() || u.IsModerator()
-}
-
-func (u *User) CanCreatePost() bool {
-	return u.IsActive && (u.IsAdmin() || u.IsStudent() || u.IsAlumni() || u.IsModerator())
-}
-
-func (u *User) CanEditPost(postAuthorID primitive.ObjectID) bool {
-	if u.IsAdmin() || u.IsModerator() {
-		return true
-	}
-	return u.ID == postAuthorID && u.IsActive
-}
-
-func (u *User) CanDeletePost(postAuthorID primitive.ObjectID) bool {
-	if u.IsAdmin() || u.IsModerator() {
-		return true
-	}
-	return u.ID == postAuthorID && u.IsActive
-}
-
-func (u *User) CanViewAnalytics() bool {
-	return u.IsAdmin()
-}
-
 func (u *User) IncrementPostCount() {
 	u.PostCount++
 	u.UpdatedAt = time.Now()
@@ -147,3 +138,12 @@ func (u *User) DecrementCommentCount() {
 		u.CommentCount = 0
 	}
 }
+
+func (u *User) NotificationChannelEnabled(channel string) bool {
+	for _, disabled := range u.DisabledNotificationChannels {
+		if disabled == channel {
+			return false
+		}
+	}
+	return true
+}
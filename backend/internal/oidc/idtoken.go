@@ -0,0 +1,226 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// clockSkew tolerates small clock differences with the provider.
+const clockSkew = time.Minute
+
+var ErrInvalidIDToken = errors.New("oidc: invalid ID token")
+
+// Claims are the verified identity from an ID token.
+type Claims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+type idTokenClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	Expiry            float64  `json:"exp"`
+	IssuedAt          float64  `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+// audience is a single string or an array in JSON.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// flexBool accepts "true" as well as true; some providers send strings.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+type signingAlg struct {
+	hash    crypto.Hash
+	matches func(crypto.PublicKey) bool
+	verify  func(key crypto.PublicKey, digest, sig []byte) bool
+}
+
+var signingAlgs = map[string]signingAlg{
+	"RS256": rsaAlg(crypto.SHA256),
+	"RS384": rsaAlg(crypto.SHA384),
+	"RS512": rsaAlg(crypto.SHA512),
+	"ES256": ecdsaAlg(crypto.SHA256, 256),
+	"ES384": ecdsaAlg(crypto.SHA384, 384),
+}
+
+// VerifyIDToken checks the signature against the provider's keys and the
+// standard claims: issuer, audience, expiry and the nonce sent with the
+// authorization request.
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	if _, err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	// Only asymmetric algorithms; "none" and HMAC are never accepted
+	alg, ok := signingAlgs[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidIDToken, header.Alg)
+	}
+
+	key, err := p.keys.key(ctx, header.Kid, alg.matches)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	h := alg.hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if !alg.verify(key, h.Sum(nil), sig) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+	}
+
+	var claims idTokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	if err := p.checkClaims(&claims, nonce, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &Claims{
+		Issuer:            claims.Issuer,
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     bool(claims.EmailVerified),
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+func (p *Provider) checkClaims(claims *idTokenClaims, nonce string, now time.Time) error {
+	if strings.TrimRight(claims.Issuer, "/") != p.cfg.Issuer {
+		return fmt.Errorf("%w: issuer %q", ErrInvalidIDToken, claims.Issuer)
+	}
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	audienceOK := false
+	for _, aud := range claims.Audience {
+		if aud == p.cfg.ClientID {
+			audienceOK = true
+			break
+		}
+	}
+	if !audienceOK {
+		return fmt.Errorf("%w: not issued for this client", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return fmt.Errorf("%w: authorized party %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+
+	expiry := time.Unix(int64(claims.Expiry), 0)
+	if claims.Expiry == 0 || now.After(expiry.Add(clockSkew)) {
+		return fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	}
+	if issued := time.Unix(int64(claims.IssuedAt), 0); issued.After(now.Add(clockSkew)) {
+		return fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func rsaAlg(hash crypto.Hash) signingAlg {
+	return signingAlg{
+		hash: hash,
+		matches: func(key crypto.PublicKey) bool {
+			_, ok := key.(*rsa.PublicKey)
+			return ok
+		},
+		verify: func(key crypto.PublicKey, digest, sig []byte) bool {
+			return rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), hash, digest, sig) == nil
+		},
+	}
+}
+
+// ecdsaAlg verifies JWS signatures, which are r and s concatenated at the
+// curve's size rather than ASN.1.
+func ecdsaAlg(hash crypto.Hash, bits int) signingAlg {
+	size := (bits + 7) / 8
+	return signingAlg{
+		hash: hash,
+		matches: func(key crypto.PublicKey) bool {
+			ec, ok := key.(*ecdsa.PublicKey)
+			return ok && ec.Curve.Params().BitSize == bits
+		},
+		verify: func(key crypto.PublicKey, digest, sig []byte) bool {
+			if len(sig) != 2*size {
+				return false
+			}
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			return ecdsa.Verify(key.(*ecdsa.PublicKey), digest, r, s)
+		},
+	}
+}
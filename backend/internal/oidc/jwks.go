@@ -0,0 +1,168 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// minKeyRefresh limits refetching the key set for unknown key IDs, so
+// tokens with made-up IDs cannot hammer the provider.
+const minKeyRefresh = time.Minute
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	id  string
+	key crypto.PublicKey
+}
+
+// keySet caches the provider's signing keys and refreshes them when a
+// token names a key it has not seen, which is how providers rotate.
+type keySet struct {
+	uri   string
+	fetch func(ctx context.Context, url string, v interface{}) error
+
+	mu        sync.Mutex
+	keys      []publicKey
+	fetchedAt time.Time
+}
+
+func newKeySet(uri string, fetch func(ctx context.Context, url string, v interface{}) error) *keySet {
+	return &keySet{uri: uri, fetch: fetch}
+}
+
+// key finds the key for kid. Without a kid the only key of the right type
+// is used.
+func (s *keySet) key(ctx context.Context, kid string, matches func(crypto.PublicKey) bool) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key := s.lookup(kid, matches); key != nil {
+		return key, nil
+	}
+	if !s.fetchedAt.IsZero() && time.Since(s.fetchedAt) < minKeyRefresh {
+		return nil, fmt.Errorf("oidc: no signing key %q", kid)
+	}
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if key := s.lookup(kid, matches); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("oidc: no signing key %q", kid)
+}
+
+func (s *keySet) lookup(kid string, matches func(crypto.PublicKey) bool) crypto.PublicKey {
+	var found crypto.PublicKey
+	candidates := 0
+	for _, k := range s.keys {
+		if !matches(k.key) {
+			continue
+		}
+		if kid != "" && k.id == kid {
+			return k.key
+		}
+		found = k.key
+		candidates++
+	}
+	if kid == "" && candidates == 1 {
+		return found
+	}
+	return nil
+}
+
+func (s *keySet) refresh(ctx context.Context) error {
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	s.fetchedAt = time.Now()
+	if err := s.fetch(ctx, s.uri, &doc); err != nil {
+		return fmt.Errorf("oidc: fetching keys: %w", err)
+	}
+
+	keys := make([]publicKey, 0, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped, not fatal
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys = append(keys, publicKey{id: jwk.Kid, key: key})
+	}
+	s.keys = keys
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) < 256 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("weak or malformed RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		var check ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, check = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, check = elliptic.P384(), ecdh.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("malformed EC key")
+		}
+		// Parsing the uncompressed point rejects points off the curve
+		if _, err := check.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
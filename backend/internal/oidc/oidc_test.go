@@ -0,0 +1,183 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/oidc/oidctest"
+)
+
+const testClientID = "fanpage"
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Provider) {
+	t.Helper()
+	idp := oidctest.NewProvider(testClientID)
+	t.Cleanup(idp.Close)
+	p := NewProvider(Config{Issuer: idp.Issuer() + "/", ClientID: testClientID, RedirectURL: "https://fanpage.test/callback"})
+	return p, idp
+}
+
+// unsignedToken builds a token with alg in its header and a junk
+// signature, for algorithms the stub provider does not sign with.
+func unsignedToken(alg string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg})
+	payload, _ := json.Marshal(claims)
+	return base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	p, idp := newTestProvider(t)
+	ctx := context.Background()
+
+	claims := idp.Claims("user-1")
+	claims["nonce"] = "nonce-1"
+	claims["email"] = "student@astanait.edu.kz"
+	claims["email_verified"] = "true"
+	claims["name"] = "Student"
+
+	for _, alg := range []string{"RS256", "ES256"} {
+		got, err := p.VerifyIDToken(ctx, idp.Sign(alg, claims), "nonce-1")
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if got.Subject != "user-1" || got.Email != "student@astanait.edu.kz" || !got.EmailVerified || got.Name != "Student" {
+			t.Errorf("%s: claims %+v", alg, got)
+		}
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	p, idp := newTestProvider(t)
+	ctx := context.Background()
+
+	valid := func() map[string]interface{} {
+		claims := idp.Claims("user-1")
+		claims["nonce"] = "nonce-1"
+		return claims
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := valid()
+		claims[key] = value
+		return claims
+	}
+	tampered := idp.Sign("RS256", valid())
+	parts := strings.Split(tampered, ".")
+	forged, _ := json.Marshal(with("sub", "admin"))
+	parts[1] = base64.RawURLEncoding.EncodeToString(forged)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"nonce mismatch", idp.Sign("RS256", with("nonce", "other-nonce"))},
+		{"missing nonce", idp.Sign("RS256", with("nonce", ""))},
+		{"wrong audience", idp.Sign("RS256", with("aud", "other-client"))},
+		{"shared audience without azp", idp.Sign("RS256", with("aud", []string{"other-client", testClientID}))},
+		{"shared audience for another party", func() string {
+			claims := with("aud", []string{"other-client", testClientID})
+			claims["azp"] = "other-client"
+			return idp.Sign("RS256", claims)
+		}()},
+		{"expired", idp.Sign("RS256", with("exp", time.Now().Add(-2*time.Minute).Unix()))},
+		{"no expiry", idp.Sign("RS256", with("exp", 0))},
+		{"issued in the future", idp.Sign("RS256", with("iat", time.Now().Add(time.Hour).Unix()))},
+		{"other issuer", idp.Sign("RS256", with("iss", "https://evil.example"))},
+		{"no subject", idp.Sign("RS256", with("sub", ""))},
+		{"alg none", unsignedToken("none", valid())},
+		{"alg HS256", unsignedToken("HS256", valid())},
+		{"bad signature", strings.Join(parts, ".")},
+		{"malformed", "not-a-token"},
+	}
+	for _, tt := range tests {
+		if claims, err := p.VerifyIDToken(ctx, tt.token, "nonce-1"); err == nil {
+			t.Errorf("%s: accepted as %+v", tt.name, claims)
+		}
+	}
+
+	// Tokens within the clock skew still pass
+	if _, err := p.VerifyIDToken(ctx, idp.Sign("RS256", with("exp", time.Now().Add(-30*time.Second).Unix())), "nonce-1"); err != nil {
+		t.Errorf("token expired within skew: %v", err)
+	}
+	claims := with("aud", []string{"other-client", testClientID})
+	claims["azp"] = testClientID
+	if _, err := p.VerifyIDToken(ctx, idp.Sign("RS256", claims), "nonce-1"); err != nil {
+		t.Errorf("shared audience for this client: %v", err)
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	p, idp := newTestProvider(t)
+	ctx := context.Background()
+
+	verifier, _ := RandomString()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, idp.URL+"/authorize?") || !strings.Contains(authURL, "code_challenge_method=S256") {
+		t.Errorf("auth URL %s", authURL)
+	}
+
+	code := idp.Authorize(authURL, idp.Claims("user-1"))
+	token, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := p.VerifyIDToken(ctx, token.IDToken, "nonce-1"); err != nil {
+		t.Errorf("ID token from exchange: %v", err)
+	}
+
+	// Codes are single use and bound to the verifier
+	if _, err := p.Exchange(ctx, code, verifier); err == nil {
+		t.Error("code redeemed twice")
+	}
+	code = idp.Authorize(authURL, idp.Claims("user-1"))
+	if _, err := p.Exchange(ctx, code, "wrong-verifier"); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("wrong verifier: err = %v, want invalid_grant", err)
+	}
+}
+
+func TestDiscoveryRejectsOtherIssuer(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 "https://evil.example",
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/jwks",
+		})
+	}))
+	defer srv.Close()
+
+	p := NewProvider(Config{Issuer: srv.URL, ClientID: testClientID})
+	if _, err := p.AuthCodeURL(context.Background(), "state", "nonce", "challenge"); err == nil {
+		t.Error("discovery document for another issuer accepted")
+	}
+}
+
+func TestUnknownKeyIDRefetchIsRateLimited(t *testing.T) {
+	p, idp := newTestProvider(t)
+	ctx := context.Background()
+
+	claims := idp.Claims("user-1")
+	claims["nonce"] = "n"
+	if _, err := p.VerifyIDToken(ctx, idp.Sign("RS256", claims), "n"); err != nil {
+		t.Fatal(err)
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "made-up"})
+	token := idp.Sign("RS256", claims)
+	parts := strings.Split(token, ".")
+	parts[0] = base64.RawURLEncoding.EncodeToString(header)
+
+	_, err := p.VerifyIDToken(ctx, strings.Join(parts, "."), "n")
+	if err == nil || errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("unknown key ID: err = %v, want a missing key error", err)
+	}
+}
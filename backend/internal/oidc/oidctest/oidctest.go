@@ -0,0 +1,231 @@
+// Package oidctest runs a stub OpenID provider for tests: discovery, a key
+// set and a token endpoint that hands out ID tokens registered with
+// Authorize.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	RSAKeyID = "rsa-1"
+	ECKeyID  = "ec-1"
+)
+
+// Provider is a running stub provider. Its issuer is the server URL.
+type Provider struct {
+	*httptest.Server
+	ClientID string
+
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+type grant struct {
+	challenge string
+	idToken   string
+}
+
+// NewProvider starts a provider that issues tokens for clientID. Close it
+// when done.
+func NewProvider(clientID string) *Provider {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	p := &Provider{
+		ClientID: clientID,
+		rsaKey:   rsaKey,
+		ecKey:    ecKey,
+		grants:   make(map[string]grant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *Provider) Issuer() string {
+	return p.URL
+}
+
+// Claims returns valid claims for subject: issued by this provider for
+// the client, just now, for an hour. They carry no nonce.
+func (p *Provider) Claims(subject string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss": p.Issuer(),
+		"sub": subject,
+		"aud": p.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+// Authorize plays the user signing in at authURL, the address the relying
+// party redirected to. The claims get the request's nonce unless they set
+// their own. It returns the code for the callback.
+func (p *Provider) Authorize(authURL string, claims map[string]interface{}) string {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		panic(err)
+	}
+	query := parsed.Query()
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = query.Get("nonce")
+	}
+
+	return p.AuthorizeToken(authURL, p.Sign("RS256", claims))
+}
+
+// AuthorizeToken is Authorize with a ready-made ID token, which need not
+// be valid.
+func (p *Provider) AuthorizeToken(authURL, idToken string) string {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		panic(err)
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.grants[code] = grant{challenge: parsed.Query().Get("code_challenge"), idToken: idToken}
+	p.mu.Unlock()
+	return code
+}
+
+// Sign returns an ID token with claims, signed RS256 or ES256.
+func (p *Provider) Sign(alg string, claims map[string]interface{}) string {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	switch alg {
+	case "RS256":
+		header["kid"] = RSAKeyID
+	case "ES256":
+		header["kid"] = ECKeyID
+	}
+	signingInput := encodeSegment(header) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var sig []byte
+	switch alg {
+	case "RS256":
+		sig, _ = rsa.SignPKCS1v15(rand.Reader, p.rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, p.ecKey, digest[:])
+		if err != nil {
+			panic(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	default:
+		panic("oidctest: cannot sign " + alg)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.URL + "/authorize",
+		"token_endpoint":         p.URL + "/token",
+		"jwks_uri":               p.URL + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	ec := p.ecKey.PublicKey
+	writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": RSAKeyID,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(p.rsaKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": ECKeyID,
+				"use": "sig",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(ec.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(ec.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	})
+}
+
+// token redeems a code once, checking the PKCE verifier against the
+// challenge the authorization request carried.
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		oauthError(w, "invalid_request")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	g, ok := p.grants[code]
+	delete(p.grants, code)
+	p.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != g.challenge {
+		oauthError(w, "invalid_grant")
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"id_token":     g.idToken,
+		"expires_in":   3600,
+	})
+}
+
+func oauthError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func encodeSegment(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func randomString() string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
@@ -0,0 +1,119 @@
+package otp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed from RFC 6238 Appendix B, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The appendix lists eight digit codes; six digit codes are their last six.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := Code(rfcSecret, Counter(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		if got != v.code {
+			t.Errorf("T=%d: code %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+
+	for _, v := range rfcVectors[1:3] {
+		counter, ok := Validate(rfcSecret, v.code, at, 1)
+		if !ok {
+			t.Errorf("code %s not accepted", v.code)
+		}
+		if want := Counter(time.Unix(v.unix, 0)); counter != want {
+			t.Errorf("code %s: counter %d, want %d", v.code, counter, want)
+		}
+	}
+
+	// Spaces from copying and lowercase secrets are tolerated
+	if _, ok := Validate(strings.ToLower(rfcSecret), "050 471", at, 0); !ok {
+		t.Error("spaced code not accepted")
+	}
+
+	tests := []struct {
+		name string
+		code string
+		at   time.Time
+	}{
+		{"wrong code", "123456", at},
+		{"too short", "05047", at},
+		{"too long", "0504710", at},
+		{"outside skew", "050471", at.Add(2 * Period)},
+	}
+	for _, tt := range tests {
+		if _, ok := Validate(rfcSecret, tt.code, tt.at, 1); ok {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+
+	if _, ok := Validate("not base32!", "050471", at, 1); ok {
+		t.Error("malformed secret accepted")
+	}
+}
+
+func TestNewSecretRoundTrips(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("secret %q has %d characters, want 32", secret, len(secret))
+	}
+
+	now := time.Now()
+	code, err := Code(secret, Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Validate(secret, code, now, 0); !ok {
+		t.Error("own code not accepted")
+	}
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := KeyURI("AITU Fanpage", "student@astanait.edu.kz", rfcSecret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("uri %s is not otpauth://totp", uri)
+	}
+	if parsed.Path != "/AITU Fanpage:student@astanait.edu.kz" {
+		t.Errorf("label %q", parsed.Path)
+	}
+	if strings.Contains(uri, "+") {
+		t.Errorf("uri %s encodes spaces as +", uri)
+	}
+
+	query := parsed.Query()
+	for key, want := range map[string]string{"secret": rfcSecret, "issuer": "AITU Fanpage", "digits": "6", "period": "30", "algorithm": "SHA1"} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
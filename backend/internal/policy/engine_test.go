@@ -0,0 +1,268 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+func newUser(role models.UserRole) *models.User {
+	return &models.User{ID: primitive.NewObjectID(), Role: role, IsActive: true}
+}
+
+func withTwoFactor(user *models.User) *models.User {
+	user.TwoFactor = &models.TwoFactor{Enabled: true}
+	return user
+}
+
+func TestAuthorizeSessionRequiresTwoFactorForStaffGrants(t *testing.T) {
+	e := NewEngine(nil, 0)
+	student := newUser(models.RoleStudent)
+	moderator := withTwoFactor(newUser(models.RoleModerator))
+	unenrolled := newUser(models.RoleModerator)
+
+	othersPost := Owned("post", primitive.NewObjectID(), student.ID)
+	ownPost := Owned("post", primitive.NewObjectID(), moderator.ID)
+
+	tests := []struct {
+		name      string
+		user      *models.User
+		res       *Resource
+		twoFactor bool
+		want      error
+	}{
+		{"moderator on others' post with 2FA session", moderator, othersPost, true, nil},
+		{"moderator on others' post without 2FA session", moderator, othersPost, false, ErrTwoFactorRequired},
+		{"unenrolled moderator on others' post", unenrolled, othersPost, true, ErrTwoFactorRequired},
+		{"moderator on own post without 2FA session", moderator, ownPost, false, nil},
+		{"student on own post", student, othersPost, false, nil},
+		{"student on moderator's post", student, ownPost, true, ErrDenied},
+	}
+	for _, tt := range tests {
+		err := e.AuthorizeSession(tt.user, PostsEdit, tt.res, tt.twoFactor)
+		if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestAuthorizeSessionForUnownedResources(t *testing.T) {
+	e := NewEngine(nil, 0)
+	admin := withTwoFactor(newUser(models.RoleAdmin))
+	target := &Resource{Type: "user", ID: primitive.NewObjectID()}
+
+	if err := e.AuthorizeSession(admin, UsersDelete, target, false); !errors.Is(err, ErrTwoFactorRequired) {
+		t.Errorf("without 2FA session: err = %v, want ErrTwoFactorRequired", err)
+	}
+	if err := e.AuthorizeSession(admin, UsersDelete, target, true); err != nil {
+		t.Errorf("with 2FA session: err = %v", err)
+	}
+}
+
+// memAuditLog keeps entries in memory.
+type memAuditLog struct {
+	entries []*models.AuditEntry
+}
+
+func (l *memAuditLog) Create(entry *models.AuditEntry) error {
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *memAuditLog) FindRecent(action string, limit, offset int) ([]*models.AuditEntry, error) {
+	return l.entries, nil
+}
+
+func TestCanFollowsDefaultRoles(t *testing.T) {
+	e := NewEngine(nil, 0)
+	student := newUser(models.RoleStudent)
+	alumni := newUser(models.RoleAlumni)
+	moderator := newUser(models.RoleModerator)
+	admin := newUser(models.RoleAdmin)
+
+	ownPost := Owned("post", primitive.NewObjectID(), student.ID)
+	othersPost := Owned("post", primitive.NewObjectID(), primitive.NewObjectID())
+	unowned := &Resource{Type: "post", ID: primitive.NewObjectID()}
+
+	tests := []struct {
+		name string
+		user *models.User
+		perm Permission
+		res  *Resource
+		want bool
+	}{
+		{"student creates a post", student, PostsCreate, nil, true},
+		{"student edits own post", student, PostsEdit, ownPost, true},
+		{"student edits others' post", student, PostsEdit, othersPost, false},
+		{"student edits a post without owner", student, PostsEdit, unowned, false},
+		{"student edits with no resource", student, PostsEdit, nil, false},
+		{"student pins own post", student, PostsPin, ownPost, false},
+		{"alumni deletes others' comment", alumni, CommentsDelete, othersPost, false},
+		{"moderator edits others' post", moderator, PostsEdit, othersPost, true},
+		{"moderator features a post", moderator, PostsFeature, nil, true},
+		{"moderator deletes a user", moderator, UsersDelete, nil, false},
+		{"moderator views others' stats", moderator, UsersViewStats, othersPost, false},
+		{"admin manages roles", admin, RolesManage, nil, true},
+		{"admin views the audit log", admin, AuditLogView, nil, true},
+		{"nobody", nil, PostsCreate, nil, false},
+	}
+	for _, tt := range tests {
+		if got := e.Can(tt.user, tt.perm, tt.res); got != tt.want {
+			t.Errorf("%s: Can = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	deactivated := newUser(models.RoleAdmin)
+	deactivated.IsActive = false
+	if e.Can(deactivated, PostsCreate, nil) {
+		t.Error("deactivated admin may create posts")
+	}
+	if e.Can(&models.User{ID: primitive.NewObjectID(), Role: "guest", IsActive: true}, PostsCreate, nil) {
+		t.Error("unknown role may create posts")
+	}
+}
+
+func TestAuthorizeRecordsDenials(t *testing.T) {
+	audit := &memAuditLog{}
+	e := NewEngine(audit, time.Hour)
+	student := newUser(models.RoleStudent)
+	post := Owned("post", primitive.NewObjectID(), primitive.NewObjectID())
+
+	if err := e.Authorize(student, PostsCreate, nil); err != nil {
+		t.Fatalf("allowed action: err = %v", err)
+	}
+	if len(audit.entries) != 0 {
+		t.Fatalf("allowed action audited: %+v", audit.entries[0])
+	}
+
+	if err := e.Authorize(student, PostsDelete, post); !errors.Is(err, ErrDenied) {
+		t.Fatalf("err = %v, want ErrDenied", err)
+	}
+	if len(audit.entries) != 1 {
+		t.Fatalf("%d audit entries, want 1", len(audit.entries))
+	}
+	entry := audit.entries[0]
+	if entry.Action != models.AuditPermissionDenied || entry.UserID != student.ID || entry.Role != models.RoleStudent {
+		t.Errorf("entry = %+v, want a denial for the student", entry)
+	}
+	if entry.Permission != string(PostsDelete) || entry.ResourceType != "post" || entry.ResourceID == nil || *entry.ResourceID != post.ID {
+		t.Errorf("entry names %s on %s %v, want the post", entry.Permission, entry.ResourceType, entry.ResourceID)
+	}
+	if got := entry.ExpiresAt.Sub(entry.CreatedAt); got != time.Hour {
+		t.Errorf("entry kept for %v, want the retention", got)
+	}
+
+	if err := e.AuthorizeRoute(student, AnalyticsView, "/api/admin/analytics", "198.51.100.1"); !errors.Is(err, ErrDenied) {
+		t.Fatalf("route: err = %v, want ErrDenied", err)
+	}
+	if entry := audit.entries[1]; entry.Route != "/api/admin/analytics" || entry.IP != "198.51.100.1" {
+		t.Errorf("route entry = %q from %q", entry.Route, entry.IP)
+	}
+
+	deactivated := newUser(models.RoleStudent)
+	deactivated.IsActive = false
+	e.Authorize(deactivated, PostsCreate, nil)
+	if entry := audit.entries[2]; entry.Detail != "account deactivated" {
+		t.Errorf("deactivated detail = %q", entry.Detail)
+	}
+
+	// Anonymous requests are denied without an entry
+	if err := e.Authorize(nil, PostsCreate, nil); !errors.Is(err, ErrDenied) {
+		t.Errorf("nil user: err = %v, want ErrDenied", err)
+	}
+	if len(audit.entries) != 3 {
+		t.Errorf("%d audit entries, want 3", len(audit.entries))
+	}
+}
+
+func TestSetRoleOverridesGrants(t *testing.T) {
+	e := NewEngine(nil, 0)
+	student := newUser(models.RoleStudent)
+	post := Owned("post", primitive.NewObjectID(), primitive.NewObjectID())
+
+	if err := e.SetRole(models.RoleStudent, []string{"posts.create", "posts.pin", "posts.edit:own", "posts.edit"}); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	if !e.Can(student, PostsPin, nil) || !e.Can(student, PostsEdit, post) {
+		t.Error("new grants not applied; the broader edit grant should win")
+	}
+	if e.Can(student, PostsDelete, Owned("post", primitive.NewObjectID(), student.ID)) {
+		t.Error("grant left out of the override still applies")
+	}
+	if got := e.Roles()[models.RoleStudent]; !reflect.DeepEqual(got, []string{"posts.create", "posts.edit", "posts.pin"}) {
+		t.Errorf("Roles = %v", got)
+	}
+
+	tests := []struct {
+		role   models.UserRole
+		grants []string
+		want   error
+	}{
+		{"guest", []string{"posts.create"}, ErrUnknownRole},
+		{models.RoleStudent, []string{"posts.fly"}, ErrInvalidGrant},
+		{models.RoleStudent, []string{"posts.pin:own"}, ErrInvalidGrant},
+	}
+	for _, tt := range tests {
+		if err := e.SetRole(tt.role, tt.grants); !errors.Is(err, tt.want) {
+			t.Errorf("SetRole(%s, %v): err = %v, want %v", tt.role, tt.grants, err, tt.want)
+		}
+	}
+	// A rejected override leaves the role as it was
+	if !e.Can(student, PostsPin, nil) {
+		t.Error("rejected override changed the role")
+	}
+}
+
+func TestLoadFileKeepsOmittedRoles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"moderator": ["posts.edit:own"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine(nil, 0)
+	if err := e.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	moderator := newUser(models.RoleModerator)
+	othersPost := Owned("post", primitive.NewObjectID(), primitive.NewObjectID())
+	if e.Can(moderator, PostsEdit, othersPost) || e.Can(moderator, PostsPin, nil) {
+		t.Error("moderator kept grants the file removed")
+	}
+	if !e.Can(moderator, PostsEdit, Owned("post", primitive.NewObjectID(), moderator.ID)) {
+		t.Error("moderator lost the own-post grant")
+	}
+	if !e.Can(newUser(models.RoleAdmin), PostsPin, nil) {
+		t.Error("admin lost defaults the file did not mention")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"moderator": ["posts.fly"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadFile(path); !errors.Is(err, ErrInvalidGrant) {
+		t.Errorf("bad file: err = %v, want ErrInvalidGrant", err)
+	}
+}
+
+func TestAuthorizeSessionRecordsTwoFactorDenial(t *testing.T) {
+	audit := &memAuditLog{}
+	e := NewEngine(audit, time.Hour)
+	moderator := withTwoFactor(newUser(models.RoleModerator))
+	post := Owned("post", primitive.NewObjectID(), primitive.NewObjectID())
+
+	if err := e.AuthorizeSession(moderator, PostsDelete, post, false); !errors.Is(err, ErrTwoFactorRequired) {
+		t.Fatalf("err = %v, want ErrTwoFactorRequired", err)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Detail != "two-factor authentication required" {
+		t.Fatalf("entries = %+v, want one two-factor denial", audit.entries)
+	}
+	if entry := audit.entries[0]; entry.ResourceID == nil || *entry.ResourceID != post.ID {
+		t.Errorf("entry resource = %v, want the post", entry.ResourceID)
+	}
+}
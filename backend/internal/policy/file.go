@@ -0,0 +1,30 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+// LoadFile applies a JSON policy file mapping roles to their grants, e.g.
+// {"moderator": ["posts.pin", "posts.edit:own"]}. Roles the file leaves out
+// keep their defaults.
+func (e *Engine) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("policy file: %w", err)
+	}
+
+	var roles map[models.UserRole][]string
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return fmt.Errorf("policy file %s: %w", path, err)
+	}
+	for role, grants := range roles {
+		if err := e.SetRole(role, grants); err != nil {
+			return fmt.Errorf("policy file %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+// Permission names an action. A role is granted a permission outright or,
+// for ownable permissions, with the ":own" suffix, which limits it to
+// resources the user owns.
+type Permission string
+
+const (
+	PostsCreate  Permission = "posts.create"
+	PostsEdit    Permission = "posts.edit"
+	PostsDelete  Permission = "posts.delete"
+	PostsPin     Permission = "posts.pin"
+	PostsFeature Permission = "posts.feature"
+
+	CommentsEdit   Permission = "comments.edit"
+	CommentsDelete Permission = "comments.delete"
+
+	UsersView      Permission = "users.view"
+	UsersViewStats Permission = "users.view_stats"
+	UsersManage    Permission = "users.manage"
+	UsersDelete    Permission = "users.delete"
+	UsersReset2FA  Permission = "users.reset_2fa"
+	UsersUnlock    Permission = "users.unlock"
+	InvitesManage  Permission = "invites.manage"
+	MediaDelete    Permission = "media.delete"
+	AnalyticsView  Permission = "analytics.view"
+	RolesManage    Permission = "roles.manage"
+	AuditLogView   Permission = "audit.view"
+)
+
+// OwnSuffix limits a grant to the user's own resources.
+const OwnSuffix = ":own"
+
+// Permissions lists every permission; the value reports whether it can be
+// granted for owned resources only.
+var Permissions = map[Permission]bool{
+	PostsCreate:    false,
+	PostsEdit:      true,
+	PostsDelete:    true,
+	PostsPin:       false,
+	PostsFeature:   false,
+	CommentsEdit:   true,
+	CommentsDelete: true,
+	UsersView:      false,
+	UsersViewStats: true,
+	UsersManage:    false,
+	UsersDelete:    false,
+	UsersReset2FA:  false,
+	UsersUnlock:    false,
+	InvitesManage:  false,
+	MediaDelete:    false,
+	AnalyticsView:  false,
+	RolesManage:    false,
+	AuditLogView:   false,
+}
+
+var memberGrants = []string{
+	string(PostsCreate),
+	string(PostsEdit) + OwnSuffix,
+	string(PostsDelete) + OwnSuffix,
+	string(CommentsEdit) + OwnSuffix,
+	string(CommentsDelete) + OwnSuffix,
+	string(UsersViewStats) + OwnSuffix,
+}
+
+// DefaultRoles is the built-in mapping, which POLICY_FILE and stored
+// overrides replace role by role.
+var DefaultRoles = map[models.UserRole][]string{
+	models.RoleStudent: memberGrants,
+	models.RoleAlumni:  memberGrants,
+	models.RoleModerator: {
+		string(PostsCreate),
+		string(PostsEdit),
+		string(PostsDelete),
+		string(PostsPin),
+		string(PostsFeature),
+		string(CommentsEdit),
+		string(CommentsDelete),
+		string(UsersViewStats) + OwnSuffix,
+	},
+	models.RoleAdmin: allGrants(),
+}
+
+func allGrants() []string {
+	grants := make([]string, 0, len(Permissions))
+	for perm := range Permissions {
+		grants = append(grants, string(perm))
+	}
+	sort.Strings(grants)
+	return grants
+}
+
+// IsRole reports whether role is one the policy knows about.
+func IsRole(role models.UserRole) bool {
+	_, ok := DefaultRoles[role]
+	return ok
+}
+
+// ParseGrants checks a role's grants and maps each permission to whether
+// it applies to any resource (true) or only owned ones (false). Granting
+// both forms keeps the broader one.
+func ParseGrants(grants []string) (map[Permission]bool, error) {
+	parsed := make(map[Permission]bool, len(grants))
+	for _, grant := range grants {
+		name, own := strings.CutSuffix(strings.TrimSpace(grant), OwnSuffix)
+		perm := Permission(name)
+		ownable, ok := Permissions[perm]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown permission %q", ErrInvalidGrant, grant)
+		}
+		if own && !ownable {
+			return nil, fmt.Errorf("%w: %q cannot be limited to owned resources", ErrInvalidGrant, name)
+		}
+		parsed[perm] = parsed[perm] || !own
+	}
+	return parsed, nil
+}
+
+func formatGrants(parsed map[Permission]bool) []string {
+	grants := make([]string, 0, len(parsed))
+	for perm, anyResource := range parsed {
+		if anyResource {
+			grants = append(grants, string(perm))
+		} else {
+			grants = append(grants, string(perm)+OwnSuffix)
+		}
+	}
+	sort.Strings(grants)
+	return grants
+}
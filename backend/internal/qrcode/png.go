@@ -0,0 +1,51 @@
+package qrcode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// quietZone is the light border, in modules, that scanners need around
+// the symbol.
+const quietZone = 4
+
+// PNG renders the code with scale pixels per module.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+
+	side := (c.Size + 2*quietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			top, left := (y+quietZone)*scale, (x+quietZone)*scale
+			for dy := 0; dy < scale; dy++ {
+				row := img.Pix[(top+dy)*img.Stride:]
+				for dx := 0; dx < scale; dx++ {
+					row[left+dx] = 1
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PNG encodes content and renders it in one step.
+func PNG(content string, level Level, scale int) ([]byte, error) {
+	code, err := Encode(content, level)
+	if err != nil {
+		return nil, err
+	}
+	return code.PNG(scale)
+}
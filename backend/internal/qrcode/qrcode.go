@@ -0,0 +1,457 @@
+// Package qrcode encodes short strings, such as otpauth URIs, as QR codes
+// (ISO/IEC 18004) in byte mode and renders them to PNG.
+package qrcode
+
+import (
+	"errors"
+	"math"
+)
+
+// Level is the error correction level. Higher levels survive more damage
+// but hold less data.
+type Level int
+
+const (
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+// MaxVersion is the largest symbol supported, 97x97 modules. It holds 666
+// bytes at Medium.
+const MaxVersion = 20
+
+var ErrTooLong = errors.New("qrcode: content does not fit in a supported symbol")
+
+// Code is an encoded symbol.
+type Code struct {
+	Version int
+	Size    int
+
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+// Encode picks the smallest version that fits content at the level and the
+// mask with the lowest penalty.
+func Encode(content string, level Level) (*Code, error) {
+	data := []byte(content)
+
+	version := 0
+	for v := 1; v <= MaxVersion; v++ {
+		if 4+charCountBits(v)+8*len(data) <= 8*dataCodewords(v, level) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	codewords := addErrorCorrection(encodeData(data, version, level), version, level)
+
+	c := newCode(version)
+	c.drawFunctionPatterns()
+	c.drawCodewords(codewords)
+
+	bestMask, bestPenalty := 0, math.MaxInt
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(level, mask)
+		if penalty := c.penalty(); penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		// Masking is an XOR, so applying it again undoes it
+		c.applyMask(mask)
+	}
+	c.applyMask(bestMask)
+	c.drawFormatBits(level, bestMask)
+
+	return c, nil
+}
+
+// Black reports whether the module at column x, row y is dark.
+func (c *Code) Black(x, y int) bool {
+	return c.modules[y][x]
+}
+
+func newCode(version int) *Code {
+	size := version*4 + 17
+	c := &Code{
+		Version:    version,
+		Size:       size,
+		modules:    make([][]bool, size),
+		isFunction: make([][]bool, size),
+	}
+	for y := range c.modules {
+		c.modules[y] = make([]bool, size)
+		c.isFunction[y] = make([]bool, size)
+	}
+	return c
+}
+
+// encodeData builds the data codewords: byte mode header, the bytes, a
+// terminator and padding up to the capacity.
+func encodeData(data []byte, version int, level Level) []byte {
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacity := dataCodewords(version, level) * 8
+	bits.append(0, min(4, capacity-bits.len()))
+	bits.append(0, (8-bits.len()%8)%8)
+	for pad := 0xEC; bits.len() < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	return bits.bytes()
+}
+
+// addErrorCorrection splits data into blocks, appends each block's
+// Reed-Solomon codewords and interleaves the result.
+func addErrorCorrection(data []byte, version int, level Level) []byte {
+	spec := blockTable[version-1][level]
+	divisor := reedSolomonDivisor(spec.ecPerBlock)
+
+	var blocks, ecc [][]byte
+	offset := 0
+	for _, group := range [][2]int{{spec.blocks1, spec.data1}, {spec.blocks2, spec.data2}} {
+		for i := 0; i < group[0]; i++ {
+			block := data[offset : offset+group[1]]
+			offset += group[1]
+			blocks = append(blocks, block)
+			ecc = append(ecc, reedSolomonRemainder(block, divisor))
+		}
+	}
+
+	result := make([]byte, 0, rawCodewords(version))
+	for i := 0; i < spec.data1 || i < spec.data2; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < spec.ecPerBlock; i++ {
+		for _, block := range ecc {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	positions := alignmentPositions(c.Version)
+	last := len(positions) - 1
+	for i, y := range positions {
+		for j, x := range positions {
+			// The corners taken by finder patterns get none
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	// Reserve the format areas; the real bits are drawn once the mask is known
+	c.drawFormatBits(Low, 0)
+	c.drawVersion()
+}
+
+// drawFinder draws a finder pattern centred on x, y along with its light
+// separator.
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.Size || yy < 0 || yy >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+func (c *Code) drawFormatBits(level Level, mask int) {
+	bits := formatBits(level, mask)
+
+	// Around the top left finder
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	// Split between the other two finders
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(bits, i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	bits := versionBits(c.Version)
+	for i := 0; i < 18; i++ {
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// drawCodewords fills the data area in the zigzag order of the standard:
+// two-module columns from the right, alternating upwards and downwards,
+// skipping the vertical timing pattern.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.isFunction[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y][x] = bit(int(codewords[i>>3]), 7-i&7)
+				i++
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.isFunction[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the symbol is to scan, following the four rules
+// of the standard: long runs, 2x2 blocks, finder-like patterns and an
+// unbalanced dark ratio.
+func (c *Code) penalty() int {
+	score := 0
+	for i := 0; i < c.Size; i++ {
+		score += c.linePenalty(func(j int) bool { return c.modules[i][j] })
+		score += c.linePenalty(func(j int) bool { return c.modules[j][i] })
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				color := c.modules[y][x]
+				if color == c.modules[y][x+1] && color == c.modules[y+1][x] && color == c.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+
+	total := c.Size * c.Size
+	percent := dark * 100 / total
+	score += abs(percent-50) / 5 * 10
+	return score
+}
+
+var finderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+func (c *Code) linePenalty(at func(int) bool) int {
+	score := 0
+
+	run := 1
+	for j := 1; j <= c.Size; j++ {
+		if j < c.Size && at(j) == at(j-1) {
+			run++
+			continue
+		}
+		if run >= 5 {
+			score += 3 + run - 5
+		}
+		run = 1
+	}
+
+	for j := 0; j+len(finderLike[0]) <= c.Size; j++ {
+		for _, pattern := range finderLike {
+			matched := true
+			for k, dark := range pattern {
+				if at(j+k) != dark {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				score += 40
+			}
+		}
+	}
+	return score
+}
+
+// formatBits is the 15-bit BCH coded level and mask, XOR masked.
+func formatBits(level Level, mask int) int {
+	// The level indicators are not in level order: L=01 M=00 Q=11 H=10
+	data := [...]int{1, 0, 3, 2}[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionBits is the 18-bit BCH coded version used from version 7 up.
+func versionBits(version int) int {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return version<<12 | rem
+}
+
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*4 + count*2 + 1) / (count*2 - 2) * 2
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, pos := count-1, version*4+10; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+// rawCodewords counts the modules left for data and error correction once
+// the function patterns are placed, in whole codewords.
+func rawCodewords(version int) int {
+	modules := (16*version+128)*version + 64
+	if version >= 2 {
+		count := version/7 + 2
+		modules -= (25*count-10)*count - 55
+		if version >= 7 {
+			modules -= 36
+		}
+	}
+	return modules / 8
+}
+
+func dataCodewords(version int, level Level) int {
+	spec := blockTable[version-1][level]
+	return spec.blocks1*spec.data1 + spec.blocks2*spec.data2
+}
+
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+func bit(x, i int) bool {
+	return (x>>i)&1 != 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		b.bits = append(b.bits, bit(value, i))
+	}
+}
+
+func (b *bitBuffer) len() int {
+	return len(b.bits)
+}
+
+func (b *bitBuffer) bytes() []byte {
+	out := make([]byte, len(b.bits)/8)
+	for i, set := range b.bits {
+		if set {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
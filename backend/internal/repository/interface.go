@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
@@ -22,10 +24,16 @@ type PostRepository interface {
 	FindFeatured(limit int) ([]*models.Post, error)
 	FindPopular(limit int, days int) ([]*models.Post, error)
 	FindByTags(tags []string, limit int) ([]*models.Post, error)
-	Search(query string, limit int) ([]*models.Post, error)
+	Search(query SearchQuery) ([]*models.Post, int, error)
 	IncrementViewCount(id primitive.ObjectID) error
+	IncrementViewCounts(counts map[primitive.ObjectID]int) error
 	GetCategoriesStats() (map[string]int, error)
 	GetCategoriesStatsAggregated() (map[string]CategoryStats, error)
+	FindFeed(query FeedQuery) ([]*models.Post, error)
+	FindByIDs(ids []primitive.ObjectID) ([]*models.Post, error)
+	// FindAllByAuthor pages through the author's posts, archived ones
+	// included.
+	FindAllByAuthor(query ActivityQuery) ([]*models.Post, error)
 }
 
 type UserRepository interface {
@@ -35,6 +43,28 @@ type UserRepository interface {
 	Update(user *models.User) error
 	Delete(id primitive.ObjectID) error
 	FindAll(limit, offset int) ([]*models.User, error)
+	FindByIDs(ids []primitive.ObjectID) ([]*models.User, error)
+	IncrementFollowCounts(id primitive.ObjectID, followers, following int) error
+	// AddSubscription and RemoveSubscription change one subscription list
+	// in place, so concurrent updates to the user are not lost, and
+	// return the updated user.
+	AddSubscription(id primitive.ObjectID, list SubscriptionList, value string) (*models.User, error)
+	RemoveSubscription(id primitive.ObjectID, list SubscriptionList, value string) (*models.User, error)
+	Search(query string, limit int) ([]*models.User, error)
+	// FindByIdentity finds the user linked to a provider account.
+	FindByIdentity(issuer, subject string) (*models.User, error)
+	// FindDueDeletions returns users whose scheduled deletion is before
+	// the time, earliest first.
+	FindDueDeletions(before time.Time, limit int) ([]*models.User, error)
+}
+
+type FollowRepository interface {
+	Create(follow *models.Follow) error
+	Delete(followerID, followingID primitive.ObjectID) (bool, error)
+	Exists(followerID, followingID primitive.ObjectID) (bool, error)
+	FindFollowers(userID primitive.ObjectID, limit, offset int) ([]*models.Follow, error)
+	FindFollowing(userID primitive.ObjectID, limit, offset int) ([]*models.Follow, error)
+	FindFollowingIDs(userID primitive.ObjectID) ([]primitive.ObjectID, error)
 }
 
 type CommentRepository interface {
@@ -45,6 +75,207 @@ type CommentRepository interface {
 	Delete(id primitive.ObjectID) error
 	DeleteByPostID(postID primitive.ObjectID) error
 	CountByPostID(postID primitive.ObjectID) (int64, error)
+	FindAll(limit, offset int) ([]*models.Comment, error)
+	FindByIDs(ids []primitive.ObjectID) ([]*models.Comment, error)
+	Search(query string, limit int) ([]*models.Comment, error)
+	FindByAuthor(query ActivityQuery) ([]*models.Comment, error)
+}
+
+type EventRepository interface {
+	FindByID(id primitive.ObjectID) (*models.Event, error)
+	FindAll(limit, offset int) ([]*models.Event, error)
+	FindByIDs(ids []primitive.ObjectID) ([]*models.Event, error)
+	Search(query string, limit int) ([]*models.Event, error)
+}
+
+type EventAttendeeRepository interface {
+	Create(attendee *models.EventAttendee) error
+	FindByUser(userID primitive.ObjectID, limit, offset int) ([]*models.EventAttendee, error)
+	DeleteByUser(userID primitive.ObjectID) error
+}
+
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	FindByHash(tokenHash string) (*models.RefreshToken, error)
+	// MarkRotated flags the token as exchanged. It reports false when the
+	// token was already rotated or revoked, e.g. by a concurrent request.
+	MarkRotated(id primitive.ObjectID, at time.Time) (bool, error)
+	RevokeFamily(familyID primitive.ObjectID, at time.Time) error
+	RevokeByUser(userID primitive.ObjectID, at time.Time) error
+	DeleteByUser(userID primitive.ObjectID) error
+}
+
+type PasswordResetRepository interface {
+	Create(reset *models.PasswordReset) error
+	FindByHash(tokenHash string) (*models.PasswordReset, error)
+	CountByUserSince(userID primitive.ObjectID, since time.Time) (int64, error)
+	// MarkUsed consumes the token. It reports false when it was already
+	// used, e.g. by a concurrent request.
+	MarkUsed(id primitive.ObjectID, at time.Time) (bool, error)
+	// InvalidateByUser consumes every unused token of the user.
+	InvalidateByUser(userID primitive.ObjectID, at time.Time) error
+}
+
+type InviteRepository interface {
+	Create(invite *models.Invite) error
+	FindByID(id primitive.ObjectID) (*models.Invite, error)
+	FindByCodeHash(codeHash string) (*models.Invite, error)
+	FindAll(limit, offset int) ([]*models.Invite, error)
+	// MarkUsed claims the invite for userID. It reports false when the
+	// invite was already used.
+	MarkUsed(id, userID primitive.ObjectID, at time.Time) (bool, error)
+	Delete(id primitive.ObjectID) error
+}
+
+type TwoFactorChallengeRepository interface {
+	Create(challenge *models.TwoFactorChallenge) error
+	FindByHash(tokenHash string) (*models.TwoFactorChallenge, error)
+	// AddAttempt counts a code submission. It reports false once the
+	// challenge is used or maxAttempts were made.
+	AddAttempt(id primitive.ObjectID, maxAttempts int) (bool, error)
+	MarkUsed(id primitive.ObjectID, at time.Time) (bool, error)
+}
+
+type OIDCLoginRepository interface {
+	Create(login *models.OIDCLogin) error
+	// Consume returns the unexpired login for stateHash and marks it used,
+	// so each state is accepted once.
+	Consume(stateHash string) (*models.OIDCLogin, error)
+}
+
+type LoginThrottleRepository interface {
+	FindByKeys(keys []string) ([]*models.LoginThrottle, error)
+	// RecordFailure counts a failure for failure.Key, starting over when
+	// the previous one is older than window, and returns the updated
+	// record.
+	RecordFailure(failure *models.LoginThrottle, window time.Duration) (*models.LoginThrottle, error)
+	Lock(key string, until time.Time) error
+	Clear(key string) error
+	DeleteByID(id primitive.ObjectID) (bool, error)
+	// FindRecent lists records with at least minFailures, most recent
+	// failure first.
+	FindRecent(minFailures, limit, offset int) ([]*models.LoginThrottle, error)
+}
+
+type AccessTokenRepository interface {
+	Create(token *models.AccessToken) error
+	FindByHash(tokenHash string) (*models.AccessToken, error)
+	FindByUser(userID primitive.ObjectID) ([]*models.AccessToken, error)
+	CountByUser(userID primitive.ObjectID) (int64, error)
+	Touch(id primitive.ObjectID, ip string, at time.Time) error
+	// Delete removes the user's token; it reports false when the user has
+	// no such token.
+	Delete(userID, id primitive.ObjectID) (bool, error)
+	DeleteByUser(userID primitive.ObjectID) error
+}
+
+type AuditLogRepository interface {
+	Create(entry *models.AuditEntry) error
+	// FindRecent lists entries newest first, only those with action when
+	// it is set.
+	FindRecent(action string, limit, offset int) ([]*models.AuditEntry, error)
+}
+
+type RolePermissionRepository interface {
+	FindAll() ([]*models.RolePermissions, error)
+	Upsert(permissions *models.RolePermissions) error
+}
+
+type SessionRepository interface {
+	Create(session *models.Session) error
+	FindByID(id primitive.ObjectID) (*models.Session, error)
+	// FindActiveByUser lists unrevoked, unexpired sessions, most recently
+	// seen first.
+	FindActiveByUser(userID primitive.ObjectID) ([]*models.Session, error)
+	Touch(id primitive.ObjectID, ip string, at time.Time) error
+	Extend(id primitive.ObjectID, ip string, at, expiresAt time.Time) error
+	Revoke(id primitive.ObjectID, at time.Time) error
+	RevokeByUser(userID primitive.ObjectID, at time.Time) error
+	DeleteByUser(userID primitive.ObjectID) error
+}
+
+type NotificationRepository interface {
+	Create(notification *models.Notification) error
+	FindByUser(userID primitive.ObjectID, unreadOnly bool, limit, offset int) ([]*models.Notification, error)
+	CountUnread(userID primitive.ObjectID) (int64, error)
+	MarkRead(userID, id primitive.ObjectID) error
+	MarkAllRead(userID primitive.ObjectID) (int64, error)
+	DeleteByUser(userID primitive.ObjectID) error
+}
+
+type DataExportRepository interface {
+	Create(export *models.DataExport) error
+	FindByID(id primitive.ObjectID) (*models.DataExport, error)
+	FindLatestByUser(userID primitive.ObjectID) (*models.DataExport, error)
+	FindByUser(userID primitive.ObjectID) ([]*models.DataExport, error)
+	// FindByStatus returns exports in the status, oldest first.
+	FindByStatus(status models.DataExportStatus, limit int) ([]*models.DataExport, error)
+	// Claim moves a pending export to running. It reports false when
+	// another worker claimed it first.
+	Claim(id primitive.ObjectID, at time.Time) (bool, error)
+	// ResetRunning puts exports left running, e.g. by a restart, back to
+	// pending.
+	ResetRunning() error
+	// FindExpired returns ready exports whose link expired before the time.
+	FindExpired(before time.Time, limit int) ([]*models.DataExport, error)
+	Update(export *models.DataExport) error
+	DeleteByUser(userID primitive.ObjectID) error
+}
+
+type SavedSearchRepository interface {
+	Create(search *models.SavedSearch) error
+	FindByID(id primitive.ObjectID) (*models.SavedSearch, error)
+	FindByUser(userID primitive.ObjectID) ([]*models.SavedSearch, error)
+	CountByUser(userID primitive.ObjectID) (int64, error)
+	Update(search *models.SavedSearch) error
+	Delete(id primitive.ObjectID) error
+	DeleteByUser(userID primitive.ObjectID) error
+	// FindActive pages through unmuted searches that could match a post in
+	// category, ordered by ID and starting after afterID.
+	FindActive(category string, afterID primitive.ObjectID, limit int) ([]*models.SavedSearch, error)
+	AddPending(id, postID primitive.ObjectID, max int) error
+	// FindDueDigests returns unmuted searches of the frequency with pending
+	// matches whose last alert was before the given time.
+	FindDueDigests(frequency models.AlertFrequency, before time.Time, limit int) ([]*models.SavedSearch, error)
+	ClearPending(id primitive.ObjectID, postIDs []primitive.ObjectID, alertedAt time.Time) error
+}
+
+type SearchQueryRepository interface {
+	InsertMany(logs []*models.SearchQueryLog) error
+	PopularQueries(since time.Time, limit int) ([]*models.SearchQueryStat, error)
+	ZeroResultQueries(source string, since time.Time, limit int) ([]*models.SearchQueryStat, error)
+}
+
+type LikeRepository interface {
+	Create(like *models.Like) error
+	Delete(userID, postID primitive.ObjectID) (bool, error)
+	Exists(userID, postID primitive.ObjectID) (bool, error)
+	FindUserIDsByPost(postID primitive.ObjectID, limit int) ([]primitive.ObjectID, error)
+	CountCoLikedPosts(userIDs []primitive.ObjectID, excludePostID primitive.ObjectID, limit int) (map[primitive.ObjectID]int, error)
+	DeleteByPostID(postID primitive.ObjectID) error
+	FindByUser(query ActivityQuery) ([]*models.Like, error)
+}
+
+type BookmarkRepository interface {
+	Create(bookmark *models.Bookmark) error
+	Delete(userID, postID primitive.ObjectID) (*models.Bookmark, error)
+	FindByUserAndPost(userID, postID primitive.ObjectID) (*models.Bookmark, error)
+	FindByUser(userID primitive.ObjectID, collectionID *primitive.ObjectID, limit, offset int) ([]*models.Bookmark, error)
+	CountByUser(userID primitive.ObjectID, collectionID *primitive.ObjectID) (int64, error)
+	FindBookmarkedPostIDs(userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error)
+	SetCollection(userID, postID primitive.ObjectID, collectionID *primitive.ObjectID) error
+	ClearCollection(collectionID primitive.ObjectID) error
+	DeleteByPostID(postID primitive.ObjectID) error
+	DeleteByUser(userID primitive.ObjectID) error
+}
+
+type BookmarkCollectionRepository interface {
+	Create(collection *models.BookmarkCollection) error
+	FindByID(id primitive.ObjectID) (*models.BookmarkCollection, error)
+	FindByUser(userID primitive.ObjectID) ([]*models.BookmarkCollection, error)
+	Update(collection *models.BookmarkCollection) error
+	Delete(id primitive.ObjectID) error
+	DeleteByUser(userID primitive.ObjectID) error
 }
 
 type CategoryStats struct {
@@ -54,3 +285,46 @@ type CategoryStats struct {
 	TotalComments int     `json:"total_comments"`
 	AvgComments   float64 `json:"avg_comments"`
 }
+
+// SearchQuery filters a post search. An empty Text matches every post and
+// orders by recency; zero From/To leave the date range open.
+type SearchQuery struct {
+	Text     string
+	Category string
+	Tag      string
+	AuthorID *primitive.ObjectID
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
+
+// SubscriptionList names a list of things a user follows besides people.
+type SubscriptionList string
+
+const (
+	SubscribedCategories SubscriptionList = "subscribed_categories"
+	SubscribedTags       SubscriptionList = "subscribed_tags"
+)
+
+// ActivityQuery selects one user's posts, comments or likes, newest first
+// and older than the (BeforeTime, BeforeID) cursor when it is set.
+type ActivityQuery struct {
+	UserID     primitive.ObjectID
+	BeforeTime time.Time
+	BeforeID   primitive.ObjectID
+	Limit      int
+	Offset     int
+}
+
+// FeedQuery selects posts matching any of the followed authors, categories
+// or tags, older than the (BeforeTime, BeforeID) cursor when it is set.
+type FeedQuery struct {
+	AuthorIDs  []primitive.ObjectID
+	Categories []string
+	Tags       []string
+	Category   string
+	BeforeTime time.Time
+	BeforeID   primitive.ObjectID
+	Limit      int
+}
@@ -0,0 +1,27 @@
+package mongorepo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+// activityFind builds the filter and options for an ActivityQuery on a
+// collection whose owner is stored in field.
+func activityFind(field string, query repository.ActivityQuery) (bson.M, *options.FindOptions) {
+	filter := bson.M{field: query.UserID}
+	if !query.BeforeTime.IsZero() {
+		filter["$or"] = []bson.M{
+			{"created_at": bson.M{"$lt": query.BeforeTime}},
+			{"created_at": query.BeforeTime, "_id": bson.M{"$lt": query.BeforeID}},
+		}
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	findOptions.SetSkip(int64(query.Offset))
+	findOptions.SetLimit(int64(query.Limit))
+
+	return filter, findOptions
+}
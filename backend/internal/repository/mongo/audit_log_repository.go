@@ -0,0 +1,80 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type AuditLogRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditLogRepository(db *mongo.Database) *AuditLogRepository {
+	r := &AuditLogRepository{
+		collection: db.Collection("audit_log"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *AuditLogRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "action", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create audit_log indexes: %v", err)
+	}
+}
+
+func (r *AuditLogRepository) Create(entry *models.AuditEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, entry)
+	return err
+}
+
+func (r *AuditLogRepository) FindRecent(action string, limit, offset int) ([]*models.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if action != "" {
+		filter["action"] = action
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*models.AuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,298 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type BookmarkRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBookmarkRepository(db *mongo.Database) *BookmarkRepository {
+	r := &BookmarkRepository{
+		collection: db.Collection("bookmarks"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *BookmarkRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "collection_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "post_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create bookmarks indexes: %v", err)
+	}
+}
+
+func (r *BookmarkRepository) Create(bookmark *models.Bookmark) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, bookmark)
+	return err
+}
+
+func (r *BookmarkRepository) Delete(userID, postID primitive.ObjectID) (*models.Bookmark, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var bookmark models.Bookmark
+	err := r.collection.FindOneAndDelete(ctx, bson.M{"user_id": userID, "post_id": postID}).Decode(&bookmark)
+	if err != nil {
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+func (r *BookmarkRepository) FindByUserAndPost(userID, postID primitive.ObjectID) (*models.Bookmark, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var bookmark models.Bookmark
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID, "post_id": postID}).Decode(&bookmark)
+	if err != nil {
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+func (r *BookmarkRepository) FindByUser(userID primitive.ObjectID, collectionID *primitive.ObjectID, limit, offset int) ([]*models.Bookmark, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, userBookmarksFilter(userID, collectionID), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var bookmarks []*models.Bookmark
+	for cursor.Next(ctx) {
+		var bookmark models.Bookmark
+		if err := cursor.Decode(&bookmark); err != nil {
+			return nil, err
+		}
+		bookmarks = append(bookmarks, &bookmark)
+	}
+
+	return bookmarks, nil
+}
+
+func (r *BookmarkRepository) CountByUser(userID primitive.ObjectID, collectionID *primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, userBookmarksFilter(userID, collectionID))
+}
+
+func (r *BookmarkRepository) FindBookmarkedPostIDs(userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bookmarked := make(map[primitive.ObjectID]bool)
+	if len(postIDs) == 0 {
+		return bookmarked, nil
+	}
+
+	findOptions := options.Find()
+	findOptions.SetProjection(bson.M{"post_id": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"user_id": userID,
+		"post_id": bson.M{"$in": postIDs},
+	}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var bookmark models.Bookmark
+		if err := cursor.Decode(&bookmark); err != nil {
+			return nil, err
+		}
+		bookmarked[bookmark.PostID] = true
+	}
+
+	return bookmarked, nil
+}
+
+func (r *BookmarkRepository) SetCollection(userID, postID primitive.ObjectID, collectionID *primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID, "post_id": postID},
+		bson.M{"$set": bson.M{"collection_id": collectionID}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *BookmarkRepository) ClearCollection(collectionID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"collection_id": collectionID},
+		bson.M{"$set": bson.M{"collection_id": nil}},
+	)
+	return err
+}
+
+func (r *BookmarkRepository) DeleteByPostID(postID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"post_id": postID})
+	return err
+}
+
+func (r *BookmarkRepository) DeleteByUser(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+func userBookmarksFilter(userID primitive.ObjectID, collectionID *primitive.ObjectID) bson.M {
+	filter := bson.M{"user_id": userID}
+	if collectionID != nil {
+		filter["collection_id"] = *collectionID
+	}
+	return filter
+}
+
+type BookmarkCollectionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBookmarkCollectionRepository(db *mongo.Database) *BookmarkCollectionRepository {
+	r := &BookmarkCollectionRepository{
+		collection: db.Collection("bookmark_collections"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *BookmarkCollectionRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Failed to create bookmark_collections indexes: %v", err)
+	}
+}
+
+func (r *BookmarkCollectionRepository) Create(collection *models.BookmarkCollection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, collection)
+	return err
+}
+
+func (r *BookmarkCollectionRepository) FindByID(id primitive.ObjectID) (*models.BookmarkCollection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var collection models.BookmarkCollection
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&collection)
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func (r *BookmarkCollectionRepository) FindByUser(userID primitive.ObjectID) ([]*models.BookmarkCollection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var collections []*models.BookmarkCollection
+	for cursor.Next(ctx) {
+		var collection models.BookmarkCollection
+		if err := cursor.Decode(&collection); err != nil {
+			return nil, err
+		}
+		collections = append(collections, &collection)
+	}
+
+	return collections, nil
+}
+
+func (r *BookmarkCollectionRepository) Update(collection *models.BookmarkCollection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collection.UpdatedAt = time.Now()
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": collection.ID},
+		bson.M{"$set": collection},
+	)
+	return err
+}
+
+func (r *BookmarkCollectionRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *BookmarkCollectionRepository) DeleteByUser(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
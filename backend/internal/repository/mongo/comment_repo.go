@@ -2,6 +2,7 @@ package mongorepo
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -10,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
 )
 
 type CommentRepository struct {
@@ -17,9 +19,23 @@ type CommentRepository struct {
 }
 
 func NewCommentRepository(db *mongo.Database) *CommentRepository {
-	return &CommentRepository{
+	r := &CommentRepository{
 		collection: db.Collection("comments"),
 	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *CommentRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		log.Printf("Failed to create comments indexes: %v", err)
+	}
 }
 
 func (r *CommentRepository) Create(comment *models.Comment) error {
@@ -109,3 +125,81 @@ func (r *CommentRepository) CountByPostID(postID primitive.ObjectID) (int64, err
 	count, err := r.collection.CountDocuments(ctx, bson.M{"post_id": postID})
 	return count, err
 }
+
+func (r *CommentRepository) FindAll(limit, offset int) ([]*models.Comment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "_id", Value: 1}})
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(limit))
+
+	return r.find(ctx, bson.M{}, findOptions)
+}
+
+func (r *CommentRepository) FindByIDs(ids []primitive.ObjectID) ([]*models.Comment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if len(ids) == 0 {
+		return []*models.Comment{}, nil
+	}
+
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (r *CommentRepository) Search(query string, limit int) ([]*models.Comment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"content": bson.M{"$regex": anyWordPattern(query), "$options": "i"}}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	findOptions.SetLimit(int64(limit))
+
+	return r.find(ctx, filter, findOptions)
+}
+
+func (r *CommentRepository) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*models.Comment, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var comments []*models.Comment
+	for cursor.Next(ctx) {
+		var comment models.Comment
+		if err := cursor.Decode(&comment); err != nil {
+			return nil, err
+		}
+		comments = append(comments, &comment)
+	}
+
+	return comments, nil
+}
+
+func (r *CommentRepository) FindByAuthor(query repository.ActivityQuery) ([]*models.Comment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter, findOptions := activityFind("author_id", query)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var comments []*models.Comment
+	for cursor.Next(ctx) {
+		var comment models.Comment
+		if err := cursor.Decode(&comment); err != nil {
+			return nil, err
+		}
+		comments = append(comments, &comment)
+	}
+
+	return comments, nil
+}
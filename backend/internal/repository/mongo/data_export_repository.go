@@ -0,0 +1,179 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type DataExportRepository struct {
+	collection *mongo.Collection
+}
+
+func NewDataExportRepository(db *mongo.Database) *DataExportRepository {
+	r := &DataExportRepository{
+		collection: db.Collection("data_exports"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *DataExportRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// One pending or running export per user
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"active": true}).
+				SetName("user_id_active"),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create data_exports indexes: %v", err)
+	}
+}
+
+func (r *DataExportRepository) Create(export *models.DataExport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, export)
+	return err
+}
+
+func (r *DataExportRepository) FindByID(id primitive.ObjectID) (*models.DataExport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var export models.DataExport
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&export)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *DataExportRepository) FindLatestByUser(userID primitive.ObjectID) (*models.DataExport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.FindOne()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var export models.DataExport
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID}, findOptions).Decode(&export)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *DataExportRepository) FindByUser(userID primitive.ObjectID) ([]*models.DataExport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.find(ctx, bson.M{"user_id": userID})
+}
+
+func (r *DataExportRepository) FindByStatus(status models.DataExportStatus, limit int) ([]*models.DataExport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: 1}})
+	findOptions.SetLimit(int64(limit))
+
+	return r.find(ctx, bson.M{"status": status}, findOptions)
+}
+
+func (r *DataExportRepository) Claim(id primitive.ObjectID, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": models.DataExportPending},
+		bson.M{"$set": bson.M{"status": models.DataExportRunning, "started_at": at}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *DataExportRepository) ResetRunning() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"status": models.DataExportRunning},
+		bson.M{"$set": bson.M{"status": models.DataExportPending}},
+	)
+	return err
+}
+
+func (r *DataExportRepository) FindExpired(before time.Time, limit int) ([]*models.DataExport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetLimit(int64(limit))
+
+	return r.find(ctx, bson.M{
+		"status":     models.DataExportReady,
+		"expires_at": bson.M{"$lt": before},
+	}, findOptions)
+}
+
+func (r *DataExportRepository) Update(export *models.DataExport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": export.ID}, export)
+	return err
+}
+
+func (r *DataExportRepository) DeleteByUser(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+func (r *DataExportRepository) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*models.DataExport, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var exports []*models.DataExport
+	for cursor.Next(ctx) {
+		var export models.DataExport
+		if err := cursor.Decode(&export); err != nil {
+			return nil, err
+		}
+		exports = append(exports, &export)
+	}
+
+	return exports, nil
+}
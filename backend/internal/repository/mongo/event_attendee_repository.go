@@ -0,0 +1,87 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type EventAttendeeRepository struct {
+	collection *mongo.Collection
+}
+
+func NewEventAttendeeRepository(db *mongo.Database) *EventAttendeeRepository {
+	r := &EventAttendeeRepository{
+		collection: db.Collection("event_attendees"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *EventAttendeeRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "event_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create event attendees indexes: %v", err)
+	}
+}
+
+func (r *EventAttendeeRepository) Create(attendee *models.EventAttendee) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, attendee)
+	return err
+}
+
+func (r *EventAttendeeRepository) FindByUser(userID primitive.ObjectID, limit, offset int) ([]*models.EventAttendee, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var attendees []*models.EventAttendee
+	for cursor.Next(ctx) {
+		var attendee models.EventAttendee
+		if err := cursor.Decode(&attendee); err != nil {
+			return nil, err
+		}
+		attendees = append(attendees, &attendee)
+	}
+
+	return attendees, nil
+}
+
+func (r *EventAttendeeRepository) DeleteByUser(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
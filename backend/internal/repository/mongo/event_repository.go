@@ -0,0 +1,96 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type EventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewEventRepository(db *mongo.Database) *EventRepository {
+	return &EventRepository{
+		collection: db.Collection("events"),
+	}
+}
+
+func (r *EventRepository) FindByID(id primitive.ObjectID) (*models.Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var event models.Event
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *EventRepository) FindAll(limit, offset int) ([]*models.Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "start_date", Value: -1}, {Key: "_id", Value: 1}})
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(limit))
+
+	return r.find(ctx, bson.M{}, findOptions)
+}
+
+func (r *EventRepository) FindByIDs(ids []primitive.ObjectID) ([]*models.Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if len(ids) == 0 {
+		return []*models.Event{}, nil
+	}
+
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (r *EventRepository) Search(query string, limit int) ([]*models.Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pattern := anyWordPattern(query)
+	filter := bson.M{"$or": []bson.M{
+		{"title": bson.M{"$regex": pattern, "$options": "i"}},
+		{"description": bson.M{"$regex": pattern, "$options": "i"}},
+		{"content": bson.M{"$regex": pattern, "$options": "i"}},
+		{"location": bson.M{"$regex": pattern, "$options": "i"}},
+	}}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "start_date", Value: -1}})
+	findOptions.SetLimit(int64(limit))
+
+	return r.find(ctx, filter, findOptions)
+}
+
+func (r *EventRepository) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*models.Event, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*models.Event
+	for cursor.Next(ctx) {
+		var event models.Event
+		if err := cursor.Decode(&event); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,140 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type FollowRepository struct {
+	collection *mongo.Collection
+}
+
+func NewFollowRepository(db *mongo.Database) *FollowRepository {
+	r := &FollowRepository{
+		collection: db.Collection("follows"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *FollowRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "follower_id", Value: 1}, {Key: "following_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "following_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create follows indexes: %v", err)
+	}
+}
+
+func (r *FollowRepository) Create(follow *models.Follow) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, follow)
+	return err
+}
+
+func (r *FollowRepository) Delete(followerID, followingID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{
+		"follower_id":  followerID,
+		"following_id": followingID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (r *FollowRepository) Exists(followerID, followingID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"follower_id":  followerID,
+		"following_id": followingID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *FollowRepository) FindFollowers(userID primitive.ObjectID, limit, offset int) ([]*models.Follow, error) {
+	return r.find(bson.M{"following_id": userID}, limit, offset)
+}
+
+func (r *FollowRepository) FindFollowing(userID primitive.ObjectID, limit, offset int) ([]*models.Follow, error) {
+	return r.find(bson.M{"follower_id": userID}, limit, offset)
+}
+
+func (r *FollowRepository) FindFollowingIDs(userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetProjection(bson.M{"following_id": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"follower_id": userID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var follow models.Follow
+		if err := cursor.Decode(&follow); err != nil {
+			return nil, err
+		}
+		ids = append(ids, follow.FollowingID)
+	}
+
+	return ids, nil
+}
+
+func (r *FollowRepository) find(filter bson.M, limit, offset int) ([]*models.Follow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var follows []*models.Follow
+	for cursor.Next(ctx) {
+		var follow models.Follow
+		if err := cursor.Decode(&follow); err != nil {
+			return nil, err
+		}
+		follows = append(follows, &follow)
+	}
+
+	return follows, nil
+}
@@ -0,0 +1,125 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type InviteRepository struct {
+	collection *mongo.Collection
+}
+
+func NewInviteRepository(db *mongo.Database) *InviteRepository {
+	r := &InviteRepository{
+		collection: db.Collection("invites"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *InviteRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "code_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: -1}},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create invites indexes: %v", err)
+	}
+}
+
+func (r *InviteRepository) Create(invite *models.Invite) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, invite)
+	return err
+}
+
+func (r *InviteRepository) FindByID(id primitive.ObjectID) (*models.Invite, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var invite models.Invite
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&invite)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *InviteRepository) FindByCodeHash(codeHash string) (*models.Invite, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var invite models.Invite
+	err := r.collection.FindOne(ctx, bson.M{"code_hash": codeHash}).Decode(&invite)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *InviteRepository) FindAll(limit, offset int) ([]*models.Invite, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var invites []*models.Invite
+	for cursor.Next(ctx) {
+		var invite models.Invite
+		if err := cursor.Decode(&invite); err != nil {
+			return nil, err
+		}
+		invites = append(invites, &invite)
+	}
+
+	return invites, nil
+}
+
+func (r *InviteRepository) MarkUsed(id, userID primitive.ObjectID, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": at, "used_by": userID}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *InviteRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,178 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+type LikeRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLikeRepository(db *mongo.Database) *LikeRepository {
+	r := &LikeRepository{
+		collection: db.Collection("likes"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *LikeRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "post_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create likes indexes: %v", err)
+	}
+}
+
+func (r *LikeRepository) Create(like *models.Like) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, like)
+	return err
+}
+
+func (r *LikeRepository) Delete(userID, postID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"user_id": userID, "post_id": postID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (r *LikeRepository) Exists(userID, postID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "post_id": postID})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *LikeRepository) FindUserIDsByPost(postID primitive.ObjectID, limit int) ([]primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	findOptions.SetLimit(int64(limit))
+	findOptions.SetProjection(bson.M{"user_id": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"post_id": postID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var userIDs []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var like models.Like
+		if err := cursor.Decode(&like); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, like.UserID)
+	}
+
+	return userIDs, nil
+}
+
+func (r *LikeRepository) CountCoLikedPosts(userIDs []primitive.ObjectID, excludePostID primitive.ObjectID, limit int) (map[primitive.ObjectID]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	counts := make(map[primitive.ObjectID]int)
+	if len(userIDs) == 0 {
+		return counts, nil
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"user_id": bson.M{"$in": userIDs},
+			"post_id": bson.M{"$ne": excludePostID},
+		}},
+		{"$group": bson.M{
+			"_id":   "$post_id",
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": limit},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var result struct {
+			PostID primitive.ObjectID `bson:"_id"`
+			Count  int                `bson:"count"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		counts[result.PostID] = result.Count
+	}
+
+	return counts, nil
+}
+
+func (r *LikeRepository) DeleteByPostID(postID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"post_id": postID})
+	return err
+}
+
+func (r *LikeRepository) FindByUser(query repository.ActivityQuery) ([]*models.Like, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter, findOptions := activityFind("user_id", query)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var likes []*models.Like
+	for cursor.Next(ctx) {
+		var like models.Like
+		if err := cursor.Decode(&like); err != nil {
+			return nil, err
+		}
+		likes = append(likes, &like)
+	}
+
+	return likes, nil
+}
@@ -0,0 +1,173 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type LoginThrottleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLoginThrottleRepository(db *mongo.Database) *LoginThrottleRepository {
+	r := &LoginThrottleRepository{
+		collection: db.Collection("login_throttles"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *LoginThrottleRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "failures", Value: 1}, {Key: "last_failure_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create login_throttles indexes: %v", err)
+	}
+}
+
+func (r *LoginThrottleRepository) FindByKeys(keys []string) ([]*models.LoginThrottle, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{"key": bson.M{"$in": keys}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var throttles []*models.LoginThrottle
+	if err := cursor.All(ctx, &throttles); err != nil {
+		return nil, err
+	}
+	return throttles, nil
+}
+
+func (r *LoginThrottleRepository) RecordFailure(failure *models.LoginThrottle, window time.Duration) (*models.LoginThrottle, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	at := failure.LastFailureAt
+
+	// Failures outside the window no longer count, unless a lock is still
+	// running
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{
+			"key":             failure.Key,
+			"last_failure_at": bson.M{"$lt": at.Add(-window)},
+			"$or": bson.A{
+				bson.M{"locked_until": bson.M{"$exists": false}},
+				bson.M{"locked_until": bson.M{"$lte": at}},
+			},
+		},
+		bson.M{"$set": bson.M{"failures": 0}, "$unset": bson.M{"locked_until": ""}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{
+		"kind":            failure.Kind,
+		"last_failure_at": at,
+		"expires_at":      at.Add(window),
+	}
+	if failure.UserID != nil {
+		set["user_id"] = failure.UserID
+		set["email"] = failure.Email
+	}
+	if failure.IP != "" {
+		set["ip"] = failure.IP
+	}
+
+	var updated models.LoginThrottle
+	err = r.collection.FindOneAndUpdate(ctx,
+		bson.M{"key": failure.Key},
+		bson.M{
+			"$inc":         bson.M{"failures": 1},
+			"$set":         set,
+			"$setOnInsert": bson.M{"_id": primitive.NewObjectID()},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (r *LoginThrottleRepository) Lock(key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The record outlives the lock so the failures still count afterwards
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"key": key},
+		[]bson.M{{"$set": bson.M{
+			"locked_until": until,
+			"expires_at":   bson.M{"$max": bson.A{"$expires_at", until}},
+		}}},
+	)
+	return err
+}
+
+func (r *LoginThrottleRepository) Clear(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"key": key})
+	return err
+}
+
+func (r *LoginThrottleRepository) DeleteByID(id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+func (r *LoginThrottleRepository) FindRecent(minFailures, limit, offset int) ([]*models.LoginThrottle, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "last_failure_at", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"failures": bson.M{"$gte": minFailures}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var throttles []*models.LoginThrottle
+	if err := cursor.All(ctx, &throttles); err != nil {
+		return nil, err
+	}
+	return throttles, nil
+}
@@ -0,0 +1,68 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type OIDCLoginRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOIDCLoginRepository(db *mongo.Database) *OIDCLoginRepository {
+	r := &OIDCLoginRepository{
+		collection: db.Collection("oidc_logins"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *OIDCLoginRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "state_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create oidc_logins indexes: %v", err)
+	}
+}
+
+func (r *OIDCLoginRepository) Create(login *models.OIDCLogin) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, login)
+	return err
+}
+
+func (r *OIDCLoginRepository) Consume(stateHash string) (*models.OIDCLogin, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var login models.OIDCLogin
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"state_hash": stateHash, "used_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"used_at": now}},
+	).Decode(&login)
+	if err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
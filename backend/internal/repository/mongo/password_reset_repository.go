@@ -0,0 +1,104 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type PasswordResetRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPasswordResetRepository(db *mongo.Database) *PasswordResetRepository {
+	r := &PasswordResetRepository{
+		collection: db.Collection("password_resets"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *PasswordResetRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			// Keep expired tokens a day so the request throttle still sees them
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32((24 * time.Hour).Seconds())),
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create password_resets indexes: %v", err)
+	}
+}
+
+func (r *PasswordResetRepository) Create(reset *models.PasswordReset) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, reset)
+	return err
+}
+
+func (r *PasswordResetRepository) FindByHash(tokenHash string) (*models.PasswordReset, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var reset models.PasswordReset
+	err := r.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&reset)
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+func (r *PasswordResetRepository) CountByUserSince(userID primitive.ObjectID, since time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{
+		"user_id":    userID,
+		"created_at": bson.M{"$gte": since},
+	})
+}
+
+func (r *PasswordResetRepository) MarkUsed(id primitive.ObjectID, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": at}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *PasswordResetRepository) InvalidateByUser(userID primitive.ObjectID, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": at}},
+	)
+	return err
+}
@@ -2,6 +2,9 @@ package mongorepo
 
 import (
 	"context"
+	"log"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
@@ -18,9 +21,44 @@ type PostRepository struct {
 }
 
 func NewPostRepository(db *mongo.Database) *PostRepository {
-	return &PostRepository{
+	r := &PostRepository{
 		collection: db.Collection("posts"),
 	}
+	r.ensureIndexes()
+	backfillFoldedTags(r.collection, "tags")
+	return r
+}
+
+func (r *PostRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Posts mix Kazakh, Russian and English, so the text index does not stem.
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "title", Value: "text"},
+				{Key: "tags", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "content", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("posts_text").
+				SetDefaultLanguage("none").
+				SetWeights(bson.D{
+					{Key: "title", Value: 10},
+					{Key: "tags", Value: 6},
+					{Key: "description", Value: 3},
+					{Key: "content", Value: 1},
+				}),
+		},
+		{
+			Keys: bson.D{{Key: "is_archived", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create posts indexes: %v", err)
+	}
 }
 
 func (r *PostRepository) Create(post *models.Post) error {
@@ -349,61 +387,99 @@ func (r *PostRepository) FindByTags(tags []string, limit int) ([]*models.Post, e
 	return posts, nil
 }
 
-func (r *PostRepository) Search(query string, limit int) ([]*models.Post, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	filter := bson.M{
-		"$text":       bson.M{"$search": query},
-		"is_archived": false,
+func (r *PostRepository) Search(query repository.SearchQuery) ([]*models.Post, int, error) {
+	if query.Text == "" {
+		return r.findWithTotal(searchFilter(query), bson.D{{Key: "created_at", Value: -1}}, nil, query)
 	}
 
-	findOptions := options.Find()
-	findOptions.SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}})
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetProjection(bson.M{
-		"score": bson.M{"$meta": "textScore"},
-	})
+	filter := searchFilter(query)
+	filter["$text"] = bson.M{"$search": query.Text}
+	sort := bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "created_at", Value: -1}}
+	projection := bson.M{"score": bson.M{"$meta": "textScore"}}
 
-	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	posts, total, err := r.findWithTotal(filter, sort, projection, query)
 	if err != nil {
-		return r.simpleSearch(query, limit)
+		return r.simpleSearch(query)
 	}
-	defer cursor.Close(ctx)
+	return posts, total, nil
+}
 
-	var posts []*models.Post
-	for cursor.Next(ctx) {
-		var post models.Post
-		if err := cursor.Decode(&post); err != nil {
-			return nil, err
-		}
-		posts = append(posts, &post)
+// simpleSearch is used when the text index is unavailable. The query is
+// matched literally.
+func (r *PostRepository) simpleSearch(query repository.SearchQuery) ([]*models.Post, int, error) {
+	pattern := regexp.QuoteMeta(query.Text)
+
+	filter := searchFilter(query)
+	filter["$or"] = []bson.M{
+		{"title": bson.M{"$regex": pattern, "$options": "i"}},
+		{"content": bson.M{"$regex": pattern, "$options": "i"}},
+		{"description": bson.M{"$regex": pattern, "$options": "i"}},
+		{"tags": bson.M{"$regex": pattern, "$options": "i"}},
 	}
 
-	return posts, nil
+	return r.findWithTotal(filter, bson.D{{Key: "created_at", Value: -1}}, nil, query)
+}
+
+// anyWordPattern builds a case-insensitive regex matching any word of query
+// literally.
+func anyWordPattern(query string) string {
+	words := strings.Fields(query)
+	if len(words) > 10 {
+		words = words[:10]
+	}
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	return strings.Join(words, "|")
 }
 
-func (r *PostRepository) simpleSearch(query string, limit int) ([]*models.Post, error) {
+func searchFilter(query repository.SearchQuery) bson.M {
+	filter := bson.M{"is_archived": false}
+
+	if query.Category != "" {
+		filter["category"] = query.Category
+	}
+	if query.Tag != "" {
+		filter["tags"] = query.Tag
+	}
+	if query.AuthorID != nil {
+		filter["author_id"] = *query.AuthorID
+	}
+
+	createdAt := bson.M{}
+	if !query.From.IsZero() {
+		createdAt["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		createdAt["$lte"] = query.To
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	return filter
+}
+
+func (r *PostRepository) findWithTotal(filter bson.M, sort bson.D, projection bson.M, query repository.SearchQuery) ([]*models.Post, int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{
-		"$or": []bson.M{
-			{"title": bson.M{"$regex": query, "$options": "i"}},
-			{"content": bson.M{"$regex": query, "$options": "i"}},
-			{"description": bson.M{"$regex": query, "$options": "i"}},
-			{"tags": bson.M{"$regex": query, "$options": "i"}},
-		},
-		"is_archived": false,
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	findOptions := options.Find()
-	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
-	findOptions.SetLimit(int64(limit))
+	findOptions.SetSort(sort)
+	findOptions.SetSkip(int64(query.Offset))
+	findOptions.SetLimit(int64(query.Limit))
+	if projection != nil {
+		findOptions.SetProjection(projection)
+	}
 
 	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer cursor.Close(ctx)
 
@@ -411,12 +487,12 @@ func (r *PostRepository) simpleSearch(query string, limit int) ([]*models.Post,
 	for cursor.Next(ctx) {
 		var post models.Post
 		if err := cursor.Decode(&post); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		posts = append(posts, &post)
 	}
 
-	return posts, nil
+	return posts, int(total), nil
 }
 
 func (r *PostRepository) IncrementViewCount(id primitive.ObjectID) error {
@@ -431,6 +507,25 @@ func (r *PostRepository) IncrementViewCount(id primitive.ObjectID) error {
 	return err
 }
 
+func (r *PostRepository) IncrementViewCounts(counts map[primitive.ObjectID]int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(counts))
+	for id, count := range counts {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$inc": bson.M{"view_count": count}}))
+	}
+
+	_, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
 func (r *PostRepository) GetCategoriesStats() (map[string]int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -522,3 +617,105 @@ func (r *PostRepository) GetCategoriesStatsAggregated() (map[string]repository.C
 
 	return stats, nil
 }
+
+func (r *PostRepository) FindFeed(query repository.FeedQuery) ([]*models.Post, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	and := []bson.M{{"is_archived": false}}
+
+	var sources []bson.M
+	if len(query.AuthorIDs) > 0 {
+		sources = append(sources, bson.M{"author_id": bson.M{"$in": query.AuthorIDs}})
+	}
+	if len(query.Categories) > 0 {
+		sources = append(sources, bson.M{"category": bson.M{"$in": query.Categories}})
+	}
+	if len(query.Tags) > 0 {
+		sources = append(sources, bson.M{"tags": bson.M{"$in": query.Tags}})
+	}
+	if len(sources) > 0 {
+		and = append(and, bson.M{"$or": sources})
+	}
+
+	if query.Category != "" {
+		and = append(and, bson.M{"category": query.Category})
+	}
+
+	if !query.BeforeTime.IsZero() {
+		and = append(and, bson.M{"$or": []bson.M{
+			{"created_at": bson.M{"$lt": query.BeforeTime}},
+			{"created_at": query.BeforeTime, "_id": bson.M{"$lt": query.BeforeID}},
+		}})
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	findOptions.SetLimit(int64(query.Limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"$and": and}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var posts []*models.Post
+	for cursor.Next(ctx) {
+		var post models.Post
+		if err := cursor.Decode(&post); err != nil {
+			return nil, err
+		}
+		posts = append(posts, &post)
+	}
+
+	return posts, nil
+}
+
+func (r *PostRepository) FindByIDs(ids []primitive.ObjectID) ([]*models.Post, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if len(ids) == 0 {
+		return []*models.Post{}, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var posts []*models.Post
+	for cursor.Next(ctx) {
+		var post models.Post
+		if err := cursor.Decode(&post); err != nil {
+			return nil, err
+		}
+		posts = append(posts, &post)
+	}
+
+	return posts, nil
+}
+
+func (r *PostRepository) FindAllByAuthor(query repository.ActivityQuery) ([]*models.Post, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter, findOptions := activityFind("author_id", query)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var posts []*models.Post
+	for cursor.Next(ctx) {
+		var post models.Post
+		if err := cursor.Decode(&post); err != nil {
+			return nil, err
+		}
+		posts = append(posts, &post)
+	}
+
+	return posts, nil
+}
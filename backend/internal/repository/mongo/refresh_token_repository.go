@@ -0,0 +1,120 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type RefreshTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRefreshTokenRepository(db *mongo.Database) *RefreshTokenRepository {
+	r := &RefreshTokenRepository{
+		collection: db.Collection("refresh_tokens"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *RefreshTokenRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "family_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			// Expired tokens are useless, so let Mongo drop them
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create refresh_tokens indexes: %v", err)
+	}
+}
+
+func (r *RefreshTokenRepository) Create(token *models.RefreshToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+func (r *RefreshTokenRepository) FindByHash(tokenHash string) (*models.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var token models.RefreshToken
+	err := r.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *RefreshTokenRepository) MarkRotated(id primitive.ObjectID, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{
+			"_id":        id,
+			"rotated_at": bson.M{"$exists": false},
+			"revoked_at": bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{"rotated_at": at}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(familyID primitive.ObjectID, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"family_id": familyID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": at}},
+	)
+	return err
+}
+
+func (r *RefreshTokenRepository) RevokeByUser(userID primitive.ObjectID, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": at}},
+	)
+	return err
+}
+
+func (r *RefreshTokenRepository) DeleteByUser(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -0,0 +1,51 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type RolePermissionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRolePermissionRepository(db *mongo.Database) *RolePermissionRepository {
+	return &RolePermissionRepository{
+		collection: db.Collection("role_permissions"),
+	}
+}
+
+func (r *RolePermissionRepository) FindAll() ([]*models.RolePermissions, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var roles []*models.RolePermissions
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *RolePermissionRepository) Upsert(permissions *models.RolePermissions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.ReplaceOne(ctx,
+		bson.M{"_id": permissions.Role},
+		permissions,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
@@ -0,0 +1,204 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type SavedSearchRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSavedSearchRepository(db *mongo.Database) *SavedSearchRepository {
+	r := &SavedSearchRepository{
+		collection: db.Collection("saved_searches"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *SavedSearchRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "muted", Value: 1}, {Key: "category", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "frequency", Value: 1}, {Key: "last_alert_at", Value: 1}},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create saved_searches indexes: %v", err)
+	}
+}
+
+func (r *SavedSearchRepository) Create(search *models.SavedSearch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, search)
+	return err
+}
+
+func (r *SavedSearchRepository) FindByID(id primitive.ObjectID) (*models.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var search models.SavedSearch
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&search)
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+func (r *SavedSearchRepository) FindByUser(userID primitive.ObjectID) ([]*models.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	return r.find(ctx, bson.M{"user_id": userID}, findOptions)
+}
+
+func (r *SavedSearchRepository) CountByUser(userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID})
+}
+
+// Update saves the user-editable fields; pending matches are left alone so
+// a concurrent match is not lost.
+func (r *SavedSearchRepository) Update(search *models.SavedSearch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	search.UpdatedAt = time.Now()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": search.ID},
+		bson.M{"$set": bson.M{
+			"name":       search.Name,
+			"frequency":  search.Frequency,
+			"muted":      search.Muted,
+			"updated_at": search.UpdatedAt,
+		}},
+	)
+	return err
+}
+
+func (r *SavedSearchRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *SavedSearchRepository) DeleteByUser(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+func (r *SavedSearchRepository) FindActive(category string, afterID primitive.ObjectID, limit int) ([]*models.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"muted":    false,
+		"category": bson.M{"$in": []interface{}{category, "", nil}},
+	}
+	if !afterID.IsZero() {
+		filter["_id"] = bson.M{"$gt": afterID}
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "_id", Value: 1}})
+	findOptions.SetLimit(int64(limit))
+
+	return r.find(ctx, filter, findOptions)
+}
+
+// AddPending queues a match, keeping only the newest max posts.
+func (r *SavedSearchRepository) AddPending(id, postID primitive.ObjectID, max int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$push": bson.M{"pending_post_ids": bson.M{
+			"$each":  []primitive.ObjectID{postID},
+			"$slice": -max,
+		}}},
+	)
+	return err
+}
+
+func (r *SavedSearchRepository) FindDueDigests(frequency models.AlertFrequency, before time.Time, limit int) ([]*models.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"frequency":          frequency,
+		"muted":              false,
+		"pending_post_ids.0": bson.M{"$exists": true},
+		"$or": []bson.M{
+			{"last_alert_at": bson.M{"$lt": before}},
+			{"last_alert_at": bson.M{"$exists": false}},
+		},
+	}
+
+	findOptions := options.Find()
+	findOptions.SetLimit(int64(limit))
+
+	return r.find(ctx, filter, findOptions)
+}
+
+func (r *SavedSearchRepository) ClearPending(id primitive.ObjectID, postIDs []primitive.ObjectID, alertedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"last_alert_at": alertedAt}}
+	if len(postIDs) > 0 {
+		update["$pullAll"] = bson.M{"pending_post_ids": postIDs}
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *SavedSearchRepository) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*models.SavedSearch, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var searches []*models.SavedSearch
+	for cursor.Next(ctx) {
+		var search models.SavedSearch
+		if err := cursor.Decode(&search); err != nil {
+			return nil, err
+		}
+		searches = append(searches, &search)
+	}
+
+	return searches, nil
+}
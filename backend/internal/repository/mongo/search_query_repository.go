@@ -0,0 +1,117 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+// searchQueryRetention is how long query logs are kept before Mongo
+// expires them.
+const searchQueryRetention = 90 * 24 * time.Hour
+
+type SearchQueryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSearchQueryRepository(db *mongo.Database) *SearchQueryRepository {
+	r := &SearchQueryRepository{
+		collection: db.Collection("search_queries"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *SearchQueryRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(searchQueryRetention / time.Second)),
+		},
+		{
+			Keys: bson.D{{Key: "source", Value: 1}, {Key: "result_count", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create search_queries indexes: %v", err)
+	}
+}
+
+func (r *SearchQueryRepository) InsertMany(logs []*models.SearchQueryLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	docs := make([]interface{}, len(logs))
+	for i, l := range logs {
+		docs[i] = l
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+// PopularQueries counts full searches that found something.
+func (r *SearchQueryRepository) PopularQueries(since time.Time, limit int) ([]*models.SearchQueryStat, error) {
+	return r.aggregate(bson.M{
+		"source":       models.SearchSourceSearch,
+		"result_count": bson.M{"$gt": 0},
+		"created_at":   bson.M{"$gte": since},
+	}, limit)
+}
+
+func (r *SearchQueryRepository) ZeroResultQueries(source string, since time.Time, limit int) ([]*models.SearchQueryStat, error) {
+	return r.aggregate(bson.M{
+		"source":       source,
+		"result_count": 0,
+		"created_at":   bson.M{"$gte": since},
+	}, limit)
+}
+
+func (r *SearchQueryRepository) aggregate(match bson.M, limit int) ([]*models.SearchQueryStat, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$query",
+			"count":     bson.M{"$sum": 1},
+			"searchers": bson.M{"$addToSet": "$searcher"},
+			"last_seen": bson.M{"$max": "$created_at"},
+		}}},
+		// Logs from before searchers were recorded add nobody
+		{{Key: "$set", Value: bson.M{"searchers": bson.M{"$size": "$searchers"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "last_seen", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats []*models.SearchQueryStat
+	for cursor.Next(ctx) {
+		var stat models.SearchQueryStat
+		if err := cursor.Decode(&stat); err != nil {
+			return nil, err
+		}
+		stats = append(stats, &stat)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,90 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/search"
+)
+
+// unfoldedTagPattern matches every tag search.Fold might change: upper
+// case or any non-ASCII letter. Folded Cyrillic tags match too and are
+// left as they are.
+const unfoldedTagPattern = `[A-Z]|[^\x00-\x7F]`
+
+// backfillFoldedTags re-normalizes tags in field that were stored before
+// tags were folded with search.Fold, so lookups by folded tag find them.
+// Documents already folded are not written.
+func backfillFoldedTags(collection *mongo.Collection, field string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx,
+		bson.M{field: bson.M{"$regex": unfoldedTagPattern}},
+		options.Find().SetProjection(bson.M{field: 1}),
+	)
+	if err != nil {
+		log.Printf("Failed to backfill %s %s: %v", collection.Name(), field, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			log.Printf("Failed to backfill %s %s: %v", collection.Name(), field, err)
+			return
+		}
+		id, _ := doc["_id"].(primitive.ObjectID)
+		raw, _ := doc[field].(bson.A)
+
+		tags := make([]string, 0, len(raw))
+		for _, tag := range raw {
+			if s, ok := tag.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+		folded, changed := foldTags(tags)
+		if !changed {
+			continue
+		}
+
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{field: folded}}); err != nil {
+			log.Printf("Failed to backfill %s %s of %s: %v", collection.Name(), field, id.Hex(), err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		log.Printf("Failed to backfill %s %s: %v", collection.Name(), field, err)
+	}
+}
+
+// foldTags folds each tag and drops the ones that become empty or
+// repeated. It reports whether the result differs from tags.
+func foldTags(tags []string) ([]string, bool) {
+	folded := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = search.Fold(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		folded = append(folded, tag)
+	}
+
+	if len(folded) != len(tags) {
+		return folded, true
+	}
+	for i := range tags {
+		if folded[i] != tags[i] {
+			return folded, true
+		}
+	}
+	return folded, false
+}
@@ -0,0 +1,41 @@
+package mongorepo
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestFoldTags(t *testing.T) {
+	tests := []struct {
+		tags    []string
+		want    []string
+		changed bool
+	}{
+		{[]string{"golang", "қазақша", "ёлка"}, []string{"golang", "қазақша", "елка"}, true},
+		{[]string{"Café", "cafe"}, []string{"cafe"}, true},
+		{[]string{"AITU", "", "aitu"}, []string{"aitu"}, true},
+		{[]string{"golang", "қазақша"}, []string{"golang", "қазақша"}, false},
+		{nil, []string{}, false},
+	}
+	for _, tt := range tests {
+		got, changed := foldTags(tt.tags)
+		if !reflect.DeepEqual(got, tt.want) || changed != tt.changed {
+			t.Errorf("foldTags(%q) = %q, %v; want %q, %v", tt.tags, got, changed, tt.want, tt.changed)
+		}
+	}
+}
+
+func TestUnfoldedTagPatternFindsCandidates(t *testing.T) {
+	pattern := regexp.MustCompile(unfoldedTagPattern)
+	for _, tag := range []string{"AITU", "café", "ёлка", "Қазақ"} {
+		if !pattern.MatchString(tag) {
+			t.Errorf("%q is not scanned", tag)
+		}
+	}
+	for _, tag := range []string{"golang", "c++", "2025"} {
+		if pattern.MatchString(tag) {
+			t.Errorf("folded ASCII tag %q is scanned", tag)
+		}
+	}
+}
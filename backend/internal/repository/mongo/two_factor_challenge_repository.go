@@ -0,0 +1,93 @@
+package mongorepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type TwoFactorChallengeRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTwoFactorChallengeRepository(db *mongo.Database) *TwoFactorChallengeRepository {
+	r := &TwoFactorChallengeRepository{
+		collection: db.Collection("two_factor_challenges"),
+	}
+	r.ensureIndexes()
+	return r
+}
+
+func (r *TwoFactorChallengeRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create two_factor_challenges indexes: %v", err)
+	}
+}
+
+func (r *TwoFactorChallengeRepository) Create(challenge *models.TwoFactorChallenge) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, challenge)
+	return err
+}
+
+func (r *TwoFactorChallengeRepository) FindByHash(tokenHash string) (*models.TwoFactorChallenge, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var challenge models.TwoFactorChallenge
+	err := r.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&challenge)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func (r *TwoFactorChallengeRepository) AddAttempt(id primitive.ObjectID, maxAttempts int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "used_at": bson.M{"$exists": false}, "attempts": bson.M{"$lt": maxAttempts}},
+		bson.M{"$inc": bson.M{"attempts": 1}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *TwoFactorChallengeRepository) MarkUsed(id primitive.ObjectID, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": at}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
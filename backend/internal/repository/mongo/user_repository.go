@@ -2,6 +2,7 @@ package mongorepo
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -10,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
 )
 
 type UserRepository struct {
@@ -17,9 +19,52 @@ type UserRepository struct {
 }
 
 func NewUserRepository(db *mongo.Database) *UserRepository {
-	return &UserRepository{
+	r := &UserRepository{
 		collection: db.Collection("users"),
 	}
+	r.ensureIndexes()
+	r.backfillEmailVerified()
+	backfillFoldedTags(r.collection, "subscribed_tags")
+	return r
+}
+
+func (r *UserRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// Only users with linked identities are indexed, so the rest
+			// do not collide on a missing key
+			Keys: bson.D{{Key: "identities.issuer", Value: 1}, {Key: "identities.subject", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"identities": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "deletion.scheduled_for", Value: 1}},
+			Options: options.Index().
+				SetPartialFilterExpression(bson.M{"deletion.scheduled_for": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to create users indexes: %v", err)
+	}
+}
+
+// backfillEmailVerified marks accounts created before email verification
+// existed as verified. New users always store the field, so only those
+// older documents lack it.
+func (r *UserRepository) backfillEmailVerified() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"email_verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
+	if err != nil {
+		log.Printf("Failed to backfill users email_verified: %v", err)
+	}
 }
 
 func (r *UserRepository) FindByID(id primitive.ObjectID) (*models.User, error) {
@@ -46,6 +91,20 @@ func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
+func (r *UserRepository) FindByIdentity(issuer, subject string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{
+		"identities": bson.M{"$elemMatch": bson.M{"issuer": issuer, "subject": subject}},
+	}).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *UserRepository) Create(user *models.User) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -71,6 +130,46 @@ func (r *UserRepository) Update(user *models.User) error {
 	return err
 }
 
+func (r *UserRepository) AddSubscription(id primitive.ObjectID, list repository.SubscriptionList, value string) (*models.User, error) {
+	// Older users store the list as null, which $addToSet refuses, so the
+	// value is appended in a pipeline instead
+	current := bson.M{"$ifNull": []interface{}{"$" + string(list), bson.A{}}}
+	return r.updateSubscriptions(id, bson.M{
+		string(list): bson.M{"$cond": bson.A{
+			bson.M{"$in": bson.A{value, current}},
+			current,
+			bson.M{"$concatArrays": bson.A{current, bson.A{value}}},
+		}},
+	})
+}
+
+func (r *UserRepository) RemoveSubscription(id primitive.ObjectID, list repository.SubscriptionList, value string) (*models.User, error) {
+	current := bson.M{"$ifNull": []interface{}{"$" + string(list), bson.A{}}}
+	return r.updateSubscriptions(id, bson.M{
+		string(list): bson.M{"$filter": bson.M{
+			"input": current,
+			"cond":  bson.M{"$ne": bson.A{"$$this", value}},
+		}},
+	})
+}
+
+func (r *UserRepository) updateSubscriptions(id primitive.ObjectID, set bson.M) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	set["updated_at"] = time.Now()
+	var user models.User
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.A{bson.M{"$set": set}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *UserRepository) Delete(id primitive.ObjectID) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -104,3 +203,116 @@ func (r *UserRepository) FindAll(limit, offset int) ([]*models.User, error) {
 
 	return users, nil
 }
+
+func (r *UserRepository) FindByIDs(ids []primitive.ObjectID) ([]*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if len(ids) == 0 {
+		return []*models.User{}, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) IncrementFollowCounts(id primitive.ObjectID, followers, following int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"follower_count": followers, "following_count": following}},
+	)
+	if err != nil {
+		return err
+	}
+
+	fixFilter := bson.M{"_id": id, "$or": []bson.M{
+		{"follower_count": bson.M{"$lt": 0}},
+		{"following_count": bson.M{"$lt": 0}},
+	}}
+	fixUpdate := []bson.M{{"$set": bson.M{
+		"follower_count":  bson.M{"$max": []interface{}{"$follower_count", 0}},
+		"following_count": bson.M{"$max": []interface{}{"$following_count", 0}},
+	}}}
+	r.collection.UpdateOne(ctx, fixFilter, fixUpdate)
+
+	return nil
+}
+
+// Search matches display names and emails. It includes deactivated users,
+// so callers filter for public use.
+func (r *UserRepository) Search(query string, limit int) ([]*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pattern := anyWordPattern(query)
+	filter := bson.M{"$or": []bson.M{
+		{"display_name": bson.M{"$regex": pattern, "$options": "i"}},
+		{"email": bson.M{"$regex": pattern, "$options": "i"}},
+		{"bio": bson.M{"$regex": pattern, "$options": "i"}},
+	}}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "display_name", Value: 1}})
+	findOptions.SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) FindDueDeletions(before time.Time, limit int) ([]*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "deletion.scheduled_for", Value: 1}})
+	findOptions.SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"deletion.scheduled_for": bson.M{"$lte": before}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, nil
+}
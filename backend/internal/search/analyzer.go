@@ -0,0 +1,26 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token is an analyzed term. Tokens that share a position are alternative
+// forms of the same word.
+type Token struct {
+	Term     string
+	Position int
+}
+
+type Analyzer interface {
+	Analyze(text string) []Token
+	// Normalize folds a single word without stemming it, for prefix
+	// queries. It may return several alternative forms.
+	Normalize(word string) []string
+}
+
+func splitWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
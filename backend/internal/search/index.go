@@ -0,0 +1,53 @@
+package search
+
+import "time"
+
+// Field is a piece of searchable text. Boost scales its contribution to the
+// score; zero means 1.
+type Field struct {
+	Name  string
+	Text  string
+	Boost float64
+}
+
+// Document is the unit of indexing. Filters hold exact-match attributes
+// such as category or author.
+type Document struct {
+	ID      string
+	Kind    string
+	Fields  []Field
+	Filters map[string][]string
+	Time    time.Time
+}
+
+// Query matches documents containing every clause of Text. Bare words are
+// terms, words ending in * are prefixes and quoted text is a phrase. When
+// nothing contains every clause, documents matching any clause are
+// returned instead unless RequireAll is set. Empty Kinds match every kind.
+type Query struct {
+	Text       string
+	Kinds      []string
+	Filters    map[string]string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+	RequireAll bool
+}
+
+type Hit struct {
+	ID    string
+	Kind  string
+	Score float64
+	Time  time.Time
+}
+
+type SearchIndex interface {
+	// Index adds the document or replaces the one with the same kind and ID.
+	Index(doc Document)
+	Remove(kind, id string)
+	// Search returns one page of hits ordered by relevance and the total
+	// number of matches.
+	Search(query Query) ([]Hit, int)
+	Len() int
+}
@@ -0,0 +1,410 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	prefixWeight        = 0.8
+	maxPrefixExpansions = 64
+	// fieldGap keeps phrases from matching across repeated fields
+	fieldGap = 8
+)
+
+// posting holds the positions of a term in one document, per field.
+type posting map[string][]int
+
+type docEntry struct {
+	doc      Document
+	lengths  map[string]int
+	boosts   map[string]float64
+	postings map[string]posting
+}
+
+// InvertedIndex is an in-memory SearchIndex scored with BM25F.
+type InvertedIndex struct {
+	analyzer Analyzer
+
+	mu          sync.RWMutex
+	docs        map[string]*docEntry
+	postings    map[string]map[string]posting
+	fieldTokens map[string]int
+	fieldDocs   map[string]int
+
+	termsMu     sync.Mutex
+	sortedTerms []string
+	termsDirty  bool
+}
+
+func NewInvertedIndex(analyzer Analyzer) *InvertedIndex {
+	return &InvertedIndex{
+		analyzer:    analyzer,
+		docs:        make(map[string]*docEntry),
+		postings:    make(map[string]map[string]posting),
+		fieldTokens: make(map[string]int),
+		fieldDocs:   make(map[string]int),
+	}
+}
+
+func docKey(kind, id string) string {
+	return kind + ":" + id
+}
+
+func (ix *InvertedIndex) Index(doc Document) {
+	entry := ix.analyze(doc)
+	key := docKey(doc.Kind, doc.ID)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.removeLocked(key)
+	ix.docs[key] = entry
+
+	for term, p := range entry.postings {
+		docs, ok := ix.postings[term]
+		if !ok {
+			docs = make(map[string]posting)
+			ix.postings[term] = docs
+			ix.termsDirty = true
+		}
+		docs[key] = p
+	}
+	for field, length := range entry.lengths {
+		ix.fieldTokens[field] += length
+		ix.fieldDocs[field]++
+	}
+}
+
+func (ix *InvertedIndex) Remove(kind, id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.removeLocked(docKey(kind, id))
+}
+
+func (ix *InvertedIndex) removeLocked(key string) {
+	entry, ok := ix.docs[key]
+	if !ok {
+		return
+	}
+
+	for term := range entry.postings {
+		docs := ix.postings[term]
+		delete(docs, key)
+		if len(docs) == 0 {
+			delete(ix.postings, term)
+			ix.termsDirty = true
+		}
+	}
+	for field, length := range entry.lengths {
+		ix.fieldTokens[field] -= length
+		ix.fieldDocs[field]--
+		if ix.fieldDocs[field] == 0 {
+			delete(ix.fieldTokens, field)
+			delete(ix.fieldDocs, field)
+		}
+	}
+	delete(ix.docs, key)
+}
+
+func (ix *InvertedIndex) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.docs)
+}
+
+func (ix *InvertedIndex) analyze(doc Document) *docEntry {
+	entry := &docEntry{
+		doc:      doc,
+		lengths:  make(map[string]int),
+		boosts:   make(map[string]float64),
+		postings: make(map[string]posting),
+	}
+
+	for _, field := range doc.Fields {
+		base := 0
+		if length, ok := entry.lengths[field.Name]; ok {
+			base = length + fieldGap
+		}
+
+		boost := field.Boost
+		if boost == 0 {
+			boost = 1
+		}
+		entry.boosts[field.Name] = boost
+
+		words := 0
+		last := -1
+		for _, token := range ix.analyzer.Analyze(field.Text) {
+			position := base + token.Position
+			if token.Position != last {
+				words++
+				last = token.Position
+			}
+
+			p, ok := entry.postings[token.Term]
+			if !ok {
+				p = make(posting)
+				entry.postings[token.Term] = p
+			}
+			p[field.Name] = append(p[field.Name], position)
+		}
+		entry.lengths[field.Name] += words
+	}
+
+	return entry
+}
+
+func (ix *InvertedIndex) Search(query Query) ([]Hit, int) {
+	clauses := parseQuery(query.Text, ix.analyzer)
+	if len(clauses) == 0 {
+		return nil, 0
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	perClause := make([]map[string]float64, len(clauses))
+	for i, c := range clauses {
+		perClause[i] = ix.scoreClause(c)
+	}
+
+	scores := combine(perClause, true)
+	// Fall back to matching any clause rather than returning nothing
+	if len(scores) == 0 && len(clauses) > 1 && !query.RequireAll {
+		scores = combine(perClause, false)
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for key, score := range scores {
+		entry := ix.docs[key]
+		if !entry.matches(query) {
+			continue
+		}
+		hits = append(hits, Hit{ID: entry.doc.ID, Kind: entry.doc.Kind, Score: score, Time: entry.doc.Time})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score == hits[j].Score {
+			return hits[i].Time.After(hits[j].Time)
+		}
+		return hits[i].Score > hits[j].Score
+	})
+
+	total := len(hits)
+	if query.Offset >= total {
+		return []Hit{}, total
+	}
+	hits = hits[query.Offset:]
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, total
+}
+
+func combine(perClause []map[string]float64, all bool) map[string]float64 {
+	result := make(map[string]float64)
+	for key := range perClause[0] {
+		result[key] = 0
+	}
+	if !all {
+		for _, scores := range perClause[1:] {
+			for key := range scores {
+				result[key] = 0
+			}
+		}
+	}
+
+	for key := range result {
+		for _, scores := range perClause {
+			score, ok := scores[key]
+			if !ok && all {
+				delete(result, key)
+				break
+			}
+			result[key] += score
+		}
+	}
+	return result
+}
+
+// MatchesFilters reports whether doc passes the kind, filter and time
+// restrictions of query, ignoring its text.
+func MatchesFilters(doc Document, query Query) bool {
+	return (&docEntry{doc: doc}).matches(query)
+}
+
+func (e *docEntry) matches(query Query) bool {
+	if len(query.Kinds) > 0 && !contains(query.Kinds, e.doc.Kind) {
+		return false
+	}
+	for name, value := range query.Filters {
+		if value != "" && !contains(e.doc.Filters[name], value) {
+			return false
+		}
+	}
+	if !query.From.IsZero() && e.doc.Time.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && e.doc.Time.After(query.To) {
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (ix *InvertedIndex) scoreClause(c clause) map[string]float64 {
+	switch c.kind {
+	case prefixClause:
+		scores := make(map[string]float64)
+		for _, prefix := range c.steps[0] {
+			for _, term := range ix.expandPrefix(prefix) {
+				weight := prefixWeight
+				if term == prefix {
+					weight = 1
+				}
+				ix.scoreTerm(term, weight, scores)
+			}
+		}
+		return scores
+
+	case phraseClause:
+		stepScores := make([]map[string]float64, len(c.steps))
+		for i, step := range c.steps {
+			stepScores[i] = ix.scoreAlternatives(step)
+		}
+
+		scores := combine(stepScores, true)
+		for key := range scores {
+			if !ix.phraseMatches(key, c) {
+				delete(scores, key)
+			}
+		}
+		return scores
+
+	default:
+		return ix.scoreAlternatives(c.steps[0])
+	}
+}
+
+func (ix *InvertedIndex) scoreAlternatives(terms []string) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, term := range terms {
+		ix.scoreTerm(term, 1, scores)
+	}
+	return scores
+}
+
+// scoreTerm records the BM25F score of term for every document containing
+// it, keeping the best score when a document matched another alternative.
+func (ix *InvertedIndex) scoreTerm(term string, weight float64, scores map[string]float64) {
+	docs := ix.postings[term]
+	if len(docs) == 0 {
+		return
+	}
+
+	n := float64(len(ix.docs))
+	df := float64(len(docs))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+	for key, p := range docs {
+		entry := ix.docs[key]
+
+		tf := 0.0
+		for field, positions := range p {
+			avg := float64(ix.fieldTokens[field]) / float64(ix.fieldDocs[field])
+			norm := 1 - bm25B
+			if avg > 0 {
+				norm += bm25B * float64(entry.lengths[field]) / avg
+			}
+			tf += entry.boosts[field] * float64(len(positions)) / norm
+		}
+
+		score := weight * idf * tf / (bm25K1 + tf)
+		if score > scores[key] {
+			scores[key] = score
+		}
+	}
+}
+
+func (ix *InvertedIndex) phraseMatches(key string, c clause) bool {
+	entry := ix.docs[key]
+
+	for field := range entry.lengths {
+		for _, first := range c.steps[0] {
+			for _, start := range ix.positions(first, key, field) {
+				if ix.phraseAt(key, field, start, c) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (ix *InvertedIndex) phraseAt(key, field string, start int, c clause) bool {
+	for i := 1; i < len(c.steps); i++ {
+		target := start + c.offsets[i]
+		found := false
+		for _, term := range c.steps[i] {
+			positions := ix.positions(term, key, field)
+			if j := sort.SearchInts(positions, target); j < len(positions) && positions[j] == target {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (ix *InvertedIndex) positions(term, key, field string) []int {
+	return ix.postings[term][key][field]
+}
+
+func (ix *InvertedIndex) expandPrefix(prefix string) []string {
+	terms := ix.terms()
+
+	start := sort.SearchStrings(terms, prefix)
+	var expansions []string
+	for i := start; i < len(terms) && strings.HasPrefix(terms[i], prefix); i++ {
+		expansions = append(expansions, terms[i])
+		if len(expansions) >= maxPrefixExpansions {
+			break
+		}
+	}
+	return expansions
+}
+
+// terms returns the sorted vocabulary. Callers hold ix.mu for reading.
+func (ix *InvertedIndex) terms() []string {
+	ix.termsMu.Lock()
+	defer ix.termsMu.Unlock()
+
+	if ix.termsDirty || ix.sortedTerms == nil {
+		ix.sortedTerms = make([]string, 0, len(ix.postings))
+		for term := range ix.postings {
+			ix.sortedTerms = append(ix.sortedTerms, term)
+		}
+		sort.Strings(ix.sortedTerms)
+		ix.termsDirty = false
+	}
+	return ix.sortedTerms
+}
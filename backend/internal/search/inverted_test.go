@@ -0,0 +1,233 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestIndex(docs ...Document) *InvertedIndex {
+	ix := NewInvertedIndex(TextAnalyzer{})
+	for _, doc := range docs {
+		ix.Index(doc)
+	}
+	return ix
+}
+
+func post(id, title, content string) Document {
+	return Document{
+		ID:   id,
+		Kind: "post",
+		Fields: []Field{
+			{Name: "title", Text: title, Boost: 3},
+			{Name: "content", Text: content},
+		},
+	}
+}
+
+func hitIDs(hits []Hit) []string {
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+func search(ix *InvertedIndex, text string) []string {
+	hits, _ := ix.Search(Query{Text: text})
+	return hitIDs(hits)
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBM25Ranking(t *testing.T) {
+	filler := "a long post about many other things on campus like food sport music and clubs"
+
+	tests := []struct {
+		name  string
+		docs  []Document
+		query string
+		want  []string
+	}{
+		{
+			name: "more occurrences rank higher",
+			docs: []Document{
+				post("once", "", "hackathon "+filler),
+				post("thrice", "", "hackathon hackathon hackathon "+filler),
+			},
+			query: "hackathon",
+			want:  []string{"thrice", "once"},
+		},
+		{
+			name: "shorter field ranks higher",
+			docs: []Document{
+				post("long", "", "hackathon "+filler),
+				post("short", "", "hackathon tonight"),
+			},
+			query: "hackathon",
+			want:  []string{"short", "long"},
+		},
+		{
+			name: "boosted field ranks higher",
+			docs: []Document{
+				post("content", "Weekend plans", "hackathon tonight"),
+				post("title", "Hackathon tonight", "weekend plans"),
+			},
+			query: "hackathon",
+			want:  []string{"title", "content"},
+		},
+		{
+			name: "rare term outweighs common one",
+			docs: []Document{
+				post("common", "", "campus campus news"),
+				post("rare", "", "campus robotics news"),
+				post("other1", "", "campus news"),
+				post("other2", "", "campus news"),
+			},
+			query: "campus robotics",
+			want:  []string{"rare"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := search(newTestIndex(tt.docs...), tt.query); !equalIDs(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBM25ScoreSaturates(t *testing.T) {
+	ix := newTestIndex(
+		post("one", "", "exam"),
+		post("ten", "", "exam exam exam exam exam exam exam exam exam exam"),
+		post("none", "", "lecture"),
+	)
+	hits, _ := ix.Search(Query{Text: "exam"})
+	if len(hits) != 2 || hits[0].ID != "ten" {
+		t.Fatalf("hits = %v, want ten first", hitIDs(hits))
+	}
+	// k1 caps what repetition buys: ten times the term is not ten times
+	// the score
+	if ratio := hits[0].Score / hits[1].Score; ratio >= 2 {
+		t.Errorf("score ratio = %.2f, want under 2", ratio)
+	}
+}
+
+func TestSearchAllClausesFirst(t *testing.T) {
+	ix := newTestIndex(
+		post("both", "", "chess club meeting"),
+		post("chess", "", "chess tournament"),
+		post("club", "", "book club"),
+	)
+
+	if got := search(ix, "chess club"); !equalIDs(got, []string{"both"}) {
+		t.Errorf("both words: %v, want [both]", got)
+	}
+
+	// Without a document holding every word, any word will do
+	hits, total := ix.Search(Query{Text: "chess book"})
+	if total != 3 {
+		t.Errorf("fallback matched %v, want all three", hitIDs(hits))
+	}
+	if hits, total := ix.Search(Query{Text: "chess book", RequireAll: true}); total != 0 {
+		t.Errorf("RequireAll matched %v", hitIDs(hits))
+	}
+}
+
+func TestSearchPhrase(t *testing.T) {
+	ix := newTestIndex(
+		post("exact", "", "the career fair opens monday"),
+		post("apart", "", "fair weather for the career day"),
+		Document{ID: "split", Kind: "post", Fields: []Field{
+			{Name: "tags", Text: "career"},
+			{Name: "tags", Text: "fair"},
+		}},
+	)
+
+	// Repeated fields are kept apart, so the phrase cannot span them
+	if got := search(ix, `"career fair"`); !equalIDs(got, []string{"exact"}) {
+		t.Errorf("phrase: %v, want [exact]", got)
+	}
+	if _, total := ix.Search(Query{Text: "career fair"}); total != 3 {
+		t.Errorf("terms matched %d documents, want 3", total)
+	}
+}
+
+func TestSearchPrefixAndTransliteration(t *testing.T) {
+	ix := newTestIndex(
+		post("cyrillic", "Алматы марафон", ""),
+		post("latin", "Almaty marathon", ""),
+		post("other", "Astana", ""),
+	)
+
+	if _, total := ix.Search(Query{Text: "almaty"}); total != 2 {
+		t.Errorf("almaty matched %d, want both scripts", total)
+	}
+	if _, total := ix.Search(Query{Text: "алм*"}); total != 2 {
+		t.Errorf("алм* matched %d, want both scripts", total)
+	}
+	if got := search(ix, "ast*"); !equalIDs(got, []string{"other"}) {
+		t.Errorf("ast*: %v, want [other]", got)
+	}
+}
+
+func TestSearchFiltersAndPaging(t *testing.T) {
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	var docs []Document
+	for i, category := range []string{"news", "news", "event", "news"} {
+		doc := post(string(rune('a'+i)), "Campus "+category, "")
+		doc.Filters = map[string][]string{"category": {category}}
+		doc.Time = day.AddDate(0, 0, i)
+		docs = append(docs, doc)
+	}
+	docs = append(docs, Document{ID: "u", Kind: "user", Fields: []Field{{Name: "name", Text: "Campus guide"}}, Time: day})
+	ix := newTestIndex(docs...)
+
+	// Equal scores fall back to newest first
+	hits, total := ix.Search(Query{Text: "campus", Kinds: []string{"post"}, Filters: map[string]string{"category": "news"}, Limit: 2})
+	if total != 3 || !equalIDs(hitIDs(hits), []string{"d", "b"}) {
+		t.Errorf("page 1 = %v of %d, want [d b] of 3", hitIDs(hits), total)
+	}
+	hits, _ = ix.Search(Query{Text: "campus", Kinds: []string{"post"}, Filters: map[string]string{"category": "news"}, Limit: 2, Offset: 2})
+	if !equalIDs(hitIDs(hits), []string{"a"}) {
+		t.Errorf("page 2 = %v, want [a]", hitIDs(hits))
+	}
+
+	hits, _ = ix.Search(Query{Text: "campus", Kinds: []string{"post"}, From: day.AddDate(0, 0, 1), To: day.AddDate(0, 0, 2)})
+	if !equalIDs(hitIDs(hits), []string{"c", "b"}) {
+		t.Errorf("date range = %v, want [c b]", hitIDs(hits))
+	}
+	if hits, total := ix.Search(Query{Text: "campus", Offset: 10}); len(hits) != 0 || total != 5 {
+		t.Errorf("past the end = %v of %d, want none of 5", hitIDs(hits), total)
+	}
+}
+
+func TestIndexReplaceAndRemove(t *testing.T) {
+	ix := newTestIndex(post("p", "Lost keys", ""))
+
+	ix.Index(post("p", "Found wallet", ""))
+	if ix.Len() != 1 {
+		t.Errorf("Len = %d after replacing, want 1", ix.Len())
+	}
+	if got := search(ix, "keys"); len(got) != 0 {
+		t.Errorf("old text still matches: %v", got)
+	}
+	if got := search(ix, "wallet"); !equalIDs(got, []string{"p"}) {
+		t.Errorf("new text: %v, want [p]", got)
+	}
+
+	ix.Remove("post", "p")
+	if ix.Len() != 0 || len(search(ix, "wallet")) != 0 {
+		t.Error("removed document still found")
+	}
+}
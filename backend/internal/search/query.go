@@ -0,0 +1,114 @@
+package search
+
+import "strings"
+
+type clauseKind int
+
+const (
+	termClause clauseKind = iota
+	prefixClause
+	phraseClause
+)
+
+// clause is one required part of a query. Each step lists alternative
+// terms; phrase steps must appear at the given offsets from the first.
+type clause struct {
+	kind    clauseKind
+	steps   [][]string
+	offsets []int
+}
+
+func parseQuery(text string, analyzer Analyzer) []clause {
+	var clauses []clause
+
+	segments := strings.Split(text, `"`)
+	for i, segment := range segments {
+		// Odd segments are inside quotes unless the last quote is unbalanced
+		if i%2 == 1 && i < len(segments)-1 {
+			if c, ok := phrase(analyzer.Analyze(segment)); ok {
+				clauses = append(clauses, c)
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(segment) {
+			if strings.HasSuffix(field, "*") {
+				words := splitWords(field)
+				if len(words) == 0 {
+					continue
+				}
+				for _, word := range words[:len(words)-1] {
+					clauses = append(clauses, termClauses(analyzer.Analyze(word))...)
+				}
+				if forms := analyzer.Normalize(words[len(words)-1]); len(forms) > 0 {
+					clauses = append(clauses, clause{kind: prefixClause, steps: [][]string{forms}})
+				}
+				continue
+			}
+
+			clauses = append(clauses, termClauses(analyzer.Analyze(field))...)
+		}
+	}
+
+	return clauses
+}
+
+// groupTokens merges tokens sharing a position into alternatives.
+func groupTokens(tokens []Token) ([][]string, []int) {
+	var steps [][]string
+	var positions []int
+	for _, token := range tokens {
+		if n := len(positions); n > 0 && positions[n-1] == token.Position {
+			steps[n-1] = append(steps[n-1], token.Term)
+			continue
+		}
+		steps = append(steps, []string{token.Term})
+		positions = append(positions, token.Position)
+	}
+	return steps, positions
+}
+
+func termClauses(tokens []Token) []clause {
+	steps, _ := groupTokens(tokens)
+	clauses := make([]clause, 0, len(steps))
+	for _, step := range steps {
+		clauses = append(clauses, clause{kind: termClause, steps: [][]string{step}})
+	}
+	return clauses
+}
+
+func phrase(tokens []Token) (clause, bool) {
+	steps, positions := groupTokens(tokens)
+	switch len(steps) {
+	case 0:
+		return clause{}, false
+	case 1:
+		return clause{kind: termClause, steps: steps}, true
+	}
+
+	offsets := make([]int, len(positions))
+	for i, position := range positions {
+		offsets[i] = position - positions[0]
+	}
+	return clause{kind: phraseClause, steps: steps, offsets: offsets}, true
+}
+
+// QueryTerms returns the analyzed terms and the prefixes a query matches,
+// for highlighting.
+func QueryTerms(text string, analyzer Analyzer) (map[string]bool, []string) {
+	terms := make(map[string]bool)
+	var prefixes []string
+
+	for _, c := range parseQuery(text, analyzer) {
+		if c.kind == prefixClause {
+			prefixes = append(prefixes, c.steps[0]...)
+			continue
+		}
+		for _, step := range c.steps {
+			for _, term := range step {
+				terms[term] = true
+			}
+		}
+	}
+	return terms, prefixes
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const snapshotVersion = 2
+
+var ErrSnapshotStale = errors.New("search snapshot is stale")
+
+type snapshot struct {
+	Version   int
+	CreatedAt time.Time
+	Documents []Document
+}
+
+// SaveSnapshot writes the indexed documents to path. The file is replaced
+// atomically so a crash never leaves a partial snapshot behind.
+func (ix *InvertedIndex) SaveSnapshot(path string) error {
+	ix.mu.RLock()
+	snap := snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now(),
+		Documents: make([]Document, 0, len(ix.docs)),
+	}
+	for _, entry := range ix.docs {
+		snap.Documents = append(snap.Documents, entry.doc)
+	}
+	ix.mu.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".search-snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(snap); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot indexes the documents saved at path. Snapshots older than
+// maxAge are rejected with ErrSnapshotStale; zero disables the check.
+func (ix *InvertedIndex) LoadSnapshot(path string, maxAge time.Duration) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var snap snapshot
+	if err := gob.NewDecoder(file).Decode(&snap); err != nil {
+		return err
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported search snapshot version %d", snap.Version)
+	}
+	if maxAge > 0 && time.Since(snap.CreatedAt) > maxAge {
+		return ErrSnapshotStale
+	}
+
+	for _, doc := range snap.Documents {
+		ix.Index(doc)
+	}
+	return nil
+}
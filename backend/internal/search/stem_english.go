@@ -0,0 +1,235 @@
+package search
+
+// stemEnglish implements the Porter stemming algorithm for lower-case
+// ASCII words.
+func stemEnglish(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+
+	p := &porter{b: []byte(word), k: len(word) - 1}
+	p.step1ab()
+	if p.k > 0 {
+		p.step1c()
+		p.step2()
+		p.step3()
+		p.step4()
+		p.step5()
+	}
+	return string(p.b[:p.k+1])
+}
+
+type porter struct {
+	b    []byte
+	k, j int
+}
+
+func (p *porter) cons(i int) bool {
+	switch p.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !p.cons(i-1)
+	}
+	return true
+}
+
+// m counts the vowel-consonant sequences in b[0..j].
+func (p *porter) m() int {
+	n, i := 0, 0
+	for {
+		if i > p.j {
+			return n
+		}
+		if !p.cons(i) {
+			break
+		}
+		i++
+	}
+	i++
+	for {
+		for {
+			if i > p.j {
+				return n
+			}
+			if p.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+		n++
+		for {
+			if i > p.j {
+				return n
+			}
+			if !p.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+	}
+}
+
+func (p *porter) vowelInStem() bool {
+	for i := 0; i <= p.j; i++ {
+		if !p.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *porter) doublec(j int) bool {
+	return j >= 1 && p.b[j] == p.b[j-1] && p.cons(j)
+}
+
+func (p *porter) cvc(i int) bool {
+	if i < 2 || !p.cons(i) || p.cons(i-1) || !p.cons(i-2) {
+		return false
+	}
+	switch p.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func (p *porter) ends(s string) bool {
+	l := len(s)
+	if l > p.k+1 || string(p.b[p.k-l+1:p.k+1]) != s {
+		return false
+	}
+	p.j = p.k - l
+	return true
+}
+
+func (p *porter) setTo(s string) {
+	p.b = append(p.b[:p.j+1], s...)
+	p.k = p.j + len(s)
+}
+
+func (p *porter) replace(s string) {
+	if p.m() > 0 {
+		p.setTo(s)
+	}
+}
+
+func (p *porter) step1ab() {
+	if p.b[p.k] == 's' {
+		switch {
+		case p.ends("sses"):
+			p.k -= 2
+		case p.ends("ies"):
+			p.setTo("i")
+		case p.b[p.k-1] != 's':
+			p.k--
+		}
+	}
+
+	if p.ends("eed") {
+		if p.m() > 0 {
+			p.k--
+		}
+		return
+	}
+
+	if (p.ends("ed") || p.ends("ing")) && p.vowelInStem() {
+		p.k = p.j
+		switch {
+		case p.ends("at"):
+			p.setTo("ate")
+		case p.ends("bl"):
+			p.setTo("ble")
+		case p.ends("iz"):
+			p.setTo("ize")
+		case p.doublec(p.k):
+			p.k--
+			switch p.b[p.k] {
+			case 'l', 's', 'z':
+				p.k++
+			}
+		default:
+			p.j = p.k
+			if p.m() == 1 && p.cvc(p.k) {
+				p.setTo("e")
+			}
+		}
+	}
+}
+
+func (p *porter) step1c() {
+	if p.ends("y") && p.vowelInStem() {
+		p.b[p.k] = 'i'
+	}
+}
+
+type suffixRule struct {
+	suffix, replacement string
+}
+
+var porterStep2 = []suffixRule{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+var porterStep3 = []suffixRule{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+var porterStep4 = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func (p *porter) step2() {
+	for _, rule := range porterStep2 {
+		if p.ends(rule.suffix) {
+			p.replace(rule.replacement)
+			return
+		}
+	}
+}
+
+func (p *porter) step3() {
+	for _, rule := range porterStep3 {
+		if p.ends(rule.suffix) {
+			p.replace(rule.replacement)
+			return
+		}
+	}
+}
+
+func (p *porter) step4() {
+	for _, suffix := range porterStep4 {
+		if !p.ends(suffix) {
+			continue
+		}
+		if suffix == "ion" && (p.j < 0 || (p.b[p.j] != 's' && p.b[p.j] != 't')) {
+			return
+		}
+		if p.m() > 1 {
+			p.k = p.j
+		}
+		return
+	}
+}
+
+func (p *porter) step5() {
+	p.j = p.k
+	if p.b[p.k] == 'e' {
+		a := p.m()
+		if a > 1 || (a == 1 && !p.cvc(p.k-1)) {
+			p.k--
+		}
+	}
+	if p.b[p.k] == 'l' && p.doublec(p.k) && p.m() > 1 {
+		p.k--
+	}
+}
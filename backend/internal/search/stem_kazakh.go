@@ -0,0 +1,40 @@
+package search
+
+// Kazakh inflectional suffixes in Cyrillic, grouped by the order in which
+// they are stripped from the end of a word: case, then possessive, then
+// plural.
+var (
+	kkCase = []string{
+		"ның", "нің", "дың", "дің", "тың", "тің",
+		"ға", "ге", "қа", "ке", "на", "не",
+		"ны", "ні", "ды", "ді", "ты", "ті",
+		"нда", "нде", "да", "де", "та", "те",
+		"нан", "нен", "дан", "ден", "тан", "тен",
+		"менен", "бенен", "пенен", "мен", "бен", "пен",
+		"ша", "ше",
+	}
+	kkPossessive = []string{
+		"ымыз", "іміз", "ыңыз", "іңіз", "мыз", "міз", "ңыз", "ңіз",
+		"сы", "сі", "ым", "ім", "ың", "ің", "ы", "і",
+	}
+	kkPlural = []string{"лар", "лер", "дар", "дер", "тар", "тер"}
+)
+
+const kazakhMinStem = 3
+
+// stemKazakh strips inflectional suffixes from a lower-case Kazakh word.
+// It is a light stemmer: derivational suffixes are left alone.
+func stemKazakh(word string) string {
+	w := []rune(word)
+	for _, group := range [][]string{kkCase, kkPossessive, kkPlural} {
+		longest := 0
+		for _, suffix := range group {
+			n := len([]rune(suffix))
+			if n > longest && len(w)-n >= kazakhMinStem && string(w[len(w)-n:]) == suffix {
+				longest = n
+			}
+		}
+		w = w[:len(w)-longest]
+	}
+	return string(w)
+}
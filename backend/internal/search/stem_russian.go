@@ -0,0 +1,144 @@
+package search
+
+// Suffix groups of the Snowball Russian stemmer. Entries in the "afterA"
+// groups only apply when preceded by а or я.
+var (
+	ruPerfectiveGerundAfterA = []string{"вшись", "вши", "в"}
+	ruPerfectiveGerund       = []string{"ившись", "ывшись", "ивши", "ывши", "ив", "ыв"}
+	ruAdjective              = []string{
+		"ими", "ыми", "его", "ого", "ему", "ому", "ее", "ие", "ые", "ое", "ей", "ий",
+		"ый", "ой", "ем", "им", "ым", "ом", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею",
+	}
+	ruParticipleAfterA = []string{"ем", "нн", "вш", "ющ", "щ"}
+	ruParticiple       = []string{"ивш", "ывш", "ующ"}
+	ruReflexive        = []string{"ся", "сь"}
+	ruVerbAfterA       = []string{
+		"ете", "йте", "ешь", "нно", "ла", "на", "ли", "ем", "ло", "но", "ет", "ют",
+		"ны", "ть", "й", "л", "н",
+	}
+	ruVerb = []string{
+		"ейте", "уйте", "ила", "ыла", "ена", "ите", "или", "ыли", "ило", "ыло", "ено",
+		"ует", "уют", "ены", "ить", "ыть", "ишь", "ей", "уй", "ил", "ыл", "им", "ым",
+		"ен", "ят", "ит", "ыт", "ую", "ю",
+	}
+	ruNoun = []string{
+		"иями", "ями", "ами", "ией", "иям", "ием", "иях", "ев", "ов", "ие", "ье", "еи",
+		"ии", "ей", "ой", "ий", "ям", "ем", "ам", "ом", "ах", "ях", "ию", "ью", "ия",
+		"ья", "а", "е", "и", "й", "о", "у", "ы", "ь", "ю", "я",
+	}
+	ruSuperlative  = []string{"ейше", "ейш"}
+	ruDerivational = []string{"ость", "ост"}
+)
+
+func isRussianVowel(r rune) bool {
+	switch r {
+	case 'а', 'е', 'и', 'о', 'у', 'ы', 'э', 'ю', 'я':
+		return true
+	}
+	return false
+}
+
+// stemRussian implements the Snowball Russian stemmer for lower-case words
+// where ё has already been folded to е.
+func stemRussian(word string) string {
+	w := []rune(word)
+
+	rv := len(w)
+	for i, r := range w {
+		if isRussianVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	r2 := regionAfter(w, regionAfter(w, 0))
+	if rv >= len(w) {
+		return word
+	}
+
+	// Step 1
+	if n, ok := ruSuffix(w, rv, ruPerfectiveGerundAfterA, ruPerfectiveGerund); ok {
+		w = w[:len(w)-n]
+	} else {
+		if n, ok := ruSuffix(w, rv, nil, ruReflexive); ok {
+			w = w[:len(w)-n]
+		}
+
+		if n, ok := ruSuffix(w, rv, nil, ruAdjective); ok {
+			w = w[:len(w)-n]
+			if n, ok := ruSuffix(w, rv, ruParticipleAfterA, ruParticiple); ok {
+				w = w[:len(w)-n]
+			}
+		} else if n, ok := ruSuffix(w, rv, ruVerbAfterA, ruVerb); ok {
+			w = w[:len(w)-n]
+		} else if n, ok := ruSuffix(w, rv, nil, ruNoun); ok {
+			w = w[:len(w)-n]
+		}
+	}
+
+	// Step 2
+	if len(w) > rv && w[len(w)-1] == 'и' {
+		w = w[:len(w)-1]
+	}
+
+	// Step 3
+	if n, ok := ruSuffix(w, r2, nil, ruDerivational); ok {
+		w = w[:len(w)-n]
+	}
+
+	// Step 4
+	switch {
+	case hasRuneSuffix(w, rv, "нн"):
+		w = w[:len(w)-1]
+	case hasRuneSuffix(w, rv, "ь"):
+		w = w[:len(w)-1]
+	default:
+		if n, ok := ruSuffix(w, rv, nil, ruSuperlative); ok {
+			w = w[:len(w)-n]
+			if hasRuneSuffix(w, rv, "нн") {
+				w = w[:len(w)-1]
+			}
+		}
+	}
+
+	return string(w)
+}
+
+// regionAfter returns the index after the first non-vowel that follows a
+// vowel at or after start.
+func regionAfter(w []rune, start int) int {
+	for i := start + 1; i < len(w); i++ {
+		if !isRussianVowel(w[i]) && isRussianVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// ruSuffix finds the longest suffix inside the region starting at start,
+// returning its length in runes.
+func ruSuffix(w []rune, start int, afterA, plain []string) (int, bool) {
+	best := 0
+	for _, suffix := range afterA {
+		n := len([]rune(suffix))
+		if n > best && hasRuneSuffix(w, start, suffix) && len(w)-n-1 >= start {
+			if prev := w[len(w)-n-1]; prev == 'а' || prev == 'я' {
+				best = n
+			}
+		}
+	}
+	for _, suffix := range plain {
+		n := len([]rune(suffix))
+		if n > best && hasRuneSuffix(w, start, suffix) {
+			best = n
+		}
+	}
+	return best, best > 0
+}
+
+func hasRuneSuffix(w []rune, start int, suffix string) bool {
+	s := []rune(suffix)
+	if len(w)-len(s) < start || len(s) > len(w) {
+		return false
+	}
+	return string(w[len(w)-len(s):]) == suffix
+}
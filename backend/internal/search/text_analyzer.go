@@ -0,0 +1,131 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// skeletonPrefix marks phonetic keys so they never collide with real terms.
+const skeletonPrefix = "~"
+
+var stopWords = map[string]bool{
+	// English
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "for": true, "from": true, "has": true, "have": true,
+	"in": true, "is": true, "it": true, "of": true, "on": true, "or": true, "that": true,
+	"the": true, "this": true, "to": true, "was": true, "will": true, "with": true,
+	// Russian
+	"а": true, "в": true, "во": true, "да": true, "для": true, "же": true, "за": true,
+	"и": true, "из": true, "к": true, "как": true, "на": true, "не": true, "но": true,
+	"о": true, "об": true, "от": true, "по": true, "с": true, "со": true, "то": true,
+	"у": true, "что": true, "это": true,
+	// Kazakh
+	"және": true, "мен": true, "бен": true, "пен": true, "бұл": true, "ол": true,
+	"үшін": true, "де": true, "те": true, "ма": true, "ме": true,
+}
+
+// TextAnalyzer handles the mix of Kazakh (Cyrillic and Latin), Russian and
+// English found in posts. Each word yields its folded stem and a phonetic
+// skeleton shared by its transliterations.
+type TextAnalyzer struct{}
+
+func (a TextAnalyzer) Analyze(text string) []Token {
+	var tokens []Token
+	for i, word := range splitWords(text) {
+		stem, skel := analyzeWord(word)
+		if stem == "" {
+			continue
+		}
+		tokens = append(tokens, Token{Term: stem, Position: i})
+		// Kept even when it spells the stem: the Cyrillic form of the word
+		// only meets a Latin one here
+		if utf8.RuneCountInString(skel) >= 3 {
+			tokens = append(tokens, Token{Term: skeletonPrefix + skel, Position: i})
+		}
+	}
+	return tokens
+}
+
+// Normalize folds a word in its own script and in the other one, so a
+// prefix typed in Latin also finds Cyrillic terms and vice versa.
+func (a TextAnalyzer) Normalize(word string) []string {
+	w := strings.ToLower(word)
+
+	var forms []string
+	switch {
+	case isCyrillic(w):
+		w = foldKazakh(Fold(w))
+		forms = []string{w, cyrillicToLatin(w)}
+	case isKazakhLatin(w):
+		forms = []string{foldKazakh(kazakhLatinToCyrillic(w)), Fold(w)}
+	default:
+		w = Fold(w)
+		forms = []string{w, latinToCyrillic(w)}
+	}
+
+	if forms[0] == forms[1] {
+		return forms[:1]
+	}
+	return forms
+}
+
+func analyzeWord(word string) (string, string) {
+	w := strings.ToLower(word)
+	if stopWords[w] {
+		return "", ""
+	}
+	if isNumber(w) {
+		return w, ""
+	}
+
+	switch {
+	case isCyrillic(w):
+		w = Fold(w)
+		var stem string
+		if isKazakhCyrillic(w) {
+			stem = stemKazakh(w)
+		} else {
+			stem = stemRussian(w)
+		}
+		stem = foldKazakh(stem)
+		return stem, skeleton(cyrillicToLatin(stem))
+
+	case isKazakhLatin(w):
+		stem := foldKazakh(stemKazakh(kazakhLatinToCyrillic(w)))
+		return stem, skeleton(cyrillicToLatin(stem))
+
+	default:
+		w = Fold(w)
+		stem := w
+		if isASCII(w) {
+			stem = stemEnglish(w)
+		}
+		return stem, skeleton(trimPlural(w))
+	}
+}
+
+func trimPlural(w string) string {
+	if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+// sharesTerm reports whether the two words analyze to a common term.
+func sharesTerm(a, b string) bool {
+	analyzer := TextAnalyzer{}
+	terms := make(map[string]bool)
+	for _, token := range analyzer.Analyze(a) {
+		terms[token.Term] = true
+	}
+	for _, token := range analyzer.Analyze(b) {
+		if terms[token.Term] {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAnalyzerMatchesWordForms(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"running", "run"},
+		{"runs", "run"},
+		{"студентов", "студент"},
+		{"Студенты", "студент"},
+		{"Café", "cafe"},
+		{"ёлка", "елка"},
+		// Transliterations share a phonetic skeleton
+		{"Almaty", "Алматы"},
+		{"Qazaqstan", "Қазақстан"},
+		{"student", "студент"},
+	}
+	for _, tt := range tests {
+		if !sharesTerm(tt.a, tt.b) {
+			t.Errorf("%q and %q share no term", tt.a, tt.b)
+		}
+	}
+
+	for _, pair := range [][2]string{{"student", "library"}, {"алма", "almaty"}} {
+		if sharesTerm(pair[0], pair[1]) {
+			t.Errorf("%q and %q share a term", pair[0], pair[1])
+		}
+	}
+}
+
+func TestAnalyzerPositions(t *testing.T) {
+	tokens := TextAnalyzer{}.Analyze("The hackathon and the Хакатон")
+
+	// Stop words are dropped but keep their place
+	positions := make(map[int]bool)
+	for _, token := range tokens {
+		positions[token.Position] = true
+	}
+	if !reflect.DeepEqual(positions, map[int]bool{1: true, 4: true}) {
+		t.Errorf("positions = %v, want 1 and 4", positions)
+	}
+
+	if got := (TextAnalyzer{}).Analyze("and the и мен"); len(got) != 0 {
+		t.Errorf("stop words analyzed to %v", got)
+	}
+	if got := (TextAnalyzer{}).Analyze("2024"); !reflect.DeepEqual(got, []Token{{Term: "2024"}}) {
+		t.Errorf("number analyzed to %v", got)
+	}
+}
+
+func TestNormalizeCoversBothScripts(t *testing.T) {
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"alm", []string{"alm", "алм"}},
+		{"Алм", []string{"алм", "alm"}},
+		{"qaz", []string{"каз", "qaz"}},
+		{"123", []string{"123"}},
+	}
+	for _, tt := range tests {
+		if got := (TextAnalyzer{}).Normalize(tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"Café":      "cafe",
+		"ÜBER":      "uber",
+		"Ёжик":      "ежик",
+		"Қазақ":     "қазақ",
+		"golang":    "golang",
+		"São Paulo": "sao paulo",
+	}
+	for in, want := range tests {
+		if got := Fold(in); got != want {
+			t.Errorf("Fold(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package search
+
+import "strings"
+
+var latinFold = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ģ': "g",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ķ': "k", 'ľ': "l", 'ł': "l", 'ļ': "l",
+	'ñ': "n", 'ń': "n", 'ň': "n", 'ņ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'ř': "r", 'ş': "s", 'ś': "s", 'š': "s", 'ș': "s", 'ť': "t", 'ț': "t", 'ţ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ž': "z", 'ź': "z", 'ż': "z", 'ß': "ss",
+	'ё': "е",
+}
+
+// Kazakh-specific Cyrillic letters folded to their closest Russian letter,
+// so words typed on a Russian keyboard still match.
+var kazakhFold = map[rune]rune{
+	'ә': 'а', 'ғ': 'г', 'қ': 'к', 'ң': 'н', 'ө': 'о', 'ұ': 'у', 'ү': 'у', 'һ': 'х', 'і': 'и',
+}
+
+var cyrillicToLatinTable = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch",
+	'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Official Kazakh Latin alphabet (2021) to Cyrillic.
+var kazakhLatinTable = map[rune]rune{
+	'a': 'а', 'ä': 'ә', 'b': 'б', 'c': 'ц', 'd': 'д', 'e': 'е', 'f': 'ф', 'g': 'г',
+	'ğ': 'ғ', 'h': 'х', 'ı': 'ы', 'i': 'і', 'j': 'ж', 'k': 'к', 'l': 'л', 'm': 'м',
+	'n': 'н', 'ñ': 'ң', 'o': 'о', 'ö': 'ө', 'p': 'п', 'q': 'қ', 'r': 'р', 's': 'с',
+	'ş': 'ш', 't': 'т', 'u': 'у', 'ū': 'ұ', 'ü': 'ү', 'v': 'в', 'w': 'у', 'x': 'х',
+	'y': 'й', 'z': 'з',
+}
+
+// Romanized Russian to Cyrillic. Digraphs are listed first so they win over
+// single letters.
+var latinToCyrillicRules = []struct {
+	latin    string
+	cyrillic string
+}{
+	{"shch", "щ"}, {"sch", "щ"}, {"zh", "ж"}, {"kh", "х"}, {"ts", "ц"}, {"ch", "ч"},
+	{"sh", "ш"}, {"ya", "я"}, {"yu", "ю"}, {"yo", "е"}, {"ye", "е"},
+	{"a", "а"}, {"b", "б"}, {"c", "к"}, {"d", "д"}, {"e", "е"}, {"f", "ф"}, {"g", "г"},
+	{"h", "х"}, {"i", "и"}, {"j", "й"}, {"k", "к"}, {"l", "л"}, {"m", "м"}, {"n", "н"},
+	{"o", "о"}, {"p", "п"}, {"q", "к"}, {"r", "р"}, {"s", "с"}, {"t", "т"}, {"u", "у"},
+	{"v", "в"}, {"w", "в"}, {"x", "кс"}, {"y", "ы"}, {"z", "з"},
+}
+
+// Fold lower-cases s and strips Latin diacritics and the diaeresis of ё.
+// Kazakh letters are kept.
+func Fold(s string) string {
+	s = strings.ToLower(s)
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if folded, ok := latinFold[r]; ok {
+			b.WriteString(folded)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func foldKazakh(s string) string {
+	return strings.Map(func(r rune) rune {
+		if folded, ok := kazakhFold[r]; ok {
+			return folded
+		}
+		return r
+	}, s)
+}
+
+func cyrillicToLatin(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if latin, ok := cyrillicToLatinTable[r]; ok {
+			b.WriteString(latin)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func latinToCyrillic(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		matched := false
+		for _, rule := range latinToCyrillicRules {
+			if strings.HasPrefix(s, rule.latin) {
+				b.WriteString(rule.cyrillic)
+				s = s[len(rule.latin):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			r := []rune(s)[0]
+			b.WriteRune(r)
+			s = s[len(string(r)):]
+		}
+	}
+	return b.String()
+}
+
+func kazakhLatinToCyrillic(s string) string {
+	s = strings.NewReplacer("sh", "ш", "ch", "ч", "zh", "ж").Replace(s)
+	return strings.Map(func(r rune) rune {
+		if cyrillic, ok := kazakhLatinTable[r]; ok {
+			return cyrillic
+		}
+		return r
+	}, s)
+}
+
+func isCyrillic(s string) bool {
+	for _, r := range s {
+		if r >= 0x0400 && r <= 0x04FF {
+			return true
+		}
+	}
+	return false
+}
+
+func isKazakhCyrillic(s string) bool {
+	for _, r := range s {
+		if _, ok := kazakhFold[r]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isKazakhLatin reports whether s uses letters found only in the Kazakh
+// Latin alphabet. A q not followed by u counts, since English rarely has one.
+func isKazakhLatin(s string) bool {
+	runes := []rune(s)
+	for i, r := range runes {
+		switch r {
+		case 'ä', 'ğ', 'ı', 'ñ', 'ö', 'ş', 'ū', 'ü':
+			return true
+		case 'q':
+			if i+1 >= len(runes) || runes[i+1] != 'u' {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// skeleton reduces a romanized word to a rough phonetic key so that
+// transliterations and loanwords meet: "сессия", "sessiya" and "session"
+// all become "ses". Trailing vowels are dropped because that is where
+// Russian and Kazakh inflect.
+func skeleton(latin string) string {
+	w := latin
+	if strings.HasSuffix(w, "tion") || strings.HasSuffix(w, "sion") {
+		w = w[:len(w)-4] + "s"
+	}
+	for len(w) > 2 && strings.ContainsRune("aeiouy", rune(w[len(w)-1])) {
+		w = w[:len(w)-1]
+	}
+
+	r := []rune(w)
+	var b strings.Builder
+	for i := 0; i < len(r); i++ {
+		var next rune
+		if i+1 < len(r) {
+			next = r[i+1]
+		}
+
+		switch {
+		case r[i] == 'z' && next == 'h':
+			b.WriteRune('j')
+			i++
+		case r[i] == 'k' && next == 'h':
+			b.WriteRune('h')
+			i++
+		case r[i] == 't' && next == 's':
+			b.WriteRune('s')
+			i++
+		case r[i] == 'p' && next == 'h':
+			b.WriteRune('f')
+			i++
+		case r[i] == 'c' && next == 'h':
+			b.WriteString("ch")
+			i++
+		case r[i] == 'c' && next == 'k':
+			b.WriteRune('k')
+			i++
+		case r[i] == 'c' && (next == 'e' || next == 'i' || next == 'y'):
+			b.WriteRune('s')
+		case r[i] == 'c' || r[i] == 'q':
+			b.WriteRune('k')
+		case r[i] == 'w':
+			b.WriteRune('v')
+		case r[i] == 'x':
+			b.WriteString("ks")
+		case r[i] == 'y':
+			b.WriteRune('i')
+		default:
+			b.WriteRune(r[i])
+		}
+	}
+
+	// Collapse doubled letters
+	var out []rune
+	for _, c := range b.String() {
+		if n := len(out); n > 0 && out[n-1] == c {
+			continue
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}
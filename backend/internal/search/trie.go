@@ -0,0 +1,266 @@
+package search
+
+import (
+	"container/heap"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxTrieKeyRunes bounds the depth of the trie; longer text is only
+	// reachable through its first runes.
+	maxTrieKeyRunes = 48
+	// maxTrieWordStarts is how many words of a text, counted from the
+	// start, begin a key of their own.
+	maxTrieWordStarts = 6
+)
+
+// Suggestion is a completion for a prefix. Weight orders suggestions of
+// the same prefix; higher comes first.
+type Suggestion struct {
+	ID     string
+	Kind   string
+	Text   string
+	Weight float64
+}
+
+type trieEntry struct {
+	Suggestion
+	keys []string
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	entries  map[*trieEntry]struct{}
+	// best is the highest weight in the subtree; Suggest visits subtrees
+	// in that order and stops once it has enough.
+	best float64
+}
+
+func (n *trieNode) updateBest() {
+	best := 0.0
+	for entry := range n.entries {
+		if entry.Weight > best {
+			best = entry.Weight
+		}
+	}
+	for _, child := range n.children {
+		if child.best > best {
+			best = child.best
+		}
+	}
+	n.best = best
+}
+
+// Trie is a concurrency-safe prefix tree of completions. Keys are folded
+// and transliterated to Latin, so Cyrillic and Latin prefixes find each
+// other, and every leading word of a text also starts a key, so a prefix
+// matches from any of the first few words.
+type Trie struct {
+	mu      sync.RWMutex
+	root    *trieNode
+	entries map[string]*trieEntry
+}
+
+func NewTrie() *Trie {
+	return &Trie{
+		root:    &trieNode{},
+		entries: make(map[string]*trieEntry),
+	}
+}
+
+// Set adds a completion or replaces the one with the same kind and ID.
+func (t *Trie) Set(kind, id, text string, weight float64) {
+	entry := &trieEntry{
+		Suggestion: Suggestion{ID: id, Kind: kind, Text: text, Weight: weight},
+		keys:       trieKeys(text),
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeLocked(docKey(kind, id))
+	if len(entry.keys) == 0 {
+		return
+	}
+	t.entries[docKey(kind, id)] = entry
+	for _, key := range entry.keys {
+		node := t.root
+		if weight > node.best {
+			node.best = weight
+		}
+		for _, r := range key {
+			if node.children == nil {
+				node.children = make(map[rune]*trieNode)
+			}
+			child, ok := node.children[r]
+			if !ok {
+				child = &trieNode{}
+				node.children[r] = child
+			}
+			node = child
+			if weight > node.best {
+				node.best = weight
+			}
+		}
+		if node.entries == nil {
+			node.entries = make(map[*trieEntry]struct{})
+		}
+		node.entries[entry] = struct{}{}
+	}
+}
+
+func (t *Trie) Remove(kind, id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removeLocked(docKey(kind, id))
+}
+
+func (t *Trie) removeLocked(key string) {
+	entry, ok := t.entries[key]
+	if !ok {
+		return
+	}
+	delete(t.entries, key)
+
+	for _, k := range entry.keys {
+		runes := []rune(k)
+		path := make([]*trieNode, 0, len(runes)+1)
+		node := t.root
+		path = append(path, node)
+		for _, r := range runes {
+			node = node.children[r]
+			if node == nil {
+				break
+			}
+			path = append(path, node)
+		}
+		if node == nil {
+			continue
+		}
+		delete(node.entries, entry)
+
+		// Prune nodes left without entries or children and lower the
+		// subtree maxima on the way up
+		for i := len(path) - 1; i >= 0; i-- {
+			if i > 0 && len(path[i].entries) == 0 && len(path[i].children) == 0 {
+				delete(path[i-1].children, runes[i-1])
+				continue
+			}
+			path[i].updateBest()
+		}
+	}
+}
+
+func (t *Trie) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.entries)
+}
+
+// Suggest returns up to limit completions of prefix, heaviest first.
+// Empty kinds match every kind.
+func (t *Trie) Suggest(prefix string, kinds []string, limit int) []Suggestion {
+	key := trieKey(prefix)
+	if key == "" || limit <= 0 {
+		return nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	node := t.root
+	for _, r := range key {
+		node = node.children[r]
+		if node == nil {
+			return nil
+		}
+	}
+
+	var suggestions []Suggestion
+	seen := make(map[*trieEntry]bool)
+	queue := &trieQueue{{node: node, weight: node.best}}
+	for queue.Len() > 0 && len(suggestions) < limit {
+		item := heap.Pop(queue).(trieItem)
+		if item.entry != nil {
+			if !seen[item.entry] {
+				seen[item.entry] = true
+				suggestions = append(suggestions, item.entry.Suggestion)
+			}
+			continue
+		}
+		for entry := range item.node.entries {
+			if !seen[entry] && (len(kinds) == 0 || contains(kinds, entry.Kind)) {
+				heap.Push(queue, trieItem{entry: entry, weight: entry.Weight})
+			}
+		}
+		for _, child := range item.node.children {
+			heap.Push(queue, trieItem{node: child, weight: child.best})
+		}
+	}
+	return suggestions
+}
+
+// trieItem is a subtree, bounded by its best weight, or a single entry.
+type trieItem struct {
+	node   *trieNode
+	entry  *trieEntry
+	weight float64
+}
+
+// trieQueue is a max-heap of trie items. Entries win ties against
+// subtrees so equal weights come out without expanding further.
+type trieQueue []trieItem
+
+func (q trieQueue) Len() int { return len(q) }
+
+func (q trieQueue) Less(i, j int) bool {
+	if q[i].weight != q[j].weight {
+		return q[i].weight > q[j].weight
+	}
+	if (q[i].entry != nil) != (q[j].entry != nil) {
+		return q[i].entry != nil
+	}
+	if q[i].entry != nil {
+		return q[i].entry.Text < q[j].entry.Text
+	}
+	return false
+}
+
+func (q trieQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *trieQueue) Push(x interface{}) { *q = append(*q, x.(trieItem)) }
+
+func (q *trieQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// trieKey folds s to its Latin form with single spaces between words.
+func trieKey(s string) string {
+	folded := Fold(s)
+	if isCyrillic(folded) {
+		folded = cyrillicToLatin(foldKazakh(folded))
+	}
+	key := strings.Join(strings.Fields(folded), " ")
+	if runes := []rune(key); len(runes) > maxTrieKeyRunes {
+		key = string(runes[:maxTrieKeyRunes])
+	}
+	return key
+}
+
+func trieKeys(text string) []string {
+	words := strings.Fields(text)
+	keys := make([]string, 0, maxTrieWordStarts)
+	seen := make(map[string]bool)
+	for i := 0; i < len(words) && i < maxTrieWordStarts; i++ {
+		key := trieKey(strings.Join(words[i:], " "))
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
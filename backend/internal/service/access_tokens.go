@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+const (
+	maxAccessTokensPerUser = 20
+	maxAccessTokenNameLen  = 100
+	// maxAccessTokenLifetime bounds the optional expiry.
+	maxAccessTokenLifetime = 365 * 24 * time.Hour
+)
+
+var (
+	ErrAccessTokenNotFound     = errors.New("access token not found")
+	ErrAccessTokenNameRequired = errors.New("access token name is required")
+	ErrInvalidAccessTokenScope = errors.New("unknown access token scope")
+	ErrNoAccessTokenScopes     = errors.New("access token needs at least one scope")
+	ErrInvalidAccessTokenTTL   = errors.New("access token expiry must be between 1 and 365 days")
+	ErrTooManyAccessTokens     = errors.New("access token limit reached; revoke one first")
+)
+
+type AccessTokenInput struct {
+	Name   string
+	Scopes []string
+	// ExpiresIn is zero for a token that does not expire.
+	ExpiresIn time.Duration
+}
+
+// CreateAccessToken returns the stored token and its value. Only the hash
+// is kept, so the value cannot be shown again.
+func (s *AuthService) CreateAccessToken(userID primitive.ObjectID, input AccessTokenInput) (*models.AccessToken, string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, "", ErrAccessTokenNameRequired
+	}
+	if runes := []rune(name); len(runes) > maxAccessTokenNameLen {
+		name = string(runes[:maxAccessTokenNameLen])
+	}
+
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresIn != 0 {
+		if input.ExpiresIn < 24*time.Hour || input.ExpiresIn > maxAccessTokenLifetime {
+			return nil, "", ErrInvalidAccessTokenTTL
+		}
+		at := time.Now().Add(input.ExpiresIn)
+		expiresAt = &at
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, "", ErrUserNotFound
+	}
+
+	count, err := s.accessTokenRepo.CountByUser(user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= maxAccessTokensPerUser {
+		return nil, "", ErrTooManyAccessTokens
+	}
+
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	value := models.AccessTokenPrefix + secret
+
+	token := models.NewAccessToken(user.ID, name, hashToken(value), value[:len(models.AccessTokenPrefix)+6], scopes, expiresAt)
+	if err := s.accessTokenRepo.Create(token); err != nil {
+		return nil, "", err
+	}
+	return token, value, nil
+}
+
+func (s *AuthService) ListAccessTokens(userID primitive.ObjectID) ([]*models.AccessToken, error) {
+	return s.accessTokenRepo.FindByUser(userID)
+}
+
+func (s *AuthService) RevokeAccessToken(userID, tokenID primitive.ObjectID) error {
+	deleted, err := s.accessTokenRepo.Delete(userID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// normalizeScopes checks the scopes and drops duplicates, keeping them in
+// the canonical order.
+func normalizeScopes(scopes []string) ([]string, error) {
+	requested := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !models.IsAccessTokenScope(scope) {
+			return nil, ErrInvalidAccessTokenScope
+		}
+		requested[scope] = true
+	}
+
+	normalized := make([]string, 0, len(requested))
+	for _, scope := range models.AccessTokenScopes {
+		if requested[scope] {
+			normalized = append(normalized, scope)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, ErrNoAccessTokenScopes
+	}
+	return normalized, nil
+}
@@ -0,0 +1,441 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+var (
+	ErrDeletionScheduled    = errors.New("account deletion is already scheduled")
+	ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+	ErrInvalidDeletionMode  = errors.New("content must be either delete or anonymize")
+	ErrCannotDeleteSelf     = errors.New("cannot delete your own account; use account deletion instead")
+)
+
+const (
+	deletionPageSize      = 100
+	deletionSweepInterval = time.Hour
+)
+
+// AccountDeletionService deletes accounts together with everything that
+// points at them. Users schedule their own deletion and can cancel it
+// during a grace period; a background sweep carries out the ones due.
+type AccountDeletionService struct {
+	userRepo            repository.UserRepository
+	postRepo            repository.PostRepository
+	commentRepo         repository.CommentRepository
+	likeRepo            repository.LikeRepository
+	followRepo          repository.FollowRepository
+	bookmarkRepo        repository.BookmarkRepository
+	collectionRepo      repository.BookmarkCollectionRepository
+	attendeeRepo        repository.EventAttendeeRepository
+	sessionRepo         repository.SessionRepository
+	refreshTokenRepo    repository.RefreshTokenRepository
+	accessTokenRepo     repository.AccessTokenRepository
+	userService         *UserService
+	postService         *PostService
+	commentService      *CommentService
+	fileService         *FileService
+	notificationService *NotificationService
+	gracePeriod         time.Duration
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func NewAccountDeletionService(
+	userRepo repository.UserRepository,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	likeRepo repository.LikeRepository,
+	followRepo repository.FollowRepository,
+	bookmarkRepo repository.BookmarkRepository,
+	collectionRepo repository.BookmarkCollectionRepository,
+	attendeeRepo repository.EventAttendeeRepository,
+	sessionRepo repository.SessionRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	accessTokenRepo repository.AccessTokenRepository,
+	userService *UserService,
+	postService *PostService,
+	commentService *CommentService,
+	fileService *FileService,
+	notificationService *NotificationService,
+	cfg *config.Config,
+) *AccountDeletionService {
+	s := &AccountDeletionService{
+		userRepo:            userRepo,
+		postRepo:            postRepo,
+		commentRepo:         commentRepo,
+		likeRepo:            likeRepo,
+		followRepo:          followRepo,
+		bookmarkRepo:        bookmarkRepo,
+		collectionRepo:      collectionRepo,
+		attendeeRepo:        attendeeRepo,
+		sessionRepo:         sessionRepo,
+		refreshTokenRepo:    refreshTokenRepo,
+		accessTokenRepo:     accessTokenRepo,
+		userService:         userService,
+		postService:         postService,
+		commentService:      commentService,
+		fileService:         fileService,
+		notificationService: notificationService,
+		gracePeriod:         cfg.Auth.DeletionGracePeriod,
+		stopCh:              make(chan struct{}),
+		doneCh:              make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Schedule deletes the account once the grace period ends, unless the
+// user cancels first. The password confirms it is really them.
+func (s *AccountDeletionService) Schedule(userID primitive.ObjectID, password string, mode models.DeletionMode) (*models.AccountDeletion, error) {
+	if !models.IsValidDeletionMode(mode) {
+		return nil, ErrInvalidDeletionMode
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.ValidatePassword(password) {
+		return nil, ErrInvalidCredentials
+	}
+	if user.Deletion != nil {
+		return nil, ErrDeletionScheduled
+	}
+
+	now := time.Now()
+	user.Deletion = &models.AccountDeletion{
+		Mode:         mode,
+		RequestedAt:  now,
+		ScheduledFor: now.Add(s.grace()),
+	}
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	notification := models.NewNotification(user.ID, models.NotificationAccountDeletion,
+		"Your account is scheduled for deletion",
+		fmt.Sprintf("Your account will be deleted on %s. Until then you can cancel from your profile page.",
+			user.Deletion.ScheduledFor.Format("January 2, 2006 at 15:04 MST")),
+		"/profile.html")
+	if err := s.notificationService.Notify(notification); err != nil {
+		log.Printf("Failed to notify %s about account deletion: %v", user.ID.Hex(), err)
+	}
+
+	return user.Deletion, nil
+}
+
+// Status returns the scheduled deletion, or nil when there is none.
+func (s *AccountDeletionService) Status(userID primitive.ObjectID) (*models.AccountDeletion, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user.Deletion, nil
+}
+
+// SetMode changes what happens to the user's content when the scheduled
+// deletion is carried out.
+func (s *AccountDeletionService) SetMode(userID primitive.ObjectID, mode models.DeletionMode) (*models.AccountDeletion, error) {
+	if !models.IsValidDeletionMode(mode) {
+		return nil, ErrInvalidDeletionMode
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Deletion == nil {
+		return nil, ErrDeletionNotScheduled
+	}
+
+	user.Deletion.Mode = mode
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return user.Deletion, nil
+}
+
+func (s *AccountDeletionService) Cancel(userID primitive.ObjectID) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.Deletion == nil {
+		return ErrDeletionNotScheduled
+	}
+
+	user.Deletion = nil
+	return s.userRepo.Update(user)
+}
+
+// DeleteUser lets an admin delete another account right away. twoFactor
+// reports whether the admin's session passed a TOTP check.
+func (s *AccountDeletionService) DeleteUser(adminID, targetUserID primitive.ObjectID, twoFactor bool, mode models.DeletionMode) error {
+	if !models.IsValidDeletionMode(mode) {
+		return ErrInvalidDeletionMode
+	}
+	if err := s.userService.AuthorizeSession(adminID, policy.UsersDelete, &policy.Resource{Type: "user", ID: targetUserID}, twoFactor); err != nil {
+		return err
+	}
+	if adminID == targetUserID {
+		return ErrCannotDeleteSelf
+	}
+
+	user, err := s.userRepo.FindByID(targetUserID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	return s.purge(user, mode)
+}
+
+// Stop ends the sweep after the deletion in progress, if any, is done.
+func (s *AccountDeletionService) Stop() {
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+func (s *AccountDeletionService) run() {
+	defer close(s.doneCh)
+
+	s.sweep()
+
+	ticker := time.NewTicker(deletionSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sweep()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// sweep carries out the deletions that are due. One that fails stays
+// scheduled and is retried on the next sweep.
+func (s *AccountDeletionService) sweep() {
+	for {
+		users, err := s.userRepo.FindDueDeletions(time.Now(), deletionPageSize)
+		if err != nil {
+			log.Printf("Failed to find due account deletions: %v", err)
+			return
+		}
+
+		for _, user := range users {
+			if err := s.purge(user, user.Deletion.Mode); err != nil {
+				log.Printf("Failed to delete account %s: %v", user.ID.Hex(), err)
+				return
+			}
+		}
+
+		if len(users) < deletionPageSize {
+			return
+		}
+	}
+}
+
+// purge removes the user and their likes, follows, bookmarks, uploads,
+// sessions and tokens. Their posts and comments are deleted or credited to a deleted
+// user depending on mode. Every step can be repeated, so a purge that
+// fails part way is finished by running it again.
+func (s *AccountDeletionService) purge(user *models.User, mode models.DeletionMode) error {
+	// Deactivating signs the user out everywhere and hides the profile
+	// while the rest is removed
+	if user.IsActive {
+		user.IsActive = false
+		if err := s.userRepo.Update(user); err != nil {
+			return err
+		}
+		s.userService.notifyUpdated(user)
+	}
+
+	if err := s.purgePosts(user.ID, mode); err != nil {
+		return fmt.Errorf("posts: %w", err)
+	}
+	if err := s.purgeComments(user.ID, mode); err != nil {
+		return fmt.Errorf("comments: %w", err)
+	}
+	if err := s.purgeLikes(user.ID); err != nil {
+		return fmt.Errorf("likes: %w", err)
+	}
+	if err := s.purgeFollows(user.ID); err != nil {
+		return fmt.Errorf("follows: %w", err)
+	}
+	if err := s.bookmarkRepo.DeleteByUser(user.ID); err != nil {
+		return fmt.Errorf("bookmarks: %w", err)
+	}
+	if err := s.collectionRepo.DeleteByUser(user.ID); err != nil {
+		return fmt.Errorf("bookmark collections: %w", err)
+	}
+	if err := s.attendeeRepo.DeleteByUser(user.ID); err != nil {
+		return fmt.Errorf("event attendance: %w", err)
+	}
+	if err := s.sessionRepo.DeleteByUser(user.ID); err != nil {
+		return fmt.Errorf("sessions: %w", err)
+	}
+	if err := s.refreshTokenRepo.DeleteByUser(user.ID); err != nil {
+		return fmt.Errorf("refresh tokens: %w", err)
+	}
+	if err := s.accessTokenRepo.DeleteByUser(user.ID); err != nil {
+		return fmt.Errorf("access tokens: %w", err)
+	}
+
+	if err := s.userRepo.Delete(user.ID); err != nil {
+		return err
+	}
+	if user.Avatar != "" {
+		s.fileService.DeleteAvatar(user.Avatar)
+	}
+
+	s.userService.notifyDeleted(user.ID)
+	return nil
+}
+
+// purgePosts always reads the first page: each post it handles no longer
+// belongs to the user, so the next page moves up.
+func (s *AccountDeletionService) purgePosts(userID primitive.ObjectID, mode models.DeletionMode) error {
+	query := repository.ActivityQuery{UserID: userID, Limit: deletionPageSize}
+	for {
+		posts, err := s.postRepo.FindAllByAuthor(query)
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			return nil
+		}
+
+		for _, post := range posts {
+			if mode == models.DeletionModeAnonymize {
+				if err := s.postService.anonymizePost(post); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := s.postService.removePost(post.ID); err != nil {
+				return err
+			}
+			for _, media := range post.Media {
+				if _, ok := s.fileService.LocalPath(media.URL); !ok {
+					continue
+				}
+				if err := s.fileService.DeleteFile(media.URL); err != nil {
+					log.Printf("Failed to delete media of post %s: %v", post.ID.Hex(), err)
+				}
+			}
+		}
+	}
+}
+
+func (s *AccountDeletionService) purgeComments(userID primitive.ObjectID, mode models.DeletionMode) error {
+	query := repository.ActivityQuery{UserID: userID, Limit: deletionPageSize}
+	for {
+		comments, err := s.commentRepo.FindByAuthor(query)
+		if err != nil {
+			return err
+		}
+		if len(comments) == 0 {
+			return nil
+		}
+
+		for _, comment := range comments {
+			if mode == models.DeletionModeAnonymize {
+				err = s.commentService.anonymizeComment(comment)
+			} else {
+				err = s.commentService.removeComment(comment)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *AccountDeletionService) purgeLikes(userID primitive.ObjectID) error {
+	query := repository.ActivityQuery{UserID: userID, Limit: deletionPageSize}
+	for {
+		likes, err := s.likeRepo.FindByUser(query)
+		if err != nil {
+			return err
+		}
+		if len(likes) == 0 {
+			return nil
+		}
+
+		for _, like := range likes {
+			deleted, err := s.likeRepo.Delete(userID, like.PostID)
+			if err != nil {
+				return err
+			}
+			if deleted {
+				if err := s.postRepo.DecrementLikeCount(like.PostID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// purgeFollows removes the user's follows in both directions and keeps
+// the other users' counts in step.
+func (s *AccountDeletionService) purgeFollows(userID primitive.ObjectID) error {
+	for {
+		following, err := s.followRepo.FindFollowing(userID, deletionPageSize, 0)
+		if err != nil {
+			return err
+		}
+		if len(following) == 0 {
+			break
+		}
+		for _, follow := range following {
+			deleted, err := s.followRepo.Delete(userID, follow.FollowingID)
+			if err != nil {
+				return err
+			}
+			if deleted {
+				incrementFollowCounts(s.userRepo, follow.FollowingID, -1, 0)
+			}
+		}
+	}
+
+	for {
+		followers, err := s.followRepo.FindFollowers(userID, deletionPageSize, 0)
+		if err != nil {
+			return err
+		}
+		if len(followers) == 0 {
+			return nil
+		}
+		for _, follow := range followers {
+			deleted, err := s.followRepo.Delete(follow.FollowerID, userID)
+			if err != nil {
+				return err
+			}
+			if deleted {
+				incrementFollowCounts(s.userRepo, follow.FollowerID, 0, -1)
+			}
+		}
+	}
+}
+
+func (s *AccountDeletionService) grace() time.Duration {
+	if s.gracePeriod <= 0 {
+		return 14 * 24 * time.Hour
+	}
+	return s.gracePeriod
+}
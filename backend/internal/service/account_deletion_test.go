@@ -0,0 +1,246 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
+)
+
+// deletionFixture is an AccountDeletionService over in-memory
+// repositories. Seed them before start, which sweeps due deletions.
+type deletionFixture struct {
+	service       *AccountDeletionService
+	users         *memUsers
+	posts         *memPosts
+	comments      *memComments
+	likes         *memLikes
+	follows       *memFollows
+	bookmarks     *memBookmarks
+	collections   *memBookmarkCollections
+	attendees     *memEventAttendees
+	sessions      *memSessions
+	refreshTokens *memRefreshTokens
+	accessTokens  *memAccessTokens
+}
+
+func newDeletionFixture(users ...*models.User) *deletionFixture {
+	return &deletionFixture{
+		users:         newMemUsers(users...),
+		posts:         &memPosts{},
+		comments:      &memComments{},
+		likes:         &memLikes{},
+		follows:       &memFollows{},
+		bookmarks:     &memBookmarks{},
+		collections:   &memBookmarkCollections{},
+		attendees:     &memEventAttendees{},
+		sessions:      &memSessions{},
+		refreshTokens: &memRefreshTokens{},
+		accessTokens:  &memAccessTokens{},
+	}
+}
+
+// start creates the service and waits for the sweep it runs first, then
+// stops it so no later sweep races the test.
+func (f *deletionFixture) start() {
+	engine := policy.NewEngine(nil, 0)
+	userService := NewUserService(f.users, engine)
+	postService := NewPostService(f.posts, f.users, f.comments, f.follows, f.bookmarks, f.likes, nil, engine)
+	commentService := NewCommentService(f.comments, f.users, f.posts, engine)
+	f.service = NewAccountDeletionService(f.users, f.posts, f.comments, f.likes, f.follows, f.bookmarks, f.collections,
+		f.attendees, f.sessions, f.refreshTokens, f.accessTokens, userService, postService, commentService, nil, nil, testConfig())
+	f.service.Stop()
+}
+
+// seedAccount gives user a post, a comment and a like on other's post, a
+// follow each way with other, a bookmark, a collection, an event signup,
+// a session and both kinds of token.
+func (f *deletionFixture) seedAccount(user, other *models.User, otherPost *models.Post) *models.Post {
+	now := time.Now()
+	post := models.NewPost("Graduation photos", "From the ceremony", "", models.PostCategory("news"), user.ID, user.DisplayName)
+	f.posts.posts = append(f.posts.posts, post)
+
+	comment := models.NewComment(otherPost.ID, user.ID, user.DisplayName, "Congratulations!")
+	f.comments.comments = append(f.comments.comments, comment)
+	otherPost.CommentCount++
+
+	f.likes.likes = append(f.likes.likes, models.NewLike(user.ID, otherPost.ID))
+	otherPost.LikeCount++
+
+	f.follows.follows = append(f.follows.follows, models.NewFollow(user.ID, other.ID), models.NewFollow(other.ID, user.ID))
+	user.FollowerCount, user.FollowingCount = user.FollowerCount+1, user.FollowingCount+1
+	other.FollowerCount, other.FollowingCount = other.FollowerCount+1, other.FollowingCount+1
+
+	f.bookmarks.bookmarks = append(f.bookmarks.bookmarks, models.NewBookmark(user.ID, otherPost.ID, nil))
+	f.collections.collections = append(f.collections.collections, &models.BookmarkCollection{ID: primitive.NewObjectID(), UserID: user.ID, Name: "Later"})
+	f.attendees.attendees = append(f.attendees.attendees, models.NewEventAttendee(primitive.NewObjectID(), user.ID))
+
+	f.sessions.sessions = append(f.sessions.sessions, &models.Session{ID: primitive.NewObjectID(), UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	f.refreshTokens.tokens = append(f.refreshTokens.tokens, &models.RefreshToken{ID: primitive.NewObjectID(), UserID: user.ID, FamilyID: primitive.NewObjectID(), CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	f.accessTokens.tokens = append(f.accessTokens.tokens, &models.AccessToken{ID: primitive.NewObjectID(), UserID: user.ID, Name: "ci", CreatedAt: now})
+	return post
+}
+
+func TestDueDeletionRemovesEverything(t *testing.T) {
+	victim := newTestUser("leaving@astanait.edu.kz", "password123", models.RoleStudent)
+	victim.Deletion = &models.AccountDeletion{Mode: models.DeletionModeDelete, ScheduledFor: time.Now().Add(-time.Minute)}
+	other := newTestUser("staying@astanait.edu.kz", "password123", models.RoleStudent)
+
+	f := newDeletionFixture(victim, other)
+	otherPost := models.NewPost("Club fair", "Booths in the atrium", "", models.PostCategory("news"), other.ID, other.DisplayName)
+	f.posts.posts = append(f.posts.posts, otherPost)
+	victimPost := f.seedAccount(victim, other, otherPost)
+	// Someone else's comment and like on the victim's post go with it
+	f.comments.comments = append(f.comments.comments, models.NewComment(victimPost.ID, other.ID, other.DisplayName, "Nice"))
+	f.likes.likes = append(f.likes.likes, models.NewLike(other.ID, victimPost.ID))
+	f.sessions.sessions = append(f.sessions.sessions, &models.Session{ID: primitive.NewObjectID(), UserID: other.ID})
+	f.refreshTokens.tokens = append(f.refreshTokens.tokens, &models.RefreshToken{ID: primitive.NewObjectID(), UserID: other.ID})
+	f.accessTokens.tokens = append(f.accessTokens.tokens, &models.AccessToken{ID: primitive.NewObjectID(), UserID: other.ID})
+
+	f.start()
+
+	if _, err := f.users.FindByID(victim.ID); err == nil {
+		t.Error("user still exists")
+	}
+	if _, err := f.posts.FindByID(victimPost.ID); err == nil {
+		t.Error("post still exists")
+	}
+	if len(f.comments.comments) != 0 {
+		t.Errorf("%d comments left, want 0", len(f.comments.comments))
+	}
+	if len(f.likes.likes) != 0 {
+		t.Errorf("%d likes left, want 0", len(f.likes.likes))
+	}
+	if otherPost.LikeCount != 0 || otherPost.CommentCount != 0 {
+		t.Errorf("other post counts = %d likes, %d comments; want 0, 0", otherPost.LikeCount, otherPost.CommentCount)
+	}
+	if len(f.follows.follows) != 0 {
+		t.Errorf("%d follows left, want 0", len(f.follows.follows))
+	}
+	if other.FollowerCount != 0 || other.FollowingCount != 0 {
+		t.Errorf("other follow counts = %d/%d, want 0/0", other.FollowerCount, other.FollowingCount)
+	}
+	if len(f.bookmarks.bookmarks) != 0 || len(f.collections.collections) != 0 || len(f.attendees.attendees) != 0 {
+		t.Error("bookmarks, collections or event signups left")
+	}
+
+	// Only the other user's sessions and tokens survive
+	for _, session := range f.sessions.sessions {
+		if session.UserID == victim.ID {
+			t.Error("session left")
+		}
+	}
+	for _, token := range f.refreshTokens.tokens {
+		if token.UserID == victim.ID {
+			t.Error("refresh token left")
+		}
+	}
+	for _, token := range f.accessTokens.tokens {
+		if token.UserID == victim.ID {
+			t.Error("access token left")
+		}
+	}
+	if len(f.sessions.sessions) != 1 || len(f.refreshTokens.tokens) != 1 || len(f.accessTokens.tokens) != 1 {
+		t.Error("another user's session or token was deleted")
+	}
+
+	// A purge that is run again finds nothing left to do
+	if err := f.service.purge(victim, models.DeletionModeDelete); err != nil {
+		t.Errorf("second purge: %v", err)
+	}
+}
+
+func TestDeletionWaitsForSchedule(t *testing.T) {
+	user := newTestUser("leaving@astanait.edu.kz", "password123", models.RoleStudent)
+	user.Deletion = &models.AccountDeletion{Mode: models.DeletionModeDelete, ScheduledFor: time.Now().Add(time.Hour)}
+
+	f := newDeletionFixture(user)
+	f.start()
+
+	if _, err := f.users.FindByID(user.ID); err != nil {
+		t.Error("user deleted before the grace period ended")
+	}
+}
+
+func TestAdminDeleteAnonymizesContent(t *testing.T) {
+	admin := newTestUser("admin@astanait.edu.kz", "password123", models.RoleAdmin)
+	admin.TwoFactor = &models.TwoFactor{Enabled: true}
+	victim := newTestUser("leaving@astanait.edu.kz", "password123", models.RoleStudent)
+	other := newTestUser("staying@astanait.edu.kz", "password123", models.RoleStudent)
+
+	f := newDeletionFixture(admin, victim, other)
+	otherPost := models.NewPost("Club fair", "Booths in the atrium", "", models.PostCategory("news"), other.ID, other.DisplayName)
+	f.posts.posts = append(f.posts.posts, otherPost)
+	victimPost := f.seedAccount(victim, other, otherPost)
+	f.start()
+
+	err := f.service.DeleteUser(admin.ID, victim.ID, false, models.DeletionModeAnonymize)
+	if !errors.Is(err, policy.ErrTwoFactorRequired) {
+		t.Fatalf("without 2FA: err = %v, want ErrTwoFactorRequired", err)
+	}
+	if _, err := f.users.FindByID(victim.ID); err != nil {
+		t.Fatal("user deleted without 2FA")
+	}
+
+	if err := f.service.DeleteUser(admin.ID, victim.ID, true, models.DeletionModeAnonymize); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := f.users.FindByID(victim.ID); err == nil {
+		t.Error("user still exists")
+	}
+
+	post, err := f.posts.FindByID(victimPost.ID)
+	if err != nil {
+		t.Fatal("anonymized post was deleted")
+	}
+	if post.AuthorID != primitive.NilObjectID || post.AuthorName != models.DeletedUserName {
+		t.Errorf("post author = %s %q, want a deleted user", post.AuthorID.Hex(), post.AuthorName)
+	}
+	if len(f.comments.comments) != 1 {
+		t.Fatalf("%d comments, want the anonymized one", len(f.comments.comments))
+	}
+	if comment := f.comments.comments[0]; comment.AuthorID != primitive.NilObjectID || comment.AuthorName != models.DeletedUserName {
+		t.Errorf("comment author = %s %q, want a deleted user", comment.AuthorID.Hex(), comment.AuthorName)
+	}
+
+	// Likes, follows and sign-ins are never kept
+	if len(f.likes.likes) != 0 || len(f.follows.follows) != 0 {
+		t.Error("likes or follows left")
+	}
+	if len(f.sessions.sessions) != 0 || len(f.refreshTokens.tokens) != 0 || len(f.accessTokens.tokens) != 0 {
+		t.Error("sessions or tokens left")
+	}
+
+	if err := f.service.DeleteUser(admin.ID, admin.ID, true, models.DeletionModeDelete); !errors.Is(err, ErrCannotDeleteSelf) {
+		t.Errorf("deleting self: err = %v, want ErrCannotDeleteSelf", err)
+	}
+}
+
+// failingLikeCounts cannot update like counts.
+type failingLikeCounts struct {
+	*memPosts
+}
+
+func (r failingLikeCounts) DecrementLikeCount(id primitive.ObjectID) error {
+	return errors.New("connection reset")
+}
+
+func TestPurgeReportsLikeCountFailure(t *testing.T) {
+	victim := newTestUser("leaving@astanait.edu.kz", "password123", models.RoleStudent)
+	other := newTestUser("staying@astanait.edu.kz", "password123", models.RoleStudent)
+
+	f := newDeletionFixture(victim, other)
+	otherPost := models.NewPost("Club fair", "Booths in the atrium", "", models.PostCategory("news"), other.ID, other.DisplayName)
+	f.posts.posts = append(f.posts.posts, otherPost)
+	f.seedAccount(victim, other, otherPost)
+	f.start()
+	f.service.postRepo = failingLikeCounts{f.posts}
+
+	if err := f.service.purgeLikes(victim.ID); err == nil {
+		t.Error("purge succeeded although the like count was not updated")
+	}
+}
@@ -2,14 +2,19 @@ package service
 
 import (
 	"errors"
+	"log"
 	"regexp"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/mail"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/middleware"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/oidc"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
 )
 
@@ -19,22 +24,60 @@ var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrInvalidEmail       = errors.New("invalid email format")
 	ErrWeakPassword       = errors.New("password must be at least 8 characters long")
+	ErrAccountDeactivated = errors.New("account is deactivated")
 )
 
 type AuthService struct {
-	userRepo repository.UserRepository
-	authMid  *middleware.AuthMiddleware
-	cfg      *config.Config
+	userRepo               repository.UserRepository
+	refreshTokenRepo       repository.RefreshTokenRepository
+	sessionRepo            repository.SessionRepository
+	passwordResetRepo      repository.PasswordResetRepository
+	inviteRepo             repository.InviteRepository
+	twoFactorChallengeRepo repository.TwoFactorChallengeRepository
+	loginThrottleRepo      repository.LoginThrottleRepository
+	accessTokenRepo        repository.AccessTokenRepository
+	oidcLoginRepo          repository.OIDCLoginRepository
+	oidcProvider           *oidc.Provider
+	registration           *RegistrationPolicy
+	policy                 *policy.Engine
+	fileService            *FileService
+	mailer                 mail.Mailer
+	authMid                *middleware.AuthMiddleware
+	cfg                    *config.Config
+	listeners              []UserListener
 }
 
-func NewAuthService(userRepo repository.UserRepository, cfg *config.Config) *AuthService {
+func NewAuthService(userRepo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository, passwordResetRepo repository.PasswordResetRepository, inviteRepo repository.InviteRepository, twoFactorChallengeRepo repository.TwoFactorChallengeRepository, loginThrottleRepo repository.LoginThrottleRepository, accessTokenRepo repository.AccessTokenRepository, engine *policy.Engine, fileService *FileService, mailer mail.Mailer, cfg *config.Config) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		authMid:  middleware.NewAuthMiddleware(cfg, userRepo),
-		cfg:      cfg,
+		userRepo:               userRepo,
+		refreshTokenRepo:       refreshTokenRepo,
+		sessionRepo:            sessionRepo,
+		passwordResetRepo:      passwordResetRepo,
+		inviteRepo:             inviteRepo,
+		twoFactorChallengeRepo: twoFactorChallengeRepo,
+		loginThrottleRepo:      loginThrottleRepo,
+		accessTokenRepo:        accessTokenRepo,
+		registration:           NewRegistrationPolicy(cfg.Register),
+		policy:                 engine,
+		fileService:            fileService,
+		mailer:                 mailer,
+		authMid:                middleware.NewAuthMiddleware(cfg, userRepo, sessionRepo, accessTokenRepo, engine),
+		cfg:                    cfg,
 	}
 }
 
+func (s *AuthService) AddListener(listener UserListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *AuthService) notifyUpdated(user *models.User) {
+	for _, listener := range s.listeners {
+		listener.UserUpdated(user)
+	}
+}
+
+// Register creates an account allowed by the registration policy. The
+// invite in req, if any, is consumed.
 func (s *AuthService) Register(req dto.RegisterRequest) (*models.User, error) {
 	if err := s.validateEmail(req.Email); err != nil {
 		return nil, err
@@ -44,43 +87,107 @@ func (s *AuthService) Register(req dto.RegisterRequest) (*models.User, error) {
 		return nil, err
 	}
 
+	invite, err := s.findInvite(req.InviteCode)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.registration.Check(req.Email, invite)
+	if err != nil {
+		return nil, err
+	}
+
 	existingUser, _ := s.userRepo.FindByEmail(req.Email)
 	if existingUser != nil {
 		return nil, ErrEmailAlreadyExists
 	}
 
-	user, err := models.NewUser(req.Email, req.Password, req.DisplayName, models.RoleStudent)
+	user, err := models.NewUser(req.Email, req.Password, req.DisplayName, role)
 	if err != nil {
 		return nil, err
 	}
 
+	if !s.cfg.Mail.RequireVerification {
+		user.EmailVerified = true
+	} else {
+		now := time.Now()
+		user.VerificationSentAt = &now
+		// Anyone can type a staff address; its role waits for the link
+		if role != models.RoleStudent {
+			user.Role, user.PendingRole = models.RoleStudent, role
+		}
+	}
+
 	if err := s.userRepo.Create(user); err != nil {
 		return nil, err
 	}
 
+	if invite != nil {
+		// Claimed after the user exists so the invite records who used it;
+		// losing a race for the same code undoes the account
+		claimed, err := s.inviteRepo.MarkUsed(invite.ID, user.ID, time.Now())
+		if err != nil || !claimed {
+			if deleteErr := s.userRepo.Delete(user.ID); deleteErr != nil {
+				log.Printf("Failed to remove user %s after invite claim failed: %v", user.ID.Hex(), deleteErr)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrInvalidInvite
+		}
+	}
+
+	if !user.EmailVerified {
+		s.sendVerificationAsync(user)
+	}
+
+	s.notifyUpdated(user)
+
 	return user, nil
 }
 
-func (s *AuthService) Login(req dto.LoginRequest) (string, *models.User, error) {
+// RegistrationPolicy exposes the policy so clients can show which domains
+// may sign up.
+func (s *AuthService) RegistrationPolicy() *RegistrationPolicy {
+	return s.registration
+}
+
+// Login checks the password. Users with two-factor authentication get a
+// challenge instead of tokens. Failed attempts slow down further ones for
+// the email and the client IP, and eventually lock the account.
+func (s *AuthService) Login(req dto.LoginRequest, client SessionClient) (*LoginResult, error) {
+	now := time.Now()
+	if err := s.checkLoginThrottle(req.Email, client.IP, now); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
-		return "", nil, ErrInvalidCredentials
+		s.recordLoginFailure(req.Email, nil, client.IP, now)
+		return nil, ErrInvalidCredentials
 	}
 
 	if !user.ValidatePassword(req.Password) {
-		return "", nil, ErrInvalidCredentials
+		s.recordLoginFailure(req.Email, user, client.IP, now)
+		return nil, ErrInvalidCredentials
 	}
 
+	s.clearLoginFailures(req.Email)
+
 	if !user.IsActive {
-		return "", nil, errors.New("account is deactivated")
+		return nil, ErrAccountDeactivated
+	}
+
+	if user.HasTwoFactor() {
+		return s.newTwoFactorChallenge(user)
 	}
 
-	token, err := s.authMid.GenerateToken(user)
+	tokens, err := s.IssueTokens(user, client)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
-	return token, user, nil
+	return &LoginResult{Tokens: tokens, User: user}, nil
 }
 
 func (s *AuthService) GetCurrentUser(userID primitive.ObjectID) (*models.User, error) {
@@ -101,13 +208,24 @@ func (s *AuthService) UpdateProfile(userID primitive.ObjectID, req dto.UpdatePro
 		user.Bio = req.Bio
 	}
 
-	if req.ProfileImage != "" {
+	// A linked image replaces an uploaded avatar, whose files go
+	staleAvatar := ""
+	if req.ProfileImage != "" && req.ProfileImage != user.ProfileImage {
+		if err := validateProfileImage(req.ProfileImage); err != nil {
+			return nil, err
+		}
 		user.ProfileImage = req.ProfileImage
+		staleAvatar, user.Avatar = user.Avatar, ""
 	}
 
 	if err := s.userRepo.Update(user); err != nil {
 		return nil, err
 	}
+	if staleAvatar != "" {
+		s.fileService.DeleteAvatar(staleAvatar)
+	}
+
+	s.notifyUpdated(user)
 
 	return user, nil
 }
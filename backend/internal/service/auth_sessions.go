@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+const maxUserAgentLength = 512
+
+// SessionClient describes the device a login comes from.
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+func (s *AuthService) ListSessions(userID primitive.ObjectID) ([]*models.Session, error) {
+	return s.sessionRepo.FindActiveByUser(userID)
+}
+
+// RevokeSession signs one of the user's devices out. Its access tokens stop
+// working immediately and its refresh tokens can no longer be exchanged.
+func (s *AuthService) RevokeSession(userID, sessionID primitive.ObjectID) error {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive(time.Now()) {
+		return ErrSessionNotFound
+	}
+
+	now := time.Now()
+	if err := s.sessionRepo.Revoke(sessionID, now); err != nil {
+		return err
+	}
+	return s.refreshTokenRepo.RevokeFamily(sessionID, now)
+}
+
+// RevokeAllSessions signs the user out everywhere, including the device
+// making the request.
+func (s *AuthService) RevokeAllSessions(userID primitive.ObjectID) error {
+	now := time.Now()
+	if err := s.sessionRepo.RevokeByUser(userID, now); err != nil {
+		return err
+	}
+	return s.refreshTokenRepo.RevokeByUser(userID, now)
+}
+
+// UserUpdated forces a deactivated user off every device.
+func (s *AuthService) UserUpdated(user *models.User) {
+	if user.IsActive {
+		return
+	}
+	if err := s.RevokeAllSessions(user.ID); err != nil {
+		log.Printf("Failed to revoke sessions of %s: %v", user.ID.Hex(), err)
+	}
+}
+
+func (s *AuthService) UserDeleted(userID primitive.ObjectID) {
+	if err := s.RevokeAllSessions(userID); err != nil {
+		log.Printf("Failed to revoke sessions of %s: %v", userID.Hex(), err)
+	}
+}
+
+func (s *AuthService) newSession(userID primitive.ObjectID, client SessionClient) *models.Session {
+	userAgent := client.UserAgent
+	if runes := []rune(userAgent); len(runes) > maxUserAgentLength {
+		userAgent = string(runes[:maxUserAgentLength])
+	}
+	return models.NewSession(userID, describeDevice(userAgent), userAgent, client.IP, s.refreshTokenTTL())
+}
+
+// describeDevice turns a user agent into a label like "Firefox on Linux".
+// Order matters: Edge and Opera also claim to be Chrome, and Chrome also
+// claims to be Safari.
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"yabrowser", "Yandex Browser"},
+		{"firefox", "Firefox"},
+		{"chrome", "Chrome"},
+		{"crios", "Chrome"},
+		{"safari", "Safari"},
+		{"curl", "curl"},
+		{"okhttp", "Android app"},
+		{"cfnetwork", "iOS app"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	system := ""
+	for _, o := range []struct{ token, name string }{
+		{"android", "Android"},
+		{"iphone", "iOS"},
+		{"ipad", "iPadOS"},
+		{"windows", "Windows"},
+		{"mac os", "macOS"},
+		{"cros", "ChromeOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			system = o.name
+			break
+		}
+	}
+
+	if system == "" {
+		return browser
+	}
+	return browser + " on " + system
+}
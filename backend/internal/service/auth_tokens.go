@@ -0,0 +1,187 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used; all sessions from this login were signed out")
+)
+
+// TokenPair is what clients hold: a short-lived access token for requests
+// and a refresh token to exchange for the next pair.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// IssueTokens signs the user in on a new session, which also starts a new
+// refresh token family. Callers must have checked the second factor of
+// users who have one; the session is recorded as having passed it.
+func (s *AuthService) IssueTokens(user *models.User, client SessionClient) (*TokenPair, error) {
+	session := s.newSession(user.ID, client)
+	session.TwoFactor = user.HasTwoFactor()
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, session.ID)
+}
+
+// Refresh exchanges a refresh token for a new pair. The presented token is
+// rotated out; presenting a rotated token again revokes its whole session,
+// since either the client or an attacker holds a stolen copy.
+func (s *AuthService) Refresh(refreshToken string, client SessionClient) (*TokenPair, *models.User, error) {
+	stored, err := s.refreshTokenRepo.FindByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+	if stored.RotatedAt != nil && stored.RevokedAt == nil {
+		s.revokeSession(stored.FamilyID, now)
+		return nil, nil, ErrRefreshTokenReused
+	}
+	if stored.RevokedAt != nil || !stored.ExpiresAt.After(now) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionRepo.FindByID(stored.FamilyID)
+	if err == mongo.ErrNoDocuments {
+		// Families issued before sessions existed get one on first refresh
+		session = s.newSession(stored.UserID, client)
+		session.ID = stored.FamilyID
+		session.CreatedAt = stored.CreatedAt
+		err = s.sessionRepo.Create(session)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !session.IsActive(now) {
+		s.revokeSession(session.ID, now)
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	rotated, err := s.refreshTokenRepo.MarkRotated(stored.ID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rotated {
+		// Another request exchanged it first
+		s.revokeSession(session.ID, now)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.userRepo.FindByID(stored.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if !user.IsActive {
+		s.revokeSession(session.ID, now)
+		return nil, nil, ErrAccountDeactivated
+	}
+
+	tokens, err := s.issueTokens(user, session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.sessionRepo.Extend(session.ID, client.IP, now, now.Add(s.refreshTokenTTL())); err != nil {
+		log.Printf("Failed to extend session %s: %v", session.ID.Hex(), err)
+	}
+	return tokens, user, nil
+}
+
+// Logout ends the session the refresh token belongs to. Unknown tokens are
+// ignored so logout always succeeds from the client's view.
+func (s *AuthService) Logout(refreshToken string) error {
+	stored, err := s.refreshTokenRepo.FindByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	if err := s.sessionRepo.Revoke(stored.FamilyID, now); err != nil {
+		return err
+	}
+	return s.refreshTokenRepo.RevokeFamily(stored.FamilyID, now)
+}
+
+func (s *AuthService) issueTokens(user *models.User, sessionID primitive.ObjectID) (*TokenPair, error) {
+	accessToken, err := s.authMid.GenerateToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := models.NewRefreshToken(user.ID, sessionID, hashToken(refreshToken), s.refreshTokenTTL())
+	if err := s.refreshTokenRepo.Create(stored); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.authMid.AccessTokenTTL(),
+	}, nil
+}
+
+func (s *AuthService) revokeSession(sessionID primitive.ObjectID, at time.Time) {
+	if err := s.sessionRepo.Revoke(sessionID, at); err != nil {
+		log.Printf("Failed to revoke session %s: %v", sessionID.Hex(), err)
+	}
+	if err := s.refreshTokenRepo.RevokeFamily(sessionID, at); err != nil {
+		log.Printf("Failed to revoke refresh token family %s: %v", sessionID.Hex(), err)
+	}
+}
+
+func (s *AuthService) refreshTokenTTL() time.Duration {
+	if s.cfg.JWT.RefreshTokenTTL <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return s.cfg.JWT.RefreshTokenTTL
+}
+
+// newOpaqueToken returns 32 random bytes, base64url encoded.
+func newOpaqueToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashToken is how opaque tokens are stored, so a database leak does not
+// hand out working tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// derivedKey is a 32-byte key for one purpose, derived from the JWT secret
+// so that keys for different purposes never coincide.
+func (s *AuthService) derivedKey(purpose string) []byte {
+	return deriveKey(s.cfg.JWT.SecretKey, purpose)
+}
+
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+var (
+	ErrPostNotFound          = errors.New("post not found")
+	ErrAlreadyBookmarked     = errors.New("post already bookmarked")
+	ErrBookmarkNotFound      = errors.New("bookmark not found")
+	ErrCollectionNotFound    = errors.New("collection not found")
+	ErrCollectionNameTaken   = errors.New("collection with this name already exists")
+	ErrInvalidCollectionName = errors.New("collection name must be between 1 and 50 characters")
+)
+
+// BookmarkEntry is a bookmark joined with its post. Bookmarks whose post is
+// archived are returned without a post.
+type BookmarkEntry struct {
+	Bookmark *models.Bookmark
+	Post     *models.Post
+}
+
+type CollectionWithCount struct {
+	Collection *models.BookmarkCollection
+	Count      int64
+}
+
+type BookmarkService struct {
+	bookmarkRepo   repository.BookmarkRepository
+	collectionRepo repository.BookmarkCollectionRepository
+	postRepo       repository.PostRepository
+}
+
+func NewBookmarkService(bookmarkRepo repository.BookmarkRepository, collectionRepo repository.BookmarkCollectionRepository, postRepo repository.PostRepository) *BookmarkService {
+	return &BookmarkService{
+		bookmarkRepo:   bookmarkRepo,
+		collectionRepo: collectionRepo,
+		postRepo:       postRepo,
+	}
+}
+
+func (s *BookmarkService) AddBookmark(userID, postID primitive.ObjectID, collectionID *primitive.ObjectID) (*models.Bookmark, error) {
+	post, err := s.postRepo.FindByID(postID)
+	if err != nil || post.IsArchived {
+		return nil, ErrPostNotFound
+	}
+
+	if collectionID != nil {
+		if _, err := s.ownedCollection(userID, *collectionID); err != nil {
+			return nil, err
+		}
+	}
+
+	if existing, _ := s.bookmarkRepo.FindByUserAndPost(userID, postID); existing != nil {
+		return nil, ErrAlreadyBookmarked
+	}
+
+	bookmark := models.NewBookmark(userID, postID, collectionID)
+	if err := s.bookmarkRepo.Create(bookmark); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrAlreadyBookmarked
+		}
+		return nil, err
+	}
+
+	return bookmark, nil
+}
+
+func (s *BookmarkService) RemoveBookmark(userID, postID primitive.ObjectID) error {
+	_, err := s.bookmarkRepo.Delete(userID, postID)
+	if err == mongo.ErrNoDocuments {
+		return ErrBookmarkNotFound
+	}
+	return err
+}
+
+func (s *BookmarkService) MoveBookmark(userID, postID primitive.ObjectID, collectionID *primitive.ObjectID) error {
+	if collectionID != nil {
+		if _, err := s.ownedCollection(userID, *collectionID); err != nil {
+			return err
+		}
+	}
+
+	err := s.bookmarkRepo.SetCollection(userID, postID, collectionID)
+	if err == mongo.ErrNoDocuments {
+		return ErrBookmarkNotFound
+	}
+	return err
+}
+
+func (s *BookmarkService) GetBookmarks(userID primitive.ObjectID, collectionID *primitive.ObjectID, limit, offset int) ([]BookmarkEntry, int64, error) {
+	if collectionID != nil {
+		if _, err := s.ownedCollection(userID, *collectionID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	bookmarks, err := s.bookmarkRepo.FindByUser(userID, collectionID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.bookmarkRepo.CountByUser(userID, collectionID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	postIDs := make([]primitive.ObjectID, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		postIDs = append(postIDs, bookmark.PostID)
+	}
+
+	posts, err := s.postRepo.FindByIDs(postIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.Post, len(posts))
+	for _, post := range posts {
+		if !post.IsArchived {
+			byID[post.ID] = post
+		}
+	}
+
+	entries := make([]BookmarkEntry, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		entries = append(entries, BookmarkEntry{
+			Bookmark: bookmark,
+			Post:     byID[bookmark.PostID],
+		})
+	}
+
+	return entries, total, nil
+}
+
+func (s *BookmarkService) BookmarkedPostIDs(userID primitive.ObjectID, posts []*models.Post) map[primitive.ObjectID]bool {
+	postIDs := make([]primitive.ObjectID, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+
+	bookmarked, err := s.bookmarkRepo.FindBookmarkedPostIDs(userID, postIDs)
+	if err != nil {
+		return map[primitive.ObjectID]bool{}
+	}
+	return bookmarked
+}
+
+func (s *BookmarkService) DeleteBookmarksForPost(postID primitive.ObjectID) error {
+	return s.bookmarkRepo.DeleteByPostID(postID)
+}
+
+func (s *BookmarkService) CreateCollection(userID primitive.ObjectID, name, description string) (*models.BookmarkCollection, error) {
+	name, err := validateCollectionName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := models.NewBookmarkCollection(userID, name, strings.TrimSpace(description))
+	if err := s.collectionRepo.Create(collection); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrCollectionNameTaken
+		}
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+func (s *BookmarkService) GetCollections(userID primitive.ObjectID) ([]CollectionWithCount, error) {
+	collections, err := s.collectionRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]CollectionWithCount, 0, len(collections))
+	for _, collection := range collections {
+		id := collection.ID
+		count, err := s.bookmarkRepo.CountByUser(userID, &id)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, CollectionWithCount{Collection: collection, Count: count})
+	}
+
+	return result, nil
+}
+
+func (s *BookmarkService) UpdateCollection(userID, collectionID primitive.ObjectID, name, description string) (*CollectionWithCount, error) {
+	collection, err := s.ownedCollection(userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		name, err = validateCollectionName(name)
+		if err != nil {
+			return nil, err
+		}
+		collection.Name = name
+	}
+	collection.Description = strings.TrimSpace(description)
+
+	if err := s.collectionRepo.Update(collection); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrCollectionNameTaken
+		}
+		return nil, err
+	}
+
+	count, err := s.bookmarkRepo.CountByUser(userID, &collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CollectionWithCount{Collection: collection, Count: count}, nil
+}
+
+// DeleteCollection removes the folder only; its bookmarks stay saved
+// without a collection.
+func (s *BookmarkService) DeleteCollection(userID, collectionID primitive.ObjectID) error {
+	if _, err := s.ownedCollection(userID, collectionID); err != nil {
+		return err
+	}
+
+	if err := s.bookmarkRepo.ClearCollection(collectionID); err != nil {
+		return err
+	}
+
+	return s.collectionRepo.Delete(collectionID)
+}
+
+func (s *BookmarkService) ownedCollection(userID, collectionID primitive.ObjectID) (*models.BookmarkCollection, error) {
+	collection, err := s.collectionRepo.FindByID(collectionID)
+	if err != nil || collection.UserID != userID {
+		return nil, ErrCollectionNotFound
+	}
+	return collection, nil
+}
+
+func validateCollectionName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 50 {
+		return "", ErrInvalidCollectionName
+	}
+	return name, nil
+}
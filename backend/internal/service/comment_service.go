@@ -6,23 +6,38 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
 )
 
+// CommentListener is notified after comments change.
+type CommentListener interface {
+	CommentCreated(comment *models.Comment)
+	CommentUpdated(comment *models.Comment)
+	CommentDeleted(commentID primitive.ObjectID)
+}
+
 type CommentService struct {
 	commentRepo repository.CommentRepository
 	userRepo    repository.UserRepository
 	postRepo    repository.PostRepository
+	policy      *policy.Engine
+	listeners   []CommentListener
 }
 
-func NewCommentService(commentRepo repository.CommentRepository, userRepo repository.UserRepository, postRepo repository.PostRepository) *CommentService {
+func NewCommentService(commentRepo repository.CommentRepository, userRepo repository.UserRepository, postRepo repository.PostRepository, engine *policy.Engine) *CommentService {
 	return &CommentService{
 		commentRepo: commentRepo,
 		userRepo:    userRepo,
 		postRepo:    postRepo,
+		policy:      engine,
 	}
 }
 
+func (s *CommentService) AddListener(listener CommentListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
 func (s *CommentService) CreateComment(postID, authorID primitive.ObjectID, content string) (*models.Comment, error) {
 	user, err := s.userRepo.FindByID(authorID)
 	if err != nil {
@@ -33,6 +48,10 @@ func (s *CommentService) CreateComment(postID, authorID primitive.ObjectID, cont
 		return nil, errors.New("user account is deactivated")
 	}
 
+	if !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
 	comment := models.NewComment(postID, authorID, user.DisplayName, content)
 
 	if err := s.commentRepo.Create(comment); err != nil {
@@ -50,6 +69,10 @@ func (s *CommentService) CreateComment(postID, authorID primitive.ObjectID, cont
 		return nil, err
 	}
 
+	for _, listener := range s.listeners {
+		listener.CommentCreated(comment)
+	}
+
 	return comment, nil
 }
 
@@ -57,7 +80,10 @@ func (s *CommentService) GetCommentsByPostID(postID primitive.ObjectID, limit, o
 	return s.commentRepo.FindByPostID(postID, limit, offset)
 }
 
-func (s *CommentService) UpdateComment(commentID, userID primitive.ObjectID, content string) (*models.Comment, error) {
+// UpdateComment edits a comment. twoFactor reports whether the caller's
+// session passed a TOTP check, which staff need to edit other people's
+// comments.
+func (s *CommentService) UpdateComment(commentID, userID primitive.ObjectID, twoFactor bool, content string) (*models.Comment, error) {
 	comment, err := s.commentRepo.FindByID(commentID)
 	if err != nil {
 		return nil, err
@@ -68,7 +94,10 @@ func (s *CommentService) UpdateComment(commentID, userID primitive.ObjectID, con
 		return nil, err
 	}
 
-	if comment.AuthorID != userID && !user.CanManageComments() {
+	if err := s.policy.AuthorizeSession(user, policy.CommentsEdit, policy.Owned("comment", comment.ID, comment.AuthorID), twoFactor); err != nil {
+		if err == policy.ErrTwoFactorRequired {
+			return nil, err
+		}
 		return nil, errors.New("not authorized to edit this comment")
 	}
 
@@ -77,10 +106,15 @@ func (s *CommentService) UpdateComment(commentID, userID primitive.ObjectID, con
 		return nil, err
 	}
 
+	for _, listener := range s.listeners {
+		listener.CommentUpdated(comment)
+	}
+
 	return comment, nil
 }
 
-func (s *CommentService) DeleteComment(commentID, userID primitive.ObjectID) error {
+// DeleteComment removes a comment; twoFactor is as for UpdateComment.
+func (s *CommentService) DeleteComment(commentID, userID primitive.ObjectID, twoFactor bool) error {
 	comment, err := s.commentRepo.FindByID(commentID)
 	if err != nil {
 		return err
@@ -91,21 +125,47 @@ func (s *CommentService) DeleteComment(commentID, userID primitive.ObjectID) err
 		return err
 	}
 
-	if comment.AuthorID != userID && !user.CanManageComments() {
+	if err := s.policy.AuthorizeSession(user, policy.CommentsDelete, policy.Owned("comment", comment.ID, comment.AuthorID), twoFactor); err != nil {
+		if err == policy.ErrTwoFactorRequired {
+			return err
+		}
 		return errors.New("not authorized to delete this comment")
 	}
 
-	if err := s.commentRepo.Delete(commentID); err != nil {
+	if err := s.removeComment(comment); err != nil {
 		return err
 	}
 
 	user.DecrementCommentCount()
 	s.userRepo.Update(user)
 
-	if err := s.postRepo.DecrementCommentCount(comment.PostID); err != nil {
+	return nil
+}
+
+func (s *CommentService) removeComment(comment *models.Comment) error {
+	if err := s.commentRepo.Delete(comment.ID); err != nil {
 		return err
 	}
 
+	for _, listener := range s.listeners {
+		listener.CommentDeleted(comment.ID)
+	}
+
+	return s.postRepo.DecrementCommentCount(comment.PostID)
+}
+
+// anonymizeComment credits the comment to a deleted user instead of its
+// author.
+func (s *CommentService) anonymizeComment(comment *models.Comment) error {
+	comment.AuthorID = primitive.NilObjectID
+	comment.AuthorName = models.DeletedUserName
+	if err := s.commentRepo.Update(comment); err != nil {
+		return err
+	}
+
+	for _, listener := range s.listeners {
+		listener.CommentUpdated(comment)
+	}
 	return nil
 }
 
@@ -0,0 +1,534 @@
+package service
+
+import (
+	"archive/zip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+var (
+	ErrExportInProgress = errors.New("an export is already being prepared")
+	ErrExportNotFound   = errors.New("export not found")
+	ErrExportExpired    = errors.New("download link is invalid or has expired")
+)
+
+const (
+	exportPageSize      = 200
+	exportQueueSize     = 100
+	exportSweepInterval = 10 * time.Minute
+)
+
+// exportProfile is the account as written to profile.json. Secrets such
+// as the password hash and two-factor keys are left out.
+type exportProfile struct {
+	ID                           string                 `json:"id"`
+	Email                        string                 `json:"email"`
+	DisplayName                  string                 `json:"display_name"`
+	Role                         string                 `json:"role"`
+	Bio                          string                 `json:"bio,omitempty"`
+	ProfileImage                 string                 `json:"profile_image,omitempty"`
+	EmailVerified                bool                   `json:"email_verified"`
+	TwoFactorEnabled             bool                   `json:"two_factor_enabled"`
+	LinkedAccounts               []exportLinkedAccount  `json:"linked_accounts,omitempty"`
+	SubscribedCategories         []models.PostCategory  `json:"subscribed_categories,omitempty"`
+	SubscribedTags               []string               `json:"subscribed_tags,omitempty"`
+	DisabledNotificationChannels []string               `json:"disabled_notification_channels,omitempty"`
+	Privacy                      models.PrivacySettings `json:"privacy"`
+	PostCount                    int                    `json:"post_count"`
+	CommentCount                 int                    `json:"comment_count"`
+	FollowerCount                int                    `json:"follower_count"`
+	FollowingCount               int                    `json:"following_count"`
+	CreatedAt                    time.Time              `json:"created_at"`
+	LastLoginAt                  time.Time              `json:"last_login_at"`
+}
+
+type exportLinkedAccount struct {
+	Issuer   string    `json:"issuer"`
+	Email    string    `json:"email,omitempty"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// DataExportService builds ZIP archives of everything stored about a user
+// in the background, one export per user at a time. Finished archives
+// are deleted once their download link expires.
+type DataExportService struct {
+	exportRepo          repository.DataExportRepository
+	userRepo            repository.UserRepository
+	postRepo            repository.PostRepository
+	commentRepo         repository.CommentRepository
+	likeRepo            repository.LikeRepository
+	fileService         *FileService
+	notificationService *NotificationService
+	cfg                 config.ExportConfig
+	secret              []byte
+
+	queue  chan primitive.ObjectID
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func NewDataExportService(
+	exportRepo repository.DataExportRepository,
+	userRepo repository.UserRepository,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	likeRepo repository.LikeRepository,
+	fileService *FileService,
+	notificationService *NotificationService,
+	cfg *config.Config,
+) *DataExportService {
+	if err := os.MkdirAll(cfg.Export.Dir, 0700); err != nil {
+		log.Printf("Failed to create export directory: %v", err)
+	}
+
+	s := &DataExportService{
+		exportRepo:          exportRepo,
+		userRepo:            userRepo,
+		postRepo:            postRepo,
+		commentRepo:         commentRepo,
+		likeRepo:            likeRepo,
+		fileService:         fileService,
+		notificationService: notificationService,
+		cfg:                 cfg.Export,
+		secret:              deriveKey(cfg.JWT.SecretKey, "data-export"),
+		queue:               make(chan primitive.ObjectID, exportQueueSize),
+		stopCh:              make(chan struct{}),
+		doneCh:              make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Request queues an export of the user's data.
+func (s *DataExportService) Request(userID primitive.ObjectID) (*models.DataExport, error) {
+	export := models.NewDataExport(userID)
+	if err := s.exportRepo.Create(export); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrExportInProgress
+		}
+		return nil, err
+	}
+
+	select {
+	case s.queue <- export.ID:
+	default:
+		// The sweep picks it up when the queue is full
+	}
+	return export, nil
+}
+
+// Latest returns the user's most recent export.
+func (s *DataExportService) Latest(userID primitive.ObjectID) (*models.DataExport, error) {
+	export, err := s.exportRepo.FindLatestByUser(userID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// DownloadURL is the signed link to a ready archive. It stops working
+// when the archive expires; it is empty for exports that are not ready.
+func (s *DataExportService) DownloadURL(export *models.DataExport) string {
+	if !export.IsDownloadable(time.Now()) {
+		return ""
+	}
+	return "/api/exports/" + export.ID.Hex() + "/download?signature=" + s.sign(export)
+}
+
+// Open checks a download link and opens its archive. The caller closes
+// the file.
+func (s *DataExportService) Open(exportID primitive.ObjectID, signature string) (*os.File, *models.DataExport, error) {
+	export, err := s.exportRepo.FindByID(exportID)
+	if err != nil || !export.IsDownloadable(time.Now()) {
+		return nil, nil, ErrExportExpired
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(export))) {
+		return nil, nil, ErrExportExpired
+	}
+
+	file, err := os.Open(s.archivePath(export.ID))
+	if err != nil {
+		return nil, nil, ErrExportExpired
+	}
+	return file, export, nil
+}
+
+// Stop ends the worker after the export in progress, if any, is done.
+func (s *DataExportService) Stop() {
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+func (s *DataExportService) UserUpdated(user *models.User) {}
+
+// UserDeleted removes the user's archives along with their records.
+func (s *DataExportService) UserDeleted(userID primitive.ObjectID) {
+	exports, err := s.exportRepo.FindByUser(userID)
+	if err != nil {
+		log.Printf("Failed to find exports of %s: %v", userID.Hex(), err)
+		return
+	}
+	for _, export := range exports {
+		s.removeArchive(export.ID)
+	}
+	if err := s.exportRepo.DeleteByUser(userID); err != nil {
+		log.Printf("Failed to delete exports of %s: %v", userID.Hex(), err)
+	}
+}
+
+func (s *DataExportService) run() {
+	defer close(s.doneCh)
+
+	// Exports interrupted by a restart start over
+	if err := s.exportRepo.ResetRunning(); err != nil {
+		log.Printf("Failed to reset running exports: %v", err)
+	}
+	s.sweep()
+
+	ticker := time.NewTicker(exportSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case id := <-s.queue:
+			s.build(id)
+		case <-ticker.C:
+			s.sweep()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// sweep deletes expired archives and queues pending exports that did not
+// fit in the queue. An export queued twice is only built once, by
+// whichever claims it first.
+func (s *DataExportService) sweep() {
+	now := time.Now()
+	for {
+		expired, err := s.exportRepo.FindExpired(now, exportPageSize)
+		if err != nil {
+			log.Printf("Failed to find expired exports: %v", err)
+			break
+		}
+		for _, export := range expired {
+			s.removeArchive(export.ID)
+			export.Status = models.DataExportExpired
+			if err := s.exportRepo.Update(export); err != nil {
+				log.Printf("Failed to expire export %s: %v", export.ID.Hex(), err)
+				return
+			}
+		}
+		if len(expired) < exportPageSize {
+			break
+		}
+	}
+
+	pending, err := s.exportRepo.FindByStatus(models.DataExportPending, exportQueueSize)
+	if err != nil {
+		log.Printf("Failed to find pending exports: %v", err)
+		return
+	}
+	for _, export := range pending {
+		select {
+		case s.queue <- export.ID:
+		default:
+			return
+		}
+	}
+}
+
+func (s *DataExportService) build(id primitive.ObjectID) {
+	claimed, err := s.exportRepo.Claim(id, time.Now())
+	if err != nil {
+		log.Printf("Failed to claim export %s: %v", id.Hex(), err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	export, err := s.exportRepo.FindByID(id)
+	if err != nil {
+		log.Printf("Failed to load export %s: %v", id.Hex(), err)
+		return
+	}
+
+	size, err := s.writeArchive(export)
+	now := time.Now()
+	export.Active = false
+	export.CompletedAt = &now
+	if err != nil {
+		log.Printf("Export %s failed: %v", id.Hex(), err)
+		export.Status = models.DataExportFailed
+		export.Error = "The archive could not be created. Please try again."
+	} else {
+		expiresAt := now.Add(s.linkTTL())
+		export.Status = models.DataExportReady
+		export.Size = size
+		export.ExpiresAt = &expiresAt
+	}
+	if err := s.exportRepo.Update(export); err != nil {
+		log.Printf("Failed to save export %s: %v", id.Hex(), err)
+		return
+	}
+
+	if export.Status == models.DataExportReady {
+		notification := models.NewNotification(export.UserID, models.NotificationDataExport,
+			"Your data export is ready",
+			fmt.Sprintf("Download it within %s, after which the archive is deleted.", describeTTL(s.linkTTL())),
+			s.DownloadURL(export))
+		if err := s.notificationService.Notify(notification); err != nil {
+			log.Printf("Failed to notify %s about export: %v", export.UserID.Hex(), err)
+		}
+	}
+}
+
+// writeArchive writes the ZIP next to its final path and moves it into
+// place once complete, so a download never sees a partial archive.
+func (s *DataExportService) writeArchive(export *models.DataExport) (int64, error) {
+	user, err := s.userRepo.FindByID(export.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	final := s.archivePath(export.ID)
+	tmp := final + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	archive := zip.NewWriter(file)
+	if err := s.writeEntries(archive, user); err != nil {
+		file.Close()
+		return 0, err
+	}
+	if err := archive.Close(); err != nil {
+		file.Close()
+		return 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp, final); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *DataExportService) writeEntries(archive *zip.Writer, user *models.User) error {
+	if err := writeJSONEntry(archive, "profile.json", newExportProfile(user)); err != nil {
+		return err
+	}
+
+	var posts []*models.Post
+	err := s.collect(user.ID, func(query repository.ActivityQuery) (int, time.Time, primitive.ObjectID, error) {
+		page, err := s.postRepo.FindAllByAuthor(query)
+		if err != nil || len(page) == 0 {
+			return 0, time.Time{}, primitive.NilObjectID, err
+		}
+		posts = append(posts, page...)
+		last := page[len(page)-1]
+		return len(page), last.CreatedAt, last.ID, nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeJSONEntry(archive, "posts.json", nonNil(posts)); err != nil {
+		return err
+	}
+
+	var comments []*models.Comment
+	err = s.collect(user.ID, func(query repository.ActivityQuery) (int, time.Time, primitive.ObjectID, error) {
+		page, err := s.commentRepo.FindByAuthor(query)
+		if err != nil || len(page) == 0 {
+			return 0, time.Time{}, primitive.NilObjectID, err
+		}
+		comments = append(comments, page...)
+		last := page[len(page)-1]
+		return len(page), last.CreatedAt, last.ID, nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeJSONEntry(archive, "comments.json", nonNil(comments)); err != nil {
+		return err
+	}
+
+	var likes []*models.Like
+	err = s.collect(user.ID, func(query repository.ActivityQuery) (int, time.Time, primitive.ObjectID, error) {
+		page, err := s.likeRepo.FindByUser(query)
+		if err != nil || len(page) == 0 {
+			return 0, time.Time{}, primitive.NilObjectID, err
+		}
+		likes = append(likes, page...)
+		last := page[len(page)-1]
+		return len(page), last.CreatedAt, last.ID, nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeJSONEntry(archive, "likes.json", nonNil(likes)); err != nil {
+		return err
+	}
+
+	// Uploaded files; linked ones are only referenced in the JSON
+	if user.Avatar != "" {
+		largest := AvatarSizes[len(AvatarSizes)-1]
+		if err := writeFileEntry(archive, "media/avatar.png", s.fileService.avatarPath(user.Avatar, largest)); err != nil {
+			return err
+		}
+	}
+	for _, post := range posts {
+		for _, media := range post.Media {
+			local, ok := s.fileService.LocalPath(media.URL)
+			if !ok {
+				continue
+			}
+			name := path.Join("media", "posts", post.ID.Hex(), filepath.Base(local))
+			if err := writeFileEntry(archive, name, local); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// collect calls page with a moving cursor until it returns a short page.
+func (s *DataExportService) collect(userID primitive.ObjectID, page func(repository.ActivityQuery) (int, time.Time, primitive.ObjectID, error)) error {
+	query := repository.ActivityQuery{UserID: userID, Limit: exportPageSize}
+	for {
+		n, lastTime, lastID, err := page(query)
+		if err != nil {
+			return err
+		}
+		if n < exportPageSize {
+			return nil
+		}
+		query.BeforeTime = lastTime
+		query.BeforeID = lastID
+	}
+}
+
+func (s *DataExportService) sign(export *models.DataExport) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("export:" + export.ID.Hex() + ":" + strconv.FormatInt(export.ExpiresAt.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *DataExportService) archivePath(id primitive.ObjectID) string {
+	return filepath.Join(s.cfg.Dir, id.Hex()+".zip")
+}
+
+func (s *DataExportService) removeArchive(id primitive.ObjectID) {
+	if err := os.Remove(s.archivePath(id)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to delete export archive %s: %v", id.Hex(), err)
+	}
+}
+
+func (s *DataExportService) linkTTL() time.Duration {
+	if s.cfg.LinkTTL <= 0 {
+		return 72 * time.Hour
+	}
+	return s.cfg.LinkTTL
+}
+
+func newExportProfile(user *models.User) exportProfile {
+	profile := exportProfile{
+		ID:                           user.ID.Hex(),
+		Email:                        user.Email,
+		DisplayName:                  user.DisplayName,
+		Role:                         string(user.Role),
+		Bio:                          user.Bio,
+		ProfileImage:                 user.ProfileImage,
+		EmailVerified:                user.EmailVerified,
+		TwoFactorEnabled:             user.TwoFactor != nil && user.TwoFactor.Enabled,
+		SubscribedCategories:         user.SubscribedCategories,
+		SubscribedTags:               user.SubscribedTags,
+		DisabledNotificationChannels: user.DisabledNotificationChannels,
+		Privacy:                      user.Privacy.Normalized(),
+		PostCount:                    user.PostCount,
+		CommentCount:                 user.CommentCount,
+		FollowerCount:                user.FollowerCount,
+		FollowingCount:               user.FollowingCount,
+		CreatedAt:                    user.CreatedAt,
+		LastLoginAt:                  user.LastLoginAt,
+	}
+	for _, identity := range user.Identities {
+		profile.LinkedAccounts = append(profile.LinkedAccounts, exportLinkedAccount{
+			Issuer:   identity.Issuer,
+			Email:    identity.Email,
+			LinkedAt: identity.LinkedAt,
+		})
+	}
+	return profile
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+func writeJSONEntry(archive *zip.Writer, name string, v interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeFileEntry copies a file into the archive. Files that no longer
+// exist are skipped.
+func writeFileEntry(archive *zip.Writer, name, source string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
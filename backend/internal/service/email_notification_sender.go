@@ -0,0 +1,58 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/mail"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+type notificationEmailData struct {
+	DisplayName  string
+	Title        string
+	Body         string
+	Link         string
+	SettingsLink string
+}
+
+// EmailNotificationSender mails notifications to users who confirmed their
+// address. Register it with NotificationService.AddSender.
+type EmailNotificationSender struct {
+	mailer  mail.Mailer
+	baseURL string
+}
+
+func NewEmailNotificationSender(mailer mail.Mailer, cfg *config.Config) *EmailNotificationSender {
+	return &EmailNotificationSender{
+		mailer:  mailer,
+		baseURL: publicBaseURL(cfg),
+	}
+}
+
+func (s *EmailNotificationSender) Channel() string {
+	return models.NotificationChannelEmail
+}
+
+func (s *EmailNotificationSender) Send(user *models.User, notification *models.Notification) error {
+	if !user.EmailVerified {
+		return nil
+	}
+
+	link := notification.Link
+	if strings.HasPrefix(link, "/") {
+		link = s.baseURL + link
+	}
+
+	msg, err := mail.Render("notification", user.Email, notificationEmailData{
+		DisplayName:  user.DisplayName,
+		Title:        notification.Title,
+		Body:         notification.Body,
+		Link:         link,
+		SettingsLink: s.baseURL + "/profile.html",
+	})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(msg)
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/mail"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+var (
+	ErrEmailNotVerified         = errors.New("confirm your email address first")
+	ErrEmailAlreadyVerified     = errors.New("email is already verified")
+	ErrInvalidVerificationToken = errors.New("invalid verification link")
+	ErrVerificationTokenExpired = errors.New("verification link has expired")
+	ErrVerificationCooldown     = errors.New("a verification email was sent recently; try again in a minute")
+)
+
+const verificationResendCooldown = time.Minute
+
+type verificationEmailData struct {
+	DisplayName string
+	Link        string
+	ExpiresIn   string
+}
+
+// VerifyEmail confirms the address the token was issued for. A token stops
+// working when it expires or the user's email changes.
+func (s *AuthService) VerifyEmail(token string) (*models.User, error) {
+	userID, emailHash, expiresAt, err := s.parseVerificationToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || !hmac.Equal([]byte(emailHash), []byte(verificationEmailHash(user.Email))) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if user.EmailVerified {
+		return user, nil
+	}
+	if time.Now().After(expiresAt) {
+		return nil, ErrVerificationTokenExpired
+	}
+
+	s.markEmailVerified(user, time.Now())
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	s.notifyUpdated(user)
+
+	return user, nil
+}
+
+// markEmailVerified records the proven address and grants the role
+// sign-up held back for it.
+func (s *AuthService) markEmailVerified(user *models.User, now time.Time) {
+	user.EmailVerified = true
+	user.EmailVerifiedAt = &now
+	if user.PendingRole != "" {
+		user.Role, user.PendingRole = user.PendingRole, ""
+	}
+}
+
+// ResendVerification mails a fresh link, at most once a minute.
+func (s *AuthService) ResendVerification(userID primitive.ObjectID) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	if user.VerificationSentAt != nil && time.Since(*user.VerificationSentAt) < verificationResendCooldown {
+		return ErrVerificationCooldown
+	}
+
+	now := time.Now()
+	user.VerificationSentAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	return s.sendVerification(user)
+}
+
+func (s *AuthService) sendVerification(user *models.User) error {
+	ttl := s.verificationTTL()
+	link := publicBaseURL(s.cfg) + "/verify-email.html?token=" + s.verificationToken(user, time.Now().Add(ttl))
+
+	msg, err := mail.Render("verify_email", user.Email, verificationEmailData{
+		DisplayName: user.DisplayName,
+		Link:        link,
+		ExpiresIn:   describeTTL(ttl),
+	})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(msg)
+}
+
+// sendVerificationAsync keeps a slow mail server from holding up
+// registration; the user can ask for another link if this one is lost.
+func (s *AuthService) sendVerificationAsync(user *models.User) {
+	go func() {
+		if err := s.sendVerification(user); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", user.ID.Hex(), err)
+		}
+	}()
+}
+
+// verificationToken signs "<user id>.<expiry>.<email hash>" so links need
+// no storage.
+func (s *AuthService) verificationToken(user *models.User, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%s.%d.%s", user.ID.Hex(), expiresAt.Unix(), verificationEmailHash(user.Email))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.verificationSignature([]byte(payload)))
+}
+
+func (s *AuthService) parseVerificationToken(token string) (primitive.ObjectID, string, time.Time, error) {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return primitive.NilObjectID, "", time.Time{}, ErrInvalidVerificationToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return primitive.NilObjectID, "", time.Time{}, ErrInvalidVerificationToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, s.verificationSignature(payload)) {
+		return primitive.NilObjectID, "", time.Time{}, ErrInvalidVerificationToken
+	}
+
+	parts := strings.Split(string(payload), ".")
+	if len(parts) != 3 {
+		return primitive.NilObjectID, "", time.Time{}, ErrInvalidVerificationToken
+	}
+	userID, err := primitive.ObjectIDFromHex(parts[0])
+	if err != nil {
+		return primitive.NilObjectID, "", time.Time{}, ErrInvalidVerificationToken
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return primitive.NilObjectID, "", time.Time{}, ErrInvalidVerificationToken
+	}
+
+	return userID, parts[2], time.Unix(expires, 0), nil
+}
+
+// verificationSignature keys the HMAC on a value derived from the JWT
+// secret, so a verification token can never pass as anything else.
+func (s *AuthService) verificationSignature(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.derivedKey("email-verification"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (s *AuthService) verificationTTL() time.Duration {
+	if s.cfg.Mail.VerificationTTL <= 0 {
+		return 48 * time.Hour
+	}
+	return s.cfg.Mail.VerificationTTL
+}
+
+func verificationEmailHash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:8])
+}
+
+// publicBaseURL is the origin used for links in emails.
+func publicBaseURL(cfg *config.Config) string {
+	if cfg.Server.PublicURL != "" {
+		return strings.TrimRight(cfg.Server.PublicURL, "/")
+	}
+	return "http://localhost:" + cfg.Server.Port
+}
+
+func describeTTL(d time.Duration) string {
+	hours := int(d.Round(time.Hour) / time.Hour)
+	switch {
+	case d < 50*time.Minute:
+		return fmt.Sprintf("%d minutes", int(d/time.Minute))
+	case hours >= 24 && hours%24 == 0:
+		if hours == 24 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", hours/24)
+	case hours > 1:
+		return fmt.Sprintf("%d hours", hours)
+	case hours == 1:
+		return "1 hour"
+	default:
+		return fmt.Sprintf("%d minutes", int(d/time.Minute))
+	}
+}
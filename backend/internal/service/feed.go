@@ -0,0 +1,120 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	feedAuthorWeight   = 3.0
+	feedCategoryWeight = 1.0
+	feedTagWeight      = 1.5
+	feedHalfLifeHours  = 24.0
+)
+
+// FeedPage is one page of the personalized feed. NextCursor is empty when
+// there are no older posts.
+type FeedPage struct {
+	Posts      []*models.Post
+	NextCursor string
+}
+
+type feedCursor struct {
+	CreatedAt time.Time
+	ID        primitive.ObjectID
+}
+
+// encodeFeedCursor marks a position in a list ordered by creation time and
+// ID, newest first. The profile activity timeline uses it too.
+func encodeFeedCursor(createdAt time.Time, id primitive.ObjectID) string {
+	raw := strconv.FormatInt(createdAt.UnixMilli(), 10) + ":" + id.Hex()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(cursor string) (*feedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	millis, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &feedCursor{CreatedAt: time.UnixMilli(millis).UTC(), ID: id}, nil
+}
+
+// feedSources holds what a user follows, used to score feed candidates.
+type feedSources struct {
+	authors    map[primitive.ObjectID]bool
+	categories map[models.PostCategory]bool
+	tags       map[string]bool
+}
+
+func (fs *feedSources) empty() bool {
+	return len(fs.authors) == 0 && len(fs.categories) == 0 && len(fs.tags) == 0
+}
+
+func (fs *feedSources) score(post *models.Post, now time.Time) float64 {
+	affinity := 0.0
+	if fs.authors[post.AuthorID] {
+		affinity += feedAuthorWeight
+	}
+	if fs.categories[post.Category] {
+		affinity += feedCategoryWeight
+	}
+	for _, tag := range post.Tags {
+		if fs.tags[normalizeTag(tag)] {
+			affinity += feedTagWeight
+		}
+	}
+	if affinity == 0 {
+		affinity = feedCategoryWeight
+	}
+
+	ageHours := now.Sub(post.CreatedAt).Hours()
+	if ageHours < 0 {
+		ageHours = 0
+	}
+	recency := math.Pow(0.5, ageHours/feedHalfLifeHours)
+	engagement := math.Log1p(math.Max(post.PopularityScore, 0))
+
+	return affinity*(1+recency) + engagement
+}
+
+// rankFeedPage orders a chronological page by feed score. Ranking only
+// happens inside the page so the chronological cursor never skips or
+// repeats posts across pages.
+func rankFeedPage(posts []*models.Post, sources *feedSources) {
+	now := time.Now()
+	scores := make(map[primitive.ObjectID]float64, len(posts))
+	for _, post := range posts {
+		scores[post.ID] = sources.score(post, now)
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		return scores[posts[i].ID] > scores[posts[j].ID]
+	})
+}
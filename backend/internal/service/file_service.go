@@ -48,6 +48,12 @@ type UploadedFile struct {
 	CreatedAt    string
 }
 
+// AvatarSizes are the square renditions, in pixels, made of every avatar.
+var AvatarSizes = []int{64, 128, 256}
+
+// maxAvatarPixels bounds the images decoded for avatars.
+const maxAvatarPixels = 40_000_000
+
 type FileService struct {
 	uploadDir        string
 	tempDir          string
@@ -76,7 +82,7 @@ func NewFileService(cfg config.UploadConfig) *FileService {
 		tempDir = "/data/temp"
 	}
 
-	for _, mediaType := range []string{"images", "videos", "documents", "temp"} {
+	for _, mediaType := range []string{"images", "videos", "documents", "avatars", "temp"} {
 		os.MkdirAll(filepath.Join(uploadDir, mediaType), 0755)
 	}
 
@@ -433,6 +439,90 @@ func (fs *FileService) DeleteFile(fileURL string) error {
 	return nil
 }
 
+// SaveAvatar validates an upload like any other file, crops it to crop
+// (the centered square when crop is empty) and writes the square
+// renditions. It returns the key that names them.
+func (fs *FileService) SaveAvatar(fileHeader *multipart.FileHeader, crop image.Rectangle) (string, error) {
+	if err := fs.validateFile(fileHeader); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidAvatar, err)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	fileBytes, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	if _, mediaType := fs.detectFileType(fileBytes, fileHeader.Filename); mediaType != MediaTypeImage {
+		return "", fmt.Errorf("%w: not an image", ErrInvalidAvatar)
+	}
+
+	// Check the dimensions before decoding so a small file cannot expand
+	// into a huge bitmap
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(fileBytes))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidAvatar, err)
+	}
+	if cfg.Width*cfg.Height > maxAvatarPixels {
+		return "", fmt.Errorf("%w: larger than %d megapixels", ErrInvalidAvatar, maxAvatarPixels/1_000_000)
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(fileBytes), imaging.AutoOrientation(true))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidAvatar, err)
+	}
+	if !crop.Empty() {
+		bounds := img.Bounds()
+		crop = crop.Add(bounds.Min)
+		if !crop.In(bounds) {
+			return "", ErrInvalidCrop
+		}
+		img = imaging.Crop(img, crop)
+	}
+
+	key := uuid.New().String()
+	for _, size := range AvatarSizes {
+		// Fill keeps the center of a crop that is not quite square
+		rendition := imaging.Fill(img, size, size, imaging.Center, imaging.Lanczos)
+		if err := imaging.Save(rendition, fs.avatarPath(key, size)); err != nil {
+			fs.DeleteAvatar(key)
+			return "", fmt.Errorf("failed to save avatar: %v", err)
+		}
+	}
+	return key, nil
+}
+
+func (fs *FileService) AvatarURL(key string, size int) string {
+	return fmt.Sprintf("%s/avatars/%s_%d.png", fs.serveURL, key, size)
+}
+
+// DeleteAvatar removes every rendition of the avatar.
+func (fs *FileService) DeleteAvatar(key string) {
+	for _, size := range AvatarSizes {
+		if err := os.Remove(fs.avatarPath(key, size)); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Failed to delete avatar %s: %v\n", key, err)
+		}
+	}
+}
+
+func (fs *FileService) avatarPath(key string, size int) string {
+	return filepath.Join(fs.uploadDir, "avatars", fmt.Sprintf("%s_%d.png", key, size))
+}
+
+// LocalPath maps the URL of an uploaded file to its path on disk. It
+// reports false for other URLs, such as linked images.
+func (fs *FileService) LocalPath(fileURL string) (string, bool) {
+	rel, ok := strings.CutPrefix(fileURL, fs.serveURL+"/")
+	if !ok || rel == "" || strings.Contains(rel, "..") {
+		return "", false
+	}
+	return filepath.Join(fs.uploadDir, filepath.FromSlash(rel)), true
+}
+
 func (fs *FileService) GetFileInfo(fileURL string) (*UploadedFile, error) {
 	parts := strings.Split(fileURL, "/")
 	if len(parts) < 3 {
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/search"
+)
+
+var (
+	ErrCannotFollowSelf = errors.New("cannot follow yourself")
+	ErrAlreadyFollowing = errors.New("already following this user")
+	ErrNotFollowing     = errors.New("not following this user")
+	ErrInvalidCategory  = errors.New("invalid category")
+	ErrInvalidTag       = errors.New("invalid tag")
+)
+
+var validCategories = map[models.PostCategory]bool{
+	models.CategoryMeme:      true,
+	models.CategoryEvent:     true,
+	models.CategoryNews:      true,
+	models.CategoryQuestion:  true,
+	models.CategoryLostFound: true,
+	models.CategoryAcademic:  true,
+	models.CategorySocial:    true,
+	models.CategorySports:    true,
+}
+
+type FollowService struct {
+	followRepo repository.FollowRepository
+	userRepo   repository.UserRepository
+}
+
+func NewFollowService(followRepo repository.FollowRepository, userRepo repository.UserRepository) *FollowService {
+	return &FollowService{
+		followRepo: followRepo,
+		userRepo:   userRepo,
+	}
+}
+
+func (s *FollowService) Follow(followerID, targetID primitive.ObjectID) error {
+	if followerID == targetID {
+		return ErrCannotFollowSelf
+	}
+
+	target, err := s.userRepo.FindByID(targetID)
+	if err != nil || !target.IsActive {
+		return ErrUserNotFound
+	}
+
+	exists, err := s.followRepo.Exists(followerID, targetID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrAlreadyFollowing
+	}
+
+	if err := s.followRepo.Create(models.NewFollow(followerID, targetID)); err != nil {
+		// A concurrent request followed first
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrAlreadyFollowing
+		}
+		return err
+	}
+
+	incrementFollowCounts(s.userRepo, followerID, 0, 1)
+	incrementFollowCounts(s.userRepo, targetID, 1, 0)
+
+	return nil
+}
+
+func (s *FollowService) Unfollow(followerID, targetID primitive.ObjectID) error {
+	deleted, err := s.followRepo.Delete(followerID, targetID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFollowing
+	}
+
+	incrementFollowCounts(s.userRepo, followerID, 0, -1)
+	incrementFollowCounts(s.userRepo, targetID, -1, 0)
+
+	return nil
+}
+
+// incrementFollowCounts adjusts the user's cached counts. The follow itself
+// is already stored, so a failure is only logged.
+func incrementFollowCounts(userRepo repository.UserRepository, userID primitive.ObjectID, followers, following int) {
+	if err := userRepo.IncrementFollowCounts(userID, followers, following); err != nil {
+		log.Printf("Failed to update follow counts of %s: %v", userID.Hex(), err)
+	}
+}
+
+func (s *FollowService) IsFollowing(followerID, targetID primitive.ObjectID) (bool, error) {
+	return s.followRepo.Exists(followerID, targetID)
+}
+
+func (s *FollowService) GetFollowers(userID primitive.ObjectID, limit, offset int) ([]*models.User, int, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, 0, ErrUserNotFound
+	}
+
+	follows, err := s.followRepo.FindFollowers(userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(follows))
+	for _, follow := range follows {
+		ids = append(ids, follow.FollowerID)
+	}
+
+	users, err := s.orderedUsers(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, user.FollowerCount, nil
+}
+
+func (s *FollowService) GetFollowing(userID primitive.ObjectID, limit, offset int) ([]*models.User, int, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, 0, ErrUserNotFound
+	}
+
+	follows, err := s.followRepo.FindFollowing(userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(follows))
+	for _, follow := range follows {
+		ids = append(ids, follow.FollowingID)
+	}
+
+	users, err := s.orderedUsers(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, user.FollowingCount, nil
+}
+
+func (s *FollowService) SubscribeCategory(userID primitive.ObjectID, category string) (*models.User, error) {
+	if !validCategories[models.PostCategory(category)] {
+		return nil, ErrInvalidCategory
+	}
+	return subscriptionResult(s.userRepo.AddSubscription(userID, repository.SubscribedCategories, category))
+}
+
+func (s *FollowService) UnsubscribeCategory(userID primitive.ObjectID, category string) (*models.User, error) {
+	return subscriptionResult(s.userRepo.RemoveSubscription(userID, repository.SubscribedCategories, category))
+}
+
+func (s *FollowService) SubscribeTag(userID primitive.ObjectID, tag string) (*models.User, error) {
+	tag = normalizeTag(tag)
+	if tag == "" {
+		return nil, ErrInvalidTag
+	}
+	return subscriptionResult(s.userRepo.AddSubscription(userID, repository.SubscribedTags, tag))
+}
+
+func (s *FollowService) UnsubscribeTag(userID primitive.ObjectID, tag string) (*models.User, error) {
+	return subscriptionResult(s.userRepo.RemoveSubscription(userID, repository.SubscribedTags, normalizeTag(tag)))
+}
+
+func subscriptionResult(user *models.User, err error) (*models.User, error) {
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// orderedUsers loads users by ID and keeps the order of ids, dropping
+// accounts that no longer exist.
+func (s *FollowService) orderedUsers(ids []primitive.ObjectID) ([]*models.User, error) {
+	users, err := s.userRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	ordered := make([]*models.User, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := byID[id]; ok {
+			ordered = append(ordered, user)
+		}
+	}
+
+	return ordered, nil
+}
+
+// normalizeTag strips the leading # and folds case and diacritics, so
+// "#Café" and "cafe" are the same tag.
+func normalizeTag(tag string) string {
+	return search.Fold(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+}
+
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+// racingFollows misses follows created since the check, as when two
+// requests follow at once.
+type racingFollows struct {
+	*memFollows
+}
+
+func (r racingFollows) Exists(followerID, followingID primitive.ObjectID) (bool, error) {
+	return false, nil
+}
+
+func TestFollowTwiceAtOnce(t *testing.T) {
+	follower := newTestUser("follower@astanait.edu.kz", "password123", models.RoleStudent)
+	target := newTestUser("target@astanait.edu.kz", "password123", models.RoleStudent)
+	users := newMemUsers(follower, target)
+	s := NewFollowService(racingFollows{&memFollows{}}, users)
+
+	if err := s.Follow(follower.ID, target.ID); err != nil {
+		t.Fatalf("first follow: %v", err)
+	}
+	if err := s.Follow(follower.ID, target.ID); !errors.Is(err, ErrAlreadyFollowing) {
+		t.Fatalf("second follow: err = %v, want ErrAlreadyFollowing", err)
+	}
+
+	// The losing request leaves the counts alone
+	if follower.FollowingCount != 1 || target.FollowerCount != 1 {
+		t.Errorf("counts = following %d, followers %d; want 1, 1", follower.FollowingCount, target.FollowerCount)
+	}
+}
+
+// wholeUserWrites fails every full-document update, which would overwrite
+// follow counts changed by concurrent requests.
+type wholeUserWrites struct {
+	*memUsers
+}
+
+func (r wholeUserWrites) Update(user *models.User) error {
+	return errors.New("subscriptions must not rewrite the whole user")
+}
+
+func TestSubscriptionsUpdateInPlace(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "password123", models.RoleStudent)
+	user.SubscribedTags = []string{"robotics"}
+	s := NewFollowService(&memFollows{}, wholeUserWrites{newMemUsers(user)})
+
+	if _, err := s.SubscribeCategory(user.ID, string(models.CategoryEvent)); err != nil {
+		t.Fatalf("SubscribeCategory: %v", err)
+	}
+	if _, err := s.SubscribeCategory(user.ID, string(models.CategoryEvent)); err != nil {
+		t.Fatalf("subscribing again: %v", err)
+	}
+	updated, err := s.SubscribeTag(user.ID, " #Hackathon ")
+	if err != nil {
+		t.Fatalf("SubscribeTag: %v", err)
+	}
+	if len(updated.SubscribedCategories) != 1 || len(updated.SubscribedTags) != 2 || updated.SubscribedTags[1] != "hackathon" {
+		t.Errorf("subscriptions = %v %v, want [event] [robotics hackathon]", updated.SubscribedCategories, updated.SubscribedTags)
+	}
+
+	if updated, err = s.UnsubscribeTag(user.ID, "ROBOTICS"); err != nil {
+		t.Fatalf("UnsubscribeTag: %v", err)
+	}
+	if updated, err = s.UnsubscribeCategory(user.ID, string(models.CategoryEvent)); err != nil {
+		t.Fatalf("UnsubscribeCategory: %v", err)
+	}
+	if len(updated.SubscribedCategories) != 0 || len(updated.SubscribedTags) != 1 || updated.SubscribedTags[0] != "hackathon" {
+		t.Errorf("subscriptions = %v %v, want [] [hackathon]", updated.SubscribedCategories, updated.SubscribedTags)
+	}
+
+	if _, err := s.SubscribeCategory(user.ID, "gossip"); !errors.Is(err, ErrInvalidCategory) {
+		t.Errorf("unknown category: err = %v, want ErrInvalidCategory", err)
+	}
+	if _, err := s.SubscribeTag(user.ID, " # "); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("empty tag: err = %v, want ErrInvalidTag", err)
+	}
+	if _, err := s.SubscribeTag(primitive.NewObjectID(), "hackathon"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("unknown user: err = %v, want ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/search"
+)
+
+type matchRange struct {
+	start, end int
+}
+
+// termMatcher decides whether a word of a result matches the query, using
+// the same analysis as the index so stems and transliterations light up.
+type termMatcher struct {
+	analyzer search.Analyzer
+	terms    map[string]bool
+	prefixes []string
+}
+
+func newTermMatcher(query string, analyzer search.Analyzer) *termMatcher {
+	terms, prefixes := search.QueryTerms(query, analyzer)
+	return &termMatcher{analyzer: analyzer, terms: terms, prefixes: prefixes}
+}
+
+func (m *termMatcher) empty() bool {
+	return len(m.terms) == 0 && len(m.prefixes) == 0
+}
+
+func (m *termMatcher) matches(word string) bool {
+	for _, token := range m.analyzer.Analyze(word) {
+		if m.terms[token.Term] {
+			return true
+		}
+	}
+	if len(m.prefixes) > 0 {
+		for _, form := range m.analyzer.Normalize(word) {
+			for _, prefix := range m.prefixes {
+				if strings.HasPrefix(form, prefix) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// findMatches returns the rune ranges of words matching the query.
+func (m *termMatcher) findMatches(runes []rune) []matchRange {
+	if m.empty() {
+		return nil
+	}
+
+	isWordRune := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	var matches []matchRange
+	for i := 0; i < len(runes); i++ {
+		if !isWordRune(runes[i]) {
+			continue
+		}
+		end := i
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if m.matches(string(runes[i:end])) {
+			matches = append(matches, matchRange{start: i, end: end})
+		}
+		i = end
+	}
+
+	return matches
+}
+
+func (m *termMatcher) contains(text string) bool {
+	return len(m.findMatches([]rune(text))) > 0
+}
+
+// highlight escapes text and marks matched words. When maxLen is positive
+// the text is cut to a window of about maxLen runes around the first match.
+func (m *termMatcher) highlight(text string, maxLen int) string {
+	runes := []rune(text)
+	matches := m.findMatches(runes)
+
+	start, end := 0, len(runes)
+	if maxLen > 0 && len(runes) > maxLen {
+		if len(matches) > 0 {
+			start = matches[0].start - maxLen/4
+			if start < 0 {
+				start = 0
+			}
+			for start > 0 && !unicode.IsSpace(runes[start-1]) && matches[0].start-start < maxLen/2 {
+				start--
+			}
+		}
+		end = start + maxLen
+		if end > len(runes) {
+			end = len(runes)
+			start = end - maxLen
+		}
+		for end < len(runes) && !unicode.IsSpace(runes[end]) && end-start < maxLen+20 {
+			end++
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	pos := start
+	for _, m := range matches {
+		if m.end <= start || m.start >= end {
+			continue
+		}
+		mStart, mEnd := max(m.start, start), min(m.end, end)
+		b.WriteString(html.EscapeString(string(runes[pos:mStart])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[mStart:mEnd])))
+		b.WriteString("</mark>")
+		pos = mEnd
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/search"
+)
+
+func TestHighlightMarksMatches(t *testing.T) {
+	tests := []struct {
+		query, text, want string
+	}{
+		{"hackathon", "Spring Hackathon results", "Spring <mark>Hackathon</mark> results"},
+		// Word forms and transliterations light up like they match
+		{"студент", "Для студентов", "Для <mark>студентов</mark>"},
+		{"almaty", "Марафон в Алматы", "Марафон в <mark>Алматы</mark>"},
+		{"lib*", "Library hours", "<mark>Library</mark> hours"},
+		// Text around matches is escaped
+		{"club", "<b>Chess</b> club & more", "&lt;b&gt;Chess&lt;/b&gt; <mark>club</mark> &amp; more"},
+		{"", "Nothing <here>", "Nothing &lt;here&gt;"},
+	}
+	for _, tt := range tests {
+		matcher := newTermMatcher(tt.query, search.TextAnalyzer{})
+		if got := matcher.highlight(tt.text, 0); got != tt.want {
+			t.Errorf("highlight(%q, %q) = %q, want %q", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightSnippetAroundFirstMatch(t *testing.T) {
+	text := strings.Repeat("filler words here ", 20) + "the robotics lab is open " + strings.Repeat("more words after ", 20)
+	got := newTermMatcher("robotics", search.TextAnalyzer{}).highlight(text, 60)
+
+	if !strings.Contains(got, "<mark>robotics</mark>") {
+		t.Fatalf("snippet misses the match: %q", got)
+	}
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("cut snippet is not marked as cut: %q", got)
+	}
+	if n := len([]rune(got)); n > 60+20+len("<mark></mark>")+2 {
+		t.Errorf("snippet has %d runes, want about 60", n)
+	}
+}
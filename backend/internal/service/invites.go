@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
+)
+
+var (
+	ErrInviteNotFound    = errors.New("invite not found")
+	ErrInvalidInviteRole = errors.New("invite role must be student, alumni or moderator")
+)
+
+type InviteInput struct {
+	Email string
+	Role  string
+	Note  string
+}
+
+// CreateInvite returns the invite and its code. Only the hash is stored, so
+// the code cannot be shown again.
+func (s *AuthService) CreateInvite(adminID primitive.ObjectID, input InviteInput) (*models.Invite, string, error) {
+	admin, err := s.userRepo.FindByID(adminID)
+	if err != nil || s.policy.Authorize(admin, policy.InvitesManage, nil) != nil {
+		return nil, "", ErrPermissionDenied
+	}
+
+	invite := models.NewInvite("", adminID, s.inviteTTL())
+	if email := strings.TrimSpace(input.Email); email != "" {
+		if err := s.validateEmail(email); err != nil {
+			return nil, "", err
+		}
+		invite.Email = email
+	}
+	if input.Role != "" {
+		role := models.UserRole(input.Role)
+		if !isRegistrableRole(role) {
+			return nil, "", ErrInvalidInviteRole
+		}
+		invite.Role = role
+	}
+	if note := strings.TrimSpace(input.Note); note != "" {
+		if runes := []rune(note); len(runes) > 200 {
+			note = string(runes[:200])
+		}
+		invite.Note = note
+	}
+
+	code, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	invite.CodeHash = hashToken(code)
+
+	if err := s.inviteRepo.Create(invite); err != nil {
+		return nil, "", err
+	}
+	return invite, code, nil
+}
+
+func (s *AuthService) ListInvites(limit, offset int) ([]*models.Invite, error) {
+	return s.inviteRepo.FindAll(limit, offset)
+}
+
+func (s *AuthService) DeleteInvite(inviteID primitive.ObjectID) error {
+	if _, err := s.inviteRepo.FindByID(inviteID); err != nil {
+		return ErrInviteNotFound
+	}
+	return s.inviteRepo.Delete(inviteID)
+}
+
+// InviteLink is the sign-up page with the code filled in.
+func (s *AuthService) InviteLink(code string) string {
+	return publicBaseURL(s.cfg) + "/register.html?invite=" + code
+}
+
+// findInvite resolves a presented code; an empty code means no invite.
+func (s *AuthService) findInvite(code string) (*models.Invite, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, nil
+	}
+	invite, err := s.inviteRepo.FindByCodeHash(hashToken(code))
+	if err != nil || !invite.IsUsable(time.Now()) {
+		return nil, ErrInvalidInvite
+	}
+	return invite, nil
+}
+
+func (s *AuthService) inviteTTL() time.Duration {
+	if s.cfg.Register.InviteTTL <= 0 {
+		return 14 * 24 * time.Hour
+	}
+	return s.cfg.Register.InviteTTL
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/mail"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
+)
+
+var (
+	ErrTooManyLoginAttempts = errors.New("too many failed sign-in attempts; wait a moment before trying again")
+	ErrAccountLocked        = errors.New("account is temporarily locked after too many failed sign-in attempts; reset your password to unlock it now")
+	ErrLoginLockNotFound    = errors.New("login lock not found")
+)
+
+// LoginThrottledError is ErrTooManyLoginAttempts or ErrAccountLocked with
+// the time until the next attempt is allowed.
+type LoginThrottledError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return e.Err
+}
+
+// LoginLock is a throttle record as admins see it.
+type LoginLock struct {
+	*models.LoginThrottle
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+type accountLockedEmailData struct {
+	DisplayName string
+	LockedFor   string
+	Link        string
+}
+
+// checkLoginThrottle runs before the password is looked at, so a locked
+// account answers the same whether or not the guess was right.
+func (s *AuthService) checkLoginThrottle(email, ip string, now time.Time) error {
+	throttles, err := s.loginThrottleRepo.FindByKeys(loginThrottleKeys(email, ip))
+	if err != nil {
+		// Sign-in stays available when the counters cannot be read
+		log.Printf("Failed to read login throttles: %v", err)
+		return nil
+	}
+
+	var wait time.Duration
+	for _, throttle := range throttles {
+		if throttle.Kind == models.LoginThrottleAccount && throttle.IsLocked(now) {
+			return &LoginThrottledError{Err: ErrAccountLocked, RetryAfter: throttle.LockedUntil.Sub(now)}
+		}
+		if w := s.loginRetryAfter(throttle, now); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		return &LoginThrottledError{Err: ErrTooManyLoginAttempts, RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed password for the email and the IP.
+// user is nil when no account has the email; it is counted all the same so
+// lockouts do not reveal which emails are registered.
+func (s *AuthService) recordLoginFailure(email string, user *models.User, ip string, now time.Time) {
+	window := s.loginFailureWindow()
+
+	account := &models.LoginThrottle{
+		Key:           accountThrottleKey(email),
+		Kind:          models.LoginThrottleAccount,
+		LastFailureAt: now,
+	}
+	if user != nil {
+		account.UserID = &user.ID
+		account.Email = user.Email
+	}
+	updated, err := s.loginThrottleRepo.RecordFailure(account, window)
+	if err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+	} else if threshold := s.cfg.Auth.LoginLockoutThreshold; threshold > 0 && updated.Failures >= threshold && !updated.IsLocked(now) {
+		until := now.Add(s.loginLockoutDuration())
+		if err := s.loginThrottleRepo.Lock(updated.Key, until); err != nil {
+			log.Printf("Failed to lock account after failed logins: %v", err)
+		} else if user != nil {
+			go func() {
+				if err := s.sendAccountLocked(user); err != nil {
+					log.Printf("Failed to send account locked email to %s: %v", user.ID.Hex(), err)
+				}
+			}()
+		}
+	}
+
+	if ip != "" {
+		_, err := s.loginThrottleRepo.RecordFailure(&models.LoginThrottle{
+			Key:           ipThrottleKey(ip),
+			Kind:          models.LoginThrottleIP,
+			IP:            ip,
+			LastFailureAt: now,
+		}, window)
+		if err != nil {
+			log.Printf("Failed to record login failure: %v", err)
+		}
+	}
+}
+
+// clearLoginFailures forgets the failures of an email, e.g. after a
+// successful login or a password reset. Those of the IP stay, so one known
+// password cannot be used to keep guessing others.
+func (s *AuthService) clearLoginFailures(email string) {
+	if err := s.loginThrottleRepo.Clear(accountThrottleKey(email)); err != nil {
+		log.Printf("Failed to clear login failures: %v", err)
+	}
+}
+
+// ListLoginLocks lists the accounts and IPs that are locked or being slowed
+// down, most recent failure first.
+func (s *AuthService) ListLoginLocks(limit, offset int) ([]LoginLock, error) {
+	minFailures := s.cfg.Auth.LoginBackoffAfter
+	if ip := s.cfg.Auth.LoginIPBackoffAfter; ip < minFailures {
+		minFailures = ip
+	}
+	if minFailures < 1 {
+		minFailures = 1
+	}
+
+	throttles, err := s.loginThrottleRepo.FindRecent(minFailures, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	locks := make([]LoginLock, 0, len(throttles))
+	for _, throttle := range throttles {
+		lock := LoginLock{LoginThrottle: throttle, RetryAfter: s.loginRetryAfter(throttle, now)}
+		if throttle.Kind == models.LoginThrottleAccount && throttle.IsLocked(now) {
+			lock.Locked = true
+			lock.RetryAfter = throttle.LockedUntil.Sub(now)
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// ClearLoginLock lifts a lock or backoff and forgets its failures.
+func (s *AuthService) ClearLoginLock(adminID, lockID primitive.ObjectID) error {
+	admin, err := s.userRepo.FindByID(adminID)
+	if err != nil || s.policy.Authorize(admin, policy.UsersUnlock, nil) != nil {
+		return ErrPermissionDenied
+	}
+
+	deleted, err := s.loginThrottleRepo.DeleteByID(lockID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrLoginLockNotFound
+	}
+	return nil
+}
+
+// loginRetryAfter is how long the key must wait: nothing for the first few
+// failures, then a delay doubling with each failure.
+func (s *AuthService) loginRetryAfter(throttle *models.LoginThrottle, now time.Time) time.Duration {
+	free := s.cfg.Auth.LoginBackoffAfter
+	if throttle.Kind == models.LoginThrottleIP {
+		free = s.cfg.Auth.LoginIPBackoffAfter
+	}
+	if free <= 0 || throttle.Failures < free {
+		return 0
+	}
+
+	base, max := s.cfg.Auth.LoginBackoffBase, s.cfg.Auth.LoginBackoffMax
+	if base <= 0 {
+		base = time.Second
+	}
+	if max <= 0 {
+		max = 15 * time.Minute
+	}
+	delay := base
+	for i := free; i < throttle.Failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	if wait := throttle.LastFailureAt.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// sendAccountLocked tells the owner about the lock. The email carries a
+// reset link, which unlocks the account, so an attacker cannot keep the
+// owner out by locking it.
+func (s *AuthService) sendAccountLocked(user *models.User) error {
+	link, _, err := s.newPasswordResetLink(user)
+	if err != nil {
+		return err
+	}
+	if link == "" {
+		// Too many reset links were sent already; the forgot form still works
+		link = publicBaseURL(s.cfg) + "/forgot-password.html"
+	}
+
+	msg, err := mail.Render("account_locked", user.Email, accountLockedEmailData{
+		DisplayName: user.DisplayName,
+		LockedFor:   describeTTL(s.loginLockoutDuration()),
+		Link:        link,
+	})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(msg)
+}
+
+func (s *AuthService) loginLockoutDuration() time.Duration {
+	if s.cfg.Auth.LoginLockoutDuration <= 0 {
+		return 30 * time.Minute
+	}
+	return s.cfg.Auth.LoginLockoutDuration
+}
+
+func (s *AuthService) loginFailureWindow() time.Duration {
+	if s.cfg.Auth.LoginFailureWindow <= 0 {
+		return time.Hour
+	}
+	return s.cfg.Auth.LoginFailureWindow
+}
+
+func loginThrottleKeys(email, ip string) []string {
+	keys := []string{accountThrottleKey(email)}
+	if ip != "" {
+		keys = append(keys, ipThrottleKey(ip))
+	}
+	return keys
+}
+
+// accountThrottleKey hashes the normalized email, so attempts at unknown
+// addresses do not store them.
+func accountThrottleKey(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return models.LoginThrottleAccount + ":" + hex.EncodeToString(sum[:])
+}
+
+func ipThrottleKey(ip string) string {
+	return models.LoginThrottleIP + ":" + ip
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+func TestLoginRetryAfterGrows(t *testing.T) {
+	f := newAuthFixture(nil)
+	now := time.Now()
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{8, 32 * time.Second},
+		{9, time.Minute},
+		{50, time.Minute},
+	}
+	for _, tt := range tests {
+		throttle := &models.LoginThrottle{Kind: models.LoginThrottleAccount, Failures: tt.failures, LastFailureAt: now}
+		if got := f.service.loginRetryAfter(throttle, now); got != tt.want {
+			t.Errorf("%d failures: wait %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	// The wait counts from the last failure
+	throttle := &models.LoginThrottle{Kind: models.LoginThrottleAccount, Failures: 4, LastFailureAt: now.Add(-1500 * time.Millisecond)}
+	if got := f.service.loginRetryAfter(throttle, now); got != 500*time.Millisecond {
+		t.Errorf("wait after 1.5s = %v, want 500ms", got)
+	}
+}
+
+func TestLoginBacksOffAfterFailures(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "correct-password", models.RoleStudent)
+	// A long base keeps slow password hashing from outlasting the wait
+	cfg := testConfig()
+	cfg.Auth.LoginBackoffBase = time.Minute
+	cfg.Auth.LoginBackoffMax = time.Hour
+	f := newAuthFixture(cfg, user)
+	client := SessionClient{IP: "198.51.100.1"}
+
+	for i := 0; i < 3; i++ {
+		_, err := f.service.Login(dto.LoginRequest{Email: user.Email, Password: "wrong"}, client)
+		if !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidCredentials", i+1, err)
+		}
+	}
+
+	// Even the right password waits, so guesses cannot be told apart
+	_, err := f.service.Login(dto.LoginRequest{Email: user.Email, Password: "correct-password"}, client)
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) || !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Fatalf("err = %v, want ErrTooManyLoginAttempts", err)
+	}
+	if throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Minute {
+		t.Errorf("RetryAfter = %v, want up to 1m", throttled.RetryAfter)
+	}
+}
+
+func TestLoginIPThrottleSpansAccounts(t *testing.T) {
+	f := newAuthFixture(nil)
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		f.service.recordLoginFailure("victim"+string(rune('a'+i))+"@example.com", nil, "203.0.113.5", now)
+	}
+
+	err := f.service.checkLoginThrottle("someone-new@example.com", "203.0.113.5", now)
+	if !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Errorf("same IP: err = %v, want ErrTooManyLoginAttempts", err)
+	}
+	if err := f.service.checkLoginThrottle("someone-new@example.com", "203.0.113.6", now); err != nil {
+		t.Errorf("other IP: err = %v, want nil", err)
+	}
+}
+
+func TestLoginLockoutEmailsOwnerAndResetUnlocks(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "correct-password", models.RoleStudent)
+	f := newAuthFixture(nil, user)
+
+	now := time.Now()
+	for i := 0; i < f.cfg.Auth.LoginLockoutThreshold; i++ {
+		f.service.recordLoginFailure(user.Email, user, "198.51.100.1", now)
+	}
+
+	token := receiveResetToken(t, f, user.Email)
+
+	_, err := f.service.Login(dto.LoginRequest{Email: user.Email, Password: "correct-password"}, SessionClient{IP: "198.51.100.2"})
+	if !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("locked login: err = %v, want ErrAccountLocked", err)
+	}
+
+	if err := f.service.ResetPassword(token, "brand-new-password"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	result, err := f.service.Login(dto.LoginRequest{Email: user.Email, Password: "brand-new-password"}, SessionClient{IP: "198.51.100.2"})
+	if err != nil {
+		t.Fatalf("login after reset: %v", err)
+	}
+	if result.Tokens == nil {
+		t.Error("login after reset issued no tokens")
+	}
+}
+
+func TestLoginLockIgnoresUnknownEmails(t *testing.T) {
+	f := newAuthFixture(nil)
+	now := time.Now()
+	for i := 0; i < f.cfg.Auth.LoginLockoutThreshold; i++ {
+		f.service.recordLoginFailure("nobody@example.com", nil, "", now)
+	}
+
+	// Unknown addresses lock like real ones but nobody is emailed
+	if err := f.service.checkLoginThrottle("nobody@example.com", "", now); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("err = %v, want ErrAccountLocked", err)
+	}
+	expectNoMail(t, f)
+}
@@ -0,0 +1,902 @@
+package service
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/mail"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+// In-memory stand-ins for the Mongo repositories. Each embeds its
+// interface, so a test calling a method the fake lacks panics instead of
+// passing silently.
+
+type memUsers struct {
+	repository.UserRepository
+	mu    sync.Mutex
+	users map[primitive.ObjectID]*models.User
+}
+
+func newMemUsers(users ...*models.User) *memUsers {
+	r := &memUsers{users: make(map[primitive.ObjectID]*models.User)}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *memUsers) FindByID(id primitive.ObjectID) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return user, nil
+}
+
+func (r *memUsers) FindByEmail(email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memUsers) FindByIDs(ids []primitive.ObjectID) ([]*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var users []*models.User
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *memUsers) FindByIdentity(issuer, subject string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		for _, identity := range user.Identities {
+			if identity.Issuer == issuer && identity.Subject == subject {
+				return user, nil
+			}
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memUsers) Create(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.ID = primitive.NewObjectID()
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *memUsers) Update(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *memUsers) Delete(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.users, id)
+	return nil
+}
+
+func (r *memUsers) FindDueDeletions(before time.Time, limit int) ([]*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var users []*models.User
+	for _, user := range r.users {
+		if user.Deletion != nil && user.Deletion.ScheduledFor.Before(before) && len(users) < limit {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *memUsers) IncrementFollowCounts(id primitive.ObjectID, followers, following int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.users[id]; ok {
+		user.FollowerCount += followers
+		user.FollowingCount += following
+	}
+	return nil
+}
+
+func (r *memUsers) AddSubscription(id primitive.ObjectID, list repository.SubscriptionList, value string) (*models.User, error) {
+	return r.updateSubscriptions(id, list, func(values []string) []string {
+		for _, existing := range values {
+			if existing == value {
+				return values
+			}
+		}
+		return append(values, value)
+	})
+}
+
+func (r *memUsers) RemoveSubscription(id primitive.ObjectID, list repository.SubscriptionList, value string) (*models.User, error) {
+	return r.updateSubscriptions(id, list, func(values []string) []string {
+		return filter(values, func(existing string) bool { return existing != value })
+	})
+}
+
+func (r *memUsers) updateSubscriptions(id primitive.ObjectID, list repository.SubscriptionList, update func([]string) []string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	switch list {
+	case repository.SubscribedCategories:
+		var values []string
+		for _, category := range user.SubscribedCategories {
+			values = append(values, string(category))
+		}
+		user.SubscribedCategories = nil
+		for _, value := range update(values) {
+			user.SubscribedCategories = append(user.SubscribedCategories, models.PostCategory(value))
+		}
+	case repository.SubscribedTags:
+		user.SubscribedTags = update(user.SubscribedTags)
+	}
+	return user, nil
+}
+
+type memLoginThrottles struct {
+	repository.LoginThrottleRepository
+	mu        sync.Mutex
+	throttles map[string]*models.LoginThrottle
+}
+
+func newMemLoginThrottles() *memLoginThrottles {
+	return &memLoginThrottles{throttles: make(map[string]*models.LoginThrottle)}
+}
+
+func (r *memLoginThrottles) FindByKeys(keys []string) ([]*models.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var throttles []*models.LoginThrottle
+	for _, key := range keys {
+		if throttle, ok := r.throttles[key]; ok {
+			copied := *throttle
+			throttles = append(throttles, &copied)
+		}
+	}
+	return throttles, nil
+}
+
+func (r *memLoginThrottles) RecordFailure(failure *models.LoginThrottle, window time.Duration) (*models.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	at := failure.LastFailureAt
+	throttle, ok := r.throttles[failure.Key]
+	if !ok {
+		throttle = &models.LoginThrottle{ID: primitive.NewObjectID(), Key: failure.Key}
+		r.throttles[failure.Key] = throttle
+	} else if throttle.LastFailureAt.Before(at.Add(-window)) && !throttle.IsLocked(at) {
+		throttle.Failures = 0
+		throttle.LockedUntil = nil
+	}
+	throttle.Kind = failure.Kind
+	throttle.Failures++
+	throttle.LastFailureAt = at
+	throttle.ExpiresAt = at.Add(window)
+	if failure.UserID != nil {
+		throttle.UserID = failure.UserID
+		throttle.Email = failure.Email
+	}
+	if failure.IP != "" {
+		throttle.IP = failure.IP
+	}
+	copied := *throttle
+	return &copied, nil
+}
+
+func (r *memLoginThrottles) Lock(key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if throttle, ok := r.throttles[key]; ok {
+		throttle.LockedUntil = &until
+	}
+	return nil
+}
+
+func (r *memLoginThrottles) Clear(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.throttles, key)
+	return nil
+}
+
+type memPasswordResets struct {
+	repository.PasswordResetRepository
+	mu     sync.Mutex
+	resets []*models.PasswordReset
+}
+
+func (r *memPasswordResets) Create(reset *models.PasswordReset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resets = append(r.resets, reset)
+	return nil
+}
+
+func (r *memPasswordResets) FindByHash(tokenHash string) (*models.PasswordReset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, reset := range r.resets {
+		if reset.TokenHash == tokenHash {
+			return reset, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memPasswordResets) CountByUserSince(userID primitive.ObjectID, since time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for _, reset := range r.resets {
+		if reset.UserID == userID && reset.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memPasswordResets) MarkUsed(id primitive.ObjectID, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, reset := range r.resets {
+		if reset.ID == id && reset.UsedAt == nil {
+			reset.UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memPasswordResets) InvalidateByUser(userID primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, reset := range r.resets {
+		if reset.UserID == userID && reset.UsedAt == nil {
+			reset.UsedAt = &at
+		}
+	}
+	return nil
+}
+
+type memRefreshTokens struct {
+	repository.RefreshTokenRepository
+	mu     sync.Mutex
+	tokens []*models.RefreshToken
+}
+
+func (r *memRefreshTokens) Create(token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *memRefreshTokens) FindByHash(tokenHash string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memRefreshTokens) MarkRotated(id primitive.ObjectID, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.ID == id && token.RotatedAt == nil && token.RevokedAt == nil {
+			token.RotatedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memRefreshTokens) RevokeFamily(familyID primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *memRefreshTokens) RevokeByUser(userID primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *memRefreshTokens) DeleteByUser(userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens = filter(r.tokens, func(token *models.RefreshToken) bool { return token.UserID != userID })
+	return nil
+}
+
+type memSessions struct {
+	repository.SessionRepository
+	mu       sync.Mutex
+	sessions []*models.Session
+}
+
+func (r *memSessions) Create(session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions = append(r.sessions, session)
+	return nil
+}
+
+func (r *memSessions) FindByID(id primitive.ObjectID) (*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.ID == id {
+			copied := *session
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memSessions) Extend(id primitive.ObjectID, ip string, at, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.ID == id {
+			session.IP = ip
+			session.LastSeenAt = at
+			session.ExpiresAt = expiresAt
+		}
+	}
+	return nil
+}
+
+func (r *memSessions) Revoke(id primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.ID == id && session.RevokedAt == nil {
+			session.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *memSessions) RevokeByUser(userID primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *memSessions) DeleteByUser(userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions = filter(r.sessions, func(session *models.Session) bool { return session.UserID != userID })
+	return nil
+}
+
+type memAccessTokens struct {
+	repository.AccessTokenRepository
+	mu     sync.Mutex
+	tokens []*models.AccessToken
+}
+
+func (r *memAccessTokens) DeleteByUser(userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens = filter(r.tokens, func(token *models.AccessToken) bool { return token.UserID != userID })
+	return nil
+}
+
+type memTwoFactorChallenges struct {
+	repository.TwoFactorChallengeRepository
+	mu         sync.Mutex
+	challenges []*models.TwoFactorChallenge
+}
+
+func (r *memTwoFactorChallenges) Create(challenge *models.TwoFactorChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.challenges = append(r.challenges, challenge)
+	return nil
+}
+
+func (r *memTwoFactorChallenges) FindByHash(tokenHash string) (*models.TwoFactorChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, challenge := range r.challenges {
+		if challenge.TokenHash == tokenHash {
+			copied := *challenge
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memTwoFactorChallenges) AddAttempt(id primitive.ObjectID, maxAttempts int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, challenge := range r.challenges {
+		if challenge.ID == id && challenge.Attempts < maxAttempts {
+			challenge.Attempts++
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memTwoFactorChallenges) MarkUsed(id primitive.ObjectID, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, challenge := range r.challenges {
+		if challenge.ID == id && challenge.UsedAt == nil {
+			challenge.UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memOIDCLogins struct {
+	repository.OIDCLoginRepository
+	mu     sync.Mutex
+	logins []*models.OIDCLogin
+}
+
+func (r *memOIDCLogins) Create(login *models.OIDCLogin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logins = append(r.logins, login)
+	return nil
+}
+
+func (r *memOIDCLogins) Consume(stateHash string) (*models.OIDCLogin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, login := range r.logins {
+		if login.StateHash == stateHash && login.UsedAt == nil && login.ExpiresAt.After(now) {
+			login.UsedAt = &now
+			copied := *login
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+type memPosts struct {
+	repository.PostRepository
+	mu    sync.Mutex
+	posts []*models.Post
+}
+
+func (r *memPosts) FindByID(id primitive.ObjectID) (*models.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, post := range r.posts {
+		if post.ID == id {
+			return post, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memPosts) FindByIDs(ids []primitive.ObjectID) ([]*models.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var posts []*models.Post
+	for _, post := range r.posts {
+		for _, id := range ids {
+			if post.ID == id {
+				posts = append(posts, post)
+				break
+			}
+		}
+	}
+	return posts, nil
+}
+
+func (r *memPosts) FindAllByAuthor(query repository.ActivityQuery) ([]*models.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	posts := filter(r.posts, func(post *models.Post) bool { return post.AuthorID == query.UserID })
+	return activityPage(posts, query, func(post *models.Post) (time.Time, primitive.ObjectID) { return post.CreatedAt, post.ID }), nil
+}
+
+// FindFeed only follows authors.
+func (r *memPosts) FindFeed(query repository.FeedQuery) ([]*models.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	posts := filter(r.posts, func(post *models.Post) bool {
+		if post.IsArchived {
+			return false
+		}
+		for _, id := range query.AuthorIDs {
+			if post.AuthorID == id {
+				return true
+			}
+		}
+		return false
+	})
+	window := repository.ActivityQuery{BeforeTime: query.BeforeTime, BeforeID: query.BeforeID, Limit: query.Limit}
+	return activityPage(posts, window, func(post *models.Post) (time.Time, primitive.ObjectID) { return post.CreatedAt, post.ID }), nil
+}
+
+func (r *memPosts) Update(post *models.Post) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.posts {
+		if existing.ID == post.ID {
+			r.posts[i] = post
+		}
+	}
+	return nil
+}
+
+func (r *memPosts) Delete(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.posts = filter(r.posts, func(post *models.Post) bool { return post.ID != id })
+	return nil
+}
+
+func (r *memPosts) DecrementLikeCount(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, post := range r.posts {
+		if post.ID == id {
+			post.LikeCount--
+		}
+	}
+	return nil
+}
+
+func (r *memPosts) DecrementCommentCount(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, post := range r.posts {
+		if post.ID == id {
+			post.CommentCount--
+		}
+	}
+	return nil
+}
+
+type memComments struct {
+	repository.CommentRepository
+	mu       sync.Mutex
+	comments []*models.Comment
+}
+
+func (r *memComments) FindByAuthor(query repository.ActivityQuery) ([]*models.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	comments := filter(r.comments, func(comment *models.Comment) bool { return comment.AuthorID == query.UserID })
+	return activityPage(comments, query, func(comment *models.Comment) (time.Time, primitive.ObjectID) { return comment.CreatedAt, comment.ID }), nil
+}
+
+func (r *memComments) Update(comment *models.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.comments {
+		if existing.ID == comment.ID {
+			r.comments[i] = comment
+		}
+	}
+	return nil
+}
+
+func (r *memComments) Delete(id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.comments = filter(r.comments, func(comment *models.Comment) bool { return comment.ID != id })
+	return nil
+}
+
+func (r *memComments) DeleteByPostID(postID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.comments = filter(r.comments, func(comment *models.Comment) bool { return comment.PostID != postID })
+	return nil
+}
+
+type memLikes struct {
+	repository.LikeRepository
+	mu    sync.Mutex
+	likes []*models.Like
+}
+
+func (r *memLikes) FindByUser(query repository.ActivityQuery) ([]*models.Like, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	likes := filter(r.likes, func(like *models.Like) bool { return like.UserID == query.UserID })
+	return activityPage(likes, query, func(like *models.Like) (time.Time, primitive.ObjectID) { return like.CreatedAt, like.ID }), nil
+}
+
+func (r *memLikes) Delete(userID, postID primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	before := len(r.likes)
+	r.likes = filter(r.likes, func(like *models.Like) bool { return like.UserID != userID || like.PostID != postID })
+	return len(r.likes) < before, nil
+}
+
+func (r *memLikes) DeleteByPostID(postID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.likes = filter(r.likes, func(like *models.Like) bool { return like.PostID != postID })
+	return nil
+}
+
+type memFollows struct {
+	repository.FollowRepository
+	mu      sync.Mutex
+	follows []*models.Follow
+}
+
+// Create rejects a repeated follow like the unique index does.
+func (r *memFollows) Create(follow *models.Follow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.follows {
+		if existing.FollowerID == follow.FollowerID && existing.FollowingID == follow.FollowingID {
+			return mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "duplicate key"}}}
+		}
+	}
+	r.follows = append(r.follows, follow)
+	return nil
+}
+
+func (r *memFollows) Exists(followerID, followingID primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, follow := range r.follows {
+		if follow.FollowerID == followerID && follow.FollowingID == followingID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memFollows) Delete(followerID, followingID primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	before := len(r.follows)
+	r.follows = filter(r.follows, func(follow *models.Follow) bool {
+		return follow.FollowerID != followerID || follow.FollowingID != followingID
+	})
+	return len(r.follows) < before, nil
+}
+
+func (r *memFollows) FindFollowers(userID primitive.ObjectID, limit, offset int) ([]*models.Follow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	follows := filter(r.follows, func(follow *models.Follow) bool { return follow.FollowingID == userID })
+	return activityPage(follows, repository.ActivityQuery{Limit: limit, Offset: offset}, followKey), nil
+}
+
+func (r *memFollows) FindFollowing(userID primitive.ObjectID, limit, offset int) ([]*models.Follow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	follows := filter(r.follows, func(follow *models.Follow) bool { return follow.FollowerID == userID })
+	return activityPage(follows, repository.ActivityQuery{Limit: limit, Offset: offset}, followKey), nil
+}
+
+func followKey(follow *models.Follow) (time.Time, primitive.ObjectID) {
+	return follow.CreatedAt, follow.ID
+}
+
+type memBookmarks struct {
+	repository.BookmarkRepository
+	mu        sync.Mutex
+	bookmarks []*models.Bookmark
+}
+
+func (r *memBookmarks) DeleteByPostID(postID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bookmarks = filter(r.bookmarks, func(bookmark *models.Bookmark) bool { return bookmark.PostID != postID })
+	return nil
+}
+
+func (r *memBookmarks) DeleteByUser(userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bookmarks = filter(r.bookmarks, func(bookmark *models.Bookmark) bool { return bookmark.UserID != userID })
+	return nil
+}
+
+type memBookmarkCollections struct {
+	repository.BookmarkCollectionRepository
+	mu          sync.Mutex
+	collections []*models.BookmarkCollection
+}
+
+func (r *memBookmarkCollections) DeleteByUser(userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collections = filter(r.collections, func(collection *models.BookmarkCollection) bool { return collection.UserID != userID })
+	return nil
+}
+
+type memEventAttendees struct {
+	repository.EventAttendeeRepository
+	mu        sync.Mutex
+	attendees []*models.EventAttendee
+}
+
+func (r *memEventAttendees) DeleteByUser(userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attendees = filter(r.attendees, func(attendee *models.EventAttendee) bool { return attendee.UserID != userID })
+	return nil
+}
+
+// filter returns the items keep accepts, in a new slice.
+func filter[T any](items []T, keep func(T) bool) []T {
+	var kept []T
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// activityPage orders items newest first and applies the query's cursor,
+// offset and limit the way the Mongo repositories do. The owner filter is
+// left to the caller.
+func activityPage[T any](items []T, query repository.ActivityQuery, key func(T) (time.Time, primitive.ObjectID)) []T {
+	if !query.BeforeTime.IsZero() {
+		items = filter(items, func(item T) bool {
+			createdAt, id := key(item)
+			return createdAt.Before(query.BeforeTime) ||
+				createdAt.Equal(query.BeforeTime) && bytes.Compare(id[:], query.BeforeID[:]) < 0
+		})
+	}
+
+	sorted := append([]T(nil), items...)
+	sort.Slice(sorted, func(i, j int) bool {
+		ti, idi := key(sorted[i])
+		tj, idj := key(sorted[j])
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return bytes.Compare(idi[:], idj[:]) > 0
+	})
+
+	if query.Offset >= len(sorted) {
+		return nil
+	}
+	sorted = sorted[query.Offset:]
+	if query.Limit > 0 && len(sorted) > query.Limit {
+		sorted = sorted[:query.Limit]
+	}
+	return sorted
+}
+
+// recordingMailer keeps sent messages; sent delivers them as they arrive,
+// for mail sent in the background.
+type recordingMailer struct {
+	sent chan mail.Message
+}
+
+func newRecordingMailer() *recordingMailer {
+	return &recordingMailer{sent: make(chan mail.Message, 16)}
+}
+
+func (m *recordingMailer) Send(msg mail.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+// authFixture is an AuthService over in-memory repositories.
+type authFixture struct {
+	service       *AuthService
+	users         *memUsers
+	throttles     *memLoginThrottles
+	resets        *memPasswordResets
+	refreshTokens *memRefreshTokens
+	sessions      *memSessions
+	challenges    *memTwoFactorChallenges
+	mailer        *recordingMailer
+	cfg           *config.Config
+}
+
+func newAuthFixture(cfg *config.Config, users ...*models.User) *authFixture {
+	if cfg == nil {
+		cfg = testConfig()
+	}
+	f := &authFixture{
+		users:         newMemUsers(users...),
+		throttles:     newMemLoginThrottles(),
+		resets:        &memPasswordResets{},
+		refreshTokens: &memRefreshTokens{},
+		sessions:      &memSessions{},
+		challenges:    &memTwoFactorChallenges{},
+		mailer:        newRecordingMailer(),
+		cfg:           cfg,
+	}
+	f.service = NewAuthService(f.users, f.refreshTokens, f.sessions, f.resets, nil, f.challenges, f.throttles, nil,
+		policy.NewEngine(nil, 0), nil, f.mailer, cfg)
+	return f
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Server: config.ServerConfig{PublicURL: "https://fanpage.test"},
+		JWT: config.JWTConfig{
+			SecretKey:       "test-secret",
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 24 * time.Hour,
+		},
+		Mail: config.MailConfig{PasswordResetTTL: time.Hour},
+		Auth: config.AuthConfig{
+			LoginBackoffAfter:     3,
+			LoginIPBackoffAfter:   20,
+			LoginBackoffBase:      time.Second,
+			LoginBackoffMax:       time.Minute,
+			LoginLockoutThreshold: 10,
+			LoginLockoutDuration:  30 * time.Minute,
+			LoginFailureWindow:    time.Hour,
+		},
+	}
+}
+
+func newTestUser(email, password string, role models.UserRole) *models.User {
+	user, err := models.NewUser(email, password, "Test User", role)
+	if err != nil {
+		panic(err)
+	}
+	user.EmailVerified = true
+	return user
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+var (
+	ErrNotificationNotFound       = errors.New("notification not found")
+	ErrUnknownNotificationChannel = errors.New("unknown notification channel")
+)
+
+// NotificationSender delivers notifications outside the app, e.g. by
+// email. Channel names the preference users toggle.
+type NotificationSender interface {
+	Channel() string
+	Send(user *models.User, notification *models.Notification) error
+}
+
+// NotificationService stores in-app notifications and fans them out to
+// every other channel the recipient has enabled.
+type NotificationService struct {
+	notificationRepo repository.NotificationRepository
+	userRepo         repository.UserRepository
+	senders          []NotificationSender
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepository, userRepo repository.UserRepository) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+	}
+}
+
+func (s *NotificationService) AddSender(sender NotificationSender) {
+	s.senders = append(s.senders, sender)
+}
+
+// Notify delivers to the user's enabled channels. Inactive users get
+// nothing; a failing external channel is logged and does not fail the
+// others.
+func (s *NotificationService) Notify(notification *models.Notification) error {
+	user, err := s.userRepo.FindByID(notification.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	if user.NotificationChannelEnabled(models.NotificationChannelInApp) {
+		if err := s.notificationRepo.Create(notification); err != nil {
+			return err
+		}
+	}
+
+	for _, sender := range s.senders {
+		if !user.NotificationChannelEnabled(sender.Channel()) {
+			continue
+		}
+		if err := sender.Send(user, notification); err != nil {
+			log.Printf("Failed to send %s notification to %s: %v", sender.Channel(), user.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+func (s *NotificationService) GetNotifications(userID primitive.ObjectID, unreadOnly bool, limit, offset int) ([]*models.Notification, int64, error) {
+	notifications, err := s.notificationRepo.FindByUser(userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	unread, err := s.notificationRepo.CountUnread(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, unread, nil
+}
+
+func (s *NotificationService) MarkRead(userID, notificationID primitive.ObjectID) error {
+	err := s.notificationRepo.MarkRead(userID, notificationID)
+	if err == mongo.ErrNoDocuments {
+		return ErrNotificationNotFound
+	}
+	return err
+}
+
+func (s *NotificationService) MarkAllRead(userID primitive.ObjectID) (int64, error) {
+	return s.notificationRepo.MarkAllRead(userID)
+}
+
+// Channels lists every known channel with whether the user enabled it.
+func (s *NotificationService) Channels(userID primitive.ObjectID) (map[string]bool, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	channels := make(map[string]bool, len(s.senders)+1)
+	for _, channel := range s.channelNames() {
+		channels[channel] = user.NotificationChannelEnabled(channel)
+	}
+	return channels, nil
+}
+
+func (s *NotificationService) SetChannel(userID primitive.ObjectID, channel string, enabled bool) (map[string]bool, error) {
+	known := false
+	for _, name := range s.channelNames() {
+		if name == channel {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return nil, ErrUnknownNotificationChannel
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	var disabled []string
+	for _, name := range user.DisabledNotificationChannels {
+		if name != channel {
+			disabled = append(disabled, name)
+		}
+	}
+	if !enabled {
+		disabled = append(disabled, channel)
+	}
+	user.DisabledNotificationChannels = disabled
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return s.Channels(userID)
+}
+
+func (s *NotificationService) channelNames() []string {
+	names := []string{models.NotificationChannelInApp}
+	for _, sender := range s.senders {
+		names = append(names, sender.Channel())
+	}
+	return names
+}
+
+func (s *NotificationService) UserUpdated(user *models.User) {}
+
+func (s *NotificationService) UserDeleted(userID primitive.ObjectID) {
+	if err := s.notificationRepo.DeleteByUser(userID); err != nil {
+		log.Printf("Failed to delete notifications of %s: %v", userID.Hex(), err)
+	}
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/oidc"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+var (
+	ErrOIDCDisabled        = errors.New("single sign-on is not configured")
+	ErrInvalidOIDCState    = errors.New("sign-in request is invalid or has expired")
+	ErrOIDCFailed          = errors.New("sign-in with the identity provider failed")
+	ErrOIDCEmailUnverified = errors.New("the identity provider has not verified your email address")
+	ErrOIDCNoAccount       = errors.New("no account exists for this sign-in")
+	ErrOIDCAccountLinked   = errors.New("this account is already linked to a different sign-in")
+)
+
+// SetOIDC enables single sign-on through provider.
+func (s *AuthService) SetOIDC(provider *oidc.Provider, loginRepo repository.OIDCLoginRepository) {
+	s.oidcProvider = provider
+	s.oidcLoginRepo = loginRepo
+}
+
+func (s *AuthService) OIDCEnabled() bool {
+	return s.oidcProvider != nil
+}
+
+// OIDCProviderName is the label for the sign-in button.
+func (s *AuthService) OIDCProviderName() string {
+	return s.cfg.OIDC.ProviderName
+}
+
+// BeginOIDCLogin starts an authorization code flow. The browser goes to
+// the returned URL; state must come back with the callback and is what
+// finds the nonce and PKCE verifier again.
+func (s *AuthService) BeginOIDCLogin() (string, string, error) {
+	if s.oidcProvider == nil {
+		return "", "", ErrOIDCDisabled
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	authURL, err := s.oidcProvider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		log.Printf("OIDC login could not start: %v", err)
+		return "", "", ErrOIDCFailed
+	}
+
+	if err := s.oidcLoginRepo.Create(models.NewOIDCLogin(hashToken(state), nonce, verifier, s.oidcLoginTTL())); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// CompleteOIDCLogin handles the provider's callback. The user is found by
+// linked identity, then by verified email, and otherwise registered.
+// Users with two-factor authentication still get a challenge.
+func (s *AuthService) CompleteOIDCLogin(code, state string, client SessionClient) (*LoginResult, error) {
+	if s.oidcProvider == nil {
+		return nil, ErrOIDCDisabled
+	}
+	if code == "" || state == "" {
+		return nil, ErrInvalidOIDCState
+	}
+
+	login, err := s.oidcLoginRepo.Consume(hashToken(state))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidOIDCState
+		}
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	token, err := s.oidcProvider.Exchange(ctx, code, login.CodeVerifier)
+	if err != nil {
+		log.Printf("OIDC code exchange failed: %v", err)
+		return nil, ErrOIDCFailed
+	}
+	claims, err := s.oidcProvider.VerifyIDToken(ctx, token.IDToken, login.Nonce)
+	if err != nil {
+		log.Printf("OIDC ID token rejected: %v", err)
+		return nil, ErrOIDCFailed
+	}
+
+	user, err := s.oidcUser(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
+
+	if user.HasTwoFactor() {
+		return s.newTwoFactorChallenge(user)
+	}
+
+	tokens, err := s.IssueTokens(user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Tokens: tokens, User: user}, nil
+}
+
+func (s *AuthService) oidcUser(claims *oidc.Claims) (*models.User, error) {
+	issuer := strings.TrimRight(claims.Issuer, "/")
+
+	user, err := s.userRepo.FindByIdentity(issuer, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// Linking by email hands over the account, so the provider must have
+	// checked the address
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailUnverified
+	}
+	if err := s.validateEmail(email); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	identity := models.ExternalIdentity{Issuer: issuer, Subject: claims.Subject, Email: email, LinkedAt: now}
+
+	if existing, _ := s.userRepo.FindByEmail(email); existing != nil {
+		if linked := existing.Identity(issuer); linked != nil {
+			return nil, ErrOIDCAccountLinked
+		}
+		existing.Identities = append(existing.Identities, identity)
+		if !existing.EmailVerified {
+			s.markEmailVerified(existing, now)
+		}
+		if err := s.userRepo.Update(existing); err != nil {
+			return nil, err
+		}
+		s.notifyUpdated(existing)
+		return existing, nil
+	}
+
+	if !s.cfg.OIDC.AutoRegister {
+		return nil, ErrOIDCNoAccount
+	}
+
+	role, err := s.registration.CheckFederated(email)
+	if err != nil {
+		return nil, err
+	}
+
+	// The account signs in through the provider; a password can be set
+	// later with the reset flow
+	password, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	user, err = models.NewUser(email, password, oidcDisplayName(claims), role)
+	if err != nil {
+		return nil, err
+	}
+	s.markEmailVerified(user, now)
+	user.Identities = []models.ExternalIdentity{identity}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	s.notifyUpdated(user)
+	return user, nil
+}
+
+func oidcDisplayName(claims *oidc.Claims) string {
+	if name := strings.TrimSpace(claims.Name); name != "" {
+		return name
+	}
+	if name := strings.TrimSpace(claims.PreferredUsername); name != "" {
+		return name
+	}
+	local, _, _ := strings.Cut(claims.Email, "@")
+	return local
+}
+
+func (s *AuthService) oidcLoginTTL() time.Duration {
+	if s.cfg.OIDC.LoginTTL > 0 {
+		return s.cfg.OIDC.LoginTTL
+	}
+	return 10 * time.Minute
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/oidc"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/oidc/oidctest"
+)
+
+type oidcFixture struct {
+	*authFixture
+	idp *oidctest.Provider
+}
+
+func newOIDCFixture(t *testing.T, autoRegister bool, users ...*models.User) *oidcFixture {
+	t.Helper()
+	idp := oidctest.NewProvider("fanpage")
+	t.Cleanup(idp.Close)
+
+	cfg := testConfig()
+	cfg.OIDC = config.OIDCConfig{Issuer: idp.Issuer(), ClientID: "fanpage", AutoRegister: autoRegister}
+	f := newAuthFixture(cfg, users...)
+	f.service.SetOIDC(oidc.NewProvider(oidc.Config{
+		Issuer:      cfg.OIDC.Issuer,
+		ClientID:    cfg.OIDC.ClientID,
+		RedirectURL: "https://fanpage.test/api/auth/oidc/callback",
+	}), &memOIDCLogins{})
+	return &oidcFixture{authFixture: f, idp: idp}
+}
+
+// signIn runs the whole flow, with the user signing in at the provider as
+// claims.
+func (f *oidcFixture) signIn(t *testing.T, claims map[string]interface{}) (*LoginResult, error) {
+	t.Helper()
+	authURL, state, err := f.service.BeginOIDCLogin()
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin: %v", err)
+	}
+	return f.service.CompleteOIDCLogin(f.idp.Authorize(authURL, claims), state, SessionClient{})
+}
+
+func (f *oidcFixture) claims(subject, email string, verified bool) map[string]interface{} {
+	claims := f.idp.Claims(subject)
+	claims["email"] = email
+	claims["email_verified"] = verified
+	claims["name"] = "Aigerim"
+	return claims
+}
+
+func TestOIDCLoginRegistersNewUser(t *testing.T) {
+	f := newOIDCFixture(t, true)
+
+	result, err := f.signIn(t, f.claims("sub-1", "aigerim@astanait.edu.kz", true))
+	if err != nil {
+		t.Fatalf("sign-in: %v", err)
+	}
+	if result.Tokens == nil {
+		t.Fatal("no tokens issued")
+	}
+	user := result.User
+	if user.DisplayName != "Aigerim" || !user.EmailVerified || user.Role != models.RoleStudent {
+		t.Errorf("registered user %+v", user)
+	}
+	if identity := user.Identity(f.idp.Issuer()); identity == nil || identity.Subject != "sub-1" {
+		t.Errorf("identity not linked: %+v", user.Identities)
+	}
+
+	// The next sign-in finds the account by subject, even with a new email
+	again, err := f.signIn(t, f.claims("sub-1", "renamed@astanait.edu.kz", false))
+	if err != nil {
+		t.Fatalf("second sign-in: %v", err)
+	}
+	if again.User.ID != user.ID {
+		t.Error("second sign-in created another account")
+	}
+}
+
+func TestOIDCLoginRejectsBadTokens(t *testing.T) {
+	f := newOIDCFixture(t, true)
+	valid := func() map[string]interface{} {
+		return f.claims("sub-1", "aigerim@astanait.edu.kz", true)
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := valid()
+		claims[key] = value
+		return claims
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{"nonce mismatch", with("nonce", "someone-elses-nonce")},
+		{"wrong audience", with("aud", "other-client")},
+		{"wrong authorized party", func() map[string]interface{} {
+			claims := with("aud", []string{"fanpage", "other-client"})
+			claims["azp"] = "other-client"
+			return claims
+		}()},
+		{"expired", with("exp", 1)},
+	}
+	for _, tt := range tests {
+		if _, err := f.signIn(t, tt.claims); !errors.Is(err, ErrOIDCFailed) {
+			t.Errorf("%s: err = %v, want ErrOIDCFailed", tt.name, err)
+		}
+	}
+
+	// An HMAC token signed with the client ID as key must not pass
+	authURL, state, err := f.service.BeginOIDCLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := url.Parse(authURL)
+	claims := valid()
+	claims["nonce"] = parsed.Query().Get("nonce")
+	token := hmacIDToken(claims, "fanpage")
+	if _, err := f.service.CompleteOIDCLogin(f.idp.AuthorizeToken(authURL, token), state, SessionClient{}); !errors.Is(err, ErrOIDCFailed) {
+		t.Errorf("HS256 token: err = %v, want ErrOIDCFailed", err)
+	}
+
+	if len(f.users.users) != 0 {
+		t.Errorf("%d accounts created from rejected tokens", len(f.users.users))
+	}
+}
+
+func TestOIDCLoginStateIsSingleUse(t *testing.T) {
+	f := newOIDCFixture(t, true)
+
+	authURL, state, err := f.service.BeginOIDCLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := f.idp.Authorize(authURL, f.claims("sub-1", "aigerim@astanait.edu.kz", true))
+	if _, err := f.service.CompleteOIDCLogin(code, state, SessionClient{}); err != nil {
+		t.Fatalf("first callback: %v", err)
+	}
+	if _, err := f.service.CompleteOIDCLogin(code, state, SessionClient{}); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("replayed callback: err = %v, want ErrInvalidOIDCState", err)
+	}
+	if _, err := f.service.CompleteOIDCLogin(code, "made-up-state", SessionClient{}); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("unknown state: err = %v, want ErrInvalidOIDCState", err)
+	}
+}
+
+func TestOIDCLoginRequiresVerifiedEmail(t *testing.T) {
+	existing := newTestUser("aigerim@astanait.edu.kz", "correct-password", models.RoleStudent)
+	f := newOIDCFixture(t, true, existing)
+
+	_, err := f.signIn(t, f.claims("sub-1", "aigerim@astanait.edu.kz", false))
+	if !errors.Is(err, ErrOIDCEmailUnverified) {
+		t.Fatalf("err = %v, want ErrOIDCEmailUnverified", err)
+	}
+	if len(existing.Identities) != 0 {
+		t.Error("unverified email linked to the existing account")
+	}
+}
+
+func TestOIDCLoginLinksByVerifiedEmail(t *testing.T) {
+	existing := newTestUser("aigerim@astanait.edu.kz", "correct-password", models.RoleAlumni)
+	existing.EmailVerified = false
+	f := newOIDCFixture(t, false, existing)
+
+	result, err := f.signIn(t, f.claims("sub-1", "aigerim@astanait.edu.kz", true))
+	if err != nil {
+		t.Fatalf("sign-in: %v", err)
+	}
+	if result.User.ID != existing.ID {
+		t.Fatal("signed in to a different account")
+	}
+	if existing.Identity(f.idp.Issuer()) == nil || !existing.EmailVerified {
+		t.Errorf("identity not linked or email not marked verified: %+v", existing)
+	}
+
+	// Another subject at the same provider cannot take the account over
+	if _, err := f.signIn(t, f.claims("sub-2", "aigerim@astanait.edu.kz", true)); !errors.Is(err, ErrOIDCAccountLinked) {
+		t.Errorf("second subject: err = %v, want ErrOIDCAccountLinked", err)
+	}
+	// Without auto-registration unknown users are turned away
+	if _, err := f.signIn(t, f.claims("sub-3", "newcomer@astanait.edu.kz", true)); !errors.Is(err, ErrOIDCNoAccount) {
+		t.Errorf("unknown user: err = %v, want ErrOIDCNoAccount", err)
+	}
+}
+
+func TestOIDCLoginLinkGrantsPendingRole(t *testing.T) {
+	existing := newTestUser("aigerim@astanait.edu.kz", "correct-password", models.RoleStudent)
+	existing.EmailVerified = false
+	existing.PendingRole = models.RoleModerator
+	f := newOIDCFixture(t, false, existing)
+
+	// The provider proves the address just as the verification link would
+	if _, err := f.signIn(t, f.claims("sub-1", "aigerim@astanait.edu.kz", true)); err != nil {
+		t.Fatalf("sign-in: %v", err)
+	}
+	if existing.Role != models.RoleModerator || existing.PendingRole != "" {
+		t.Errorf("role = %q, pending %q; want the pending role applied", existing.Role, existing.PendingRole)
+	}
+}
+
+func TestOIDCLoginChallengesTwoFactorUsers(t *testing.T) {
+	existing := newTestUser("moderator@astanait.edu.kz", "correct-password", models.RoleModerator)
+	f := newOIDCFixture(t, false, existing)
+	enrollTwoFactor(t, f.authFixture, existing)
+
+	result, err := f.signIn(t, f.claims("sub-1", "moderator@astanait.edu.kz", true))
+	if err != nil {
+		t.Fatalf("sign-in: %v", err)
+	}
+	if result.Tokens != nil || result.Challenge == "" {
+		t.Error("sign-in with 2FA enrolled skipped the challenge")
+	}
+}
+
+// hmacIDToken signs claims HS256 with key, as an attacker who knows the
+// public client ID could.
+func hmacIDToken(claims map[string]interface{}, key string) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
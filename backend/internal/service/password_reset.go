@@ -0,0 +1,143 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/mail"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired password reset link")
+
+const (
+	// maxPasswordResetsPerHour stops the forgot form from flooding an inbox.
+	maxPasswordResetsPerHour = 3
+)
+
+type passwordResetEmailData struct {
+	DisplayName string
+	Link        string
+	ExpiresIn   string
+}
+
+// ForgotPassword mails a reset link if the email belongs to an active
+// account. The lookup and sending happen in the background, so neither the
+// result nor the response time tells whether the email is registered.
+func (s *AuthService) ForgotPassword(email string) error {
+	if err := s.validateEmail(email); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.sendPasswordReset(email); err != nil {
+			log.Printf("Failed to send password reset email: %v", err)
+		}
+	}()
+	return nil
+}
+
+// ResetPassword consumes the token and sets the new password. Every
+// session of the user is signed out, other outstanding reset links stop
+// working and a login lock is lifted.
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	if err := s.validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	reset, err := s.passwordResetRepo.FindByHash(hashToken(token))
+	if err != nil || reset.UsedAt != nil || !reset.ExpiresAt.After(time.Now()) {
+		return ErrInvalidResetToken
+	}
+
+	now := time.Now()
+	used, err := s.passwordResetRepo.MarkUsed(reset.ID, now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.FindByID(reset.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	if err := user.UpdatePassword(newPassword); err != nil {
+		return err
+	}
+	// The link arrived by email, which proves the address too
+	if !user.EmailVerified {
+		s.markEmailVerified(user, now)
+	}
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if err := s.RevokeAllSessions(user.ID); err != nil {
+		log.Printf("Failed to revoke sessions of %s after password reset: %v", user.ID.Hex(), err)
+	}
+	if err := s.passwordResetRepo.InvalidateByUser(user.ID, now); err != nil {
+		log.Printf("Failed to invalidate password resets of %s: %v", user.ID.Hex(), err)
+	}
+	s.clearLoginFailures(user.Email)
+
+	s.notifyUpdated(user)
+
+	return nil
+}
+
+func (s *AuthService) sendPasswordReset(email string) error {
+	user, err := s.userRepo.FindByEmail(strings.TrimSpace(email))
+	if err != nil || !user.IsActive {
+		return nil
+	}
+
+	link, ttl, err := s.newPasswordResetLink(user)
+	if err != nil || link == "" {
+		return err
+	}
+
+	msg, err := mail.Render("password_reset", user.Email, passwordResetEmailData{
+		DisplayName: user.DisplayName,
+		Link:        link,
+		ExpiresIn:   describeTTL(ttl),
+	})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(msg)
+}
+
+// newPasswordResetLink creates a reset token for user and returns its link.
+// The link is empty when the hourly limit of reset emails was reached.
+func (s *AuthService) newPasswordResetLink(user *models.User) (string, time.Duration, error) {
+	recent, err := s.passwordResetRepo.CountByUserSince(user.ID, time.Now().Add(-time.Hour))
+	if err != nil {
+		return "", 0, err
+	}
+	if recent >= maxPasswordResetsPerHour {
+		return "", 0, nil
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", 0, err
+	}
+
+	ttl := s.passwordResetTTL()
+	if err := s.passwordResetRepo.Create(models.NewPasswordReset(user.ID, hashToken(token), ttl)); err != nil {
+		return "", 0, err
+	}
+	return publicBaseURL(s.cfg) + "/reset-password.html?token=" + token, ttl, nil
+}
+
+func (s *AuthService) passwordResetTTL() time.Duration {
+	if s.cfg.Mail.PasswordResetTTL <= 0 {
+		return time.Hour
+	}
+	return s.cfg.Mail.PasswordResetTTL
+}
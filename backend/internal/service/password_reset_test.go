@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+var resetLinkPattern = regexp.MustCompile(`reset-password\.html\?token=([^\s"]+)`)
+
+// receiveResetToken waits for the next email to to and returns the reset
+// token from its link.
+func receiveResetToken(t *testing.T, f *authFixture, to string) string {
+	t.Helper()
+	select {
+	case msg := <-f.mailer.sent:
+		if msg.To != to {
+			t.Errorf("email to %q, want %q", msg.To, to)
+		}
+		match := resetLinkPattern.FindStringSubmatch(msg.Text)
+		if match == nil {
+			t.Fatalf("email has no reset link:\n%s", msg.Text)
+		}
+		token, _ := url.QueryUnescape(match[1])
+		return token
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reset email sent")
+		return ""
+	}
+}
+
+func expectNoMail(t *testing.T, f *authFixture) {
+	t.Helper()
+	select {
+	case msg := <-f.mailer.sent:
+		t.Errorf("unexpected email to %q", msg.To)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPasswordResetIsSingleUse(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "old-password", models.RoleStudent)
+	user.EmailVerified = false
+	f := newAuthFixture(nil, user)
+
+	if err := f.service.ForgotPassword(user.Email); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	token := receiveResetToken(t, f, user.Email)
+
+	if err := f.service.ResetPassword(token, "short"); err == nil {
+		t.Error("weak password accepted")
+	}
+	if err := f.service.ResetPassword(token, "brand-new-password"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if !user.ValidatePassword("brand-new-password") {
+		t.Error("password not changed")
+	}
+	if !user.EmailVerified {
+		t.Error("reset did not verify the email")
+	}
+
+	if err := f.service.ResetPassword(token, "another-password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("second use: err = %v, want ErrInvalidResetToken", err)
+	}
+	if err := f.service.ResetPassword("made-up", "another-password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("unknown token: err = %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestPasswordResetExpires(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "old-password", models.RoleStudent)
+	f := newAuthFixture(nil, user)
+
+	f.service.ForgotPassword(user.Email)
+	token := receiveResetToken(t, f, user.Email)
+	f.resets.resets[0].ExpiresAt = time.Now().Add(-time.Second)
+
+	if err := f.service.ResetPassword(token, "brand-new-password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("err = %v, want ErrInvalidResetToken", err)
+	}
+	if !user.ValidatePassword("old-password") {
+		t.Error("expired link changed the password")
+	}
+}
+
+func TestPasswordResetSignsOutEverywhere(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "old-password", models.RoleStudent)
+	f := newAuthFixture(nil, user)
+	stolen := signIn(t, f, user)
+
+	f.service.ForgotPassword(user.Email)
+	first := receiveResetToken(t, f, user.Email)
+	f.service.ForgotPassword(user.Email)
+	second := receiveResetToken(t, f, user.Email)
+
+	if err := f.service.ResetPassword(second, "brand-new-password"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+
+	if _, _, err := f.service.Refresh(stolen.RefreshToken, SessionClient{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh after reset: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	if !f.sessionRevoked(t, stolen.RefreshToken) {
+		t.Error("session survived the reset")
+	}
+	// An older link that leaked with the mailbox no longer works
+	if err := f.service.ResetPassword(first, "attacker-password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("older link: err = %v, want ErrInvalidResetToken", err)
+	}
+
+	_, err := f.service.Login(dto.LoginRequest{Email: user.Email, Password: "brand-new-password"}, SessionClient{IP: "198.51.100.2"})
+	if err != nil {
+		t.Errorf("login with the new password: %v", err)
+	}
+}
+
+func TestForgotPasswordSendsNothingForUnknownOrInactive(t *testing.T) {
+	inactive := newTestUser("gone@astanait.edu.kz", "password123", models.RoleStudent)
+	inactive.IsActive = false
+	f := newAuthFixture(nil, inactive)
+
+	// Both succeed so the form does not reveal who is registered
+	if err := f.service.ForgotPassword("nobody@astanait.edu.kz"); err != nil {
+		t.Errorf("unknown email: %v", err)
+	}
+	if err := f.service.ForgotPassword(inactive.Email); err != nil {
+		t.Errorf("inactive account: %v", err)
+	}
+	expectNoMail(t, f)
+	if len(f.resets.resets) != 0 {
+		t.Errorf("%d reset tokens created, want 0", len(f.resets.resets))
+	}
+}
+
+func TestForgotPasswordHourlyLimit(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "password123", models.RoleStudent)
+	f := newAuthFixture(nil, user)
+
+	for i := 0; i < maxPasswordResetsPerHour; i++ {
+		f.service.ForgotPassword(user.Email)
+		receiveResetToken(t, f, user.Email)
+	}
+	f.service.ForgotPassword(user.Email)
+	expectNoMail(t, f)
+
+	// Links from over an hour ago no longer count
+	f.resets.mu.Lock()
+	for _, reset := range f.resets.resets {
+		reset.CreatedAt = time.Now().Add(-2 * time.Hour)
+	}
+	f.resets.mu.Unlock()
+	f.service.ForgotPassword(user.Email)
+	receiveResetToken(t, f, user.Email)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+var ErrAdminLockout = errors.New("the admin role must keep roles.manage")
+
+// PolicyService manages the role permissions stored in the database and
+// reads the audit log.
+type PolicyService struct {
+	engine             *policy.Engine
+	rolePermissionRepo repository.RolePermissionRepository
+	auditLogRepo       repository.AuditLogRepository
+	userRepo           repository.UserRepository
+}
+
+func NewPolicyService(engine *policy.Engine, rolePermissionRepo repository.RolePermissionRepository, auditLogRepo repository.AuditLogRepository, userRepo repository.UserRepository) *PolicyService {
+	return &PolicyService{
+		engine:             engine,
+		rolePermissionRepo: rolePermissionRepo,
+		auditLogRepo:       auditLogRepo,
+		userRepo:           userRepo,
+	}
+}
+
+// Load applies the stored overrides on top of the defaults and policy
+// file. An invalid override is skipped so the role keeps its previous
+// grants.
+func (s *PolicyService) Load() error {
+	stored, err := s.rolePermissionRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	for _, role := range stored {
+		if err := s.engine.SetRole(role.Role, role.Permissions); err != nil {
+			log.Printf("Ignoring stored permissions for role %s: %v", role.Role, err)
+		}
+	}
+	return nil
+}
+
+func (s *PolicyService) Roles() map[models.UserRole][]string {
+	return s.engine.Roles()
+}
+
+// UpdateRole stores new grants for role and applies them immediately.
+func (s *PolicyService) UpdateRole(adminID primitive.ObjectID, role models.UserRole, grants []string) ([]string, error) {
+	admin, err := s.userRepo.FindByID(adminID)
+	if err != nil || s.engine.Authorize(admin, policy.RolesManage, nil) != nil {
+		return nil, ErrPermissionDenied
+	}
+
+	if !policy.IsRole(role) {
+		return nil, ErrInvalidRole
+	}
+	parsed, err := policy.ParseGrants(grants)
+	if err != nil {
+		return nil, err
+	}
+	if role == models.RoleAdmin && !parsed[policy.RolesManage] {
+		return nil, ErrAdminLockout
+	}
+
+	if err := s.rolePermissionRepo.Upsert(&models.RolePermissions{
+		Role:        role,
+		Permissions: grants,
+		UpdatedAt:   time.Now(),
+		UpdatedBy:   adminID,
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.engine.SetRole(role, grants); err != nil {
+		return nil, err
+	}
+
+	updated := s.engine.Roles()[role]
+	entry := models.NewAuditEntry(models.AuditPermissionsChanged, admin, s.engine.Retention())
+	entry.Detail = fmt.Sprintf("%s: %s", role, strings.Join(updated, ", "))
+	s.engine.Record(entry)
+
+	return updated, nil
+}
+
+func (s *PolicyService) ListAuditLog(action string, limit, offset int) ([]*models.AuditEntry, error) {
+	return s.auditLogRepo.FindRecent(action, limit, offset)
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
+)
+
+func TestUnlikeRemovesStoredLike(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "password123", models.RoleStudent)
+	author := newTestUser("author@astanait.edu.kz", "password123", models.RoleStudent)
+	post := models.NewPost("Club fair", "Booths in the atrium", "", models.PostCategory("news"), author.ID, author.DisplayName)
+	post.LikeCount = 1
+
+	// Liked long ago, or before the server restarted
+	like := models.NewLike(user.ID, post.ID)
+	like.CreatedAt = time.Now().Add(-24 * time.Hour)
+	posts := &memPosts{posts: []*models.Post{post}}
+	likes := &memLikes{likes: []*models.Like{like}}
+	service := NewPostService(posts, newMemUsers(user, author), &memComments{}, &memFollows{}, &memBookmarks{}, likes, nil, policy.NewEngine(nil, 0))
+
+	if err := service.UnlikePost(post.ID, user.ID); err != nil {
+		t.Fatalf("UnlikePost: %v", err)
+	}
+	if len(likes.likes) != 0 {
+		t.Error("like still stored")
+	}
+	if count, _ := service.GetPostLikeCount(post.ID); count != 0 {
+		t.Errorf("like count = %d, want 0", count)
+	}
+
+	// Nothing left to remove, so the count stays put
+	if err := service.UnlikePost(post.ID, user.ID); err == nil {
+		t.Error("second unlike succeeded")
+	}
+	if count, _ := service.GetPostLikeCount(post.ID); count != 0 {
+		t.Errorf("like count after second unlike = %d, want 0", count)
+	}
+}
@@ -5,27 +5,46 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
 )
 
+// PostListener is notified after posts change so derived data such as
+// caches and indexes can stay in sync.
+type PostListener interface {
+	PostCreated(post *models.Post)
+	PostUpdated(post *models.Post)
+	PostDeleted(postID primitive.ObjectID)
+}
+
 type PostService struct {
-	postRepo    repository.PostRepository
-	userRepo    repository.UserRepository
-	commentRepo repository.CommentRepository
-	rateLimiter *RateLimiter
-	likeTracker *LikeTracker
+	postRepo     repository.PostRepository
+	userRepo     repository.UserRepository
+	commentRepo  repository.CommentRepository
+	followRepo   repository.FollowRepository
+	bookmarkRepo repository.BookmarkRepository
+	likeRepo     repository.LikeRepository
+	viewCounter  *ViewCounter
+	policy       *policy.Engine
+	listeners    []PostListener
+	rateLimiter  *RateLimiter
 }
 
-func NewPostService(postRepo repository.PostRepository, userRepo repository.UserRepository, commentRepo repository.CommentRepository) *PostService {
+func NewPostService(postRepo repository.PostRepository, userRepo repository.UserRepository, commentRepo repository.CommentRepository, followRepo repository.FollowRepository, bookmarkRepo repository.BookmarkRepository, likeRepo repository.LikeRepository, viewCounter *ViewCounter, engine *policy.Engine) *PostService {
 	service := &PostService{
-		postRepo:    postRepo,
-		userRepo:    userRepo,
-		commentRepo: commentRepo,
-		rateLimiter: NewRateLimiter(),
-		likeTracker: NewLikeTracker(),
+		postRepo:     postRepo,
+		userRepo:     userRepo,
+		commentRepo:  commentRepo,
+		followRepo:   followRepo,
+		bookmarkRepo: bookmarkRepo,
+		likeRepo:     likeRepo,
+		viewCounter:  viewCounter,
+		policy:       engine,
+		rateLimiter:  NewRateLimiter(),
 	}
 
 	go service.backgroundCleanup()
@@ -33,16 +52,24 @@ func NewPostService(postRepo repository.PostRepository, userRepo repository.User
 	return service
 }
 
+func (s *PostService) AddListener(listener PostListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
 func (s *PostService) CreatePost(req dto.CreatePostRequest, authorID primitive.ObjectID, uploadedFiles []*UploadedFile) (*models.Post, error) {
 	user, err := s.userRepo.FindByID(authorID)
 	if err != nil {
 		return nil, err
 	}
 
-	if !user.CanCreatePost() {
+	if err := s.policy.Authorize(user, policy.PostsCreate, nil); err != nil {
 		return nil, errors.New("user cannot create posts")
 	}
 
+	if !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
 	authorName := user.DisplayName
 	if authorName == "" {
 		authorName = "Anonymous User"
@@ -59,7 +86,7 @@ func (s *PostService) CreatePost(req dto.CreatePostRequest, authorID primitive.O
 	)
 
 	if len(req.Tags) > 0 {
-		post.AddTags(req.Tags...)
+		post.AddTags(normalizeTags(req.Tags)...)
 	}
 
 	for i, uploadedFile := range uploadedFiles {
@@ -92,6 +119,10 @@ func (s *PostService) CreatePost(req dto.CreatePostRequest, authorID primitive.O
 	user.IncrementPostCount()
 	s.userRepo.Update(user)
 
+	for _, listener := range s.listeners {
+		listener.PostCreated(post)
+	}
+
 	return post, nil
 }
 
@@ -99,11 +130,71 @@ func (s *PostService) GetPosts(limit, offset int) ([]*models.Post, error) {
 	return s.postRepo.FindAll(limit, offset)
 }
 
-func (s *PostService) GetFeed(userID primitive.ObjectID, category string, limit, offset int) ([]*models.Post, error) {
-	if category != "" {
-		return s.postRepo.FindByCategory(category, limit)
+func (s *PostService) GetFeed(userID primitive.ObjectID, category, cursor string, limit int) (*FeedPage, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
 	}
-	return s.postRepo.FindAll(limit, offset)
+
+	followingIDs, err := s.followRepo.FindFollowingIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := &feedSources{
+		authors:    make(map[primitive.ObjectID]bool),
+		categories: make(map[models.PostCategory]bool),
+		tags:       make(map[string]bool),
+	}
+	for _, id := range followingIDs {
+		sources.authors[id] = true
+	}
+	for _, c := range user.SubscribedCategories {
+		sources.categories[c] = true
+	}
+	for _, t := range user.SubscribedTags {
+		sources.tags[t] = true
+	}
+
+	query := repository.FeedQuery{
+		Category: category,
+		Limit:    limit,
+	}
+
+	// Users without follows or subscriptions get the global recent feed
+	if !sources.empty() {
+		query.AuthorIDs = followingIDs
+		for c := range sources.categories {
+			query.Categories = append(query.Categories, string(c))
+		}
+		for t := range sources.tags {
+			query.Tags = append(query.Tags, t)
+		}
+	}
+
+	if cursor != "" {
+		decoded, err := decodeFeedCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query.BeforeTime = decoded.CreatedAt
+		query.BeforeID = decoded.ID
+	}
+
+	posts, err := s.postRepo.FindFeed(query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &FeedPage{Posts: posts}
+	if len(posts) == limit {
+		last := posts[len(posts)-1]
+		page.NextCursor = encodeFeedCursor(last.CreatedAt, last.ID)
+	}
+
+	rankFeedPage(page.Posts, sources)
+
+	return page, nil
 }
 
 func (s *PostService) GetPostsByCategory(category string, limit int) ([]*models.Post, error) {
@@ -115,15 +206,18 @@ func (s *PostService) GetPostsByAuthor(authorID primitive.ObjectID, limit int) (
 }
 
 func (s *PostService) GetPostByID(postID primitive.ObjectID) (*models.Post, error) {
-	post, err := s.postRepo.FindByID(postID)
-	if err != nil {
-		return nil, err
-	}
-
-	go s.postRepo.IncrementViewCount(postID)
-	post.IncrementViewCount()
+	return s.postRepo.FindByID(postID)
+}
 
-	return post, nil
+// RecordView counts a unique view of the post. The stored counter is
+// updated by the next flush; the in-memory post reflects it immediately.
+func (s *PostService) RecordView(post *models.Post, viewer Viewer) {
+	if post.IsArchived {
+		return
+	}
+	if s.viewCounter.RecordView(post.ID, viewer) {
+		post.IncrementViewCount()
+	}
 }
 
 func (s *PostService) GetPinnedPosts(limit int) ([]*models.Post, error) {
@@ -139,11 +233,7 @@ func (s *PostService) GetPopularPosts(limit int, days int) ([]*models.Post, erro
 }
 
 func (s *PostService) GetPostsByTags(tags []string, limit int) ([]*models.Post, error) {
-	return s.postRepo.FindByTags(tags, limit)
-}
-
-func (s *PostService) SearchPosts(query string, limit int) ([]*models.Post, error) {
-	return s.postRepo.Search(query, limit)
+	return s.postRepo.FindByTags(normalizeTags(tags), limit)
 }
 
 func (s *PostService) GetCategoriesStats() (map[string]int, error) {
@@ -159,10 +249,22 @@ func (s *PostService) LikePost(postID, userID primitive.ObjectID) error {
 		return errors.New("rate limit exceeded: maximum 3 likes per 3 minutes")
 	}
 
-	s.rateLimiter.RecordLike(userID)
+	liked, err := s.likeRepo.Exists(userID, postID)
+	if err != nil {
+		return err
+	}
+	if liked {
+		return errors.New("post already liked")
+	}
 
-	timestamp := time.Now()
-	s.likeTracker.AddLike(postID, userID, timestamp)
+	if err := s.likeRepo.Create(models.NewLike(userID, postID)); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("post already liked")
+		}
+		return err
+	}
+
+	s.rateLimiter.RecordLike(userID)
 
 	if err := s.postRepo.IncrementLikeCount(postID); err != nil {
 		return errors.New("failed to update like count: " + err.Error())
@@ -172,13 +274,12 @@ func (s *PostService) LikePost(postID, userID primitive.ObjectID) error {
 }
 
 func (s *PostService) UnlikePost(postID, userID primitive.ObjectID) error {
-	likeRecord := s.likeTracker.CanUnlike(postID, userID)
-	if likeRecord == nil {
-		return errors.New("cannot unlike: no active likes on this post within the last 3 minutes")
+	deleted, err := s.likeRepo.Delete(userID, postID)
+	if err != nil {
+		return errors.New("failed to update database: " + err.Error())
 	}
-
-	if !s.likeTracker.Unlike(likeRecord.ID) {
-		return errors.New("unlike failed")
+	if !deleted {
+		return errors.New("cannot unlike: post is not liked")
 	}
 
 	if err := s.postRepo.DecrementLikeCount(postID); err != nil {
@@ -198,11 +299,12 @@ func (s *PostService) backgroundCleanup() {
 
 	for range ticker.C {
 		s.rateLimiter.Cleanup()
-		s.likeTracker.Cleanup()
 	}
 }
 
-func (s *PostService) UpdatePost(postID, userID primitive.ObjectID, req dto.UpdatePostRequest) (*models.Post, error) {
+// UpdatePost edits a post. twoFactor reports whether the caller's session
+// passed a TOTP check, which staff need to edit other people's posts.
+func (s *PostService) UpdatePost(postID, userID primitive.ObjectID, twoFactor bool, req dto.UpdatePostRequest) (*models.Post, error) {
 	post, err := s.postRepo.FindByID(postID)
 	if err != nil {
 		return nil, err
@@ -213,7 +315,10 @@ func (s *PostService) UpdatePost(postID, userID primitive.ObjectID, req dto.Upda
 		return nil, err
 	}
 
-	if !user.CanEditPost(post.AuthorID) {
+	if err := s.policy.AuthorizeSession(user, policy.PostsEdit, policy.Owned("post", post.ID, post.AuthorID), twoFactor); err != nil {
+		if err == policy.ErrTwoFactorRequired {
+			return nil, err
+		}
 		return nil, errors.New("not authorized to edit this post")
 	}
 
@@ -230,7 +335,7 @@ func (s *PostService) UpdatePost(postID, userID primitive.ObjectID, req dto.Upda
 		post.Category = models.PostCategory(req.Category)
 	}
 	if len(req.Tags) > 0 {
-		post.Tags = req.Tags
+		post.Tags = normalizeTags(req.Tags)
 	}
 
 	post.CalculatePopularityScore()
@@ -239,10 +344,15 @@ func (s *PostService) UpdatePost(postID, userID primitive.ObjectID, req dto.Upda
 		return nil, err
 	}
 
+	for _, listener := range s.listeners {
+		listener.PostUpdated(post)
+	}
+
 	return post, nil
 }
 
-func (s *PostService) DeletePost(postID, userID primitive.ObjectID) error {
+// DeletePost removes a post; twoFactor is as for UpdatePost.
+func (s *PostService) DeletePost(postID, userID primitive.ObjectID, twoFactor bool) error {
 	post, err := s.postRepo.FindByID(postID)
 	if err != nil {
 		return err
@@ -253,14 +363,48 @@ func (s *PostService) DeletePost(postID, userID primitive.ObjectID) error {
 		return err
 	}
 
-	if !user.CanDeletePost(post.AuthorID) {
+	if err := s.policy.AuthorizeSession(user, policy.PostsDelete, policy.Owned("post", post.ID, post.AuthorID), twoFactor); err != nil {
+		if err == policy.ErrTwoFactorRequired {
+			return err
+		}
 		return errors.New("not authorized to delete this post")
 	}
 
+	return s.removePost(postID)
+}
+
+// removePost deletes the post along with its comments, likes and
+// bookmarks.
+func (s *PostService) removePost(postID primitive.ObjectID) error {
 	if err := s.commentRepo.DeleteByPostID(postID); err != nil {
 	}
 
-	return s.postRepo.Delete(postID)
+	if err := s.postRepo.Delete(postID); err != nil {
+		return err
+	}
+
+	s.bookmarkRepo.DeleteByPostID(postID)
+	s.likeRepo.DeleteByPostID(postID)
+
+	for _, listener := range s.listeners {
+		listener.PostDeleted(postID)
+	}
+
+	return nil
+}
+
+// anonymizePost credits the post to a deleted user instead of its author.
+func (s *PostService) anonymizePost(post *models.Post) error {
+	post.AuthorID = primitive.NilObjectID
+	post.AuthorName = models.DeletedUserName
+	if err := s.postRepo.Update(post); err != nil {
+		return err
+	}
+
+	for _, listener := range s.listeners {
+		listener.PostUpdated(post)
+	}
+	return nil
 }
 
 func (s *PostService) PinPost(postID, userID primitive.ObjectID) error {
@@ -269,7 +413,7 @@ func (s *PostService) PinPost(postID, userID primitive.ObjectID) error {
 		return err
 	}
 
-	if !user.CanManagePosts() {
+	if err := s.policy.Authorize(user, policy.PostsPin, &policy.Resource{Type: "post", ID: postID}); err != nil {
 		return errors.New("not authorized to pin posts")
 	}
 
@@ -288,7 +432,7 @@ func (s *PostService) UnpinPost(postID, userID primitive.ObjectID) error {
 		return err
 	}
 
-	if !user.CanManagePosts() {
+	if err := s.policy.Authorize(user, policy.PostsPin, &policy.Resource{Type: "post", ID: postID}); err != nil {
 		return errors.New("not authorized to unpin posts")
 	}
 
@@ -307,7 +451,7 @@ func (s *PostService) FeaturePost(postID, userID primitive.ObjectID) error {
 		return err
 	}
 
-	if !user.CanManagePosts() {
+	if err := s.policy.Authorize(user, policy.PostsFeature, &policy.Resource{Type: "post", ID: postID}); err != nil {
 		return errors.New("not authorized to feature posts")
 	}
 
@@ -326,7 +470,7 @@ func (s *PostService) UnfeaturePost(postID, userID primitive.ObjectID) error {
 		return err
 	}
 
-	if !user.CanManagePosts() {
+	if err := s.policy.Authorize(user, policy.PostsFeature, &policy.Resource{Type: "post", ID: postID}); err != nil {
 		return errors.New("not authorized to unfeature posts")
 	}
 
@@ -340,16 +484,29 @@ func (s *PostService) UnfeaturePost(postID, userID primitive.ObjectID) error {
 }
 
 func (s *PostService) GetPostLikes(postID primitive.ObjectID) ([]string, error) {
-	return []string{}, nil
+	userIDs, err := s.likeRepo.FindUserIDsByPost(postID, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	likes := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		likes = append(likes, id.Hex())
+	}
+	return likes, nil
 }
 
 func (s *PostService) GetPostLikeCount(postID primitive.ObjectID) (int, error) {
-	count := s.likeTracker.GetPostLikeCount(postID)
-	return count, nil
+	post, err := s.postRepo.FindByID(postID)
+	if err != nil {
+		return 0, err
+	}
+	return post.LikeCount, nil
 }
 
 func (s *PostService) HasUserLiked(postID, userID primitive.ObjectID) bool {
-	return false
+	liked, err := s.likeRepo.Exists(userID, postID)
+	return err == nil && liked
 }
 
 func (s *PostService) GetUserByID(userID primitive.ObjectID) (*models.User, error) {
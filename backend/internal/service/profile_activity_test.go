@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+// activityFixture is a ProfileService over in-memory repositories with one
+// user whose activity the tests seed. Times are whole milliseconds, as
+// cursors keep them.
+type activityFixture struct {
+	service  *ProfileService
+	user     *models.User
+	other    *models.User
+	posts    *memPosts
+	comments *memComments
+	likes    *memLikes
+	follows  *memFollows
+	start    time.Time
+}
+
+func newActivityFixture() *activityFixture {
+	user := newTestUser("student@astanait.edu.kz", "password123", models.RoleStudent)
+	other := newTestUser("friend@astanait.edu.kz", "password123", models.RoleStudent)
+	f := &activityFixture{
+		user:     user,
+		other:    other,
+		posts:    &memPosts{},
+		comments: &memComments{},
+		likes:    &memLikes{},
+		follows:  &memFollows{},
+		start:    time.Now().Add(-time.Hour).Truncate(time.Millisecond),
+	}
+	f.service = NewProfileService(newMemUsers(user, other), f.posts, f.comments, f.likes, f.follows, nil, nil)
+	return f
+}
+
+func (f *activityFixture) at(minute int) time.Time {
+	return f.start.Add(time.Duration(minute) * time.Minute)
+}
+
+func (f *activityFixture) post(author *models.User, minute int) *models.Post {
+	post := models.NewPost("Club fair", "Booths in the atrium", "", models.PostCategory("news"), author.ID, author.DisplayName)
+	post.CreatedAt = f.at(minute)
+	f.posts.posts = append(f.posts.posts, post)
+	return post
+}
+
+func (f *activityFixture) comment(post *models.Post, minute int) *models.Comment {
+	comment := models.NewComment(post.ID, f.user.ID, f.user.DisplayName, "See you there")
+	comment.CreatedAt = f.at(minute)
+	f.comments.comments = append(f.comments.comments, comment)
+	return comment
+}
+
+func (f *activityFixture) like(post *models.Post, minute int) *models.Like {
+	like := models.NewLike(f.user.ID, post.ID)
+	like.CreatedAt = f.at(minute)
+	f.likes.likes = append(f.likes.likes, like)
+	return like
+}
+
+// timeline pages through the whole timeline as viewer and returns the
+// items in order and how many pages it took.
+func (f *activityFixture) timeline(t *testing.T, viewer *primitive.ObjectID, limit int) ([]ActivityItem, int) {
+	t.Helper()
+	var items []ActivityItem
+	cursor := ""
+	for pages := 1; pages <= 20; pages++ {
+		page, err := f.service.GetActivity(viewer, f.user.ID, cursor, limit)
+		if err != nil {
+			t.Fatalf("GetActivity page %d: %v", pages, err)
+		}
+		if len(page.Items) > limit {
+			t.Fatalf("page %d has %d items, limit %d", pages, len(page.Items), limit)
+		}
+		items = append(items, page.Items...)
+		if page.NextCursor == "" {
+			return items, pages
+		}
+		cursor = page.NextCursor
+	}
+	t.Fatal("timeline did not end")
+	return nil, 0
+}
+
+func activityIDs(items []ActivityItem) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+func sameIDs(a, b []primitive.ObjectID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestActivityMergesNewestFirstAcrossPages(t *testing.T) {
+	f := newActivityFixture()
+	othersPost := f.post(f.other, 0)
+
+	own1 := f.post(f.user, 1)
+	like1 := f.like(othersPost, 2)
+	comment1 := f.comment(othersPost, 3)
+	own2 := f.post(f.user, 4)
+	// A comment and a like in the same millisecond are ordered by ID
+	comment2 := f.comment(own2, 5)
+	like2 := f.like(own2, 5)
+	comment3 := f.comment(othersPost, 6)
+
+	want := []primitive.ObjectID{comment3.ID, like2.ID, comment2.ID, own2.ID, comment1.ID, like1.ID, own1.ID}
+	for _, limit := range []int{1, 2, 3, 7, 10} {
+		items, pages := f.timeline(t, nil, limit)
+		if got := activityIDs(items); !sameIDs(got, want) {
+			t.Errorf("limit %d: timeline = %v, want %v", limit, got, want)
+		}
+		if wantPages := (len(want) + limit - 1) / limit; pages != wantPages {
+			t.Errorf("limit %d: %d pages, want %d", limit, pages, wantPages)
+		}
+	}
+
+	items, _ := f.timeline(t, nil, 10)
+	kinds := map[primitive.ObjectID]string{own1.ID: ActivityPost, like1.ID: ActivityLike, comment1.ID: ActivityComment}
+	for _, item := range items {
+		if kind, ok := kinds[item.ID]; ok && item.Kind != kind {
+			t.Errorf("item %s is a %s, want a %s", item.ID.Hex(), item.Kind, kind)
+		}
+		if item.Post == nil || item.Post.ID != item.PostID {
+			t.Errorf("%s %s has no post", item.Kind, item.ID.Hex())
+		}
+	}
+}
+
+func TestActivityDropsArchivedPostsWithoutEndingEarly(t *testing.T) {
+	f := newActivityFixture()
+	visiblePost := f.post(f.other, 0)
+	archivedPost := f.post(f.other, 0)
+	archivedPost.IsArchived = true
+	deletedPost := &models.Post{ID: primitive.NewObjectID()}
+
+	first := f.like(visiblePost, 1)
+	f.post(f.user, 2).IsArchived = true
+	f.like(archivedPost, 3)
+	f.comment(deletedPost, 4)
+	last := f.comment(visiblePost, 5)
+
+	// The second page holds only dropped items but still leads to the third
+	page, err := f.service.GetActivity(nil, f.user.ID, "", 1)
+	if err != nil {
+		t.Fatalf("GetActivity: %v", err)
+	}
+	page, err = f.service.GetActivity(nil, f.user.ID, page.NextCursor, 1)
+	if err != nil {
+		t.Fatalf("GetActivity: %v", err)
+	}
+	if len(page.Items) != 0 || page.NextCursor == "" {
+		t.Errorf("page 2 = %d items, cursor %q; want none and a cursor", len(page.Items), page.NextCursor)
+	}
+
+	items, _ := f.timeline(t, nil, 1)
+	if got := activityIDs(items); !sameIDs(got, []primitive.ObjectID{last.ID, first.ID}) {
+		t.Errorf("timeline = %v, want only the items on the visible post", got)
+	}
+}
+
+func TestActivityHidesPrivateSections(t *testing.T) {
+	f := newActivityFixture()
+	othersPost := f.post(f.other, 0)
+	post := f.post(f.user, 1)
+	comment := f.comment(othersPost, 2)
+	like := f.like(othersPost, 3)
+	f.user.Privacy = models.PrivacySettings{Comments: models.VisibilityFollowers, Likes: models.VisibilityOnlyMe}
+
+	stranger := primitive.NewObjectID()
+	tests := []struct {
+		name   string
+		viewer *primitive.ObjectID
+		want   []primitive.ObjectID
+	}{
+		{"anonymous", nil, []primitive.ObjectID{post.ID}},
+		{"stranger", &stranger, []primitive.ObjectID{post.ID}},
+		{"follower", &f.other.ID, []primitive.ObjectID{comment.ID, post.ID}},
+		{"owner", &f.user.ID, []primitive.ObjectID{like.ID, comment.ID, post.ID}},
+	}
+	f.follows.follows = append(f.follows.follows, models.NewFollow(f.other.ID, f.user.ID))
+	for _, tt := range tests {
+		items, _ := f.timeline(t, tt.viewer, 10)
+		if got := activityIDs(items); !sameIDs(got, tt.want) {
+			t.Errorf("%s sees %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestActivityRejects(t *testing.T) {
+	f := newActivityFixture()
+
+	if _, err := f.service.GetActivity(nil, f.user.ID, "not a cursor", 10); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("bad cursor: err = %v, want ErrInvalidCursor", err)
+	}
+	if _, err := f.service.GetActivity(nil, primitive.NewObjectID(), "", 10); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("unknown user: err = %v, want ErrUserNotFound", err)
+	}
+	f.user.IsActive = false
+	if _, err := f.service.GetActivity(nil, f.user.ID, "", 10); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("deactivated user: err = %v, want ErrUserNotFound", err)
+	}
+}
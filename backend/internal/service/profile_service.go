@@ -0,0 +1,446 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+var (
+	ErrProfileSectionHidden = errors.New("this part of the profile is private")
+	ErrInvalidVisibility    = errors.New("visibility must be everyone, followers or only_me")
+)
+
+const (
+	ActivityPost    = "post"
+	ActivityComment = "comment"
+	ActivityLike    = "like"
+)
+
+// Profile is a user's public profile as one viewer sees it. Sections the
+// viewer may not see are nil.
+type Profile struct {
+	User     *models.User
+	Badges   []models.Badge
+	Visible  map[models.ProfileSection]bool
+	Posts    *ProfilePostPage
+	Comments *ProfileCommentPage
+	Events   *ProfileEventPage
+}
+
+type ProfilePostPage struct {
+	Posts   []*models.Post
+	HasMore bool
+}
+
+// ProfileComment is a comment with the title of the post it is on.
+type ProfileComment struct {
+	Comment   *models.Comment
+	PostTitle string
+}
+
+type ProfileCommentPage struct {
+	Comments []ProfileComment
+	HasMore  bool
+}
+
+type ProfileEventPage struct {
+	Events  []*models.Event
+	HasMore bool
+}
+
+// ActivityItem is a post, comment or like on the timeline. Post is the
+// post written, commented on or liked.
+type ActivityItem struct {
+	Kind      string
+	ID        primitive.ObjectID
+	CreatedAt time.Time
+	PostID    primitive.ObjectID
+	Post      *models.Post
+	Comment   *models.Comment
+}
+
+// ActivityPage is one page of the timeline. NextCursor is empty on the
+// last page.
+type ActivityPage struct {
+	Items      []ActivityItem
+	NextCursor string
+}
+
+type ProfileService struct {
+	userRepo     repository.UserRepository
+	postRepo     repository.PostRepository
+	commentRepo  repository.CommentRepository
+	likeRepo     repository.LikeRepository
+	followRepo   repository.FollowRepository
+	eventRepo    repository.EventRepository
+	attendeeRepo repository.EventAttendeeRepository
+}
+
+func NewProfileService(
+	userRepo repository.UserRepository,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	likeRepo repository.LikeRepository,
+	followRepo repository.FollowRepository,
+	eventRepo repository.EventRepository,
+	attendeeRepo repository.EventAttendeeRepository,
+) *ProfileService {
+	return &ProfileService{
+		userRepo:     userRepo,
+		postRepo:     postRepo,
+		commentRepo:  commentRepo,
+		likeRepo:     likeRepo,
+		followRepo:   followRepo,
+		eventRepo:    eventRepo,
+		attendeeRepo: attendeeRepo,
+	}
+}
+
+// GetProfile returns the profile with the first page of every section the
+// viewer may see. viewerID is nil for anonymous visitors.
+func (s *ProfileService) GetProfile(viewerID *primitive.ObjectID, userID primitive.ObjectID, limit int) (*Profile, error) {
+	user, visible, err := s.load(viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{
+		User:    user,
+		Badges:  user.Badges(time.Now()),
+		Visible: visible,
+	}
+	if visible[models.SectionPosts] {
+		if profile.Posts, err = s.posts(userID, limit, 0); err != nil {
+			return nil, err
+		}
+	}
+	if visible[models.SectionComments] {
+		if profile.Comments, err = s.comments(userID, limit, 0); err != nil {
+			return nil, err
+		}
+	}
+	if visible[models.SectionEvents] {
+		if profile.Events, err = s.events(userID, limit, 0); err != nil {
+			return nil, err
+		}
+	}
+	return profile, nil
+}
+
+func (s *ProfileService) GetPosts(viewerID *primitive.ObjectID, userID primitive.ObjectID, limit, offset int) (*ProfilePostPage, error) {
+	if err := s.checkSection(viewerID, userID, models.SectionPosts); err != nil {
+		return nil, err
+	}
+	return s.posts(userID, limit, offset)
+}
+
+func (s *ProfileService) GetComments(viewerID *primitive.ObjectID, userID primitive.ObjectID, limit, offset int) (*ProfileCommentPage, error) {
+	if err := s.checkSection(viewerID, userID, models.SectionComments); err != nil {
+		return nil, err
+	}
+	return s.comments(userID, limit, offset)
+}
+
+func (s *ProfileService) GetEvents(viewerID *primitive.ObjectID, userID primitive.ObjectID, limit, offset int) (*ProfileEventPage, error) {
+	if err := s.checkSection(viewerID, userID, models.SectionEvents); err != nil {
+		return nil, err
+	}
+	return s.events(userID, limit, offset)
+}
+
+// GetActivity merges the user's posts, comments and likes the viewer may
+// see, newest first.
+func (s *ProfileService) GetActivity(viewerID *primitive.ObjectID, userID primitive.ObjectID, cursor string, limit int) (*ActivityPage, error) {
+	_, visible, err := s.load(viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var before *feedCursor
+	if cursor != "" {
+		if before, err = decodeFeedCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// One more than the page from each source tells whether there is a
+	// next page
+	query := repository.ActivityQuery{UserID: userID, Limit: limit + 1}
+	if before != nil {
+		query.BeforeTime = before.CreatedAt
+		query.BeforeID = before.ID
+	}
+
+	var items []ActivityItem
+	if visible[models.SectionPosts] {
+		posts, err := s.postRepo.FindFeed(repository.FeedQuery{
+			AuthorIDs:  []primitive.ObjectID{userID},
+			BeforeTime: query.BeforeTime,
+			BeforeID:   query.BeforeID,
+			Limit:      query.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			items = append(items, ActivityItem{Kind: ActivityPost, ID: post.ID, CreatedAt: post.CreatedAt, PostID: post.ID, Post: post})
+		}
+	}
+	if visible[models.SectionComments] {
+		comments, err := s.commentRepo.FindByAuthor(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, comment := range comments {
+			items = append(items, ActivityItem{Kind: ActivityComment, ID: comment.ID, CreatedAt: comment.CreatedAt, PostID: comment.PostID, Comment: comment})
+		}
+	}
+	if visible[models.SectionLikes] {
+		likes, err := s.likeRepo.FindByUser(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, like := range likes {
+			items = append(items, ActivityItem{Kind: ActivityLike, ID: like.ID, CreatedAt: like.CreatedAt, PostID: like.PostID})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		}
+		return bytes.Compare(items[i].ID[:], items[j].ID[:]) > 0
+	})
+
+	page := &ActivityPage{}
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[len(items)-1]
+		page.NextCursor = encodeFeedCursor(last.CreatedAt, last.ID)
+	}
+
+	// The cursor is set before items on archived or deleted posts are
+	// dropped, so a short page does not end the timeline early
+	page.Items, err = s.withPosts(items)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Privacy returns the user's settings with the defaults filled in.
+func (s *ProfileService) Privacy(userID primitive.ObjectID) (models.PrivacySettings, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return models.PrivacySettings{}, ErrUserNotFound
+	}
+	return user.Privacy.Normalized(), nil
+}
+
+// UpdatePrivacy changes the sections set in update and keeps the others.
+func (s *ProfileService) UpdatePrivacy(userID primitive.ObjectID, update models.PrivacySettings) (models.PrivacySettings, error) {
+	for _, v := range []models.Visibility{update.Posts, update.Comments, update.Likes, update.Events} {
+		if v != "" && !models.IsValidVisibility(v) {
+			return models.PrivacySettings{}, ErrInvalidVisibility
+		}
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return models.PrivacySettings{}, ErrUserNotFound
+	}
+
+	settings := user.Privacy.Normalized()
+	if update.Posts != "" {
+		settings.Posts = update.Posts
+	}
+	if update.Comments != "" {
+		settings.Comments = update.Comments
+	}
+	if update.Likes != "" {
+		settings.Likes = update.Likes
+	}
+	if update.Events != "" {
+		settings.Events = update.Events
+	}
+	user.Privacy = settings
+	user.UpdatedAt = time.Now()
+
+	if err := s.userRepo.Update(user); err != nil {
+		return models.PrivacySettings{}, err
+	}
+	return settings, nil
+}
+
+// load finds an active user and the sections viewerID may see.
+func (s *ProfileService) load(viewerID *primitive.ObjectID, userID primitive.ObjectID) (*models.User, map[models.ProfileSection]bool, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || !user.IsActive {
+		return nil, nil, ErrUserNotFound
+	}
+
+	owner := viewerID != nil && *viewerID == userID
+	follower := false
+	visible := make(map[models.ProfileSection]bool, len(models.ProfileSections))
+	checkedFollow := false
+	for _, section := range models.ProfileSections {
+		switch user.Privacy.Of(section) {
+		case models.VisibilityEveryone:
+			visible[section] = true
+		case models.VisibilityFollowers:
+			if !owner && viewerID != nil && !checkedFollow {
+				if follower, err = s.followRepo.Exists(*viewerID, userID); err != nil {
+					return nil, nil, err
+				}
+				checkedFollow = true
+			}
+			visible[section] = owner || follower
+		default:
+			visible[section] = owner
+		}
+	}
+	return user, visible, nil
+}
+
+func (s *ProfileService) checkSection(viewerID *primitive.ObjectID, userID primitive.ObjectID, section models.ProfileSection) error {
+	_, visible, err := s.load(viewerID, userID)
+	if err != nil {
+		return err
+	}
+	if !visible[section] {
+		return ErrProfileSectionHidden
+	}
+	return nil
+}
+
+func (s *ProfileService) posts(userID primitive.ObjectID, limit, offset int) (*ProfilePostPage, error) {
+	posts, total, err := s.postRepo.Search(repository.SearchQuery{
+		AuthorID: &userID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ProfilePostPage{Posts: posts, HasMore: offset+len(posts) < total}, nil
+}
+
+func (s *ProfileService) comments(userID primitive.ObjectID, limit, offset int) (*ProfileCommentPage, error) {
+	comments, err := s.commentRepo.FindByAuthor(repository.ActivityQuery{
+		UserID: userID,
+		Limit:  limit + 1,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ProfileCommentPage{Comments: []ProfileComment{}}
+	if len(comments) > limit {
+		comments = comments[:limit]
+		page.HasMore = true
+	}
+
+	postIDs := make([]primitive.ObjectID, 0, len(comments))
+	for _, comment := range comments {
+		postIDs = append(postIDs, comment.PostID)
+	}
+	postByID, err := s.postsByID(postIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		post, ok := postByID[comment.PostID]
+		if !ok {
+			continue
+		}
+		page.Comments = append(page.Comments, ProfileComment{Comment: comment, PostTitle: post.Title})
+	}
+	return page, nil
+}
+
+func (s *ProfileService) events(userID primitive.ObjectID, limit, offset int) (*ProfileEventPage, error) {
+	attendees, err := s.attendeeRepo.FindByUser(userID, limit+1, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ProfileEventPage{Events: []*models.Event{}}
+	if len(attendees) > limit {
+		attendees = attendees[:limit]
+		page.HasMore = true
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(attendees))
+	for _, attendee := range attendees {
+		ids = append(ids, attendee.EventID)
+	}
+	events, err := s.eventRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	eventByID := make(map[primitive.ObjectID]*models.Event, len(events))
+	for _, event := range events {
+		eventByID[event.ID] = event
+	}
+	for _, attendee := range attendees {
+		if event, ok := eventByID[attendee.EventID]; ok {
+			page.Events = append(page.Events, event)
+		}
+	}
+	return page, nil
+}
+
+// withPosts loads the posts comments and likes refer to and drops the items
+// whose post is gone or archived.
+func (s *ProfileService) withPosts(items []ActivityItem) ([]ActivityItem, error) {
+	var postIDs []primitive.ObjectID
+	for _, item := range items {
+		if item.Post == nil {
+			postIDs = append(postIDs, item.PostID)
+		}
+	}
+	postByID, err := s.postsByID(postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ActivityItem, 0, len(items))
+	for _, item := range items {
+		if item.Post == nil {
+			post, ok := postByID[item.PostID]
+			if !ok {
+				continue
+			}
+			item.Post = post
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// postsByID loads the posts that are not archived among ids.
+func (s *ProfileService) postsByID(ids []primitive.ObjectID) (map[primitive.ObjectID]*models.Post, error) {
+	postByID := make(map[primitive.ObjectID]*models.Post, len(ids))
+	if len(ids) == 0 {
+		return postByID, nil
+	}
+	posts, err := s.postRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		if !post.IsArchived {
+			postByID[post.ID] = post
+		}
+	}
+	return postByID, nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+// signIn starts a session for user and returns its tokens.
+func signIn(t *testing.T, f *authFixture, user *models.User) *TokenPair {
+	t.Helper()
+	tokens, err := f.service.IssueTokens(user, SessionClient{IP: "198.51.100.1", UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0"})
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	return tokens
+}
+
+func (f *authFixture) sessionRevoked(t *testing.T, refreshToken string) bool {
+	t.Helper()
+	stored, err := f.refreshTokens.FindByHash(hashToken(refreshToken))
+	if err != nil {
+		t.Fatalf("refresh token not stored: %v", err)
+	}
+	session, err := f.sessions.FindByID(stored.FamilyID)
+	if err != nil {
+		t.Fatalf("session not stored: %v", err)
+	}
+	return session.RevokedAt != nil
+}
+
+func TestRefreshRotates(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "password123", models.RoleStudent)
+	f := newAuthFixture(nil, user)
+	first := signIn(t, f, user)
+
+	second, refreshed, err := f.service.Refresh(first.RefreshToken, SessionClient{IP: "198.51.100.2"})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if refreshed.ID != user.ID {
+		t.Errorf("refreshed user %s, want %s", refreshed.ID.Hex(), user.ID.Hex())
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Fatal("refresh did not issue a new pair")
+	}
+
+	// The new token belongs to the same session, which moved to the new IP
+	old, _ := f.refreshTokens.FindByHash(hashToken(first.RefreshToken))
+	next, _ := f.refreshTokens.FindByHash(hashToken(second.RefreshToken))
+	if old.RotatedAt == nil {
+		t.Error("exchanged token is not marked rotated")
+	}
+	if next.FamilyID != old.FamilyID {
+		t.Error("new token started another session")
+	}
+	session, _ := f.sessions.FindByID(old.FamilyID)
+	if session.IP != "198.51.100.2" {
+		t.Errorf("session IP = %q, want the refreshing client's", session.IP)
+	}
+
+	if _, _, err := f.service.Refresh(second.RefreshToken, SessionClient{}); err != nil {
+		t.Errorf("refreshing again: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "password123", models.RoleStudent)
+	f := newAuthFixture(nil, user)
+	stolen := signIn(t, f, user)
+	other := signIn(t, f, user)
+
+	current, _, err := f.service.Refresh(stolen.RefreshToken, SessionClient{})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// Whoever presents the rotated token again signs the session out
+	if _, _, err := f.service.Refresh(stolen.RefreshToken, SessionClient{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuse: err = %v, want ErrRefreshTokenReused", err)
+	}
+	if !f.sessionRevoked(t, stolen.RefreshToken) {
+		t.Error("session survived reuse")
+	}
+	if _, _, err := f.service.Refresh(current.RefreshToken, SessionClient{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("latest token after reuse: err = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	// Other logins are left alone
+	if _, _, err := f.service.Refresh(other.RefreshToken, SessionClient{}); err != nil {
+		t.Errorf("other session: %v", err)
+	}
+}
+
+// racingRefreshTokens loses every rotation, as when another request
+// exchanged the token between lookup and update.
+type racingRefreshTokens struct {
+	*memRefreshTokens
+}
+
+func (r racingRefreshTokens) MarkRotated(id primitive.ObjectID, at time.Time) (bool, error) {
+	return false, nil
+}
+
+func TestRefreshConcurrentExchange(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "password123", models.RoleStudent)
+	f := newAuthFixture(nil, user)
+	f.service.refreshTokenRepo = racingRefreshTokens{f.refreshTokens}
+	tokens := signIn(t, f, user)
+
+	if _, _, err := f.service.Refresh(tokens.RefreshToken, SessionClient{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("err = %v, want ErrRefreshTokenReused", err)
+	}
+	if !f.sessionRevoked(t, tokens.RefreshToken) {
+		t.Error("session survived a double exchange")
+	}
+}
+
+func TestRefreshRejects(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "password123", models.RoleStudent)
+
+	t.Run("unknown", func(t *testing.T) {
+		f := newAuthFixture(nil, user)
+		if _, _, err := f.service.Refresh("not-a-token", SessionClient{}); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("err = %v, want ErrInvalidRefreshToken", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		f := newAuthFixture(nil, user)
+		tokens := signIn(t, f, user)
+		f.refreshTokens.tokens[0].ExpiresAt = time.Now().Add(-time.Second)
+		if _, _, err := f.service.Refresh(tokens.RefreshToken, SessionClient{}); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("err = %v, want ErrInvalidRefreshToken", err)
+		}
+	})
+
+	t.Run("logged out", func(t *testing.T) {
+		f := newAuthFixture(nil, user)
+		tokens := signIn(t, f, user)
+		if err := f.service.Logout(tokens.RefreshToken); err != nil {
+			t.Fatalf("Logout: %v", err)
+		}
+		if _, _, err := f.service.Refresh(tokens.RefreshToken, SessionClient{}); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("err = %v, want ErrInvalidRefreshToken", err)
+		}
+	})
+
+	t.Run("deactivated", func(t *testing.T) {
+		deactivated := newTestUser("gone@astanait.edu.kz", "password123", models.RoleStudent)
+		f := newAuthFixture(nil, deactivated)
+		tokens := signIn(t, f, deactivated)
+		deactivated.IsActive = false
+		if _, _, err := f.service.Refresh(tokens.RefreshToken, SessionClient{}); !errors.Is(err, ErrAccountDeactivated) {
+			t.Errorf("err = %v, want ErrAccountDeactivated", err)
+		}
+		if !f.sessionRevoked(t, tokens.RefreshToken) {
+			t.Error("deactivated user's session survived")
+		}
+	})
+}
+
+func TestRefreshCreatesSessionForOldFamily(t *testing.T) {
+	user := newTestUser("student@astanait.edu.kz", "password123", models.RoleStudent)
+	f := newAuthFixture(nil, user)
+
+	// A token from before sessions were stored
+	family := primitive.NewObjectID()
+	f.refreshTokens.Create(models.NewRefreshToken(user.ID, family, hashToken("legacy-token"), time.Hour))
+
+	if _, _, err := f.service.Refresh("legacy-token", SessionClient{IP: "198.51.100.1"}); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	session, err := f.sessions.FindByID(family)
+	if err != nil {
+		t.Fatal("no session created for the family")
+	}
+	if session.UserID != user.ID || !session.IsActive(time.Now()) {
+		t.Errorf("session = %+v, want an active one for the user", session)
+	}
+}
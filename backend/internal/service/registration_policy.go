@@ -0,0 +1,153 @@
+package service
+
+import (
+	"log"
+	"strings"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+)
+
+// RegistrationError is a sign-up rejection. Code is stable for clients to
+// branch on; Message is for people.
+type RegistrationError struct {
+	Code    string
+	Message string
+}
+
+func (e *RegistrationError) Error() string {
+	return e.Message
+}
+
+var (
+	ErrEmailDomainNotAllowed = &RegistrationError{Code: "email_domain_not_allowed", Message: "registration is limited to university email addresses"}
+	ErrDisposableEmail       = &RegistrationError{Code: "disposable_email", Message: "disposable email addresses cannot be used"}
+	ErrInviteRequired        = &RegistrationError{Code: "invite_required", Message: "registration is by invitation only"}
+	ErrInvalidInvite         = &RegistrationError{Code: "invalid_invite", Message: "invite code is invalid, already used or expired"}
+	ErrInviteEmailMismatch   = &RegistrationError{Code: "invite_email_mismatch", Message: "this invite was issued for a different email address"}
+)
+
+// RegistrationPolicy decides whether an address may sign up and with which
+// role. A valid invite lifts the domain allow-list but never the block
+// list.
+type RegistrationPolicy struct {
+	allowed    []string
+	blocked    []string
+	roles      map[string]models.UserRole
+	inviteOnly bool
+}
+
+func NewRegistrationPolicy(cfg config.RegistrationConfig) *RegistrationPolicy {
+	p := &RegistrationPolicy{
+		allowed:    normalizeDomains(cfg.AllowedDomains),
+		blocked:    normalizeDomains(cfg.BlockedDomains),
+		roles:      make(map[string]models.UserRole),
+		inviteOnly: cfg.InviteOnly,
+	}
+	for domain, role := range cfg.RoleDomains {
+		if !isRegistrableRole(models.UserRole(role)) {
+			log.Printf("Ignoring registration role %q for %s: only student, alumni and moderator can be granted", role, domain)
+			continue
+		}
+		p.roles[strings.TrimPrefix(strings.ToLower(domain), "@")] = models.UserRole(role)
+	}
+	return p
+}
+
+// Check returns the role a new account for email gets. invite is nil when
+// none was presented; otherwise it must already be known to be usable.
+func (p *RegistrationPolicy) Check(email string, invite *models.Invite) (models.UserRole, error) {
+	domain := emailDomain(email)
+
+	if matchesDomain(domain, p.blocked) {
+		return "", ErrDisposableEmail
+	}
+
+	if invite == nil {
+		if p.inviteOnly {
+			return "", ErrInviteRequired
+		}
+		if len(p.allowed) > 0 && !matchesDomain(domain, p.allowed) {
+			return "", ErrEmailDomainNotAllowed
+		}
+	} else {
+		if invite.Email != "" && !strings.EqualFold(invite.Email, strings.TrimSpace(email)) {
+			return "", ErrInviteEmailMismatch
+		}
+		if invite.Role != "" {
+			return invite.Role, nil
+		}
+	}
+
+	return p.roleFor(domain), nil
+}
+
+// CheckFederated returns the role for an account created on first single
+// sign-on. The identity provider vouches for the user in place of an
+// invite, so only the domain lists apply.
+func (p *RegistrationPolicy) CheckFederated(email string) (models.UserRole, error) {
+	domain := emailDomain(email)
+
+	if matchesDomain(domain, p.blocked) {
+		return "", ErrDisposableEmail
+	}
+	if len(p.allowed) > 0 && !matchesDomain(domain, p.allowed) {
+		return "", ErrEmailDomainNotAllowed
+	}
+	return p.roleFor(domain), nil
+}
+
+// AllowedDomains lists the domains open for sign-up; empty means any.
+func (p *RegistrationPolicy) AllowedDomains() []string {
+	return p.allowed
+}
+
+func (p *RegistrationPolicy) InviteOnly() bool {
+	return p.inviteOnly
+}
+
+// roleFor picks the role of the most specific configured domain, so
+// "staff.astanait.edu.kz" wins over "astanait.edu.kz".
+func (p *RegistrationPolicy) roleFor(domain string) models.UserRole {
+	role, best := models.RoleStudent, -1
+	for configured, r := range p.roles {
+		if matchesDomain(domain, []string{configured}) && len(configured) > best {
+			role, best = r, len(configured)
+		}
+	}
+	return role
+}
+
+func isRegistrableRole(role models.UserRole) bool {
+	return role == models.RoleStudent || role == models.RoleAlumni || role == models.RoleModerator
+}
+
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
+
+// matchesDomain reports whether domain is one of domains or a subdomain of
+// one.
+func matchesDomain(domain string, domains []string) bool {
+	for _, d := range domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "@")
+		if d != "" {
+			normalized = append(normalized, d)
+		}
+	}
+	return normalized
+}
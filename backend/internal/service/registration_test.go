@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
+)
+
+func staffDomainConfig(requireVerification bool) *config.Config {
+	cfg := testConfig()
+	cfg.Mail.RequireVerification = requireVerification
+	cfg.Mail.VerificationTTL = time.Hour
+	cfg.Register = config.RegistrationConfig{
+		AllowedDomains: []string{"astanait.edu.kz"},
+		RoleDomains:    map[string]string{"staff.astanait.edu.kz": "moderator"},
+	}
+	return cfg
+}
+
+func TestRoleDomainWaitsForVerification(t *testing.T) {
+	f := newAuthFixture(staffDomainConfig(true))
+	engine := policy.NewEngine(nil, 0)
+
+	user, err := f.service.Register(dto.RegisterRequest{
+		Email:       "teacher@staff.astanait.edu.kz",
+		Password:    "correct-password",
+		DisplayName: "Teacher",
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if user.Role != models.RoleStudent || user.PendingRole != models.RoleModerator {
+		t.Fatalf("role before verification = %q (pending %q), want student (pending moderator)", user.Role, user.PendingRole)
+	}
+	if engine.Can(user, policy.PostsPin, nil) {
+		t.Error("unverified staff address can pin posts")
+	}
+
+	token := f.service.verificationToken(user, time.Now().Add(time.Hour))
+	verified, err := f.service.VerifyEmail(token)
+	if err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if verified.Role != models.RoleModerator || verified.PendingRole != "" {
+		t.Errorf("role after verification = %q (pending %q), want moderator", verified.Role, verified.PendingRole)
+	}
+}
+
+func TestRoleDomainWithoutVerification(t *testing.T) {
+	f := newAuthFixture(staffDomainConfig(false))
+
+	user, err := f.service.Register(dto.RegisterRequest{
+		Email:       "teacher@staff.astanait.edu.kz",
+		Password:    "correct-password",
+		DisplayName: "Teacher",
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if user.Role != models.RoleModerator {
+		t.Errorf("role = %q, want moderator when verification is off", user.Role)
+	}
+}
+
+func TestAdminRoleChangeDropsPendingRole(t *testing.T) {
+	admin := newTestUser("admin@astanait.edu.kz", "correct-password", models.RoleAdmin)
+	pending := newTestUser("teacher@staff.astanait.edu.kz", "correct-password", models.RoleStudent)
+	pending.EmailVerified = false
+	pending.PendingRole = models.RoleModerator
+	users := newMemUsers(admin, pending)
+
+	if err := NewUserService(users, policy.NewEngine(nil, 0)).UpdateUserRole(admin.ID, pending.ID, models.RoleAlumni); err != nil {
+		t.Fatalf("UpdateUserRole: %v", err)
+	}
+	if pending.Role != models.RoleAlumni || pending.PendingRole != "" {
+		t.Errorf("role = %q (pending %q), want alumni", pending.Role, pending.PendingRole)
+	}
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/search"
+)
+
+const (
+	relatedTagWeight      = 0.35
+	relatedCategoryWeight = 0.15
+	relatedCoLikeWeight   = 0.25
+	relatedTextWeight     = 0.25
+
+	relatedCandidateLimit = 50
+	relatedLikersLimit    = 200
+	relatedCacheTTL       = 15 * time.Minute
+)
+
+type relatedEntry struct {
+	postIDs   []primitive.ObjectID
+	expiresAt time.Time
+}
+
+// RelatedService recommends posts similar to a given post. Scores combine
+// tag overlap, category, co-likes and TF-IDF text similarity and are cached
+// per post until the post changes or the entry expires.
+type RelatedService struct {
+	postRepo repository.PostRepository
+	likeRepo repository.LikeRepository
+	analyzer search.Analyzer
+
+	mu    sync.RWMutex
+	cache map[primitive.ObjectID]relatedEntry
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func NewRelatedService(postRepo repository.PostRepository, likeRepo repository.LikeRepository, analyzer search.Analyzer) *RelatedService {
+	s := &RelatedService{
+		postRepo: postRepo,
+		likeRepo: likeRepo,
+		analyzer: analyzer,
+		cache:    make(map[primitive.ObjectID]relatedEntry),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *RelatedService) GetRelatedPosts(postID primitive.ObjectID, limit int) ([]*models.Post, error) {
+	s.mu.RLock()
+	entry, ok := s.cache[postID]
+	s.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		post, err := s.postRepo.FindByID(postID)
+		if err != nil {
+			return nil, ErrPostNotFound
+		}
+
+		ids, err := s.computeRelated(post)
+		if err != nil {
+			return nil, err
+		}
+
+		entry = relatedEntry{postIDs: ids, expiresAt: time.Now().Add(relatedCacheTTL)}
+		s.mu.Lock()
+		s.cache[postID] = entry
+		s.mu.Unlock()
+	}
+
+	return s.loadPosts(entry.postIDs, limit)
+}
+
+func (s *RelatedService) Invalidate(postID primitive.ObjectID) {
+	s.mu.Lock()
+	delete(s.cache, postID)
+	s.mu.Unlock()
+}
+
+func (s *RelatedService) PostCreated(post *models.Post) {}
+
+func (s *RelatedService) PostUpdated(post *models.Post) {
+	s.Invalidate(post.ID)
+}
+
+func (s *RelatedService) PostDeleted(postID primitive.ObjectID) {
+	s.Invalidate(postID)
+}
+
+// Cleanup drops expired cache entries
+func (s *RelatedService) Cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, entry := range s.cache {
+		if now.After(entry.expiresAt) {
+			delete(s.cache, id)
+		}
+	}
+}
+
+// Stop ends the periodic cache cleanup.
+func (s *RelatedService) Stop() {
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+func (s *RelatedService) run() {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(relatedCacheTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Cleanup()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *RelatedService) loadPosts(ids []primitive.ObjectID, limit int) ([]*models.Post, error) {
+	if len(ids) == 0 {
+		return []*models.Post{}, nil
+	}
+
+	posts, err := s.postRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+
+	result := make([]*models.Post, 0, limit)
+	for _, id := range ids {
+		post, ok := byID[id]
+		if !ok || post.IsArchived {
+			continue
+		}
+		result = append(result, post)
+		if len(result) >= limit {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+func (s *RelatedService) computeRelated(post *models.Post) ([]primitive.ObjectID, error) {
+	candidates := make(map[primitive.ObjectID]*models.Post)
+	add := func(posts []*models.Post) {
+		for _, p := range posts {
+			if p.ID != post.ID && !p.IsArchived {
+				candidates[p.ID] = p
+			}
+		}
+	}
+
+	if len(post.Tags) > 0 {
+		byTags, err := s.postRepo.FindByTags(post.Tags, relatedCandidateLimit)
+		if err != nil {
+			return nil, err
+		}
+		add(byTags)
+	}
+
+	byCategory, err := s.postRepo.FindByCategory(string(post.Category), relatedCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	add(byCategory)
+
+	coLikes := map[primitive.ObjectID]int{}
+	likers, err := s.likeRepo.FindUserIDsByPost(post.ID, relatedLikersLimit)
+	if err == nil && len(likers) > 0 {
+		coLikes, err = s.likeRepo.CountCoLikedPosts(likers, post.ID, relatedCandidateLimit)
+		if err != nil {
+			coLikes = map[primitive.ObjectID]int{}
+		}
+
+		var missing []primitive.ObjectID
+		for id := range coLikes {
+			if _, ok := candidates[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+		if coLiked, err := s.postRepo.FindByIDs(missing); err == nil {
+			add(coLiked)
+		}
+	}
+
+	recent, err := s.postRepo.FindAll(relatedCandidateLimit, 0)
+	if err != nil {
+		return nil, err
+	}
+	add(recent)
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	maxCoLikes := 0
+	for _, count := range coLikes {
+		if count > maxCoLikes {
+			maxCoLikes = count
+		}
+	}
+
+	corpus := make([]*models.Post, 0, len(candidates)+1)
+	corpus = append(corpus, post)
+	for _, c := range candidates {
+		corpus = append(corpus, c)
+	}
+	vectors := tfidfVectors(corpus, s.analyzer)
+	target := vectors[post.ID]
+
+	type scored struct {
+		id    primitive.ObjectID
+		score float64
+		at    time.Time
+	}
+
+	var results []scored
+	for id, candidate := range candidates {
+		score := relatedTagWeight * jaccard(post.Tags, candidate.Tags)
+		if candidate.Category == post.Category {
+			score += relatedCategoryWeight
+		}
+		if maxCoLikes > 0 {
+			score += relatedCoLikeWeight * float64(coLikes[id]) / float64(maxCoLikes)
+		}
+		score += relatedTextWeight * cosine(target, vectors[id])
+
+		if score > 0 {
+			results = append(results, scored{id: id, score: score, at: candidate.CreatedAt})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score == results[j].score {
+			return results[i].at.After(results[j].at)
+		}
+		return results[i].score > results[j].score
+	})
+
+	if len(results) > relatedCandidateLimit {
+		results = results[:relatedCandidateLimit]
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.id)
+	}
+	return ids, nil
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	setA := make(map[string]bool, len(a))
+	for _, tag := range a {
+		setA[normalizeTag(tag)] = true
+	}
+
+	union := len(setA)
+	intersection := 0
+	seen := make(map[string]bool, len(b))
+	for _, tag := range b {
+		tag = normalizeTag(tag)
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		if setA[tag] {
+			intersection++
+		} else {
+			union++
+		}
+	}
+
+	return float64(intersection) / float64(union)
+}
+
+// tfidfVectors builds L2-normalized TF-IDF vectors of title and content,
+// using the given posts as the corpus for document frequencies.
+func tfidfVectors(posts []*models.Post, analyzer search.Analyzer) map[primitive.ObjectID]map[string]float64 {
+	termFreqs := make(map[primitive.ObjectID]map[string]int, len(posts))
+	docFreq := make(map[string]int)
+
+	for _, post := range posts {
+		tf := make(map[string]int)
+		// Title terms count double
+		for _, token := range analyzer.Analyze(post.Title) {
+			tf[token.Term] += 2
+		}
+		for _, token := range analyzer.Analyze(post.Content) {
+			tf[token.Term]++
+		}
+		termFreqs[post.ID] = tf
+		for term := range tf {
+			docFreq[term]++
+		}
+	}
+
+	n := float64(len(posts))
+	vectors := make(map[primitive.ObjectID]map[string]float64, len(posts))
+	for id, tf := range termFreqs {
+		vector := make(map[string]float64, len(tf))
+		norm := 0.0
+		for term, count := range tf {
+			weight := (1 + math.Log(float64(count))) * math.Log(1+n/float64(docFreq[term]))
+			vector[term] = weight
+			norm += weight * weight
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for term := range vector {
+				vector[term] /= norm
+			}
+		}
+		vectors[id] = vector
+	}
+
+	return vectors
+}
+
+func cosine(a, b map[string]float64) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	sum := 0.0
+	for term, weight := range a {
+		sum += weight * b[term]
+	}
+	return sum
+}
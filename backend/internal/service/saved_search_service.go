@@ -0,0 +1,370 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+var (
+	ErrSavedSearchNotFound    = errors.New("saved search not found")
+	ErrTooManySavedSearches   = errors.New("saved search limit reached")
+	ErrInvalidSavedSearchName = errors.New("saved search name must be between 1 and 100 characters")
+	ErrInvalidAlertFrequency  = errors.New("frequency must be instant, daily or weekly")
+)
+
+const (
+	maxSavedSearchesPerUser = 20
+	savedSearchPageSize     = 200
+	// maxPendingAlertPosts caps the matches kept for one digest.
+	maxPendingAlertPosts = 50
+	digestCheckInterval  = 5 * time.Minute
+	digestTitlesShown    = 3
+)
+
+// SavedSearchInput creates or edits a saved search. The filters are fixed
+// at creation; Muted and Frequency may change later.
+type SavedSearchInput struct {
+	Name      string
+	Query     string
+	Category  string
+	Tag       string
+	AuthorID  *primitive.ObjectID
+	Frequency string
+	Muted     *bool
+}
+
+// SavedSearchService stores post searches and alerts their owners about
+// new posts that match. Instant searches notify as soon as a post is
+// created; daily and weekly ones collect matches into a digest.
+type SavedSearchService struct {
+	savedSearchRepo     repository.SavedSearchRepository
+	postRepo            repository.PostRepository
+	searchService       *SearchService
+	notificationService *NotificationService
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func NewSavedSearchService(savedSearchRepo repository.SavedSearchRepository, postRepo repository.PostRepository, searchService *SearchService, notificationService *NotificationService) *SavedSearchService {
+	s := &SavedSearchService{
+		savedSearchRepo:     savedSearchRepo,
+		postRepo:            postRepo,
+		searchService:       searchService,
+		notificationService: notificationService,
+		stopCh:              make(chan struct{}),
+		doneCh:              make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *SavedSearchService) Create(userID primitive.ObjectID, input SavedSearchInput) (*models.SavedSearch, error) {
+	params, err := NormalizePostSearch(PostSearchParams{
+		Query:    input.Query,
+		Category: input.Category,
+		Tag:      input.Tag,
+		AuthorID: input.AuthorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = savedSearchName(params)
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		return nil, ErrInvalidSavedSearchName
+	}
+
+	frequency := models.AlertInstant
+	if input.Frequency != "" {
+		frequency = models.AlertFrequency(input.Frequency)
+		if !frequency.IsValid() {
+			return nil, ErrInvalidAlertFrequency
+		}
+	}
+
+	count, err := s.savedSearchRepo.CountByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxSavedSearchesPerUser {
+		return nil, ErrTooManySavedSearches
+	}
+
+	saved := models.NewSavedSearch(userID, name, frequency)
+	saved.Query = params.Query
+	saved.Category = params.Category
+	saved.Tag = params.Tag
+	saved.AuthorID = params.AuthorID
+	if input.Muted != nil {
+		saved.Muted = *input.Muted
+	}
+
+	if err := s.savedSearchRepo.Create(saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (s *SavedSearchService) List(userID primitive.ObjectID) ([]*models.SavedSearch, error) {
+	return s.savedSearchRepo.FindByUser(userID)
+}
+
+func (s *SavedSearchService) Update(userID, searchID primitive.ObjectID, input SavedSearchInput) (*models.SavedSearch, error) {
+	saved, err := s.owned(userID, searchID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(input.Name); name != "" {
+		if runes := []rune(name); len(runes) > 100 {
+			return nil, ErrInvalidSavedSearchName
+		}
+		saved.Name = name
+	}
+	if input.Frequency != "" {
+		frequency := models.AlertFrequency(input.Frequency)
+		if !frequency.IsValid() {
+			return nil, ErrInvalidAlertFrequency
+		}
+		saved.Frequency = frequency
+	}
+	if input.Muted != nil {
+		saved.Muted = *input.Muted
+	}
+
+	if err := s.savedSearchRepo.Update(saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (s *SavedSearchService) Delete(userID, searchID primitive.ObjectID) error {
+	if _, err := s.owned(userID, searchID); err != nil {
+		return err
+	}
+	return s.savedSearchRepo.Delete(searchID)
+}
+
+// Stop ends the digest loop.
+func (s *SavedSearchService) Stop() {
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+// PostCreated matches the post against every unmuted saved search in the
+// background so post creation is not held up.
+func (s *SavedSearchService) PostCreated(post *models.Post) {
+	go s.matchPost(post)
+}
+
+func (s *SavedSearchService) PostUpdated(post *models.Post) {}
+
+func (s *SavedSearchService) PostDeleted(postID primitive.ObjectID) {}
+
+func (s *SavedSearchService) UserUpdated(user *models.User) {}
+
+func (s *SavedSearchService) UserDeleted(userID primitive.ObjectID) {
+	if err := s.savedSearchRepo.DeleteByUser(userID); err != nil {
+		log.Printf("Failed to delete saved searches of %s: %v", userID.Hex(), err)
+	}
+}
+
+func (s *SavedSearchService) matchPost(post *models.Post) {
+	if post.IsArchived {
+		return
+	}
+
+	matcher := s.searchService.NewPostMatcher(post)
+	// One instant alert per user even if several of their searches match
+	alerted := make(map[primitive.ObjectID]bool)
+
+	var afterID primitive.ObjectID
+	for {
+		searches, err := s.savedSearchRepo.FindActive(string(post.Category), afterID, savedSearchPageSize)
+		if err != nil {
+			log.Printf("Failed to load saved searches for post %s: %v", post.ID.Hex(), err)
+			return
+		}
+
+		for _, saved := range searches {
+			if saved.UserID == post.AuthorID || !matcher.Matches(savedSearchParams(saved)) {
+				continue
+			}
+
+			if saved.Frequency != models.AlertInstant {
+				if err := s.savedSearchRepo.AddPending(saved.ID, post.ID, maxPendingAlertPosts); err != nil {
+					log.Printf("Failed to queue match for saved search %s: %v", saved.ID.Hex(), err)
+				}
+				continue
+			}
+
+			if alerted[saved.UserID] {
+				continue
+			}
+			alerted[saved.UserID] = true
+
+			notification := models.NewNotification(saved.UserID, models.NotificationSavedSearch,
+				fmt.Sprintf("New match for \"%s\"", saved.Name),
+				post.Title,
+				"/post-detail.html?id="+post.ID.Hex(),
+			)
+			if err := s.notificationService.Notify(notification); err != nil {
+				log.Printf("Failed to notify saved search %s: %v", saved.ID.Hex(), err)
+				continue
+			}
+			if err := s.savedSearchRepo.ClearPending(saved.ID, nil, time.Now()); err != nil {
+				log.Printf("Failed to update saved search %s: %v", saved.ID.Hex(), err)
+			}
+		}
+
+		if len(searches) < savedSearchPageSize {
+			return
+		}
+		afterID = searches[len(searches)-1].ID
+	}
+}
+
+func (s *SavedSearchService) run() {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.SendDigests()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// SendDigests notifies daily and weekly searches whose period has passed
+// and that collected matches since.
+func (s *SavedSearchService) SendDigests() {
+	now := time.Now()
+	for _, frequency := range []models.AlertFrequency{models.AlertDaily, models.AlertWeekly} {
+		for {
+			due, err := s.savedSearchRepo.FindDueDigests(frequency, now.Add(-frequency.Interval()), savedSearchPageSize)
+			if err != nil {
+				log.Printf("Failed to load %s saved search digests: %v", frequency, err)
+				break
+			}
+			for _, saved := range due {
+				s.sendDigest(saved, now)
+			}
+			if len(due) < savedSearchPageSize {
+				break
+			}
+		}
+	}
+}
+
+func (s *SavedSearchService) sendDigest(saved *models.SavedSearch, now time.Time) {
+	posts, err := s.postRepo.FindByIDs(saved.PendingPostIDs)
+	if err != nil {
+		log.Printf("Failed to load digest posts for saved search %s: %v", saved.ID.Hex(), err)
+		return
+	}
+
+	var titles []string
+	for _, post := range posts {
+		if !post.IsArchived {
+			titles = append(titles, post.Title)
+		}
+	}
+
+	// Matches that were archived or deleted meanwhile are dropped silently
+	if len(titles) > 0 {
+		body := strings.Join(titles[:min(len(titles), digestTitlesShown)], ", ")
+		if len(titles) > digestTitlesShown {
+			body += fmt.Sprintf(" and %d more", len(titles)-digestTitlesShown)
+		}
+
+		notification := models.NewNotification(saved.UserID, models.NotificationSavedSearch,
+			fmt.Sprintf("%d new matches for \"%s\"", len(titles), saved.Name),
+			body,
+			"/search.html?"+savedSearchQuery(saved),
+		)
+		if err := s.notificationService.Notify(notification); err != nil {
+			log.Printf("Failed to send digest for saved search %s: %v", saved.ID.Hex(), err)
+			return
+		}
+	}
+
+	if err := s.savedSearchRepo.ClearPending(saved.ID, saved.PendingPostIDs, now); err != nil {
+		log.Printf("Failed to clear digest for saved search %s: %v", saved.ID.Hex(), err)
+	}
+}
+
+func (s *SavedSearchService) owned(userID, searchID primitive.ObjectID) (*models.SavedSearch, error) {
+	saved, err := s.savedSearchRepo.FindByID(searchID)
+	if err != nil || saved.UserID != userID {
+		return nil, ErrSavedSearchNotFound
+	}
+	return saved, nil
+}
+
+func savedSearchParams(saved *models.SavedSearch) PostSearchParams {
+	return PostSearchParams{
+		Query:    saved.Query,
+		Category: saved.Category,
+		Tag:      saved.Tag,
+		AuthorID: saved.AuthorID,
+	}
+}
+
+// savedSearchName describes the filters when the user gave no name.
+func savedSearchName(params PostSearchParams) string {
+	var parts []string
+	if params.Query != "" {
+		parts = append(parts, params.Query)
+	}
+	if params.Category != "" {
+		parts = append(parts, "in "+params.Category)
+	}
+	if params.Tag != "" {
+		parts = append(parts, "#"+params.Tag)
+	}
+	if len(parts) == 0 {
+		return "Posts by author"
+	}
+	name := strings.Join(parts, " ")
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	return name
+}
+
+func savedSearchQuery(saved *models.SavedSearch) string {
+	values := url.Values{}
+	if saved.Query != "" {
+		values.Set("q", saved.Query)
+	}
+	if saved.Category != "" {
+		values.Set("category", saved.Category)
+	}
+	if saved.Tag != "" {
+		values.Set("tag", saved.Tag)
+	}
+	if saved.AuthorID != nil {
+		values.Set("author", saved.AuthorID.Hex())
+	}
+	return values.Encode()
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/search"
+)
+
+const (
+	postDocumentKind    = "post"
+	commentDocumentKind = "comment"
+	userDocumentKind    = "user"
+	eventDocumentKind   = "event"
+
+	indexBatchSize = 500
+)
+
+// BuildIndex adds every non-archived post, every comment, every active
+// user and every event to the index.
+func (s *SearchService) BuildIndex() error {
+	if s.index == nil {
+		return nil
+	}
+
+	for offset := 0; ; offset += indexBatchSize {
+		posts, err := s.postRepo.FindAll(indexBatchSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			s.index.Index(postDocument(post))
+		}
+		if len(posts) < indexBatchSize {
+			break
+		}
+	}
+
+	for offset := 0; ; offset += indexBatchSize {
+		comments, err := s.commentRepo.FindAll(indexBatchSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			s.index.Index(commentDocument(comment))
+		}
+		if len(comments) < indexBatchSize {
+			break
+		}
+	}
+
+	for offset := 0; ; offset += indexBatchSize {
+		users, err := s.userRepo.FindAll(indexBatchSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if user.IsActive {
+				s.index.Index(userDocument(user))
+			}
+		}
+		if len(users) < indexBatchSize {
+			break
+		}
+	}
+
+	for offset := 0; ; offset += indexBatchSize {
+		events, err := s.eventRepo.FindAll(indexBatchSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			s.index.Index(eventDocument(event))
+		}
+		if len(events) < indexBatchSize {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (s *SearchService) PostCreated(post *models.Post) {
+	s.PostUpdated(post)
+}
+
+func (s *SearchService) PostUpdated(post *models.Post) {
+	if s.index == nil {
+		return
+	}
+	if post.IsArchived {
+		s.index.Remove(postDocumentKind, post.ID.Hex())
+		return
+	}
+	s.index.Index(postDocument(post))
+}
+
+func (s *SearchService) PostDeleted(postID primitive.ObjectID) {
+	if s.index != nil {
+		s.index.Remove(postDocumentKind, postID.Hex())
+	}
+}
+
+func (s *SearchService) CommentCreated(comment *models.Comment) {
+	s.CommentUpdated(comment)
+}
+
+func (s *SearchService) CommentUpdated(comment *models.Comment) {
+	if s.index != nil {
+		s.index.Index(commentDocument(comment))
+	}
+}
+
+func (s *SearchService) CommentDeleted(commentID primitive.ObjectID) {
+	if s.index != nil {
+		s.index.Remove(commentDocumentKind, commentID.Hex())
+	}
+}
+
+func (s *SearchService) UserUpdated(user *models.User) {
+	if s.index == nil {
+		return
+	}
+	if !user.IsActive {
+		s.index.Remove(userDocumentKind, user.ID.Hex())
+		return
+	}
+	s.index.Index(userDocument(user))
+}
+
+func (s *SearchService) UserDeleted(userID primitive.ObjectID) {
+	if s.index != nil {
+		s.index.Remove(userDocumentKind, userID.Hex())
+	}
+}
+
+func postDocument(post *models.Post) search.Document {
+	return search.Document{
+		ID:   post.ID.Hex(),
+		Kind: postDocumentKind,
+		Fields: []search.Field{
+			{Name: "title", Text: post.Title, Boost: 3},
+			{Name: "tags", Text: strings.Join(post.Tags, " "), Boost: 2},
+			{Name: "description", Text: post.Description, Boost: 1.5},
+			{Name: "content", Text: post.Content},
+		},
+		Filters: map[string][]string{
+			"category": {string(post.Category)},
+			"tags":     normalizeTags(post.Tags),
+			"author":   {post.AuthorID.Hex()},
+		},
+		Time: post.CreatedAt,
+	}
+}
+
+func commentDocument(comment *models.Comment) search.Document {
+	return search.Document{
+		ID:   comment.ID.Hex(),
+		Kind: commentDocumentKind,
+		Fields: []search.Field{
+			{Name: "content", Text: comment.Content},
+		},
+		Filters: map[string][]string{
+			"author": {comment.AuthorID.Hex()},
+			"post":   {comment.PostID.Hex()},
+		},
+		Time: comment.CreatedAt,
+	}
+}
+
+// userDocument indexes public profile fields only; emails stay private.
+func userDocument(user *models.User) search.Document {
+	return search.Document{
+		ID:   user.ID.Hex(),
+		Kind: userDocumentKind,
+		Fields: []search.Field{
+			{Name: "title", Text: user.DisplayName, Boost: 3},
+			{Name: "content", Text: user.Bio},
+		},
+		Time: user.CreatedAt,
+	}
+}
+
+func eventDocument(event *models.Event) search.Document {
+	return search.Document{
+		ID:   event.ID.Hex(),
+		Kind: eventDocumentKind,
+		Fields: []search.Field{
+			{Name: "title", Text: event.Title, Boost: 3},
+			{Name: "description", Text: event.Description, Boost: 1.5},
+			{Name: "content", Text: event.Content},
+			{Name: "location", Text: event.Location},
+		},
+		Filters: map[string][]string{
+			"category": {string(event.Category)},
+			"status":   {string(event.Status)},
+		},
+		Time: event.StartDate,
+	}
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/search"
+)
+
+var (
+	ErrEmptySearch      = errors.New("search query or filter is required")
+	ErrInvalidDateRange = errors.New("invalid date range")
+)
+
+const (
+	maxSearchQueryLength = 200
+	maxSearchLimit       = 50
+	searchSnippetLength  = 200
+)
+
+type PostSearchParams struct {
+	Query    string
+	Category string
+	Tag      string
+	AuthorID *primitive.ObjectID
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+	// Searcher identifies who searched for the query log.
+	Searcher string
+}
+
+// PostSearchHit is a matched post with HTML-escaped highlights; matched
+// words are wrapped in <mark>.
+type PostSearchHit struct {
+	Post           *models.Post
+	TitleHighlight string
+	Snippet        string
+	Score          float64
+}
+
+type PostSearchResult struct {
+	Hits   []PostSearchHit
+	Total  int
+	Limit  int
+	Offset int
+}
+
+// SearchService searches posts, comments, users and events through the
+// in-process index when one is set and falls back to Mongo otherwise.
+type SearchService struct {
+	postRepo    repository.PostRepository
+	commentRepo repository.CommentRepository
+	userRepo    repository.UserRepository
+	eventRepo   repository.EventRepository
+	analyzer    search.Analyzer
+	index       search.SearchIndex
+	recorder    QueryRecorder
+}
+
+func NewSearchService(
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	userRepo repository.UserRepository,
+	eventRepo repository.EventRepository,
+	analyzer search.Analyzer,
+) *SearchService {
+	return &SearchService{
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		userRepo:    userRepo,
+		eventRepo:   eventRepo,
+		analyzer:    analyzer,
+	}
+}
+
+func (s *SearchService) SetIndex(index search.SearchIndex) {
+	s.index = index
+}
+
+// SetRecorder logs the first page of every text search with its total.
+func (s *SearchService) SetRecorder(recorder QueryRecorder) {
+	s.recorder = recorder
+}
+
+func (s *SearchService) record(query, searcher string, offset, total int) {
+	if s.recorder != nil && query != "" && offset == 0 {
+		s.recorder.RecordQuery(models.SearchSourceSearch, query, searcher, total)
+	}
+}
+
+// NormalizePostSearch trims the query, normalizes the tag and rejects
+// searches without any query or filter.
+func NormalizePostSearch(params PostSearchParams) (PostSearchParams, error) {
+	params.Query = strings.TrimSpace(params.Query)
+	if runes := []rune(params.Query); len(runes) > maxSearchQueryLength {
+		params.Query = string(runes[:maxSearchQueryLength])
+	}
+
+	if params.Category != "" && !validCategories[models.PostCategory(params.Category)] {
+		return params, ErrInvalidCategory
+	}
+
+	params.Tag = normalizeTag(params.Tag)
+
+	if params.Query == "" && params.Category == "" && params.Tag == "" && params.AuthorID == nil {
+		return params, ErrEmptySearch
+	}
+	return params, nil
+}
+
+func (s *SearchService) SearchPosts(params PostSearchParams) (*PostSearchResult, error) {
+	params, err := NormalizePostSearch(params)
+	if err != nil {
+		return nil, err
+	}
+	query, tag := params.Query, params.Tag
+
+	if !params.From.IsZero() && !params.To.IsZero() && params.From.After(params.To) {
+		return nil, ErrInvalidDateRange
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset := params.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	var posts []*models.Post
+	var total int
+	if s.index != nil && query != "" {
+		posts, total, err = s.searchIndex(query, params, tag, limit, offset)
+	} else {
+		posts, total, err = s.postRepo.Search(repository.SearchQuery{
+			Text:     query,
+			Category: params.Category,
+			Tag:      tag,
+			AuthorID: params.AuthorID,
+			From:     params.From,
+			To:       params.To,
+			Limit:    limit,
+			Offset:   offset,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.record(query, params.Searcher, offset, total)
+
+	matcher := newTermMatcher(query, s.analyzer)
+	hits := make([]PostSearchHit, 0, len(posts))
+	for _, post := range posts {
+		hits = append(hits, postHit(post, matcher))
+	}
+
+	return &PostSearchResult{
+		Hits:   hits,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+func (s *SearchService) searchIndex(query string, params PostSearchParams, tag string, limit, offset int) ([]*models.Post, int, error) {
+	hits, total := s.index.Search(search.Query{
+		Text:    query,
+		Kinds:   []string{postDocumentKind},
+		Filters: postFilters(params.Category, tag, params.AuthorID),
+		From:    params.From,
+		To:      params.To,
+		Limit:   limit,
+		Offset:  offset,
+	})
+
+	ids := make([]primitive.ObjectID, 0, len(hits))
+	for _, hit := range hits {
+		if id, err := primitive.ObjectIDFromHex(hit.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	found, err := s.postRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.Post, len(found))
+	for _, post := range found {
+		byID[post.ID] = post
+	}
+
+	posts := make([]*models.Post, 0, len(ids))
+	for _, id := range ids {
+		if post, ok := byID[id]; ok && !post.IsArchived {
+			posts = append(posts, post)
+		}
+	}
+	return posts, total, nil
+}
+
+func postHit(post *models.Post, matcher *termMatcher) PostSearchHit {
+	body := post.Content
+	if !matcher.contains(body) && matcher.contains(post.Description) {
+		body = post.Description
+	}
+
+	return PostSearchHit{
+		Post:           post,
+		TitleHighlight: matcher.highlight(post.Title, 0),
+		Snippet:        matcher.highlight(body, searchSnippetLength),
+	}
+}
+
+func postFilters(category, tag string, authorID *primitive.ObjectID) map[string]string {
+	filters := map[string]string{
+		"category": category,
+		"tags":     tag,
+	}
+	if authorID != nil {
+		filters["author"] = authorID.Hex()
+	}
+	return filters
+}
+
+// PostMatcher checks stored searches against one post with the analysis
+// and filters SearchPosts uses. Every query clause must match.
+type PostMatcher struct {
+	doc   search.Document
+	index *search.InvertedIndex
+}
+
+func (s *SearchService) NewPostMatcher(post *models.Post) *PostMatcher {
+	doc := postDocument(post)
+	index := search.NewInvertedIndex(s.analyzer)
+	index.Index(doc)
+	return &PostMatcher{doc: doc, index: index}
+}
+
+// Matches reports whether params, already normalized, select the post.
+func (m *PostMatcher) Matches(params PostSearchParams) bool {
+	query := search.Query{
+		Text:       params.Query,
+		Kinds:      []string{postDocumentKind},
+		Filters:    postFilters(params.Category, params.Tag, params.AuthorID),
+		RequireAll: true,
+	}
+	if !search.MatchesFilters(m.doc, query) {
+		return false
+	}
+	if params.Query == "" {
+		return true
+	}
+	hits, _ := m.index.Search(query)
+	return len(hits) > 0
+}
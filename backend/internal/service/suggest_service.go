@@ -0,0 +1,386 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/search"
+)
+
+const (
+	SuggestKindPost  = "post"
+	SuggestKindTag   = "tag"
+	SuggestKindUser  = "user"
+	SuggestKindQuery = "query"
+
+	defaultSuggestLimit = 8
+	maxSuggestLimit     = 20
+
+	maxLoggedQueryRunes = 100
+	queryLogBufferSize  = 200
+	// maxPendingQueryLogs drops the oldest logs when Mongo is unreachable
+	// for a long time.
+	maxPendingQueryLogs = 10 * queryLogBufferSize
+	popularQueryLimit   = 500
+	// minPopularQuerySearchers keeps a query out of suggestions until this
+	// many different people searched for it, so no suggestion reveals what
+	// one person looked up.
+	minPopularQuerySearchers = 5
+)
+
+var (
+	emailPattern     = regexp.MustCompile(`\S+@\S+`)
+	longDigitPattern = regexp.MustCompile(`\d{6,}`)
+)
+
+// QueryRecorder receives every search so it can be logged. searcher
+// identifies who searched, e.g. by user ID; it is only stored hashed.
+type QueryRecorder interface {
+	RecordQuery(source, query, searcher string, results int)
+}
+
+// SuggestService completes search prefixes from post titles, tags, user
+// display names and popular queries held in an in-memory trie. Titles,
+// tags and names follow post and user changes; popular queries are
+// reloaded from the query log periodically. Queries are logged in
+// batches without user, IP or exact time; a salted hash of the searcher
+// only serves to count distinct people.
+type SuggestService struct {
+	trie      *search.Trie
+	postRepo  repository.PostRepository
+	userRepo  repository.UserRepository
+	queryRepo repository.SearchQueryRepository
+	cfg       config.SearchConfig
+
+	mu        sync.Mutex
+	tagCounts map[string]int
+	postTags  map[primitive.ObjectID][]string
+	popular   map[string]bool
+	pending   []*models.SearchQueryLog
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+func NewSuggestService(postRepo repository.PostRepository, userRepo repository.UserRepository, queryRepo repository.SearchQueryRepository, cfg config.SearchConfig) *SuggestService {
+	if cfg.QueryLogFlushInterval <= 0 {
+		cfg.QueryLogFlushInterval = 10 * time.Second
+	}
+	if cfg.PopularQueryRefresh <= 0 {
+		cfg.PopularQueryRefresh = 10 * time.Minute
+	}
+	if cfg.PopularQueryWindow <= 0 {
+		cfg.PopularQueryWindow = 7 * 24 * time.Hour
+	}
+
+	s := &SuggestService{
+		trie:      search.NewTrie(),
+		postRepo:  postRepo,
+		userRepo:  userRepo,
+		queryRepo: queryRepo,
+		cfg:       cfg,
+		tagCounts: make(map[string]int),
+		postTags:  make(map[primitive.ObjectID][]string),
+		popular:   make(map[string]bool),
+		flushCh:   make(chan struct{}, 1),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Build loads titles, tags, display names and popular queries.
+func (s *SuggestService) Build() error {
+	for offset := 0; ; offset += indexBatchSize {
+		posts, err := s.postRepo.FindAll(indexBatchSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			s.PostUpdated(post)
+		}
+		if len(posts) < indexBatchSize {
+			break
+		}
+	}
+
+	for offset := 0; ; offset += indexBatchSize {
+		users, err := s.userRepo.FindAll(indexBatchSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			s.UserUpdated(user)
+		}
+		if len(users) < indexBatchSize {
+			break
+		}
+	}
+
+	return s.RefreshPopular()
+}
+
+// Suggest returns completions for prefix, heaviest first, with duplicate
+// texts collapsed. The request is logged as a suggest query by searcher.
+func (s *SuggestService) Suggest(prefix, searcher string, limit int) []search.Suggestion {
+	prefix = strings.TrimSpace(prefix)
+	if runes := []rune(prefix); len(runes) > maxSearchQueryLength {
+		prefix = string(runes[:maxSearchQueryLength])
+	}
+	if prefix == "" {
+		return []search.Suggestion{}
+	}
+
+	if limit <= 0 {
+		limit = defaultSuggestLimit
+	}
+	if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+
+	// Over-fetch so collapsing duplicates still fills the page
+	candidates := s.trie.Suggest(prefix, nil, limit*2)
+	suggestions := make([]search.Suggestion, 0, limit)
+	seen := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		key := search.Fold(c.Text)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		suggestions = append(suggestions, c)
+		if len(suggestions) == limit {
+			break
+		}
+	}
+
+	s.RecordQuery(models.SearchSourceSuggest, prefix, searcher, len(suggestions))
+	return suggestions
+}
+
+// RecordQuery buffers an anonymized copy of query for the next flush.
+func (s *SuggestService) RecordQuery(source, query, searcher string, results int) {
+	query = anonymizeQuery(query)
+	if query == "" {
+		return
+	}
+
+	entry := &models.SearchQueryLog{
+		ID:          primitive.NewObjectID(),
+		Query:       query,
+		Source:      source,
+		ResultCount: results,
+		Searcher:    s.searcherHash(searcher),
+		CreatedAt:   time.Now().UTC().Truncate(time.Hour),
+	}
+
+	s.mu.Lock()
+	s.pending = append(s.pending, entry)
+	full := len(s.pending) >= queryLogBufferSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *SuggestService) ZeroResultQueries(source string, since time.Time, limit int) ([]*models.SearchQueryStat, error) {
+	if source == "" {
+		source = models.SearchSourceSearch
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.queryRepo.ZeroResultQueries(source, since, limit)
+}
+
+// RefreshPopular replaces the popular queries in the trie with the ones
+// enough different people searched for within the configured window.
+func (s *SuggestService) RefreshPopular() error {
+	stats, err := s.queryRepo.PopularQueries(time.Now().Add(-s.cfg.PopularQueryWindow), popularQueryLimit)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool, len(stats))
+	for _, stat := range stats {
+		if stat.Searchers < minPopularQuerySearchers {
+			continue
+		}
+		current[stat.Query] = true
+		s.trie.Set(SuggestKindQuery, stat.Query, stat.Query, 2+math.Log1p(float64(stat.Searchers)))
+	}
+
+	s.mu.Lock()
+	previous := s.popular
+	s.popular = current
+	s.mu.Unlock()
+
+	for query := range previous {
+		if !current[query] {
+			s.trie.Remove(SuggestKindQuery, query)
+		}
+	}
+	return nil
+}
+
+// Stop flushes pending query logs and stops the background loop.
+func (s *SuggestService) Stop() {
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+func (s *SuggestService) PostCreated(post *models.Post) {
+	s.PostUpdated(post)
+}
+
+func (s *SuggestService) PostUpdated(post *models.Post) {
+	if post.IsArchived {
+		s.PostDeleted(post.ID)
+		return
+	}
+
+	s.trie.Set(SuggestKindPost, post.ID.Hex(), post.Title, 1+math.Log1p(math.Max(post.PopularityScore, 0)))
+	s.setPostTags(post.ID, normalizeTags(post.Tags))
+}
+
+func (s *SuggestService) PostDeleted(postID primitive.ObjectID) {
+	s.trie.Remove(SuggestKindPost, postID.Hex())
+	s.setPostTags(postID, nil)
+}
+
+func (s *SuggestService) UserUpdated(user *models.User) {
+	if !user.IsActive {
+		s.trie.Remove(SuggestKindUser, user.ID.Hex())
+		return
+	}
+	s.trie.Set(SuggestKindUser, user.ID.Hex(), user.DisplayName, 1+math.Log1p(float64(user.FollowerCount)))
+}
+
+func (s *SuggestService) UserDeleted(userID primitive.ObjectID) {
+	s.trie.Remove(SuggestKindUser, userID.Hex())
+}
+
+// setPostTags moves the tag counts from the post's previous tags to tags
+// and reweights every tag that changed.
+func (s *SuggestService) setPostTags(postID primitive.ObjectID, tags []string) {
+	s.mu.Lock()
+	changed := make(map[string]int)
+	for _, tag := range s.postTags[postID] {
+		s.tagCounts[tag]--
+		changed[tag] = s.tagCounts[tag]
+	}
+	for _, tag := range tags {
+		s.tagCounts[tag]++
+		changed[tag] = s.tagCounts[tag]
+	}
+	if len(tags) > 0 {
+		s.postTags[postID] = tags
+	} else {
+		delete(s.postTags, postID)
+	}
+	for tag, count := range changed {
+		if count <= 0 {
+			delete(s.tagCounts, tag)
+		}
+	}
+	s.mu.Unlock()
+
+	for tag, count := range changed {
+		if count <= 0 {
+			s.trie.Remove(SuggestKindTag, tag)
+		} else {
+			s.trie.Set(SuggestKindTag, tag, tag, 2+math.Log1p(float64(count)))
+		}
+	}
+}
+
+func (s *SuggestService) run() {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(s.cfg.QueryLogFlushInterval)
+	defer ticker.Stop()
+
+	refresh := time.NewTicker(s.cfg.PopularQueryRefresh)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.flushCh:
+			s.flush()
+		case <-refresh.C:
+			if err := s.RefreshPopular(); err != nil {
+				log.Printf("Failed to refresh popular queries: %v", err)
+			}
+		case <-s.stopCh:
+			s.flush()
+			return
+		}
+	}
+}
+
+func (s *SuggestService) flush() {
+	s.mu.Lock()
+	if len(s.pending) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if err := s.queryRepo.InsertMany(batch); err != nil {
+		log.Printf("Failed to flush %d search query logs: %v", len(batch), err)
+
+		// Retry with the next flush, oldest first; past the cap the oldest
+		// logs are dropped
+		s.mu.Lock()
+		s.pending = append(batch, s.pending...)
+		if len(s.pending) > maxPendingQueryLogs {
+			s.pending = s.pending[len(s.pending)-maxPendingQueryLogs:]
+		}
+		s.mu.Unlock()
+	}
+}
+
+// searcherHash is a salted hash of searcher. Without the salt it cannot be
+// matched to a user ID or address.
+func (s *SuggestService) searcherHash(searcher string) string {
+	if searcher == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(s.cfg.QueryLogSalt + "|" + searcher))
+	return hex.EncodeToString(hash[:16])
+}
+
+// anonymizeQuery folds the query and masks emails and long numbers such
+// as phone or student IDs, so the log cannot point back at a person.
+func anonymizeQuery(query string) string {
+	query = search.Fold(strings.Join(strings.Fields(query), " "))
+	query = emailPattern.ReplaceAllString(query, "<email>")
+	query = longDigitPattern.ReplaceAllString(query, "<number>")
+	if runes := []rune(query); len(runes) > maxLoggedQueryRunes {
+		query = string(runes[:maxLoggedQueryRunes])
+	}
+	return query
+}
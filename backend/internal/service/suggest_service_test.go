@@ -0,0 +1,128 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+// queryLogRepo groups logs like the Mongo aggregate does.
+type queryLogRepo struct {
+	repository.SearchQueryRepository
+
+	mu   sync.Mutex
+	logs []*models.SearchQueryLog
+}
+
+func (r *queryLogRepo) InsertMany(logs []*models.SearchQueryLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, logs...)
+	return nil
+}
+
+func (r *queryLogRepo) PopularQueries(since time.Time, limit int) ([]*models.SearchQueryStat, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make(map[string]*models.SearchQueryStat)
+	searchers := make(map[string]map[string]bool)
+	var order []string
+	for _, entry := range r.logs {
+		stat, ok := stats[entry.Query]
+		if !ok {
+			stat = &models.SearchQueryStat{Query: entry.Query}
+			stats[entry.Query] = stat
+			searchers[entry.Query] = make(map[string]bool)
+			order = append(order, entry.Query)
+		}
+		stat.Count++
+		if entry.Searcher != "" {
+			searchers[entry.Query][entry.Searcher] = true
+		}
+		stat.Searchers = len(searchers[entry.Query])
+	}
+
+	result := make([]*models.SearchQueryStat, 0, len(order))
+	for _, query := range order {
+		result = append(result, stats[query])
+	}
+	return result, nil
+}
+
+func newTestSuggestService(repo *queryLogRepo) *SuggestService {
+	return NewSuggestService(nil, nil, repo, config.SearchConfig{
+		QueryLogFlushInterval: time.Hour,
+		PopularQueryRefresh:   time.Hour,
+		QueryLogSalt:          "test-salt",
+	})
+}
+
+func suggestsQuery(s *SuggestService, prefix, query string) bool {
+	for _, suggestion := range s.Suggest(prefix, "checker", 20) {
+		if suggestion.Kind == SuggestKindQuery && suggestion.Text == query {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPopularQueriesNeedDistinctSearchers(t *testing.T) {
+	repo := &queryLogRepo{}
+	s := newTestSuggestService(repo)
+
+	// One person searching over and over is not popular
+	for i := 0; i < 20; i++ {
+		s.RecordQuery(models.SearchSourceSearch, "diploma defense", "user:1", 3)
+	}
+	// Four people are not enough either
+	for _, searcher := range []string{"user:1", "user:2", "ip:198.51.100.1", "ip:198.51.100.2"} {
+		s.RecordQuery(models.SearchSourceSearch, "hackathon teams", searcher, 3)
+	}
+	s.Stop()
+
+	if err := s.RefreshPopular(); err != nil {
+		t.Fatalf("RefreshPopular: %v", err)
+	}
+	if suggestsQuery(s, "dipl", "diploma defense") {
+		t.Error("query from a single searcher was suggested")
+	}
+	if suggestsQuery(s, "hack", "hackathon teams") {
+		t.Error("query from four searchers was suggested")
+	}
+
+	repo.InsertMany([]*models.SearchQueryLog{{Query: "hackathon teams", Searcher: s.searcherHash("user:5")}})
+	if err := s.RefreshPopular(); err != nil {
+		t.Fatalf("RefreshPopular: %v", err)
+	}
+	if !suggestsQuery(s, "hack", "hackathon teams") {
+		t.Error("query from five searchers was not suggested")
+	}
+}
+
+func TestQueryLogHashesSearcher(t *testing.T) {
+	repo := &queryLogRepo{}
+	s := newTestSuggestService(repo)
+	s.RecordQuery(models.SearchSourceSearch, "library hours", "user:650000000000000000000001", 1)
+	s.RecordQuery(models.SearchSourceSearch, "library hours", "user:650000000000000000000001", 1)
+	s.RecordQuery(models.SearchSourceSearch, "library hours", "ip:198.51.100.1", 1)
+	s.Stop()
+
+	if len(repo.logs) != 3 {
+		t.Fatalf("logged %d queries, want 3", len(repo.logs))
+	}
+	first, again, other := repo.logs[0].Searcher, repo.logs[1].Searcher, repo.logs[2].Searcher
+	if first == "" || first == "user:650000000000000000000001" {
+		t.Errorf("searcher stored as %q, want a hash", first)
+	}
+	if first != again {
+		t.Error("same searcher hashed differently")
+	}
+	if first == other {
+		t.Error("different searchers hashed alike")
+	}
+}
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/otp"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/qrcode"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorSetupNotStarted  = errors.New("start two-factor setup first")
+	ErrInvalidTwoFactorCode      = errors.New("invalid authentication code")
+	ErrInvalidTwoFactorChallenge = errors.New("sign-in attempt expired or failed too often; sign in again")
+	ErrTwoFactorRequiredForRole  = errors.New("two-factor authentication is required for your role")
+)
+
+const (
+	recoveryCodeCount = 10
+	// maxTwoFactorAttempts is how many codes one password login may try.
+	maxTwoFactorAttempts = 5
+	// totpSkew accepts the previous and next code to allow for clock drift.
+	totpSkew    = 1
+	qrCodeScale = 6
+)
+
+// TwoFactorSetup is what the user needs to add the account to an
+// authenticator app. Secret is shown for manual entry; QRCode is a PNG of
+// URI.
+type TwoFactorSetup struct {
+	Secret string
+	URI    string
+	QRCode []byte
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool
+	Required          bool
+	RecoveryCodesLeft int
+}
+
+// LoginResult is either a signed-in session or, for users with two-factor
+// authentication, a challenge to answer with a code from
+// CompleteTwoFactorLogin.
+type LoginResult struct {
+	Tokens             *TokenPair
+	User               *models.User
+	Challenge          string
+	ChallengeExpiresIn time.Duration
+}
+
+func (s *AuthService) TwoFactorStatus(userID primitive.ObjectID) (*TwoFactorStatus, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	status := &TwoFactorStatus{
+		Enabled:  user.HasTwoFactor(),
+		Required: user.Role.RequiresTwoFactor(),
+	}
+	if status.Enabled {
+		status.RecoveryCodesLeft = len(user.TwoFactor.RecoveryCodes)
+	}
+	return status, nil
+}
+
+// BeginTwoFactorSetup generates a new secret and keeps it pending until
+// EnableTwoFactor confirms the user's app produces matching codes.
+// Starting again replaces the pending secret.
+func (s *AuthService) BeginTwoFactorSetup(userID primitive.ObjectID) (*TwoFactorSetup, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.HasTwoFactor() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := otp.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.sealSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	uri := otp.KeyURI(s.twoFactorIssuer(), user.Email, secret)
+	png, err := qrcode.PNG(uri, qrcode.Medium, qrCodeScale)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TwoFactor = &models.TwoFactor{PendingSecret: sealed}
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{Secret: secret, URI: uri, QRCode: png}, nil
+}
+
+// EnableTwoFactor confirms setup with a code from the app and returns the
+// recovery codes, which are shown this once. Every existing session is
+// signed out, since none of them passed a second factor, and the caller
+// gets tokens for a fresh one that did.
+func (s *AuthService) EnableTwoFactor(userID primitive.ObjectID, code string, client SessionClient) (*TokenPair, []string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, nil, ErrUserNotFound
+	}
+	if user.HasTwoFactor() {
+		return nil, nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactor == nil || user.TwoFactor.PendingSecret == "" {
+		return nil, nil, ErrTwoFactorSetupNotStarted
+	}
+
+	secret, err := s.openSecret(user.TwoFactor.PendingSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	counter, ok := otp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	user.TwoFactor = &models.TwoFactor{
+		Enabled:       true,
+		Secret:        user.TwoFactor.PendingSecret,
+		RecoveryCodes: hashes,
+		LastCounter:   counter,
+		EnabledAt:     &now,
+	}
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.RevokeAllSessions(user.ID); err != nil {
+		return nil, nil, err
+	}
+	tokens, err := s.IssueTokens(user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, codes, nil
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a code.
+// Roles that require 2FA cannot turn it off.
+func (s *AuthService) DisableTwoFactor(userID primitive.ObjectID, password, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.HasTwoFactor() {
+		return ErrTwoFactorNotEnabled
+	}
+	if user.Role.RequiresTwoFactor() {
+		return ErrTwoFactorRequiredForRole
+	}
+	if !user.ValidatePassword(password) {
+		return ErrInvalidCredentials
+	}
+
+	ok, err := s.verifySecondFactor(user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	user.TwoFactor = nil
+	return s.userRepo.Update(user)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code. It takes a code
+// from the app, not a recovery code, so a leaked sheet cannot renew itself.
+func (s *AuthService) RegenerateRecoveryCodes(userID primitive.ObjectID, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.HasTwoFactor() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	ok, err := s.verifyTOTP(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.TwoFactor.RecoveryCodes = hashes
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ResetTwoFactor is the admin escape hatch for a user who lost both their
+// device and recovery codes. The user is signed out everywhere; staff
+// accounts must enroll again before using their role.
+func (s *AuthService) ResetTwoFactor(adminID, userID primitive.ObjectID) error {
+	admin, err := s.userRepo.FindByID(adminID)
+	if err != nil || s.policy.Authorize(admin, policy.UsersReset2FA, nil) != nil {
+		return ErrPermissionDenied
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.HasTwoFactor() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	user.TwoFactor = nil
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	return s.RevokeAllSessions(user.ID)
+}
+
+// CompleteTwoFactorLogin answers the challenge from Login with a TOTP or
+// recovery code. Each challenge allows a few attempts and is single use.
+func (s *AuthService) CompleteTwoFactorLogin(challengeToken, code string, client SessionClient) (*TokenPair, *models.User, error) {
+	challenge, err := s.twoFactorChallengeRepo.FindByHash(hashToken(challengeToken))
+	if err != nil || challenge.UsedAt != nil || !challenge.ExpiresAt.After(time.Now()) {
+		return nil, nil, ErrInvalidTwoFactorChallenge
+	}
+
+	allowed, err := s.twoFactorChallengeRepo.AddAttempt(challenge.ID, maxTwoFactorAttempts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !allowed {
+		return nil, nil, ErrInvalidTwoFactorChallenge
+	}
+
+	user, err := s.userRepo.FindByID(challenge.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidTwoFactorChallenge
+	}
+	if !user.IsActive {
+		return nil, nil, ErrAccountDeactivated
+	}
+	if !user.HasTwoFactor() {
+		// Reset by an admin after the password step
+		return nil, nil, ErrInvalidTwoFactorChallenge
+	}
+
+	ok, err := s.verifySecondFactor(user, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrInvalidTwoFactorCode
+	}
+
+	used, err := s.twoFactorChallengeRepo.MarkUsed(challenge.ID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !used {
+		return nil, nil, ErrInvalidTwoFactorChallenge
+	}
+
+	tokens, err := s.IssueTokens(user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+func (s *AuthService) newTwoFactorChallenge(user *models.User) (*LoginResult, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := s.twoFactorChallengeTTL()
+	if err := s.twoFactorChallengeRepo.Create(models.NewTwoFactorChallenge(user.ID, hashToken(token), ttl)); err != nil {
+		return nil, err
+	}
+	return &LoginResult{Challenge: token, ChallengeExpiresIn: ttl}, nil
+}
+
+// verifySecondFactor accepts a TOTP code or an unused recovery code, which
+// is then consumed.
+func (s *AuthService) verifySecondFactor(user *models.User, code string) (bool, error) {
+	if ok, err := s.verifyTOTP(user, code); ok || err != nil {
+		return ok, err
+	}
+
+	hash := s.hashRecoveryCode(code)
+	for i, stored := range user.TwoFactor.RecoveryCodes {
+		if hmac.Equal([]byte(stored), []byte(hash)) {
+			codes := user.TwoFactor.RecoveryCodes
+			user.TwoFactor.RecoveryCodes = append(codes[:i:i], codes[i+1:]...)
+			return true, s.userRepo.Update(user)
+		}
+	}
+	return false, nil
+}
+
+// verifyTOTP checks a code from the app. A code is accepted once, so one
+// seen over the user's shoulder cannot be replayed within its window.
+func (s *AuthService) verifyTOTP(user *models.User, code string) (bool, error) {
+	secret, err := s.openSecret(user.TwoFactor.Secret)
+	if err != nil {
+		return false, err
+	}
+
+	counter, ok := otp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok || counter <= user.TwoFactor.LastCounter {
+		return false, nil
+	}
+
+	user.TwoFactor.LastCounter = counter
+	return true, s.userRepo.Update(user)
+}
+
+// newRecoveryCodes returns codes like "abcde-fgh23" and the hashes to
+// store for them.
+func (s *AuthService) newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(base32.StdEncoding.EncodeToString(raw))[:10]
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+		hashes[i] = s.hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode keys the hash on the server secret, so leaked hashes
+// alone cannot be brute forced.
+func (s *AuthService) hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	mac := hmac.New(sha256.New, s.derivedKey("two-factor-recovery"))
+	mac.Write([]byte(normalized))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sealSecret encrypts a TOTP secret for storage. The key derives from the
+// JWT secret; rotating that makes existing enrolments unreadable and they
+// need an admin reset.
+func (s *AuthService) sealSecret(secret string) (string, error) {
+	gcm, err := s.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *AuthService) openSecret(sealed string) (string, error) {
+	gcm, err := s.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < gcm.NonceSize() {
+		return "", errors.New("malformed two-factor secret")
+	}
+	secret, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+func (s *AuthService) secretCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.derivedKey("two-factor-secret"))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *AuthService) twoFactorIssuer() string {
+	if s.cfg.Auth.TwoFactorIssuer == "" {
+		return "AITU Fanpage"
+	}
+	return s.cfg.Auth.TwoFactorIssuer
+}
+
+func (s *AuthService) twoFactorChallengeTTL() time.Duration {
+	if s.cfg.Auth.TwoFactorChallengeTTL <= 0 {
+		return 5 * time.Minute
+	}
+	return s.cfg.Auth.TwoFactorChallengeTTL
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/dto"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/otp"
+)
+
+// enrollTwoFactor turns on 2FA for user and returns the plain secret.
+func enrollTwoFactor(t *testing.T, f *authFixture, user *models.User) string {
+	t.Helper()
+	setup, err := f.service.BeginTwoFactorSetup(user.ID)
+	if err != nil {
+		t.Fatalf("BeginTwoFactorSetup: %v", err)
+	}
+	// Confirm with the previous step's code so the current one is still
+	// unused for the test
+	code, _ := otp.Code(setup.Secret, otp.Counter(time.Now())-1)
+	if _, _, err := f.service.EnableTwoFactor(user.ID, code, SessionClient{}); err != nil {
+		t.Fatalf("EnableTwoFactor: %v", err)
+	}
+	return setup.Secret
+}
+
+func TestTOTPCodeIsSingleUse(t *testing.T) {
+	user := newTestUser("moderator@astanait.edu.kz", "correct-password", models.RoleModerator)
+	f := newAuthFixture(nil, user)
+	secret := enrollTwoFactor(t, f, user)
+
+	code, _ := otp.Code(secret, otp.Counter(time.Now()))
+	if ok, err := f.service.verifyTOTP(user, code); err != nil || !ok {
+		t.Fatalf("first use: ok = %v, err = %v", ok, err)
+	}
+	if ok, _ := f.service.verifyTOTP(user, code); ok {
+		t.Error("code accepted twice")
+	}
+
+	// Neither is an earlier code still inside the skew window
+	previous, _ := otp.Code(secret, otp.Counter(time.Now())-1)
+	if ok, _ := f.service.verifyTOTP(user, previous); ok {
+		t.Error("code older than the last used one accepted")
+	}
+}
+
+func TestTwoFactorLoginRejectsReplayedCode(t *testing.T) {
+	user := newTestUser("moderator@astanait.edu.kz", "correct-password", models.RoleModerator)
+	f := newAuthFixture(nil, user)
+	secret := enrollTwoFactor(t, f, user)
+	client := SessionClient{IP: "198.51.100.1"}
+
+	login := func() string {
+		t.Helper()
+		result, err := f.service.Login(dto.LoginRequest{Email: user.Email, Password: "correct-password"}, client)
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		if result.Tokens != nil || result.Challenge == "" {
+			t.Fatal("login with 2FA issued tokens instead of a challenge")
+		}
+		return result.Challenge
+	}
+
+	code, _ := otp.Code(secret, otp.Counter(time.Now()))
+	if _, _, err := f.service.CompleteTwoFactorLogin(login(), code, client); err != nil {
+		t.Fatalf("CompleteTwoFactorLogin: %v", err)
+	}
+	if session := f.sessions.sessions[len(f.sessions.sessions)-1]; !session.TwoFactor {
+		t.Error("session after 2FA login not marked two-factor")
+	}
+
+	_, _, err := f.service.CompleteTwoFactorLogin(login(), code, client)
+	if !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("replayed code: err = %v, want ErrInvalidTwoFactorCode", err)
+	}
+}
+
+func TestTwoFactorChallengeIsSingleUse(t *testing.T) {
+	user := newTestUser("moderator@astanait.edu.kz", "correct-password", models.RoleModerator)
+	f := newAuthFixture(nil, user)
+	secret := enrollTwoFactor(t, f, user)
+
+	result, err := f.service.Login(dto.LoginRequest{Email: user.Email, Password: "correct-password"}, SessionClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := otp.Code(secret, otp.Counter(time.Now()))
+	if _, _, err := f.service.CompleteTwoFactorLogin(result.Challenge, code, SessionClient{}); err != nil {
+		t.Fatalf("CompleteTwoFactorLogin: %v", err)
+	}
+
+	next, _ := otp.Code(secret, otp.Counter(time.Now())+1)
+	if _, _, err := f.service.CompleteTwoFactorLogin(result.Challenge, next, SessionClient{}); !errors.Is(err, ErrInvalidTwoFactorChallenge) {
+		t.Errorf("reused challenge: err = %v, want ErrInvalidTwoFactorChallenge", err)
+	}
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/search"
+)
+
+var ErrInvalidSearchType = errors.New("invalid search type")
+
+const (
+	SearchTypeAll      = "all"
+	SearchTypePosts    = "posts"
+	SearchTypeComments = "comments"
+	SearchTypeUsers    = "users"
+	SearchTypeEvents   = "events"
+
+	// groupSearchLimit caps each group when every type is searched at once.
+	groupSearchLimit = 5
+	// searchCandidateLimit caps the rows fetched per type from Mongo when
+	// there is no in-process index to rank against.
+	searchCandidateLimit = 100
+)
+
+var searchTypeKinds = map[string]string{
+	SearchTypePosts:    postDocumentKind,
+	SearchTypeComments: commentDocumentKind,
+	SearchTypeUsers:    userDocumentKind,
+	SearchTypeEvents:   eventDocumentKind,
+}
+
+type UnifiedSearchParams struct {
+	Query  string
+	Type   string
+	Limit  int
+	Offset int
+	// Searcher identifies who searched for the query log.
+	Searcher string
+}
+
+type CommentSearchHit struct {
+	Comment   *models.Comment
+	PostTitle string
+	Snippet   string
+	Score     float64
+}
+
+// UserSearchHit carries the user model for mapping; callers must expose
+// public profile fields only.
+type UserSearchHit struct {
+	User          *models.User
+	NameHighlight string
+	BioSnippet    string
+	Score         float64
+}
+
+type EventSearchHit struct {
+	Event          *models.Event
+	TitleHighlight string
+	Snippet        string
+	Score          float64
+}
+
+// UnifiedSearchResult groups hits by type. Scores come from one index, so
+// they are comparable across groups.
+type UnifiedSearchResult struct {
+	Query        string
+	Type         string
+	Limit        int
+	Offset       int
+	Posts        []PostSearchHit
+	PostTotal    int
+	Comments     []CommentSearchHit
+	CommentTotal int
+	Users        []UserSearchHit
+	UserTotal    int
+	Events       []EventSearchHit
+	EventTotal   int
+}
+
+// Search looks up posts, comments, users and events matching the query.
+// With type "all" each group holds its top few hits and the offset is
+// ignored; a single type pages like SearchPosts.
+func (s *SearchService) Search(params UnifiedSearchParams) (*UnifiedSearchResult, error) {
+	query := strings.TrimSpace(params.Query)
+	if runes := []rune(query); len(runes) > maxSearchQueryLength {
+		query = string(runes[:maxSearchQueryLength])
+	}
+	if query == "" {
+		return nil, ErrEmptySearch
+	}
+
+	searchType := params.Type
+	if searchType == "" {
+		searchType = SearchTypeAll
+	}
+
+	var types []string
+	limit, offset := groupSearchLimit, 0
+	if searchType == SearchTypeAll {
+		types = []string{SearchTypePosts, SearchTypeComments, SearchTypeUsers, SearchTypeEvents}
+	} else {
+		if _, ok := searchTypeKinds[searchType]; !ok {
+			return nil, ErrInvalidSearchType
+		}
+		types = []string{searchType}
+
+		limit = params.Limit
+		if limit <= 0 {
+			limit = 20
+		}
+		if limit > maxSearchLimit {
+			limit = maxSearchLimit
+		}
+		if params.Offset > 0 {
+			offset = params.Offset
+		}
+	}
+
+	index, err := s.rankingIndex(query, types)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UnifiedSearchResult{
+		Query:  query,
+		Type:   searchType,
+		Limit:  limit,
+		Offset: offset,
+	}
+	matcher := newTermMatcher(query, s.analyzer)
+
+	for _, t := range types {
+		hits, total := index.Search(search.Query{
+			Text:   query,
+			Kinds:  []string{searchTypeKinds[t]},
+			Limit:  limit,
+			Offset: offset,
+		})
+
+		switch t {
+		case SearchTypePosts:
+			result.Posts, err = s.postSearchHits(hits, matcher)
+			result.PostTotal = total
+		case SearchTypeComments:
+			result.Comments, err = s.commentSearchHits(hits, matcher)
+			result.CommentTotal = total
+		case SearchTypeUsers:
+			result.Users, err = s.userSearchHits(hits, matcher)
+			result.UserTotal = total
+		case SearchTypeEvents:
+			result.Events, err = s.eventSearchHits(hits, matcher)
+			result.EventTotal = total
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.record(query, params.Searcher, offset, result.PostTotal+result.CommentTotal+result.UserTotal+result.EventTotal)
+
+	return result, nil
+}
+
+// rankingIndex returns the in-process index, or without one builds a
+// throwaway index from Mongo candidates so every type is still ranked
+// by the same BM25 scoring.
+func (s *SearchService) rankingIndex(query string, types []string) (search.SearchIndex, error) {
+	if s.index != nil {
+		return s.index, nil
+	}
+
+	index := search.NewInvertedIndex(s.analyzer)
+	for _, t := range types {
+		switch t {
+		case SearchTypePosts:
+			posts, _, err := s.postRepo.Search(repository.SearchQuery{
+				Text:  query,
+				Limit: searchCandidateLimit,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, post := range posts {
+				index.Index(postDocument(post))
+			}
+		case SearchTypeComments:
+			comments, err := s.commentRepo.Search(query, searchCandidateLimit)
+			if err != nil {
+				return nil, err
+			}
+			for _, comment := range comments {
+				index.Index(commentDocument(comment))
+			}
+		case SearchTypeUsers:
+			users, err := s.userRepo.Search(query, searchCandidateLimit)
+			if err != nil {
+				return nil, err
+			}
+			for _, user := range users {
+				if user.IsActive {
+					index.Index(userDocument(user))
+				}
+			}
+		case SearchTypeEvents:
+			events, err := s.eventRepo.Search(query, searchCandidateLimit)
+			if err != nil {
+				return nil, err
+			}
+			for _, event := range events {
+				index.Index(eventDocument(event))
+			}
+		}
+	}
+	return index, nil
+}
+
+func (s *SearchService) postSearchHits(hits []search.Hit, matcher *termMatcher) ([]PostSearchHit, error) {
+	ids, scores := hitIDs(hits)
+	posts, err := s.postRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+
+	result := make([]PostSearchHit, 0, len(ids))
+	for _, id := range ids {
+		post, ok := byID[id]
+		if !ok || post.IsArchived {
+			continue
+		}
+		hit := postHit(post, matcher)
+		hit.Score = scores[id]
+		result = append(result, hit)
+	}
+	return result, nil
+}
+
+// commentSearchHits drops comments whose post is gone or archived, since
+// those are no longer reachable.
+func (s *SearchService) commentSearchHits(hits []search.Hit, matcher *termMatcher) ([]CommentSearchHit, error) {
+	ids, scores := hitIDs(hits)
+	comments, err := s.commentRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	postIDs := make([]primitive.ObjectID, 0, len(comments))
+	for _, comment := range comments {
+		postIDs = append(postIDs, comment.PostID)
+	}
+	posts, err := s.postRepo.FindByIDs(postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	postByID := make(map[primitive.ObjectID]*models.Post, len(posts))
+	for _, post := range posts {
+		postByID[post.ID] = post
+	}
+	byID := make(map[primitive.ObjectID]*models.Comment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+
+	result := make([]CommentSearchHit, 0, len(ids))
+	for _, id := range ids {
+		comment, ok := byID[id]
+		if !ok {
+			continue
+		}
+		post, ok := postByID[comment.PostID]
+		if !ok || post.IsArchived {
+			continue
+		}
+		result = append(result, CommentSearchHit{
+			Comment:   comment,
+			PostTitle: post.Title,
+			Snippet:   matcher.highlight(comment.Content, searchSnippetLength),
+			Score:     scores[id],
+		})
+	}
+	return result, nil
+}
+
+// userSearchHits hides deactivated accounts even if the index is stale.
+func (s *SearchService) userSearchHits(hits []search.Hit, matcher *termMatcher) ([]UserSearchHit, error) {
+	ids, scores := hitIDs(hits)
+	users, err := s.userRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	result := make([]UserSearchHit, 0, len(ids))
+	for _, id := range ids {
+		user, ok := byID[id]
+		if !ok || !user.IsActive {
+			continue
+		}
+		result = append(result, UserSearchHit{
+			User:          user,
+			NameHighlight: matcher.highlight(user.DisplayName, 0),
+			BioSnippet:    matcher.highlight(user.Bio, searchSnippetLength),
+			Score:         scores[id],
+		})
+	}
+	return result, nil
+}
+
+func (s *SearchService) eventSearchHits(hits []search.Hit, matcher *termMatcher) ([]EventSearchHit, error) {
+	ids, scores := hitIDs(hits)
+	events, err := s.eventRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.Event, len(events))
+	for _, event := range events {
+		byID[event.ID] = event
+	}
+
+	result := make([]EventSearchHit, 0, len(ids))
+	for _, id := range ids {
+		event, ok := byID[id]
+		if !ok {
+			continue
+		}
+
+		body := event.Content
+		if !matcher.contains(body) && matcher.contains(event.Description) {
+			body = event.Description
+		}
+		result = append(result, EventSearchHit{
+			Event:          event,
+			TitleHighlight: matcher.highlight(event.Title, 0),
+			Snippet:        matcher.highlight(body, searchSnippetLength),
+			Score:          scores[id],
+		})
+	}
+	return result, nil
+}
+
+func hitIDs(hits []search.Hit) ([]primitive.ObjectID, map[primitive.ObjectID]float64) {
+	ids := make([]primitive.ObjectID, 0, len(hits))
+	scores := make(map[primitive.ObjectID]float64, len(hits))
+	for _, hit := range hits {
+		if id, err := primitive.ObjectIDFromHex(hit.ID); err == nil {
+			ids = append(ids, id)
+			scores[id] = hit.Score
+		}
+	}
+	return ids, scores
+}
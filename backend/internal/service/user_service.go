@@ -2,12 +2,12 @@ package service
 
 import (
 	"errors"
-	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/models"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/policy"
 	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
 )
 
@@ -17,18 +17,71 @@ var (
 	ErrCannotDeactivateSelf = errors.New("cannot deactivate your own account")
 )
 
+// UserListener is notified after a user's public profile or active state
+// changes.
+type UserListener interface {
+	UserUpdated(user *models.User)
+	UserDeleted(userID primitive.ObjectID)
+}
+
 type UserService struct {
-	userRepo repository.UserRepository
+	userRepo  repository.UserRepository
+	policy    *policy.Engine
+	listeners []UserListener
+}
+
+func NewUserService(userRepo repository.UserRepository, engine *policy.Engine) *UserService {
+	return &UserService{userRepo: userRepo, policy: engine}
 }
 
-func NewUserService(userRepo repository.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func (s *UserService) AddListener(listener UserListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *UserService) notifyUpdated(user *models.User) {
+	for _, listener := range s.listeners {
+		listener.UserUpdated(user)
+	}
+}
+
+func (s *UserService) notifyDeleted(userID primitive.ObjectID) {
+	for _, listener := range s.listeners {
+		listener.UserDeleted(userID)
+	}
 }
 
 func (s *UserService) GetUserByID(userID primitive.ObjectID) (*models.User, error) {
 	return s.userRepo.FindByID(userID)
 }
 
+// Authorize checks whether the user may perform perm on res.
+func (s *UserService) Authorize(userID primitive.ObjectID, perm policy.Permission, res *policy.Resource) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return ErrPermissionDenied
+	}
+	if err := s.policy.Authorize(user, perm, res); err != nil {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// AuthorizeSession is Authorize for a session that may or may not have
+// passed a TOTP check.
+func (s *UserService) AuthorizeSession(userID primitive.ObjectID, perm policy.Permission, res *policy.Resource, twoFactor bool) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return ErrPermissionDenied
+	}
+	if err := s.policy.AuthorizeSession(user, perm, res, twoFactor); err != nil {
+		if err == policy.ErrTwoFactorRequired {
+			return err
+		}
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
 func (s *UserService) GetAllUsers(limit, offset int) ([]*models.User, error) {
 	return s.userRepo.FindAll(limit, offset)
 }
@@ -39,7 +92,7 @@ func (s *UserService) UpdateUserRole(adminID, targetUserID primitive.ObjectID, n
 		return err
 	}
 
-	if !admin.CanManageUsers() {
+	if err := s.policy.Authorize(admin, policy.UsersManage, &policy.Resource{Type: "user", ID: targetUserID}); err != nil {
 		return ErrPermissionDenied
 	}
 
@@ -55,7 +108,8 @@ func (s *UserService) UpdateUserRole(adminID, targetUserID primitive.ObjectID, n
 	// Validate role
 	switch newRole {
 	case models.RoleAdmin, models.RoleStudent, models.RoleAlumni, models.RoleModerator:
-		targetUser.Role = newRole
+		// An admin's choice replaces whatever sign-up held back
+		targetUser.Role, targetUser.PendingRole = newRole, ""
 	default:
 		return ErrInvalidRole
 	}
@@ -69,7 +123,7 @@ func (s *UserService) DeactivateUser(adminID, targetUserID primitive.ObjectID) e
 		return err
 	}
 
-	if !admin.CanManageUsers() {
+	if err := s.policy.Authorize(admin, policy.UsersManage, &policy.Resource{Type: "user", ID: targetUserID}); err != nil {
 		return ErrPermissionDenied
 	}
 
@@ -83,7 +137,12 @@ func (s *UserService) DeactivateUser(adminID, targetUserID primitive.ObjectID) e
 	}
 
 	targetUser.IsActive = false
-	return s.userRepo.Update(targetUser)
+	if err := s.userRepo.Update(targetUser); err != nil {
+		return err
+	}
+
+	s.notifyUpdated(targetUser)
+	return nil
 }
 
 func (s *UserService) ActivateUser(adminID, targetUserID primitive.ObjectID) error {
@@ -92,7 +151,7 @@ func (s *UserService) ActivateUser(adminID, targetUserID primitive.ObjectID) err
 		return err
 	}
 
-	if !admin.CanManageUsers() {
+	if err := s.policy.Authorize(admin, policy.UsersManage, &policy.Resource{Type: "user", ID: targetUserID}); err != nil {
 		return ErrPermissionDenied
 	}
 
@@ -102,24 +161,12 @@ func (s *UserService) ActivateUser(adminID, targetUserID primitive.ObjectID) err
 	}
 
 	targetUser.IsActive = true
-	return s.userRepo.Update(targetUser)
-}
-
-func (s *UserService) DeleteUser(adminID, targetUserID primitive.ObjectID) error {
-	admin, err := s.userRepo.FindByID(adminID)
-	if err != nil {
+	if err := s.userRepo.Update(targetUser); err != nil {
 		return err
 	}
 
-	if !admin.IsAdmin() {
-		return ErrPermissionDenied
-	}
-
-	if adminID == targetUserID {
-		return errors.New("cannot delete your own account")
-	}
-
-	return s.userRepo.Delete(targetUserID)
+	s.notifyUpdated(targetUser)
+	return nil
 }
 
 func (s *UserService) GetUserStats(userID primitive.ObjectID) (map[string]interface{}, error) {
@@ -145,24 +192,5 @@ func (s *UserService) GetUserStats(userID primitive.ObjectID) (map[string]interf
 }
 
 func (s *UserService) SearchUsers(query string, limit int) ([]*models.User, error) {
-	allUsers, err := s.userRepo.FindAll(1000, 0)
-	if err != nil {
-		return nil, err
-	}
-
-	var filteredUsers []*models.User
-	query = strings.ToLower(query)
-
-	for _, user := range allUsers {
-		if strings.Contains(strings.ToLower(user.Email), query) ||
-			strings.Contains(strings.ToLower(user.DisplayName), query) {
-			filteredUsers = append(filteredUsers, user)
-		}
-
-		if len(filteredUsers) >= limit {
-			break
-		}
-	}
-
-	return filteredUsers, nil
+	return s.userRepo.Search(query, limit)
 }
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+// Viewer identifies who is looking at a post. UserID is nil for anonymous
+// visitors, who are identified by a salted hash of IP and User-Agent.
+type Viewer struct {
+	UserID    *primitive.ObjectID
+	IP        string
+	UserAgent string
+	Prefetch  bool
+}
+
+// ViewCounter deduplicates post views within a window and batches the
+// resulting counter increments into periodic bulk writes.
+type ViewCounter struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	pending map[primitive.ObjectID]int
+	total   int
+
+	postRepo repository.PostRepository
+	cfg      config.ViewConfig
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+func NewViewCounter(postRepo repository.PostRepository, cfg config.ViewConfig) *ViewCounter {
+	if cfg.DedupWindow <= 0 {
+		cfg.DedupWindow = 30 * time.Minute
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 10 * time.Second
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 500
+	}
+	if cfg.DedupMaxEntries <= 0 {
+		cfg.DedupMaxEntries = 100000
+	}
+
+	vc := &ViewCounter{
+		seen:     make(map[string]time.Time),
+		pending:  make(map[primitive.ObjectID]int),
+		postRepo: postRepo,
+		cfg:      cfg,
+		flushCh:  make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+
+	go vc.run()
+
+	return vc
+}
+
+// RecordView counts a view unless the viewer is a bot, the request is a
+// prefetch, or the same viewer already saw the post within the window.
+// Once DedupMaxEntries viewers are remembered, new ones are counted
+// without being remembered until cleanup frees room, so clients rotating
+// their User-Agent cannot grow memory without bound.
+func (vc *ViewCounter) RecordView(postID primitive.ObjectID, viewer Viewer) bool {
+	if viewer.Prefetch || vc.IsBot(viewer.UserAgent) {
+		return false
+	}
+
+	key := vc.viewerKey(viewer) + ":" + postID.Hex()
+	now := time.Now()
+
+	vc.mu.Lock()
+	if expiry, ok := vc.seen[key]; ok && expiry.After(now) {
+		vc.mu.Unlock()
+		return false
+	}
+	if len(vc.seen) < vc.cfg.DedupMaxEntries {
+		vc.seen[key] = now.Add(vc.cfg.DedupWindow)
+	}
+	vc.pending[postID]++
+	vc.total++
+	full := vc.total >= vc.cfg.BufferSize
+	vc.mu.Unlock()
+
+	if full {
+		select {
+		case vc.flushCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return true
+}
+
+// IsBot reports whether the User-Agent looks like a crawler, link preview
+// or scripted client. Empty user agents are treated as bots.
+func (vc *ViewCounter) IsBot(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+	for _, marker := range vc.cfg.BotUserAgents {
+		if strings.Contains(ua, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop flushes pending counts and stops the background loop.
+func (vc *ViewCounter) Stop() {
+	close(vc.stopCh)
+	<-vc.doneCh
+}
+
+func (vc *ViewCounter) viewerKey(viewer Viewer) string {
+	if viewer.UserID != nil {
+		return "u:" + viewer.UserID.Hex()
+	}
+	hash := sha256.Sum256([]byte(vc.cfg.HashSalt + "|" + viewer.IP + "|" + viewer.UserAgent))
+	return "a:" + hex.EncodeToString(hash[:16])
+}
+
+func (vc *ViewCounter) run() {
+	defer close(vc.doneCh)
+
+	ticker := time.NewTicker(vc.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(time.Minute)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			vc.flush()
+		case <-vc.flushCh:
+			vc.flush()
+		case <-cleanup.C:
+			vc.Cleanup()
+		case <-vc.stopCh:
+			vc.flush()
+			return
+		}
+	}
+}
+
+func (vc *ViewCounter) flush() {
+	vc.mu.Lock()
+	if len(vc.pending) == 0 {
+		vc.mu.Unlock()
+		return
+	}
+	batch := vc.pending
+	vc.pending = make(map[primitive.ObjectID]int)
+	vc.total = 0
+	vc.mu.Unlock()
+
+	if err := vc.postRepo.IncrementViewCounts(batch); err != nil {
+		log.Printf("Failed to flush %d view counters: %v", len(batch), err)
+		vc.requeue(batch)
+	}
+}
+
+// requeue adds counts that failed to reach Mongo to the ones recorded
+// since, so they go out with the next write.
+func (vc *ViewCounter) requeue(batch map[primitive.ObjectID]int) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	for id, count := range batch {
+		vc.pending[id] += count
+		vc.total += count
+	}
+}
+
+// Cleanup removes expired dedup entries
+func (vc *ViewCounter) Cleanup() {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	now := time.Now()
+	for key, expiry := range vc.seen {
+		if !expiry.After(now) {
+			delete(vc.seen, key)
+		}
+	}
+}
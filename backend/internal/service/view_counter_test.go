@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/config"
+	"github.com/Yeras1kAITU/aitu_fanpage/internal/repository"
+)
+
+const browserUA = "Mozilla/5.0 (X11; Linux x86_64) Firefox/125.0"
+
+type viewCountRepo struct {
+	repository.PostRepository
+	mu     sync.Mutex
+	fail   bool
+	counts map[primitive.ObjectID]int
+}
+
+func (r *viewCountRepo) IncrementViewCounts(counts map[primitive.ObjectID]int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("mongo unavailable")
+	}
+	if r.counts == nil {
+		r.counts = make(map[primitive.ObjectID]int)
+	}
+	for id, count := range counts {
+		r.counts[id] += count
+	}
+	return nil
+}
+
+func newTestViewCounter(repo *viewCountRepo) *ViewCounter {
+	return NewViewCounter(repo, config.ViewConfig{
+		DedupWindow:   time.Hour,
+		FlushInterval: time.Hour,
+		BufferSize:    1000,
+		HashSalt:      "salt",
+		BotUserAgents: []string{"bot", "curl", "facebookexternalhit"},
+	})
+}
+
+func TestViewCounterDedupesViewers(t *testing.T) {
+	vc := newTestViewCounter(&viewCountRepo{})
+	defer vc.Stop()
+
+	post := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+	anon := Viewer{IP: "198.51.100.1", UserAgent: browserUA}
+
+	if !vc.RecordView(post, anon) {
+		t.Fatal("first anonymous view not counted")
+	}
+	if vc.RecordView(post, anon) {
+		t.Error("repeat anonymous view counted")
+	}
+	if !vc.RecordView(other, anon) {
+		t.Error("view of another post not counted")
+	}
+	if !vc.RecordView(post, Viewer{IP: "198.51.100.2", UserAgent: browserUA}) {
+		t.Error("view from another IP not counted")
+	}
+
+	// Signed-in viewers are the same person from any address
+	userID := primitive.NewObjectID()
+	if !vc.RecordView(post, Viewer{UserID: &userID, IP: "198.51.100.1", UserAgent: browserUA}) {
+		t.Error("first signed-in view not counted")
+	}
+	if vc.RecordView(post, Viewer{UserID: &userID, IP: "203.0.113.9", UserAgent: "Other/1.0"}) {
+		t.Error("signed-in view from a new address counted again")
+	}
+}
+
+func TestViewCounterCountsAgainAfterWindow(t *testing.T) {
+	vc := newTestViewCounter(&viewCountRepo{})
+	defer vc.Stop()
+	vc.cfg.DedupWindow = time.Millisecond
+
+	post := primitive.NewObjectID()
+	viewer := Viewer{IP: "198.51.100.1", UserAgent: browserUA}
+	vc.RecordView(post, viewer)
+	time.Sleep(5 * time.Millisecond)
+
+	vc.Cleanup()
+	vc.mu.Lock()
+	remaining := len(vc.seen)
+	vc.mu.Unlock()
+	if remaining != 0 {
+		t.Errorf("%d dedup entries left after cleanup, want 0", remaining)
+	}
+	if !vc.RecordView(post, viewer) {
+		t.Error("view after the window not counted")
+	}
+}
+
+func TestViewCounterSkipsBots(t *testing.T) {
+	vc := newTestViewCounter(&viewCountRepo{})
+	defer vc.Stop()
+
+	tests := []struct {
+		name   string
+		viewer Viewer
+	}{
+		{"empty user agent", Viewer{IP: "198.51.100.1"}},
+		{"blank user agent", Viewer{IP: "198.51.100.1", UserAgent: "   "}},
+		{"crawler", Viewer{IP: "198.51.100.1", UserAgent: "Mozilla/5.0 (compatible; Googlebot/2.1)"}},
+		{"link preview", Viewer{IP: "198.51.100.1", UserAgent: "facebookexternalhit/1.1"}},
+		{"script", Viewer{IP: "198.51.100.1", UserAgent: "curl/8.5.0"}},
+		{"prefetch", Viewer{IP: "198.51.100.1", UserAgent: browserUA, Prefetch: true}},
+	}
+	for _, tt := range tests {
+		if vc.RecordView(primitive.NewObjectID(), tt.viewer) {
+			t.Errorf("%s: view counted", tt.name)
+		}
+	}
+}
+
+func TestViewCounterFlushRetriesFailedBatch(t *testing.T) {
+	repo := &viewCountRepo{fail: true}
+	vc := newTestViewCounter(repo)
+
+	post := primitive.NewObjectID()
+	vc.RecordView(post, Viewer{IP: "198.51.100.1", UserAgent: browserUA})
+	vc.RecordView(post, Viewer{IP: "198.51.100.2", UserAgent: browserUA})
+	vc.flush()
+
+	vc.mu.Lock()
+	pending := vc.pending[post]
+	vc.mu.Unlock()
+	if pending != 2 {
+		t.Fatalf("pending after failed flush = %d, want 2", pending)
+	}
+
+	repo.mu.Lock()
+	repo.fail = false
+	repo.mu.Unlock()
+	vc.RecordView(post, Viewer{IP: "198.51.100.3", UserAgent: browserUA})
+	vc.Stop()
+
+	if got := repo.counts[post]; got != 3 {
+		t.Errorf("flushed views = %d, want 3", got)
+	}
+}
+
+func TestViewCounterCapsRememberedViewers(t *testing.T) {
+	vc := newTestViewCounter(&viewCountRepo{})
+	defer vc.Stop()
+	vc.cfg.DedupMaxEntries = 3
+
+	// A client rotating its User-Agent is a new viewer every time
+	post := primitive.NewObjectID()
+	for i := 0; i < 10; i++ {
+		viewer := Viewer{IP: "198.51.100.1", UserAgent: browserUA + " r" + string(rune('a'+i))}
+		if !vc.RecordView(post, viewer) {
+			t.Fatalf("view %d not counted", i)
+		}
+	}
+
+	vc.mu.Lock()
+	remembered, pending := len(vc.seen), vc.pending[post]
+	vc.mu.Unlock()
+	if remembered != 3 {
+		t.Errorf("%d viewers remembered, want the cap of 3", remembered)
+	}
+	if pending != 10 {
+		t.Errorf("%d views pending, want 10", pending)
+	}
+
+	// Viewers remembered before the cap are still deduplicated
+	if vc.RecordView(post, Viewer{IP: "198.51.100.1", UserAgent: browserUA + " ra"}) {
+		t.Error("remembered viewer counted again")
+	}
+}
@@ -448,7 +448,7 @@ function initAdminEventListeners() {
         // Delete user
         if (e.target.closest('.delete-btn')) {
             const userId = e.target.closest('.delete-btn').dataset.userId;
-            if (confirm('Are you sure you want to delete this user? This action cannot be undone.')) {
+            if (confirm('Are you sure you want to delete this user? Their posts and comments will be kept as "Deleted user". This action cannot be undone.')) {
                 try {
                     await adminManager.deleteUser(userId);
                     await loadUsers();
@@ -496,7 +496,7 @@ function initAdminEventListeners() {
     modalDelete.addEventListener('click', async () => {
         const userId = document.getElementById('edit-user-id').value;
 
-        if (confirm('Are you sure you want to delete this user? This action cannot be undone.')) {
+        if (confirm('Are you sure you want to delete this user? Their posts and comments will be kept as "Deleted user". This action cannot be undone.')) {
             try {
                 await adminManager.deleteUser(userId);
                 closeModal();
@@ -19,7 +19,7 @@ class AuthManager {
     clearUser() {
         this.currentUser = null;
         localStorage.removeItem('user');
-        localStorage.removeItem('token');
+        removeAuthToken();
     }
 
     async login(email, password) {
@@ -33,13 +33,16 @@ class AuthManager {
             });
 
             if (!response.ok) {
-                const error = await response.json();
-                throw new Error(error.message || 'Login failed');
+                // Errors are plain text, e.g. the lockout notice after too many failures
+                const message = (await response.text()).trim();
+                throw new Error(message || 'Login failed');
             }
 
             const data = await response.json();
-            setAuthToken(data.token);
-            this.saveUserToStorage(data.user);
+            if (data.two_factor_required) {
+                return { twoFactorRequired: true, challengeToken: data.challenge_token };
+            }
+            this.setSession(data);
             return data.user;
         } catch (error) {
             console.error('Login error:', error);
@@ -47,6 +50,46 @@ class AuthManager {
         }
     }
 
+    async completeTwoFactorLogin(challengeToken, code) {
+        const response = await fetch(`${API_BASE}/api/auth/login/2fa`, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({ challenge_token: challengeToken, code })
+        });
+
+        if (!response.ok) {
+            const error = new Error((await response.text()).trim() || 'Verification failed');
+            error.status = response.status;
+            throw error;
+        }
+
+        const data = await response.json();
+        this.setSession(data);
+        return data.user;
+    }
+
+    // completeSingleSignOn stores the tokens from the SSO callback and loads
+    // the profile they belong to.
+    async completeSingleSignOn(token, refreshToken) {
+        setAuthToken(token);
+        setRefreshToken(refreshToken);
+
+        const response = await fetchWithAuth('/api/users/me');
+        if (!response.ok) {
+            this.clearUser();
+            throw new Error('Sign-in failed');
+        }
+        this.saveUserToStorage(await response.json());
+    }
+
+    setSession(data) {
+        setAuthToken(data.token);
+        setRefreshToken(data.refresh_token);
+        this.saveUserToStorage(data.user);
+    }
+
     async register(userData) {
         try {
             const response = await fetch(`${API_BASE}/api/auth/register`, {
@@ -64,6 +107,7 @@ class AuthManager {
 
             const data = await response.json();
             setAuthToken(data.token);
+            setRefreshToken(data.refresh_token);
             this.saveUserToStorage(data.user);
             return data.user;
         } catch (error) {
@@ -74,7 +118,13 @@ class AuthManager {
 
     async logout() {
         try {
-            await fetchWithAuth('/api/auth/logout', { method: 'POST' });
+            await fetch(`${API_BASE}/api/auth/logout`, {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: JSON.stringify({ refresh_token: getRefreshToken() })
+            });
         } catch (error) {
         } finally {
             this.clearUser();
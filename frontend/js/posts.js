@@ -295,6 +295,16 @@ class PostManager {
         }
     }
 
+    async getRelatedPosts(id, limit = 5) {
+        try {
+            const response = await fetchWithAuth(`/api/posts/${id}/related?limit=${limit}`);
+            return response.json();
+        } catch (error) {
+            console.error('Get related posts error:', error);
+            throw error;
+        }
+    }
+
     async getCategoriesStats() {
         try {
             const response = await fetchWithAuth('/api/posts/categories/stats');
@@ -526,7 +536,7 @@ function initPostEventListeners() {
         if (e.target.closest('.share-btn')) {
             const postCard = e.target.closest('.post-card');
             const postId = postCard.dataset.postId;
-            const url = `${window.location.origin}/post-detail.html?id=${postId}`;
+            const url = `${window.location.origin}/p/${postId}`;
 
             navigator.clipboard.writeText(url)
                 .then(() => showNotification('Link copied to clipboard!', 'success'))
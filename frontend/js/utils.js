@@ -78,6 +78,51 @@ function setAuthToken(token) {
 
 function removeAuthToken() {
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
+}
+
+function getRefreshToken() {
+    return localStorage.getItem('refresh_token');
+}
+
+function setRefreshToken(token) {
+    localStorage.setItem('refresh_token', token);
+}
+
+let refreshPromise = null;
+
+// refreshAuthToken trades the stored refresh token for a new pair. Parallel
+// callers share one request, since each refresh token is single-use.
+function refreshAuthToken() {
+    const refreshToken = getRefreshToken();
+    if (!refreshToken) {
+        return Promise.resolve(false);
+    }
+
+    if (!refreshPromise) {
+        refreshPromise = fetch(`${API_BASE}/api/auth/refresh`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken })
+        })
+            .then(async response => {
+                if (!response.ok) {
+                    return false;
+                }
+                const data = await response.json();
+                setAuthToken(data.token);
+                setRefreshToken(data.refresh_token);
+                if (data.user) {
+                    localStorage.setItem('user', JSON.stringify(data.user));
+                }
+                return true;
+            })
+            .catch(() => false)
+            .finally(() => {
+                refreshPromise = null;
+            });
+    }
+    return refreshPromise;
 }
 
 function getUserRole() {
@@ -94,12 +139,12 @@ function isModerator() {
     return role === 'admin' || role === 'moderator';
 }
 
-async function fetchWithAuth(url, options = {}) {
+async function fetchWithAuth(url, options = {}, retried = false) {
     const token = getAuthToken();
-    const headers = {
-        'Content-Type': 'application/json',
-        ...options.headers
-    };
+    // The browser sets the multipart boundary for form data itself
+    const headers = options.body instanceof FormData
+        ? { ...options.headers }
+        : { 'Content-Type': 'application/json', ...options.headers };
 
     if (token) {
         headers['Authorization'] = `Bearer ${token}`;
@@ -111,6 +156,9 @@ async function fetchWithAuth(url, options = {}) {
     });
 
     if (response.status === 401) {
+        if (!retried && await refreshAuthToken()) {
+            return fetchWithAuth(url, options, true);
+        }
         removeAuthToken();
         localStorage.removeItem('user');
         window.location.href = '/login.html';
@@ -118,6 +166,11 @@ async function fetchWithAuth(url, options = {}) {
     }
 
     if (response.status === 403) {
+        const body = await response.clone().json().catch(() => null);
+        if (body && body.code === 'two_factor_required') {
+            window.location.href = '/two-factor.html';
+            throw new Error(body.message);
+        }
         showNotification('Permission denied', 'error');
         throw new Error('Permission denied');
     }